@@ -11,14 +11,24 @@ import (
 
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/api-gateway/internal/config"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/api-gateway/internal/server"
+	sharedconfig "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/config"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/httpclient"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/httpserver"
 	sharedlogger "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/logger"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/metrics"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/tlsconfig"
 	sharedtracing "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/tracing"
+	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
 )
 
 func main() {
 	// Load configuration
-	cfg, err := config.LoadConfig()
+	loader, err := config.NewLoader()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	cfg, err := config.Build(loader)
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
@@ -30,11 +40,22 @@ func main() {
 		Service:     cfg.Service.Name,
 		Version:     cfg.Service.Version,
 		OutputPaths: cfg.Logger.OutputPaths,
+		Sampling: &sharedlogger.SamplingConfig{
+			Initial:    cfg.Logger.Sampling.Initial,
+			Thereafter: cfg.Logger.Sampling.Thereafter,
+			Tick:       cfg.Logger.Sampling.Tick,
+		},
+		Redaction: &sharedlogger.RedactionConfig{
+			Mode:    cfg.Logger.Redaction.Mode,
+			Fields:  cfg.Logger.Redaction.Fields,
+			HashKey: cfg.Logger.Redaction.HashKey.Value(),
+		},
 	})
 	if err != nil {
 		log.Fatalf("Failed to initialize logger: %v", err)
 	}
 	defer func() { _ = appLogger.Sync() }()
+	prometheus.MustRegister(metrics.NewLoggerMetrics(appLogger, cfg.Service.Name))
 	logger := appLogger.Logger
 
 	logger.Info("Starting API Gateway service...")
@@ -60,15 +81,61 @@ func main() {
 		}
 	}()
 
+	internalTLS, err := tlsconfig.Load(cfg.InternalTLS, logger)
+	if err != nil {
+		logger.Fatal("Failed to load internal TLS files", zap.Error(err))
+	}
+
 	// Create and start server
 	srv, err := server.NewServer(server.Options{
-		Config: cfg,
-		Logger: logger,
+		Config:    cfg,
+		Logger:    logger,
+		Transport: httpclient.NewTransport(internalTLS),
 	})
 	if err != nil {
 		logger.Fatal("Failed to create server", zap.Error(err))
 	}
 
+	// Apply configuration changes without a restart. Only rate limits, circuit breakers, the JWT
+	// secret and the log level are reloadable; other keys take effect on the next start.
+	watcher := sharedconfig.NewWatcher(loader, cfg, config.Build, logger)
+	watcher.SetRefreshInterval(time.Duration(cfg.SecretRefreshInterval) * time.Second)
+	sharedconfig.OnChange(watcher, func(c *config.Config) config.RateLimitConfig { return c.RateLimit }, func(rl config.RateLimitConfig) {
+		srv.UpdateRateLimit(rl)
+		logger.Info("Rate limit updated",
+			zap.Int("requests_per_minute", rl.RequestsPerMinute),
+			zap.Int("window_duration_seconds", rl.WindowDuration))
+	})
+	sharedconfig.OnChange(watcher, func(c *config.Config) config.CircuitBreakerConfig { return c.CircuitBreaker }, func(cb config.CircuitBreakerConfig) {
+		srv.UpdateCircuitBreaker(cb)
+		logger.Info("Circuit breakers reconfigured",
+			zap.Int("failure_threshold", cb.FailureThreshold),
+			zap.Int("window_seconds", cb.WindowSeconds),
+			zap.Int("open_timeout_seconds", cb.OpenTimeoutSeconds))
+	})
+	sharedconfig.OnChange(watcher, func(c *config.Config) sharedconfig.Secret { return c.JWTSecret }, func(secret sharedconfig.Secret) {
+		srv.UpdateJWTSecret(secret.Value())
+		logger.Info("JWT secret rotated")
+	})
+	sharedconfig.OnChange(watcher, func(c *config.Config) string { return c.Logger.Level }, func(level string) {
+		if err := appLogger.SetLevel(level); err != nil {
+			logger.Error("Ignoring invalid log level", zap.String("level", level), zap.Error(err))
+			return
+		}
+		logger.Info("Log level updated", zap.String("level", level))
+	})
+
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	if internalTLS != nil {
+		go internalTLS.Run(watchCtx)
+	}
+	go func() {
+		if err := watcher.Run(watchCtx); err != nil {
+			logger.Error("Configuration hot reload disabled", zap.Error(err))
+		}
+	}()
+
 	// Setup graceful shutdown
 	done := make(chan os.Signal, 1)
 	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
@@ -80,11 +147,30 @@ func main() {
 		}
 	}()
 
+	// The admin listener serves pprof, metrics, the log level and build info on a port the
+	// gateway's own routes never reach.
+	var admin *httpserver.AdminServer
+	if cfg.Server.AdminPort != "" {
+		admin = httpserver.NewAdmin(httpserver.AdminOptions{
+			Addr:     cfg.Server.Host + ":" + cfg.Server.AdminPort,
+			Logger:   logger,
+			LogLevel: appLogger,
+			Service:  cfg.Service.Name,
+			Version:  cfg.Service.Version,
+		})
+		go func() {
+			if err := admin.Start(); err != nil && err != http.ErrServerClosed {
+				logger.Error("Admin server failed", zap.Error(err))
+			}
+		}()
+	}
+
 	logger.Info("API Gateway started successfully")
 
 	// Wait for interrupt signal
 	<-done
 	logger.Info("API Gateway is shutting down...")
+	stopWatching()
 
 	// Create shutdown context with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -96,4 +182,9 @@ func main() {
 	} else {
 		logger.Info("API Gateway stopped gracefully")
 	}
+	if admin != nil {
+		if err := admin.Stop(ctx); err != nil {
+			logger.Error("Admin server forced to shutdown", zap.Error(err))
+		}
+	}
 }
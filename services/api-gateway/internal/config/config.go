@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"net/url"
 
@@ -19,10 +20,13 @@ type Config struct {
 	PaymentServiceURL      string                `mapstructure:"payment_service_url"`
 	InventoryServiceURL    string                `mapstructure:"inventory_service_url"`
 	NotificationServiceURL string                `mapstructure:"notification_service_url"`
-	JWTSecret              string                `mapstructure:"jwt_secret"`
+	JWTSecret              config.Secret         `mapstructure:"jwt_secret"`
 	RateLimit              RateLimitConfig       `mapstructure:"rate_limit"`
 	CircuitBreaker         CircuitBreakerConfig  `mapstructure:"circuit_breaker"`
 	ProxyTimeout           int                   `mapstructure:"proxy_timeout_seconds"`
+	SecretRefreshInterval  int                   `mapstructure:"secret_refresh_interval_seconds"`
+	// InternalTLS is used by the reverse proxy when backend URLs are https://.
+	InternalTLS config.TLSConfig `mapstructure:"internal_tls"`
 }
 
 type RateLimitConfig struct {
@@ -68,20 +72,44 @@ func (c CircuitBreakerConfig) Validate() error {
 	return nil
 }
 
+// LoadConfig builds the gateway configuration once. Use NewLoader and Build instead when the
+// configuration is to be reloaded later.
 func LoadConfig() (*Config, error) {
-	var cfg Config
+	loader, err := NewLoader()
+	if err != nil {
+		return nil, err
+	}
+	return Build(loader)
+}
 
+// NewLoader returns a loader with the gateway's defaults and environment bindings. Besides the
+// config file it reads API_GATEWAY_CONFIG_DIR, a key-per-file directory such as a mounted
+// ConfigMap.
+func NewLoader() (*config.CfgLoader, error) {
 	loader := config.New("api_gateway")
 	loader.SetDefault("server.host", "0.0.0.0")
+	loader.SetDefault("server.admin_port", "9091")
+	loader.SetDefault("internal_tls.cert_file", "")
+	loader.SetDefault("internal_tls.key_file", "")
+	loader.SetDefault("internal_tls.ca_file", "")
+	loader.SetDefault("internal_tls.server_name", "")
+	loader.SetDefault("internal_tls.reload_interval", "1m")
 	loader.SetDefault("rate_limit.requests_per_minute", 100)
 	loader.SetDefault("rate_limit.window_duration_seconds", 60)
 	loader.SetDefault("circuit_breaker.failure_threshold", 5)
 	loader.SetDefault("circuit_breaker.window_seconds", 60)
 	loader.SetDefault("circuit_breaker.open_timeout_seconds", 30)
 	loader.SetDefault("proxy_timeout_seconds", 30)
+	loader.SetDefault("secret_refresh_interval_seconds", 300)
 	loader.SetDefault("logger.level", "info")
 	loader.SetDefault("logger.environment", "development")
 	loader.SetDefault("logger.output_paths", []string{"stdout"})
+	loader.SetDefault("logger.sampling.initial", 100)
+	loader.SetDefault("logger.sampling.thereafter", 100)
+	loader.SetDefault("logger.sampling.tick", "1s")
+	loader.SetDefault("logger.redaction.mode", "mask")
+	loader.SetDefault("logger.redaction.fields", []string{})
+	loader.SetDefault("logger.redaction.hash_key", "")
 	loader.SetDefault("service.name", "api-gateway")
 	loader.SetDefault("service.version", "1.0.0")
 
@@ -116,28 +144,39 @@ func LoadConfig() (*Config, error) {
 	if err := loader.BindEnv("jwt_secret", "JWT_SECRET"); err != nil {
 		return nil, fmt.Errorf("failed to bind jwt_secret: %w", err)
 	}
+	return loader, nil
+}
 
-	err := loader.Load(&cfg)
-	if err != nil {
+// Build loads and validates the configuration from loader. It re-reads the config sources on
+// every call, so it also serves as the reload step of a config.Watcher.
+func Build(loader *config.CfgLoader) (*Config, error) {
+	var cfg Config
+	if err := loader.Load(&cfg); err != nil {
 		return nil, err
 	}
 
-	// Get the database URL string directly from viper
-	dbURLString := loader.GetString("database.url")
+	// Get the database URL string directly from viper; it may be a secret reference
+	dbURL, err := loader.Secret("database.url")
+	if err != nil {
+		return nil, err
+	}
+	dbURLString := dbURL.Value()
 	if dbURLString == "" {
 		return nil, fmt.Errorf("API_GATEWAY_DATABASE_URL environment variable is not set")
 	}
 
 	parsedURL, err := url.Parse(dbURLString)
 	if err != nil {
-		return nil, fmt.Errorf("invalid API_GATEWAY_DATABASE_URL: %w", err)
+		// url.Error repeats the URL, password included, so only its cause is reported.
+		return nil, fmt.Errorf("invalid API_GATEWAY_DATABASE_URL: %w", errors.Unwrap(err))
 	}
 
 	// Populate DatabaseConfig fields from parsed URL
 	cfg.Database.Host = parsedURL.Hostname()
 	cfg.Database.Port = parsedURL.Port()
 	cfg.Database.User = parsedURL.User.Username()
-	cfg.Database.Password, _ = parsedURL.User.Password()
+	password, _ := parsedURL.User.Password()
+	cfg.Database.Password = config.Secret(password)
 	cfg.Database.DBName = parsedURL.Path[1:] // Remove leading slash
 	cfg.Database.SSLMode = parsedURL.Query().Get("sslmode")
 
@@ -154,6 +193,12 @@ func (c *Config) Validate() error {
 	if c.Server.Port == "" {
 		return fmt.Errorf("API_GATEWAY_SERVER_PORT (or API_GATEWAY_PORT) environment variable is not set")
 	}
+	if err := c.Server.Validate(); err != nil {
+		return fmt.Errorf("server configuration invalid: %w", err)
+	}
+	if err := c.InternalTLS.Validate(); err != nil {
+		return fmt.Errorf("internal TLS configuration invalid: %w", err)
+	}
 
 	// Validation for Redis
 	if c.Redis.URL == "" {
@@ -213,6 +258,10 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("circuit breaker configuration invalid: %w", err)
 	}
 
+	if c.SecretRefreshInterval < 0 {
+		return fmt.Errorf("secret refresh interval must not be negative, got %d", c.SecretRefreshInterval)
+	}
+
 	// Final validation of database fields after parsing (which happens in LoadConfig)
 	if c.Database.Host == "" {
 		return fmt.Errorf("database host is required in API_GATEWAY_DATABASE_URL")
@@ -255,7 +304,7 @@ func (c *Config) validateJWTSecret() error {
 	}
 
 	for _, weak := range weakSecrets {
-		if c.JWTSecret == weak {
+		if c.JWTSecret.Value() == weak {
 			return fmt.Errorf("JWT_SECRET appears to be a default/weak value. Please generate a strong secret using: openssl rand -base64 32")
 		}
 	}
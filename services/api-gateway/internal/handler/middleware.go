@@ -2,15 +2,17 @@ package handler
 
 import (
 	"context"
-	"encoding/json"
 	"net/http"
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	apperrors "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/errors"
 	"github.com/golang-jwt/jwt/v5"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	"golang.org/x/time/rate"
 )
 
@@ -51,6 +53,17 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
+// MarshalLogObject lets claims be logged with zap.Object without the email, which is personal
+// data the log redaction cannot see inside an object.
+func (c Claims) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("user_id", c.UserID)
+	enc.AddString("role", c.Role)
+	if c.ExpiresAt != nil {
+		enc.AddTime("expires_at", c.ExpiresAt.Time)
+	}
+	return nil
+}
+
 // defaultCleanupInterval is used when NewRateLimiter is called with a non-positive
 // windowDuration.
 const defaultCleanupInterval = 5 * time.Minute
@@ -63,6 +76,7 @@ type RateLimiter struct {
 	burst           int
 	maxClients      int
 	cleanupInterval time.Duration
+	cleanupReset    chan struct{}
 	done            chan struct{}
 }
 
@@ -70,6 +84,25 @@ type RateLimiter struct {
 // sets how often the stale-client cleanup goroutine runs; a non-positive value falls back to
 // defaultCleanupInterval.
 func NewRateLimiter(requestsPerMinute int, windowDuration time.Duration) *RateLimiter {
+	rateLimit, burstSize := rateAndBurst(requestsPerMinute)
+
+	rl := &RateLimiter{
+		limiters:        make(map[string]*rate.Limiter),
+		rate:            rateLimit,
+		burst:           burstSize,
+		maxClients:      10000,
+		cleanupInterval: cleanupIntervalFor(windowDuration),
+		cleanupReset:    make(chan struct{}, 1),
+		done:            make(chan struct{}),
+	}
+
+	// Start cleanup goroutine
+	go rl.cleanup()
+	return rl
+}
+
+// rateAndBurst converts a per-minute request allowance into a token bucket refill rate and burst.
+func rateAndBurst(requestsPerMinute int) (rate.Limit, int) {
 	if requestsPerMinute <= 0 {
 		requestsPerMinute = 1 // Minimum rate to avoid division by zero
 	}
@@ -84,24 +117,39 @@ func NewRateLimiter(requestsPerMinute int, windowDuration time.Duration) *RateLi
 	if burstSize > 10 {
 		burstSize = requestsPerMinute/3 + 2
 	}
+	return rateLimit, burstSize
+}
 
-	cleanupInterval := windowDuration
-	if cleanupInterval <= 0 {
-		cleanupInterval = defaultCleanupInterval
+func cleanupIntervalFor(windowDuration time.Duration) time.Duration {
+	if windowDuration <= 0 {
+		return defaultCleanupInterval
 	}
+	return windowDuration
+}
 
-	rl := &RateLimiter{
-		limiters:        make(map[string]*rate.Limiter),
-		rate:            rateLimit,
-		burst:           burstSize,
-		maxClients:      10000,
-		cleanupInterval: cleanupInterval,
-		done:            make(chan struct{}),
+// Update changes the limits, as NewRateLimiter would set them, without dropping tracked clients:
+// their buckets keep the tokens they hold and refill at the new rate from now on.
+func (rl *RateLimiter) Update(requestsPerMinute int, windowDuration time.Duration) {
+	rateLimit, burstSize := rateAndBurst(requestsPerMinute)
+	interval := cleanupIntervalFor(windowDuration)
+
+	rl.mutex.Lock()
+	rl.rate = rateLimit
+	rl.burst = burstSize
+	for _, limiter := range rl.limiters {
+		limiter.SetLimit(rateLimit)
+		limiter.SetBurst(burstSize)
 	}
+	intervalChanged := interval != rl.cleanupInterval
+	rl.cleanupInterval = interval
+	rl.mutex.Unlock()
 
-	// Start cleanup goroutine
-	go rl.cleanup()
-	return rl
+	if intervalChanged {
+		select {
+		case rl.cleanupReset <- struct{}{}:
+		default:
+		}
+	}
 }
 
 // Allow checks if a request should be allowed for the given client
@@ -154,7 +202,7 @@ func (rl *RateLimiter) evictOldestClients() {
 
 // cleanup periodically removes inactive limiters
 func (rl *RateLimiter) cleanup() {
-	ticker := time.NewTicker(rl.cleanupInterval)
+	ticker := time.NewTicker(rl.currentCleanupInterval())
 
 	defer func() {
 		ticker.Stop()
@@ -171,6 +219,9 @@ func (rl *RateLimiter) cleanup() {
 			}
 			rl.mutex.Unlock()
 
+		case <-rl.cleanupReset:
+			ticker.Reset(rl.currentCleanupInterval())
+
 		case <-rl.done:
 			// Properly stop ticker and exit
 			return
@@ -178,6 +229,12 @@ func (rl *RateLimiter) cleanup() {
 	}
 }
 
+func (rl *RateLimiter) currentCleanupInterval() time.Duration {
+	rl.mutex.RLock()
+	defer rl.mutex.RUnlock()
+	return rl.cleanupInterval
+}
+
 // Close stops the cleanup goroutine safely
 func (rl *RateLimiter) Close() {
 	// Use sync.Once pattern to prevent double-close panic
@@ -205,13 +262,8 @@ func RateLimitMiddleware(rateLimiter *RateLimiter, metrics *Metrics) func(http.H
 			}
 
 			if !allowed {
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusTooManyRequests)
-
-				if err := json.NewEncoder(w).Encode(map[string]string{
-					"error": "Rate limit exceeded",
-				}); err != nil {
-					// Log encoding error using http.Error as fallback
+				if err := apperrors.WriteProblem(w, r, apperrors.NewTooManyRequests("Rate limit exceeded")); err != nil {
+					// Fall back to a plain text error if the problem document cannot be encoded
 					http.Error(w, "Internal server error", http.StatusInternalServerError)
 				}
 				return
@@ -222,8 +274,32 @@ func RateLimitMiddleware(rateLimiter *RateLimiter, metrics *Metrics) func(http.H
 	}
 }
 
+// JWTSecret holds the HMAC key JWTMiddleware verifies tokens with. Set replaces it while requests
+// are being served, so a rotated secret takes effect without a restart; tokens signed with the
+// previous secret are rejected from then on.
+type JWTSecret struct {
+	key atomic.Pointer[[]byte]
+}
+
+// NewJWTSecret returns a JWTSecret holding secret.
+func NewJWTSecret(secret string) *JWTSecret {
+	s := &JWTSecret{}
+	s.Set(secret)
+	return s
+}
+
+// Set replaces the key.
+func (s *JWTSecret) Set(secret string) {
+	key := []byte(secret)
+	s.key.Store(&key)
+}
+
+func (s *JWTSecret) bytes() []byte {
+	return *s.key.Load()
+}
+
 // JWTMiddleware creates a JWT authentication middleware
-func JWTMiddleware(secret string, logger *zap.Logger, metrics *Metrics) func(http.Handler) http.Handler {
+func JWTMiddleware(secret *JWTSecret, logger *zap.Logger, metrics *Metrics) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Skip authentication for public endpoints with secure path checking
@@ -244,14 +320,14 @@ func JWTMiddleware(secret string, logger *zap.Logger, metrics *Metrics) func(htt
 			authHeader := r.Header.Get("Authorization")
 			if authHeader == "" {
 				result = "missing_header"
-				writeJWTError(w, "Missing Authorization header", http.StatusUnauthorized)
+				writeJWTError(w, r, "Missing Authorization header")
 				return
 			}
 
 			// Check Bearer format
 			if !strings.HasPrefix(authHeader, "Bearer ") {
 				result = "invalid_format"
-				writeJWTError(w, "Invalid Authorization header format", http.StatusUnauthorized)
+				writeJWTError(w, r, "Invalid Authorization header format")
 				return
 			}
 
@@ -265,26 +341,26 @@ func JWTMiddleware(secret string, logger *zap.Logger, metrics *Metrics) func(htt
 				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 					return nil, jwt.ErrSignatureInvalid
 				}
-				return []byte(secret), nil
+				return secret.bytes(), nil
 			})
 
 			if err != nil {
 				result = "invalid_token"
 				logger.Warn("JWT validation failed", zap.Error(err))
-				writeJWTError(w, "Invalid token", http.StatusUnauthorized)
+				writeJWTError(w, r, "Invalid token")
 				return
 			}
 
 			if !token.Valid {
 				result = "invalid_token"
-				writeJWTError(w, "Invalid token", http.StatusUnauthorized)
+				writeJWTError(w, r, "Invalid token")
 				return
 			}
 
 			// Validate claims structure
 			if claims.UserID == "" || claims.Email == "" || claims.Role == "" {
 				result = "invalid_claims"
-				writeJWTError(w, "Invalid token claims", http.StatusUnauthorized)
+				writeJWTError(w, r, "Invalid token claims")
 				return
 			}
 
@@ -304,13 +380,9 @@ func JWTMiddleware(secret string, logger *zap.Logger, metrics *Metrics) func(htt
 	}
 }
 
-// writeJWTError writes a JSON error response for JWT failures
-func writeJWTError(w http.ResponseWriter, message string, statusCode int) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-
-	response := map[string]string{"error": message}
-	if err := json.NewEncoder(w).Encode(response); err != nil {
+// writeJWTError answers a request that failed authentication with a 401 problem document
+func writeJWTError(w http.ResponseWriter, r *http.Request, message string) {
+	if err := apperrors.WriteProblem(w, r, apperrors.NewUnauthorized(message)); err != nil {
 		// Fallback to plain text error if JSON encoding fails
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
@@ -13,7 +13,9 @@ import (
 	"testing"
 	"time"
 
+	apperrors "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/errors"
 	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap/zapcore"
 	"go.uber.org/zap/zaptest"
 )
 
@@ -188,13 +190,13 @@ func TestRateLimitMiddleware(t *testing.T) {
 		t.Errorf("Second request should be rate limited, got status %d", w.Code)
 	}
 
-	var response map[string]string
+	var response apperrors.Problem
 	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
 		t.Errorf("Failed to parse error response: %v", err)
 	}
 
-	if response["error"] != "Rate limit exceeded" {
-		t.Errorf("Expected error message 'Rate limit exceeded', got '%s'", response["error"])
+	if response.Detail != "Rate limit exceeded" {
+		t.Errorf("Expected error message 'Rate limit exceeded', got '%s'", response.Detail)
 	}
 }
 
@@ -234,7 +236,7 @@ func TestRateLimitMiddleware_XForwardedFor(t *testing.T) {
 
 func TestJWTMiddleware_MissingToken(t *testing.T) {
 	logger := zaptest.NewLogger(t)
-	middleware := JWTMiddleware("secret", logger, nil)
+	middleware := JWTMiddleware(NewJWTSecret("secret"), logger, nil)
 
 	handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -251,19 +253,19 @@ func TestJWTMiddleware_MissingToken(t *testing.T) {
 		t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, w.Code)
 	}
 
-	var response map[string]string
+	var response apperrors.Problem
 	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
 		t.Fatalf("Failed to parse error response: %v", err)
 	}
 
-	if response["error"] != "Missing Authorization header" {
-		t.Errorf("Expected error message about missing header, got '%s'", response["error"])
+	if response.Detail != "Missing Authorization header" {
+		t.Errorf("Expected error message about missing header, got '%s'", response.Detail)
 	}
 }
 
 func TestJWTMiddleware_InvalidFormat(t *testing.T) {
 	logger := zaptest.NewLogger(t)
-	middleware := JWTMiddleware("secret", logger, nil)
+	middleware := JWTMiddleware(NewJWTSecret("secret"), logger, nil)
 
 	handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -281,20 +283,20 @@ func TestJWTMiddleware_InvalidFormat(t *testing.T) {
 		t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, w.Code)
 	}
 
-	var response map[string]string
+	var response apperrors.Problem
 	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
 		t.Fatalf("Failed to parse error response: %v", err)
 	}
 
-	if response["error"] != "Invalid Authorization header format" {
-		t.Errorf("Expected error message about invalid format, got '%s'", response["error"])
+	if response.Detail != "Invalid Authorization header format" {
+		t.Errorf("Expected error message about invalid format, got '%s'", response.Detail)
 	}
 }
 
 func TestJWTMiddleware_ValidToken(t *testing.T) {
 	secret := "test-secret"
 	logger := zaptest.NewLogger(t)
-	middleware := JWTMiddleware(secret, logger, nil)
+	middleware := JWTMiddleware(NewJWTSecret(secret), logger, nil)
 
 	// Create a valid token
 	claims := &Claims{
@@ -339,7 +341,7 @@ func TestJWTMiddleware_ValidToken(t *testing.T) {
 
 func TestJWTMiddleware_InvalidToken(t *testing.T) {
 	logger := zaptest.NewLogger(t)
-	middleware := JWTMiddleware("secret", logger, nil)
+	middleware := JWTMiddleware(NewJWTSecret("secret"), logger, nil)
 
 	handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -357,20 +359,20 @@ func TestJWTMiddleware_InvalidToken(t *testing.T) {
 		t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, w.Code)
 	}
 
-	var response map[string]string
+	var response apperrors.Problem
 	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
 		t.Fatalf("Failed to parse error response: %v", err)
 	}
 
-	if response["error"] != "Invalid token" {
-		t.Errorf("Expected error message about invalid token, got '%s'", response["error"])
+	if response.Detail != "Invalid token" {
+		t.Errorf("Expected error message about invalid token, got '%s'", response.Detail)
 	}
 }
 
 func TestJWTMiddleware_ExpiredToken(t *testing.T) {
 	secret := "test-secret"
 	logger := zaptest.NewLogger(t)
-	middleware := JWTMiddleware(secret, logger, nil)
+	middleware := JWTMiddleware(NewJWTSecret(secret), logger, nil)
 
 	// Create an expired token
 	claims := &Claims{
@@ -405,7 +407,7 @@ func TestJWTMiddleware_ExpiredToken(t *testing.T) {
 
 func TestJWTMiddleware_HealthCheckBypass(t *testing.T) {
 	logger := zaptest.NewLogger(t)
-	middleware := JWTMiddleware("secret", logger, nil)
+	middleware := JWTMiddleware(NewJWTSecret("secret"), logger, nil)
 
 	handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -443,7 +445,7 @@ func TestJWTMiddleware_HealthCheckBypass(t *testing.T) {
 
 func TestJWTMiddleware_ReadinessAndLivenessBypass(t *testing.T) {
 	logger := zaptest.NewLogger(t)
-	middleware := JWTMiddleware("secret", logger, nil)
+	middleware := JWTMiddleware(NewJWTSecret("secret"), logger, nil)
 
 	handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -532,7 +534,7 @@ func TestRateLimiter_CleanupStops(_ *testing.T) {
 func TestJWTMiddleware_UnsupportedSigningMethod(t *testing.T) {
 	secret := "test-secret"
 	logger := zaptest.NewLogger(t)
-	middleware := JWTMiddleware(secret, logger, nil)
+	middleware := JWTMiddleware(NewJWTSecret(secret), logger, nil)
 
 	// A token genuinely signed with a non-HMAC algorithm, so the keyfunc's signing-method
 	// check itself rejects it, rather than failing to parse before that check ever runs.
@@ -570,13 +572,13 @@ func TestJWTMiddleware_UnsupportedSigningMethod(t *testing.T) {
 		t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, w.Code)
 	}
 
-	var response map[string]string
+	var response apperrors.Problem
 	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
 		t.Fatalf("Failed to parse error response: %v", err)
 	}
 
-	if response["error"] != "Invalid token" {
-		t.Errorf("Expected error message about invalid token, got '%s'", response["error"])
+	if response.Detail != "Invalid token" {
+		t.Errorf("Expected error message about invalid token, got '%s'", response.Detail)
 	}
 }
 
@@ -632,17 +634,17 @@ func TestRateLimitMiddleware_JSONResponse(t *testing.T) {
 		t.Errorf("Expected status %d, got %d", http.StatusTooManyRequests, w.Code)
 	}
 
-	if w.Header().Get("Content-Type") != "application/json" {
-		t.Error("Expected Content-Type: application/json")
+	if w.Header().Get("Content-Type") != apperrors.ProblemContentType {
+		t.Errorf("Expected Content-Type: %s", apperrors.ProblemContentType)
 	}
 
-	var response map[string]string
+	var response apperrors.Problem
 	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
 		t.Fatalf("Failed to parse JSON response: %v", err)
 	}
 
-	if response["error"] != "Rate limit exceeded" {
-		t.Errorf("Expected 'Rate limit exceeded', got '%s'", response["error"])
+	if response.Detail != "Rate limit exceeded" {
+		t.Errorf("Expected 'Rate limit exceeded', got '%s'", response.Detail)
 	}
 }
 
@@ -661,7 +663,7 @@ func TestJWTMiddleware_InvalidClaims(t *testing.T) {
 	req.Header.Set("Authorization", "Bearer "+tokenString)
 	w := httptest.NewRecorder()
 
-	middleware := JWTMiddleware(secret, logger, nil)
+	middleware := JWTMiddleware(NewJWTSecret(secret), logger, nil)
 	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
@@ -672,13 +674,13 @@ func TestJWTMiddleware_InvalidClaims(t *testing.T) {
 		t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, w.Code)
 	}
 
-	var response map[string]string
+	var response apperrors.Problem
 	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
 		t.Fatalf("Failed to parse JSON response: %v", err)
 	}
 
-	if response["error"] != "Invalid token claims" {
-		t.Errorf("Expected 'Invalid token claims', got '%s'", response["error"])
+	if response.Detail != "Invalid token claims" {
+		t.Errorf("Expected 'Invalid token claims', got '%s'", response.Detail)
 	}
 }
 
@@ -760,7 +762,7 @@ func TestJWTMiddlewareWithMetrics_AllPaths(t *testing.T) {
 			req := tc.setupRequest()
 			w := httptest.NewRecorder()
 
-			middleware := JWTMiddleware(secret, logger, metrics)
+			middleware := JWTMiddleware(NewJWTSecret(secret), logger, metrics)
 			handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 				w.WriteHeader(http.StatusOK)
 			}))
@@ -903,7 +905,7 @@ func TestRateLimitMiddleware_EncodeErrorFallsBackToPlainText(t *testing.T) {
 func TestWriteJWTError_EncodeErrorFallsBackToPlainText(t *testing.T) {
 	w := &failingResponseWriter{}
 
-	writeJWTError(w, "boom", http.StatusUnauthorized)
+	writeJWTError(w, httptest.NewRequest("GET", "/api/v1/orders", nil), "boom")
 
 	if len(w.statusCodes) != 2 || w.statusCodes[0] != http.StatusUnauthorized || w.statusCodes[1] != http.StatusInternalServerError {
 		t.Errorf("Expected status codes [401 500] (JWT error body, then encode-failure fallback), got %v", w.statusCodes)
@@ -927,3 +929,106 @@ func TestGetClientIPFromRequest_UnknownWhenNoHeadersOrRemoteAddr(t *testing.T) {
 		t.Errorf("Expected 'unknown' when no headers or RemoteAddr are set, got %q", ip)
 	}
 }
+
+func TestRateLimiter_UpdateAppliesToTrackedAndNewClients(t *testing.T) {
+	rl := NewRateLimiter(1, time.Minute)
+	defer rl.Close()
+
+	if !rl.Allow("tracked") || rl.Allow("tracked") {
+		t.Fatal("Expected a rate of 1 per minute to allow exactly one request")
+	}
+
+	rl.Update(600, 30*time.Second)
+
+	if rl.burst != 202 {
+		t.Errorf("burst = %d, want 202 for 600 requests per minute", rl.burst)
+	}
+	if got := rl.currentCleanupInterval(); got != 30*time.Second {
+		t.Errorf("cleanupInterval = %v, want 30s", got)
+	}
+
+	// The tracked client's bucket now refills every 100ms, so it gets a token back shortly.
+	time.Sleep(150 * time.Millisecond)
+	if !rl.Allow("tracked") {
+		t.Error("Expected the tracked client to be allowed again at the new rate")
+	}
+	for i := 0; i < 5; i++ {
+		if !rl.Allow("new") {
+			t.Fatalf("request %d from a new client rejected, want the new burst to apply", i)
+		}
+	}
+}
+
+func TestRateLimiter_UpdateResetsCleanupTicker(t *testing.T) {
+	rl := NewRateLimiter(100, time.Hour)
+	rl.maxClients = 20
+
+	for i := 0; i < 15; i++ {
+		rl.Allow(fmt.Sprintf("client-%d", i))
+	}
+
+	// With an hour-long interval nothing would be cleaned up; shortening it must take effect on
+	// the running goroutine.
+	rl.Update(100, 20*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	rl.Close()
+
+	rl.mutex.RLock()
+	count := len(rl.limiters)
+	rl.mutex.RUnlock()
+
+	if count >= 15 {
+		t.Errorf("Expected the shortened cleanup interval to evict clients, got %d remaining", count)
+	}
+}
+
+func TestJWTMiddleware_SecretRotation(t *testing.T) {
+	secret := NewJWTSecret("old-secret")
+	wrappedHandler := JWTMiddleware(secret, zaptest.NewLogger(t), nil)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	sign := func(key string) string {
+		claims := &Claims{UserID: "user123", Email: "test@example.com", Role: "user"}
+		claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Hour))
+		tokenString, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(key))
+		if err != nil {
+			t.Fatalf("Failed to create test token: %v", err)
+		}
+		return tokenString
+	}
+	status := func(tokenString string) int {
+		req := httptest.NewRequest("GET", "/api/v1/orders", nil)
+		req.Header.Set("Authorization", "Bearer "+tokenString)
+		w := httptest.NewRecorder()
+		wrappedHandler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := status(sign("old-secret")); code != http.StatusOK {
+		t.Fatalf("Expected status %d before rotation, got %d", http.StatusOK, code)
+	}
+
+	secret.Set("new-secret")
+
+	if code := status(sign("new-secret")); code != http.StatusOK {
+		t.Errorf("Expected a token signed with the new secret to pass, got %d", code)
+	}
+	if code := status(sign("old-secret")); code != http.StatusUnauthorized {
+		t.Errorf("Expected a token signed with the old secret to be rejected, got %d", code)
+	}
+}
+
+func TestClaims_MarshalLogObject_LeavesOutEmail(t *testing.T) {
+	enc := zapcore.NewMapObjectEncoder()
+	claims := Claims{UserID: "user-1", Email: "jane@example.com", Role: "customer"}
+	if err := claims.MarshalLogObject(enc); err != nil {
+		t.Fatalf("MarshalLogObject() error = %v", err)
+	}
+	if _, ok := enc.Fields["email"]; ok {
+		t.Errorf("logged claims = %v, want no email", enc.Fields)
+	}
+	if enc.Fields["user_id"] != "user-1" || enc.Fields["role"] != "customer" {
+		t.Errorf("logged claims = %v, want user_id and role", enc.Fields)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/api-gateway/internal/config"
+	apperrors "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/errors"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/resilience"
 	"github.com/google/uuid"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
@@ -75,14 +76,6 @@ type backendProxy struct {
 	breaker *resilience.Breaker
 }
 
-// ErrorResponse defines the structure for error responses
-type ErrorResponse struct {
-	Error     string            `json:"error"`
-	Code      string            `json:"code"`
-	RequestID string            `json:"request_id,omitempty"`
-	Details   map[string]string `json:"details,omitempty"`
-}
-
 // NewRouter creates a new router instance, building one reverse proxy per
 // backend service up front. It returns an error if a configured service URL
 // cannot be parsed. metrics may be nil, in which case no metrics are recorded.
@@ -135,50 +128,77 @@ func (r *Router) newBackendProxy(name, rawURL string) (*backendProxy, error) {
 	proxy.ErrorHandler = func(w http.ResponseWriter, req *http.Request, err error) {
 		r.proxyErrorHandler(w, req, err, name)
 	}
-	// otelhttp.NewTransport both opens a client span for each proxied call and injects the
-	// current trace context into the outgoing request headers, so the backend continues the
-	// same trace.
-	proxy.Transport = otelhttp.NewTransport(http.DefaultTransport,
+	proxy.Transport = proxyTransport(name, http.DefaultTransport)
+
+	breaker := resilience.NewBreaker(breakerConfig(name, r.config.CircuitBreaker))
+
+	return &backendProxy{name: name, target: target, proxy: proxy, breaker: breaker}, nil
+}
+
+// proxyTransport wraps base for calls to the named backend. otelhttp.NewTransport both opens a
+// client span for each proxied call and injects the current trace context into the outgoing
+// request headers, so the backend continues the same trace.
+func proxyTransport(name string, base http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(base,
 		otelhttp.WithSpanNameFormatter(func(_ string, req *http.Request) string {
 			return req.Method + " " + name
 		}),
 	)
+}
 
-	breaker := resilience.NewBreaker(resilience.Config{
-		Name:             name,
-		FailureThreshold: breakerFailureThreshold(r.config),
-		Window:           breakerWindow(r.config),
-		OpenTimeout:      breakerOpenTimeout(r.config),
-	})
+// SetTransport makes every backend proxy send its requests through transport, such as the
+// shared TLS transport from httpclient.NewTransport. It must be called before the router serves
+// requests.
+func (r *Router) SetTransport(transport http.RoundTripper) {
+	for name, bp := range r.proxies {
+		bp.proxy.Transport = proxyTransport(name, transport)
+	}
+}
 
-	return &backendProxy{name: name, target: target, proxy: proxy, breaker: breaker}, nil
+// ReconfigureBreakers applies new circuit breaker settings to every backend. Each breaker starts
+// over closed with an empty failure count, so a backend whose breaker was open is tried again at
+// once.
+func (r *Router) ReconfigureBreakers(cb config.CircuitBreakerConfig) {
+	for name, bp := range r.proxies {
+		bp.breaker.Reconfigure(breakerConfig(name, cb))
+	}
+}
+
+// breakerConfig builds the settings for the named backend's circuit breaker from cb.
+func breakerConfig(name string, cb config.CircuitBreakerConfig) resilience.Config {
+	return resilience.Config{
+		Name:             name,
+		FailureThreshold: breakerFailureThreshold(cb),
+		Window:           breakerWindow(cb),
+		OpenTimeout:      breakerOpenTimeout(cb),
+	}
 }
 
 // breakerFailureThreshold returns the configured circuit breaker failure threshold, or the
-// built-in default when cfg leaves it unset.
-func breakerFailureThreshold(cfg *config.Config) uint32 {
-	if cfg == nil || cfg.CircuitBreaker.FailureThreshold <= 0 {
+// built-in default when cb leaves it unset.
+func breakerFailureThreshold(cb config.CircuitBreakerConfig) uint32 {
+	if cb.FailureThreshold <= 0 {
 		return defaultBreakerFailureThreshold
 	}
-	return uint32(cfg.CircuitBreaker.FailureThreshold)
+	return uint32(cb.FailureThreshold)
 }
 
 // breakerWindow returns the configured circuit breaker closed-state counting window, or the
-// built-in default when cfg leaves it unset.
-func breakerWindow(cfg *config.Config) time.Duration {
-	if cfg == nil || cfg.CircuitBreaker.WindowSeconds <= 0 {
+// built-in default when cb leaves it unset.
+func breakerWindow(cb config.CircuitBreakerConfig) time.Duration {
+	if cb.WindowSeconds <= 0 {
 		return defaultBreakerWindow
 	}
-	return time.Duration(cfg.CircuitBreaker.WindowSeconds) * time.Second
+	return time.Duration(cb.WindowSeconds) * time.Second
 }
 
 // breakerOpenTimeout returns the configured circuit breaker open-state recovery timeout, or the
-// built-in default when cfg leaves it unset.
-func breakerOpenTimeout(cfg *config.Config) time.Duration {
-	if cfg == nil || cfg.CircuitBreaker.OpenTimeoutSeconds <= 0 {
+// built-in default when cb leaves it unset.
+func breakerOpenTimeout(cb config.CircuitBreakerConfig) time.Duration {
+	if cb.OpenTimeoutSeconds <= 0 {
 		return defaultBreakerOpenTimeout
 	}
-	return time.Duration(cfg.CircuitBreaker.OpenTimeoutSeconds) * time.Second
+	return time.Duration(cb.OpenTimeoutSeconds) * time.Second
 }
 
 // SetupRoutes configures all routes
@@ -354,7 +374,7 @@ func (r *Router) proxyToService(w http.ResponseWriter, req *http.Request, name s
 	// any other error was already turned into a response by proxyErrorHandler or came straight
 	// from the backend, so there is nothing left to write here.
 	if stderrors.Is(err, resilience.ErrOpen) {
-		r.writeCircuitOpenResponse(recorder, name)
+		r.writeCircuitOpenResponse(recorder, req, name)
 	}
 
 	if r.metrics != nil {
@@ -364,23 +384,14 @@ func (r *Router) proxyToService(w http.ResponseWriter, req *http.Request, name s
 
 // writeCircuitOpenResponse answers a request short-circuited by an open breaker with a 503
 // instead of forwarding it to a backend that is being given time to recover.
-func (r *Router) writeCircuitOpenResponse(w http.ResponseWriter, name string) {
+func (r *Router) writeCircuitOpenResponse(w http.ResponseWriter, req *http.Request, name string) {
 	if r.metrics != nil {
 		r.metrics.RecordProxyError(name, "SERVICE_UNAVAILABLE")
 	}
 
-	response := ErrorResponse{
-		Error: "Backend service is unavailable",
-		Code:  "SERVICE_UNAVAILABLE",
-		Details: map[string]string{
-			"target_service": name,
-		},
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusServiceUnavailable)
-
-	if err := json.NewEncoder(w).Encode(response); err != nil {
+	appErr := apperrors.NewServiceUnavailable("Backend service is unavailable")
+	appErr.Details = fmt.Sprintf("the %s service is not accepting requests while it recovers", name)
+	if err := apperrors.WriteProblem(w, req, appErr); err != nil {
 		r.logger.Error("Failed to encode circuit open response", zap.Error(err))
 	}
 }
@@ -490,24 +501,11 @@ func routePath(path string) string {
 }
 
 // notFoundHandler answers requests under /api/v1/ that do not match a known
-// backend route with a JSON error instead of the mux's empty 404 body.
+// backend route with a problem document instead of the mux's empty 404 body.
 func (r *Router) notFoundHandler(w http.ResponseWriter, req *http.Request) {
-	response := ErrorResponse{
-		Error: "Route not found",
-		Code:  "NOT_FOUND",
-		Details: map[string]string{
-			"path":   req.URL.Path,
-			"method": req.Method,
-		},
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusNotFound)
-
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		r.logger.Error("Failed to encode not found response", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-	}
+	appErr := apperrors.NewNotFound("Route")
+	appErr.Details = fmt.Sprintf("no backend serves %s %s", req.Method, req.URL.Path)
+	r.writeProblem(w, req, appErr)
 }
 
 // proxyErrorHandler handles proxy errors
@@ -541,22 +539,19 @@ func (r *Router) proxyErrorHandler(w http.ResponseWriter, req *http.Request, err
 		r.metrics.RecordProxyError(targetService, errorCode)
 	}
 
-	// Create error response
-	errorResponse := ErrorResponse{
-		Error: "Failed to proxy request to backend service",
-		Code:  errorCode,
-		Details: map[string]string{
-			"target_url": req.URL.String(),
-			"method":     req.Method,
-		},
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
+	r.writeProblem(w, req, &apperrors.AppError{
+		Code:     errorCode,
+		Message:  "Failed to proxy request to backend service",
+		Details:  fmt.Sprintf("%s %s could not be forwarded to the %s service", req.Method, req.URL.Path, targetService),
+		HTTPCode: statusCode,
+	})
+}
 
-	if encodeErr := json.NewEncoder(w).Encode(errorResponse); encodeErr != nil {
-		r.logger.Error("Failed to encode error response", zap.Error(encodeErr))
-		// Fallback to plain text error
+// writeProblem answers req with appErr as an RFC 7807 problem document, falling back to a plain
+// text error if the document cannot be encoded.
+func (r *Router) writeProblem(w http.ResponseWriter, req *http.Request, appErr *apperrors.AppError) {
+	if err := apperrors.WriteProblem(w, req, appErr); err != nil {
+		r.logger.Error("Failed to encode error response", zap.Error(err))
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
 }
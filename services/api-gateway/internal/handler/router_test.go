@@ -13,6 +13,7 @@ import (
 
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/api-gateway/internal/config"
 	sharedConfig "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/config"
+	apperrors "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/errors"
 	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/sony/gobreaker/v2"
 	"go.uber.org/zap"
@@ -372,7 +373,7 @@ func TestProxyErrorHandler_AllErrorTypes(t *testing.T) {
 				t.Errorf("Expected status %d, got %d", tc.expectedStatus, w.Code)
 			}
 
-			var response ErrorResponse
+			var response apperrors.Problem
 			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
 				t.Errorf("Failed to parse error response: %v", err)
 				return
@@ -815,7 +816,7 @@ func TestNotFoundHandler_UnknownAPIRoute(t *testing.T) {
 		t.Fatalf("Expected status %d, got %d", http.StatusNotFound, w.Code)
 	}
 
-	var response ErrorResponse
+	var response apperrors.Problem
 	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
 		t.Fatalf("Failed to parse not found response: %v", err)
 	}
@@ -869,7 +870,7 @@ func TestProxyToService_CircuitBreakerOpensAfterConsecutiveFailures(t *testing.T
 		t.Fatalf("expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
 	}
 
-	var response ErrorResponse
+	var response apperrors.Problem
 	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
 		t.Fatalf("failed to parse response: %v", err)
 	}
@@ -1096,7 +1097,7 @@ func TestWriteCircuitOpenResponse_RecordsMetricsAndHasNoPlainTextFallback(t *tes
 	before := testutil.ToFloat64(metrics.ProxyErrorsTotal.WithLabelValues(backendOrder, "SERVICE_UNAVAILABLE"))
 
 	w := &failingResponseWriter{}
-	router.writeCircuitOpenResponse(w, backendOrder)
+	router.writeCircuitOpenResponse(w, httptest.NewRequest("GET", "/api/v1/orders", nil), backendOrder)
 
 	after := testutil.ToFloat64(metrics.ProxyErrorsTotal.WithLabelValues(backendOrder, "SERVICE_UNAVAILABLE"))
 	if after != before+1 {
@@ -1163,3 +1164,46 @@ func TestNotFoundHandler_KnownRoutesStillProxy(t *testing.T) {
 		t.Errorf("Expected known route to still proxy successfully, got status %d", w.Code)
 	}
 }
+
+func TestRouter_ReconfigureBreakersAppliesNewThreshold(t *testing.T) {
+	var calls int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer backend.Close()
+
+	cfg := &config.Config{
+		OrderServiceURL: backend.URL,
+		ProxyTimeout:    5,
+		CircuitBreaker:  config.CircuitBreakerConfig{FailureThreshold: 1},
+	}
+	logger, _ := zap.NewDevelopment()
+	router := newTestRouter(t, cfg, logger, time.Now())
+	router.SetupRoutes()
+
+	serve := func() {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/v1/orders/123", nil))
+	}
+
+	serve()
+	if state := router.proxies[backendOrder].breaker.State(); state != gobreaker.StateOpen {
+		t.Fatalf("breaker state = %v, want open with a threshold of 1", state)
+	}
+
+	router.ReconfigureBreakers(config.CircuitBreakerConfig{FailureThreshold: 3})
+
+	// The reconfigured breaker starts closed and now tolerates two failures.
+	serve()
+	serve()
+	if state := router.proxies[backendOrder].breaker.State(); state != gobreaker.StateClosed {
+		t.Errorf("breaker state after two failures = %v, want closed with a threshold of 3", state)
+	}
+	serve()
+	if state := router.proxies[backendOrder].breaker.State(); state != gobreaker.StateOpen {
+		t.Errorf("breaker state after three failures = %v, want open", state)
+	}
+	if calls := atomic.LoadInt32(&calls); calls != 4 {
+		t.Errorf("calls to backend = %d, want 4", calls)
+	}
+}
@@ -22,6 +22,7 @@ type Server struct {
 	rateLimiter *handler.RateLimiter
 	metrics     *handler.Metrics
 	router      *handler.Router
+	jwtSecret   *handler.JWTSecret
 }
 
 // Options contains options for creating a new server
@@ -29,6 +30,9 @@ type Options struct {
 	Config  *config.Config
 	Logger  *zap.Logger
 	Metrics *handler.Metrics
+	// Transport is optional: when set, requests are proxied to the backends through it, so
+	// https:// backend URLs can be verified with the internal CA. Nil uses the default transport.
+	Transport http.RoundTripper
 }
 
 // NewServer creates a new server instance
@@ -50,6 +54,11 @@ func NewServer(opts Options) (*Server, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create router: %w", err)
 	}
+	if opts.Transport != nil {
+		router.SetTransport(opts.Transport)
+	}
+
+	jwtSecret := handler.NewJWTSecret(opts.Config.JWTSecret.Value())
 
 	// Setup main handler with middleware chain
 	mux := http.NewServeMux()
@@ -63,7 +72,7 @@ func NewServer(opts Options) (*Server, error) {
 	// Apply middleware chain to router. Rate limiting runs before authentication;
 	// request ID and panic recovery wrap everything ahead of rate limiting.
 	var finalHandler http.Handler = router
-	finalHandler = handler.JWTMiddleware(opts.Config.JWTSecret, opts.Logger, metrics)(finalHandler)
+	finalHandler = handler.JWTMiddleware(jwtSecret, opts.Logger, metrics)(finalHandler)
 	finalHandler = handler.RateLimitMiddleware(rateLimiter, metrics)(finalHandler)
 	finalHandler = recordRequestMetrics(metrics)(finalHandler)
 	finalHandler = forwardRequestID(finalHandler)
@@ -96,6 +105,7 @@ func NewServer(opts Options) (*Server, error) {
 		rateLimiter: rateLimiter,
 		metrics:     metrics,
 		router:      router,
+		jwtSecret:   jwtSecret,
 	}, nil
 }
 
@@ -159,6 +169,21 @@ func (s *Server) Stop(ctx context.Context) error {
 	return s.httpServer.Shutdown(ctx)
 }
 
+// UpdateRateLimit applies new rate limits to the running server.
+func (s *Server) UpdateRateLimit(rl config.RateLimitConfig) {
+	s.rateLimiter.Update(rl.RequestsPerMinute, time.Duration(rl.WindowDuration)*time.Second)
+}
+
+// UpdateCircuitBreaker applies new circuit breaker settings to every backend of the running server.
+func (s *Server) UpdateCircuitBreaker(cb config.CircuitBreakerConfig) {
+	s.router.ReconfigureBreakers(cb)
+}
+
+// UpdateJWTSecret replaces the secret tokens are verified with, for a rotated JWT secret.
+func (s *Server) UpdateJWTSecret(secret string) {
+	s.jwtSecret.Set(secret)
+}
+
 // GetHTTPServer returns the underlying HTTP server
 func (s *Server) GetHTTPServer() *http.Server {
 	return s.httpServer
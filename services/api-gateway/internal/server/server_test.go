@@ -402,7 +402,7 @@ func TestServer_RequestIDIsGeneratedAndForwarded(t *testing.T) {
 	})
 
 	req := httptest.NewRequest("GET", "/api/v1/orders/123", nil)
-	req.Header.Set("Authorization", "Bearer "+validJWT(t, cfg.JWTSecret))
+	req.Header.Set("Authorization", "Bearer "+validJWT(t, cfg.JWTSecret.Value()))
 	w := httptest.NewRecorder()
 
 	srv.httpServer.Handler.ServeHTTP(w, req)
@@ -458,7 +458,7 @@ func TestServer_RequestIDIsPreservedFromClient(t *testing.T) {
 	})
 
 	req := httptest.NewRequest("GET", "/api/v1/orders/123", nil)
-	req.Header.Set("Authorization", "Bearer "+validJWT(t, cfg.JWTSecret))
+	req.Header.Set("Authorization", "Bearer "+validJWT(t, cfg.JWTSecret.Value()))
 	req.Header.Set("X-Request-ID", "client-supplied-id")
 	w := httptest.NewRecorder()
 
@@ -11,6 +11,7 @@ import (
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/api-gateway/internal/config"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/api-gateway/internal/handler"
 	sharedConfig "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/config"
+	apperrors "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/errors"
 	"go.uber.org/zap/zaptest"
 )
 
@@ -82,7 +83,7 @@ func TestEnhancedErrorHandling(t *testing.T) {
 		t.Errorf("Expected status code %d, got %d", http.StatusBadGateway, w.Code)
 	}
 
-	var response handler.ErrorResponse
+	var response apperrors.Problem
 	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
 		t.Errorf("Failed to parse error response: %v", err)
 		return
@@ -92,7 +93,7 @@ func TestEnhancedErrorHandling(t *testing.T) {
 		t.Errorf("Expected error code 'PROXY_ERROR' or 'INVALID_SERVICE_URL', got '%s'", response.Code)
 	}
 
-	if response.Error == "" {
+	if response.Title == "" {
 		t.Error("Expected error message in response")
 	}
 }
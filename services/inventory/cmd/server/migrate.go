@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/inventory/internal/config"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/inventory/migrations"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/database"
+	sharedlogger "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/logger"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/migrate"
+	"go.uber.org/zap"
+)
+
+// runMigrate carries out "inventory-service migrate <command>" against the database the service is
+// configured for, then returns instead of starting the service.
+func runMigrate(args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	appLogger, err := sharedlogger.New(sharedlogger.Config{
+		Level:       cfg.Logger.Level,
+		Environment: cfg.Logger.Environment,
+		Service:     cfg.Service.Name,
+		Version:     cfg.Service.Version,
+		OutputPaths: cfg.Logger.OutputPaths,
+	})
+	if err != nil {
+		return fmt.Errorf("initialize logger: %w", err)
+	}
+	defer func() { _ = appLogger.Sync() }()
+
+	db, err := database.NewPostgresConnection(database.PostgresConfig{
+		URL:          cfg.DatabaseURL.Value(),
+		MaxOpenConns: 2,
+		MaxIdleConns: 1,
+		MaxLifetime:  time.Minute,
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	migrator, err := migrate.New(db.DB, migrations.FS, appLogger.Logger)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	return migrate.Run(ctx, migrator, args, os.Stdout)
+}
+
+// migrateOnStartup applies pending migrations before the service starts serving. It fails, and
+// so stops the service from starting, when the database is at a newer version than this binary
+// knows: the newer code that migrated it may write data this one does not understand.
+func migrateOnStartup(db *database.DB, timeout time.Duration, logger *zap.Logger) error {
+	migrator, err := migrate.New(db.DB, migrations.FS, logger)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return migrator.Up(ctx)
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"net/url"
 	"time"
@@ -13,6 +14,9 @@ type DatabasePoolConfig struct {
 	MaxOpenConns int           `mapstructure:"max_open_conns"`
 	MaxIdleConns int           `mapstructure:"max_idle_conns"`
 	MaxLifetime  time.Duration `mapstructure:"max_lifetime"`
+	// SlowQueryThreshold logs a warning for queries that take at least this long; 0 turns the
+	// slow query log off.
+	SlowQueryThreshold time.Duration `mapstructure:"slow_query_threshold"`
 }
 
 // OutboxConfig sizes the outbox relay poll loop.
@@ -27,14 +31,18 @@ type Config struct {
 	DatabasePool DatabasePoolConfig    `mapstructure:"database_pool"`
 	// DatabaseURL is the raw connection string used to open the pool; Database
 	// above holds the same information split into fields for validation.
-	DatabaseURL   string               `mapstructure:"-"`
-	Redis         config.RedisConfig   `mapstructure:"redis"`
-	RedisPoolSize int                  `mapstructure:"redis_pool_size"`
-	Kafka         config.KafkaConfig   `mapstructure:"kafka"`
-	Outbox        OutboxConfig         `mapstructure:"outbox"`
-	Jaeger        config.JaegerConfig  `mapstructure:"jaeger"`
-	Logger        config.LoggerConfig  `mapstructure:"logger"`
-	Service       config.ServiceConfig `mapstructure:"service"`
+	DatabaseURL     config.Secret                `mapstructure:"-"`
+	DatabaseReplica config.DatabaseReplicaConfig `mapstructure:"database_replica"`
+	Redis           config.RedisConfig           `mapstructure:"redis"`
+	RedisPoolSize   int                          `mapstructure:"redis_pool_size"`
+	LocalCache      config.LocalCacheConfig      `mapstructure:"local_cache"`
+	Kafka           config.KafkaConfig           `mapstructure:"kafka"`
+	Outbox          OutboxConfig                 `mapstructure:"outbox"`
+	FeatureFlags    config.FeatureFlagsConfig    `mapstructure:"feature_flags"`
+	Jaeger          config.JaegerConfig          `mapstructure:"jaeger"`
+	Logger          config.LoggerConfig          `mapstructure:"logger"`
+	Migrations      config.MigrationsConfig      `mapstructure:"migrations"`
+	Service         config.ServiceConfig         `mapstructure:"service"`
 }
 
 func LoadConfig() (*Config, error) {
@@ -42,18 +50,45 @@ func LoadConfig() (*Config, error) {
 
 	loader := config.New("inventory")
 	loader.SetDefault("server.host", "0.0.0.0")
+	loader.SetDefault("server.admin_port", "9091")
+	loader.SetDefault("server.tls.cert_file", "")
+	loader.SetDefault("server.tls.key_file", "")
+	loader.SetDefault("server.tls.ca_file", "")
+	loader.SetDefault("server.tls.reload_interval", "1m")
 	loader.SetDefault("kafka.group_id", "inventory-service")
 	loader.SetDefault("kafka.dlq_topic", "inventory.events.dlq")
+	loader.SetDefault("kafka.log_payloads", false)
 	loader.SetDefault("logger.level", "info")
 	loader.SetDefault("logger.environment", "development")
 	loader.SetDefault("logger.output_paths", []string{"stdout"})
+	loader.SetDefault("logger.sampling.initial", 100)
+	loader.SetDefault("logger.sampling.thereafter", 100)
+	loader.SetDefault("logger.sampling.tick", "1s")
+	loader.SetDefault("logger.redaction.mode", "mask")
+	loader.SetDefault("logger.redaction.fields", []string{})
+	loader.SetDefault("logger.redaction.hash_key", "")
 	loader.SetDefault("service.name", "inventory")
 	loader.SetDefault("service.version", "1.0.0")
 	loader.SetDefault("database_pool.max_open_conns", 25)
 	loader.SetDefault("database_pool.max_idle_conns", 5)
 	loader.SetDefault("database_pool.max_lifetime", "5m")
+	loader.SetDefault("database_pool.slow_query_threshold", "200ms")
+	loader.SetDefault("migrations.on_startup", false)
+	loader.SetDefault("migrations.timeout", "2m")
+	loader.SetDefault("redis.mode", "standalone")
+	loader.SetDefault("redis.addrs", []string{})
+	loader.SetDefault("redis.master_name", "")
+	loader.SetDefault("database_replica.url", "")
+	loader.SetDefault("database_replica.max_lag", "5s")
+	loader.SetDefault("database_replica.lag_check_interval", "1s")
+	loader.SetDefault("database_replica.read_your_writes_window", "5s")
 	loader.SetDefault("redis_pool_size", 10)
+	loader.SetDefault("local_cache.max_entries", 10000)
+	loader.SetDefault("local_cache.ttl", "10s")
 	loader.SetDefault("outbox.relay_interval", "1s")
+	loader.SetDefault("feature_flags.backend", "")
+	loader.SetDefault("feature_flags.path", "")
+	loader.SetDefault("feature_flags.refresh_interval", "30s")
 	loader.SetDefault("outbox.relay_batch_size", 100)
 
 	// Explicitly bind environment variables
@@ -66,6 +101,21 @@ func LoadConfig() (*Config, error) {
 	if err := loader.BindEnv("redis.url", "REDIS_URL"); err != nil {
 		return nil, fmt.Errorf("failed to bind redis.url: %w", err)
 	}
+	if err := loader.BindEnv("redis.mode", "REDIS_MODE"); err != nil {
+		return nil, fmt.Errorf("failed to bind redis.mode: %w", err)
+	}
+	if err := loader.BindEnv("redis.addrs", "REDIS_ADDRS"); err != nil {
+		return nil, fmt.Errorf("failed to bind redis.addrs: %w", err)
+	}
+	if err := loader.BindEnv("redis.master_name", "REDIS_MASTER_NAME"); err != nil {
+		return nil, fmt.Errorf("failed to bind redis.master_name: %w", err)
+	}
+	if err := loader.BindEnv("redis.password", "REDIS_PASSWORD"); err != nil {
+		return nil, fmt.Errorf("failed to bind redis.password: %w", err)
+	}
+	if err := loader.BindEnv("redis.sentinel_password", "REDIS_SENTINEL_PASSWORD"); err != nil {
+		return nil, fmt.Errorf("failed to bind redis.sentinel_password: %w", err)
+	}
 	if err := loader.BindEnv("redis_pool_size", "REDIS_POOL_SIZE"); err != nil {
 		return nil, fmt.Errorf("failed to bind redis_pool_size: %w", err)
 	}
@@ -84,23 +134,29 @@ func LoadConfig() (*Config, error) {
 		return nil, err
 	}
 
-	// Get the database URL string directly from viper
-	dbURLString := loader.GetString("database.url")
+	// Get the database URL string directly from viper; it may be a secret reference
+	dbURL, err := loader.Secret("database.url")
+	if err != nil {
+		return nil, err
+	}
+	dbURLString := dbURL.Value()
 	if dbURLString == "" {
 		return nil, fmt.Errorf("INVENTORY_DATABASE_URL environment variable is not set")
 	}
-	cfg.DatabaseURL = dbURLString
+	cfg.DatabaseURL = dbURL
 
 	parsedURL, err := url.Parse(dbURLString)
 	if err != nil {
-		return nil, fmt.Errorf("invalid INVENTORY_DATABASE_URL: %w", err)
+		// url.Error repeats the URL, password included, so only its cause is reported.
+		return nil, fmt.Errorf("invalid INVENTORY_DATABASE_URL: %w", errors.Unwrap(err))
 	}
 
 	// Populate DatabaseConfig fields from parsed URL
 	cfg.Database.Host = parsedURL.Hostname()
 	cfg.Database.Port = parsedURL.Port()
 	cfg.Database.User = parsedURL.User.Username()
-	cfg.Database.Password, _ = parsedURL.User.Password()
+	password, _ := parsedURL.User.Password()
+	cfg.Database.Password = config.Secret(password)
 	cfg.Database.DBName = parsedURL.Path[1:] // Remove leading slash
 	cfg.Database.SSLMode = parsedURL.Query().Get("sslmode")
 
@@ -117,10 +173,29 @@ func (c *Config) Validate() error {
 	if c.Server.Port == "" {
 		return fmt.Errorf("INVENTORY_SERVER_PORT (or INVENTORY_SERVICE_PORT) environment variable is not set")
 	}
+	if err := c.Server.Validate(); err != nil {
+		return fmt.Errorf("server configuration invalid: %w", err)
+	}
+	if err := c.Migrations.Validate(); err != nil {
+		return fmt.Errorf("migrations configuration invalid: %w", err)
+	}
+	if err := c.DatabaseReplica.Validate(); err != nil {
+		return fmt.Errorf("database replica configuration invalid: %w", err)
+	}
 
 	// Validation for Redis
-	if c.Redis.URL == "" {
-		return fmt.Errorf("REDIS_URL environment variable is not set")
+	if err := c.Redis.Validate(); err != nil {
+		return fmt.Errorf("redis configuration invalid: %w", err)
+	}
+	if c.LocalCache.MaxEntries < 0 {
+		return fmt.Errorf("INVENTORY_LOCAL_CACHE_MAX_ENTRIES must not be negative")
+	}
+	if c.LocalCache.MaxEntries > 0 && c.LocalCache.TTL <= 0 {
+		return fmt.Errorf("INVENTORY_LOCAL_CACHE_TTL must be positive when the local cache is enabled")
+	}
+
+	if err := c.FeatureFlags.Validate(); err != nil {
+		return fmt.Errorf("feature flag configuration invalid: %w", err)
 	}
 
 	// Validation for Kafka
@@ -197,8 +197,8 @@ func TestLoadConfig(t *testing.T) {
 				if cfg.Logger.Level != "info" {
 					t.Errorf("LoadConfig() Logger.Level = %v, want info", cfg.Logger.Level)
 				}
-				if cfg.DatabaseURL != tt.envVars["INVENTORY_DATABASE_URL"] {
-					t.Errorf("LoadConfig() DatabaseURL = %v, want %v", cfg.DatabaseURL, tt.envVars["INVENTORY_DATABASE_URL"])
+				if cfg.DatabaseURL.Value() != tt.envVars["INVENTORY_DATABASE_URL"] {
+					t.Errorf("LoadConfig() DatabaseURL = %v, want %v", cfg.DatabaseURL.Value(), tt.envVars["INVENTORY_DATABASE_URL"])
 				}
 				if cfg.DatabasePool.MaxOpenConns != 25 {
 					t.Errorf("LoadConfig() DatabasePool.MaxOpenConns = %v, want 25", cfg.DatabasePool.MaxOpenConns)
@@ -215,6 +215,12 @@ func TestLoadConfig(t *testing.T) {
 				if cfg.Outbox.RelayBatchSize != 100 {
 					t.Errorf("LoadConfig() Outbox.RelayBatchSize = %v, want 100", cfg.Outbox.RelayBatchSize)
 				}
+				if cfg.LocalCache.MaxEntries != 10000 {
+					t.Errorf("LoadConfig() LocalCache.MaxEntries = %v, want 10000", cfg.LocalCache.MaxEntries)
+				}
+				if cfg.LocalCache.TTL != 10*time.Second {
+					t.Errorf("LoadConfig() LocalCache.TTL = %v, want 10s", cfg.LocalCache.TTL)
+				}
 				if cfg.RedisPoolSize != 10 {
 					t.Errorf("LoadConfig() RedisPoolSize = %v, want 10", cfg.RedisPoolSize)
 				}
@@ -398,6 +404,51 @@ func TestValidate(t *testing.T) {
 	}
 }
 
+func TestValidate_LocalCache(t *testing.T) {
+	tests := []struct {
+		name       string
+		localCache config.LocalCacheConfig
+		errMsg     string
+	}{
+		{name: "disabled", localCache: config.LocalCacheConfig{}},
+		{name: "enabled", localCache: config.LocalCacheConfig{MaxEntries: 1000, TTL: 10 * time.Second}},
+		{
+			name:       "negative size",
+			localCache: config.LocalCacheConfig{MaxEntries: -1, TTL: 10 * time.Second},
+			errMsg:     "INVENTORY_LOCAL_CACHE_MAX_ENTRIES must not be negative",
+		},
+		{
+			name:       "enabled without a TTL",
+			localCache: config.LocalCacheConfig{MaxEntries: 1000},
+			errMsg:     "INVENTORY_LOCAL_CACHE_TTL must be positive when the local cache is enabled",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				Server:     config.ServerConfig{Port: "8080"},
+				Database:   config.DatabaseConfig{Host: "localhost", Port: "5432", User: "user", Password: "pass", DBName: "inventory"},
+				Redis:      config.RedisConfig{URL: "redis://localhost:6379"},
+				Kafka:      config.KafkaConfig{Brokers: []string{"localhost:9092"}},
+				Jaeger:     config.JaegerConfig{Endpoint: "http://localhost:14268/api/traces"},
+				LocalCache: tt.localCache,
+			}
+
+			err := cfg.Validate()
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("Validate() unexpected error = %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.errMsg {
+				t.Errorf("Validate() error = %v, want %v", err, tt.errMsg)
+			}
+		})
+	}
+}
+
 func clearEnvVars() {
 	envVars := []string{
 		"INVENTORY_SERVER_PORT",
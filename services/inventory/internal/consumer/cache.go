@@ -4,17 +4,22 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/inventory/internal/service"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/events"
 	"go.uber.org/zap"
 )
 
-// ProductCache is the invalidation port the cache consumer deletes keys through.
+// ProductCache is the invalidation port the cache consumer deletes keys through. Only one replica
+// in the consumer group sees each event, so Delete must evict the keys from Redis and from every
+// replica's local tier, as cache.Cache.Delete does.
 type ProductCache interface {
 	Delete(ctx context.Context, keys ...string) error
+	InvalidateTag(ctx context.Context, tags ...string) error
 }
 
 // CacheConsumer invalidates cached product reads when inventory.events reports a change:
-// product.updated, inventory.reserved or inventory.released.
+// product.updated, inventory.reserved or inventory.released. product.updated also drops every
+// cached product list page.
 type CacheConsumer struct {
 	subscriber subscriber
 	cache      ProductCache
@@ -34,6 +39,12 @@ func (c *CacheConsumer) Start(ctx context.Context) error {
 // handle deletes the cached product entries affected by event. Event types this consumer does
 // not act on, and events with no recognizable product id, are skipped without error.
 func (c *CacheConsumer) handle(ctx context.Context, event events.Event) error {
+	if event.Type == events.EventTypeProductUpdated {
+		if err := c.cache.InvalidateTag(ctx, service.ProductListCacheTag); err != nil {
+			return fmt.Errorf("invalidate product list cache: %w", err)
+		}
+	}
+
 	productIDs := productIDsFromEvent(event)
 	if len(productIDs) == 0 {
 		return nil
@@ -7,6 +7,7 @@ import (
 	"sort"
 	"testing"
 
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/inventory/internal/service"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/events"
 	"github.com/google/uuid"
 	"go.uber.org/zap/zaptest"
@@ -16,8 +17,10 @@ var errTestProductCache = errors.New("cache failure")
 
 // fakeProductCache is an in-memory ProductCache test double.
 type fakeProductCache struct {
-	deleted [][]string
-	err     error
+	deleted     [][]string
+	invalidated []string
+	err         error
+	tagErr      error
 }
 
 func (f *fakeProductCache) Delete(_ context.Context, keys ...string) error {
@@ -25,6 +28,11 @@ func (f *fakeProductCache) Delete(_ context.Context, keys ...string) error {
 	return f.err
 }
 
+func (f *fakeProductCache) InvalidateTag(_ context.Context, tags ...string) error {
+	f.invalidated = append(f.invalidated, tags...)
+	return f.tagErr
+}
+
 func newCacheConsumer(t *testing.T, cache ProductCache) *CacheConsumer {
 	t.Helper()
 	return &CacheConsumer{cache: cache, logger: zaptest.NewLogger(t)}
@@ -177,6 +185,48 @@ func TestCacheConsumer_Handle_CacheError(t *testing.T) {
 	}
 }
 
+func TestCacheConsumer_Handle_ProductUpdatedDropsListPages(t *testing.T) {
+	tests := []struct {
+		name      string
+		eventType string
+		want      []string
+	}{
+		{name: "product.updated", eventType: events.EventTypeProductUpdated, want: []string{service.ProductListCacheTag}},
+		{name: "inventory.reserved", eventType: events.EventTypeInventoryReserved, want: nil},
+		{name: "inventory.released", eventType: events.EventTypeInventoryReleased, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := &fakeProductCache{}
+			c := newCacheConsumer(t, cache)
+
+			event := events.Event{ID: uuid.New().String(), Type: tt.eventType, Data: map[string]interface{}{"product_id": "p1"}}
+			if err := c.handle(context.Background(), event); err != nil {
+				t.Fatalf("handle() error = %v", err)
+			}
+			if !reflect.DeepEqual(cache.invalidated, tt.want) {
+				t.Errorf("InvalidateTag calls = %v, want %v", cache.invalidated, tt.want)
+			}
+		})
+	}
+}
+
+func TestCacheConsumer_Handle_TagInvalidationError(t *testing.T) {
+	cache := &fakeProductCache{tagErr: errTestProductCache}
+	c := newCacheConsumer(t, cache)
+
+	event := events.Event{
+		ID:   uuid.New().String(),
+		Type: events.EventTypeProductUpdated,
+		Data: map[string]interface{}{"product_id": "p1"},
+	}
+
+	if err := c.handle(context.Background(), event); !errors.Is(err, errTestProductCache) {
+		t.Errorf("handle() error = %v, want %v", err, errTestProductCache)
+	}
+}
+
 func TestCacheConsumer_Start(t *testing.T) {
 	event := events.Event{
 		ID:   uuid.New().String(),
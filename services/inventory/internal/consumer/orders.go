@@ -6,6 +6,7 @@ import (
 	"database/sql"
 	"fmt"
 
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/database"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/events"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
@@ -51,28 +52,16 @@ func (c *OrdersConsumer) handle(ctx context.Context, event events.Event) error {
 		return nil
 	}
 
-	tx, err := c.db.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("begin transaction: %w", err)
-	}
-	defer func() { _ = tx.Rollback() }()
-
-	processed, err := c.processed.MarkProcessed(ctx, tx, event.ID, event.Type)
-	if err != nil {
-		return err
-	}
-	if !processed {
-		return tx.Commit() // already handled, redelivery is a no-op
-	}
-
-	if err := c.stock.HandleOrderEvent(ctx, tx, event.Type, orderID); err != nil {
-		return err
-	}
-
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("commit transaction: %w", err)
-	}
-	return nil
+	return database.WithTx(ctx, c.db, func(ctx context.Context, tx *sql.Tx) error {
+		processed, err := c.processed.MarkProcessed(ctx, tx, event.ID, event.Type)
+		if err != nil {
+			return err
+		}
+		if !processed {
+			return nil // already handled, redelivery is a no-op
+		}
+		return c.stock.HandleOrderEvent(ctx, tx, event.Type, orderID)
+	})
 }
 
 func orderIDFromEvent(event events.Event) (uuid.UUID, error) {
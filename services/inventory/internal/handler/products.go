@@ -5,12 +5,14 @@ import (
 	"context"
 	"encoding/json"
 	stderrors "errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/inventory/internal/domain"
 	apperrors "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/errors"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/validation"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
@@ -25,6 +27,7 @@ const (
 // failure, so the handler can surface that to the caller.
 type ProductRepository interface {
 	GetByID(ctx context.Context, id uuid.UUID) (product *domain.Product, stale bool, err error)
+	GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*domain.Product, error)
 	List(ctx context.Context, category string, activeOnly bool, limit, offset int) ([]*domain.Product, error)
 }
 
@@ -89,7 +92,7 @@ type listProductsResponse struct {
 func (h *ProductsHandler) List(w http.ResponseWriter, r *http.Request) {
 	limit, offset, err := parsePagination(r)
 	if err != nil {
-		h.writeError(w, err)
+		h.writeError(w, r, err)
 		return
 	}
 
@@ -98,7 +101,7 @@ func (h *ProductsHandler) List(w http.ResponseWriter, r *http.Request) {
 
 	products, err := h.products.List(r.Context(), category, activeOnly, limit, offset)
 	if err != nil {
-		h.writeError(w, err)
+		h.writeError(w, r, err)
 		return
 	}
 
@@ -114,13 +117,13 @@ func (h *ProductsHandler) List(w http.ResponseWriter, r *http.Request) {
 func (h *ProductsHandler) Get(w http.ResponseWriter, r *http.Request) {
 	id, err := uuid.Parse(r.PathValue("id"))
 	if err != nil {
-		h.writeError(w, apperrors.NewBadRequest("invalid product id"))
+		h.writeError(w, r, apperrors.NewBadRequest("invalid product id"))
 		return
 	}
 
 	product, stale, err := h.products.GetByID(r.Context(), id)
 	if err != nil {
-		h.writeError(w, err)
+		h.writeError(w, r, err)
 		return
 	}
 
@@ -130,6 +133,49 @@ func (h *ProductsHandler) Get(w http.ResponseWriter, r *http.Request) {
 	h.writeJSON(w, http.StatusOK, newProductResponse(product))
 }
 
+type batchProductsRequest struct {
+	IDs []string `json:"ids" validate:"min=1,max=100"`
+}
+
+type batchProductsResponse struct {
+	Products []productResponse `json:"products"`
+}
+
+// Batch handles POST /api/v1/products/batch, returning the products with the given ids as they
+// are in the database right now. Ids with no product are left out of the response.
+func (h *ProductsHandler) Batch(w http.ResponseWriter, r *http.Request) {
+	var req batchProductsRequest
+	if err := validation.DecodeJSON(w, r, &req); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	ids := make([]uuid.UUID, len(req.IDs))
+	var violations validation.Violations
+	for i, raw := range req.IDs {
+		id, err := uuid.Parse(raw)
+		violations.Check(err == nil, fmt.Sprintf("ids[%d]", i), "must be a valid uuid")
+		ids[i] = id
+	}
+	if err := violations.Err(); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	products, err := h.products.GetByIDs(r.Context(), ids)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	responses := make([]productResponse, len(products))
+	for i, product := range products {
+		responses[i] = newProductResponse(product)
+	}
+
+	h.writeJSON(w, http.StatusOK, batchProductsResponse{Products: responses})
+}
+
 type inventoryResponse struct {
 	ProductID         string `json:"product_id"`
 	QuantityAvailable int    `json:"quantity_available"`
@@ -140,13 +186,13 @@ type inventoryResponse struct {
 func (h *ProductsHandler) Inventory(w http.ResponseWriter, r *http.Request) {
 	productID, err := uuid.Parse(r.PathValue("product_id"))
 	if err != nil {
-		h.writeError(w, apperrors.NewBadRequest("invalid product id"))
+		h.writeError(w, r, apperrors.NewBadRequest("invalid product id"))
 		return
 	}
 
 	stock, err := h.inventory.GetByProductID(r.Context(), productID)
 	if err != nil {
-		h.writeError(w, err)
+		h.writeError(w, r, err)
 		return
 	}
 
@@ -189,11 +235,13 @@ func (h *ProductsHandler) writeJSON(w http.ResponseWriter, status int, body any)
 	}
 }
 
-func (h *ProductsHandler) writeError(w http.ResponseWriter, err error) {
+func (h *ProductsHandler) writeError(w http.ResponseWriter, r *http.Request, err error) {
 	var appErr *apperrors.AppError
 	if !stderrors.As(err, &appErr) {
 		h.logger.Error("unexpected error", zap.Error(err))
 		appErr = apperrors.NewInternalServerError("internal server error")
 	}
-	h.writeJSON(w, appErr.HTTPCode, appErr)
+	if err := apperrors.WriteProblem(w, r, appErr); err != nil {
+		h.logger.Error("failed to encode error response", zap.Error(err))
+	}
 }
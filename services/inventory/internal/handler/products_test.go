@@ -6,6 +6,7 @@ import (
 	stderrors "errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/inventory/internal/domain"
@@ -41,6 +42,19 @@ func (f *fakeProductRepository) GetByID(_ context.Context, id uuid.UUID) (*domai
 	return product, f.getStale, nil
 }
 
+func (f *fakeProductRepository) GetByIDs(_ context.Context, ids []uuid.UUID) ([]*domain.Product, error) {
+	if f.getErr != nil {
+		return nil, f.getErr
+	}
+	products := make([]*domain.Product, 0, len(ids))
+	for _, id := range ids {
+		if product, ok := f.productsByID[id]; ok {
+			products = append(products, product)
+		}
+	}
+	return products, nil
+}
+
 func (f *fakeProductRepository) List(_ context.Context, category string, activeOnly bool, limit, offset int) ([]*domain.Product, error) {
 	f.lastCategory = category
 	f.lastActive = activeOnly
@@ -75,17 +89,23 @@ func newTestMux(t *testing.T, products ProductRepository, inventory InventoryRep
 	mux := http.NewServeMux()
 	mux.HandleFunc("GET /api/v1/products", h.List)
 	mux.HandleFunc("GET /api/v1/products/{id}", h.Get)
+	mux.HandleFunc("POST /api/v1/products/batch", h.Batch)
 	mux.HandleFunc("GET /api/v1/inventory/{product_id}", h.Inventory)
 	return mux
 }
 
-func decodeAppError(t *testing.T, body []byte) apperrors.AppError {
+// decodeProblem decodes the RFC 7807 problem in w, failing the test unless it was served as
+// application/problem+json.
+func decodeProblem(t *testing.T, w *httptest.ResponseRecorder) apperrors.Problem {
 	t.Helper()
-	var appErr apperrors.AppError
-	if err := json.Unmarshal(body, &appErr); err != nil {
+	if got := w.Header().Get("Content-Type"); got != apperrors.ProblemContentType {
+		t.Errorf("Content-Type = %q, want %q", got, apperrors.ProblemContentType)
+	}
+	var problem apperrors.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
 		t.Fatalf("failed to decode error body: %v", err)
 	}
-	return appErr
+	return problem
 }
 
 func TestProductsHandler_List(t *testing.T) {
@@ -182,9 +202,9 @@ func TestProductsHandler_List(t *testing.T) {
 				return
 			}
 
-			appErr := decodeAppError(t, w.Body.Bytes())
-			if appErr.Code != tt.wantCode {
-				t.Errorf("Code = %v, want %v", appErr.Code, tt.wantCode)
+			problem := decodeProblem(t, w)
+			if problem.Code != tt.wantCode {
+				t.Errorf("Code = %v, want %v", problem.Code, tt.wantCode)
 			}
 		})
 	}
@@ -269,9 +289,96 @@ func TestProductsHandler_Get(t *testing.T) {
 				return
 			}
 
-			appErr := decodeAppError(t, w.Body.Bytes())
-			if appErr.Code != tt.wantCode {
-				t.Errorf("Code = %v, want %v", appErr.Code, tt.wantCode)
+			problem := decodeProblem(t, w)
+			if problem.Code != tt.wantCode {
+				t.Errorf("Code = %v, want %v", problem.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestProductsHandler_Batch(t *testing.T) {
+	product := &domain.Product{ID: uuid.New(), Name: "Widget", SKU: "WID-1", PriceCents: 999, Currency: "USD", IsActive: true}
+	repo := &fakeProductRepository{productsByID: map[uuid.UUID]*domain.Product{product.ID: product}}
+
+	tests := []struct {
+		name       string
+		body       string
+		repo       *fakeProductRepository
+		wantStatus int
+		wantCode   string
+		wantIDs    []string
+	}{
+		{
+			name:       "unknown ids are left out",
+			body:       `{"ids":["` + product.ID.String() + `","` + uuid.New().String() + `"]}`,
+			repo:       repo,
+			wantStatus: http.StatusOK,
+			wantIDs:    []string{product.ID.String()},
+		},
+		{
+			name:       "no ids",
+			body:       `{"ids":[]}`,
+			repo:       repo,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "VALIDATION_ERROR",
+		},
+		{
+			name:       "invalid id",
+			body:       `{"ids":["not-a-uuid"]}`,
+			repo:       repo,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "VALIDATION_ERROR",
+		},
+		{
+			name:       "too many ids",
+			body:       `{"ids":[` + strings.Repeat(`"`+product.ID.String()+`",`, 100) + `"` + product.ID.String() + `"]}`,
+			repo:       repo,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "VALIDATION_ERROR",
+		},
+		{
+			name:       "repository failure",
+			body:       `{"ids":["` + product.ID.String() + `"]}`,
+			repo:       &fakeProductRepository{getErr: errTestRepository},
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   "INTERNAL_SERVER_ERROR",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := newTestMux(t, tt.repo, &fakeInventoryRepository{})
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/products/batch", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body=%s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+
+			if tt.wantStatus == http.StatusOK {
+				var got batchProductsResponse
+				if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				if len(got.Products) != len(tt.wantIDs) {
+					t.Fatalf("len(Products) = %d, want %d", len(got.Products), len(tt.wantIDs))
+				}
+				for i, id := range tt.wantIDs {
+					if got.Products[i].ID != id {
+						t.Errorf("Products[%d].ID = %v, want %v", i, got.Products[i].ID, id)
+					}
+				}
+				return
+			}
+
+			problem := decodeProblem(t, w)
+			if problem.Code != tt.wantCode {
+				t.Errorf("Code = %v, want %v", problem.Code, tt.wantCode)
 			}
 		})
 	}
@@ -341,9 +448,9 @@ func TestProductsHandler_Inventory(t *testing.T) {
 				return
 			}
 
-			appErr := decodeAppError(t, w.Body.Bytes())
-			if appErr.Code != tt.wantCode {
-				t.Errorf("Code = %v, want %v", appErr.Code, tt.wantCode)
+			problem := decodeProblem(t, w)
+			if problem.Code != tt.wantCode {
+				t.Errorf("Code = %v, want %v", problem.Code, tt.wantCode)
 			}
 		})
 	}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/inventory/internal/domain"
 	apperrors "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/errors"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/validation"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
@@ -30,13 +31,13 @@ func NewReservationsHandler(stock StockRepository, logger *zap.Logger) *Reservat
 }
 
 type reserveItemRequest struct {
-	ProductID string `json:"product_id"`
-	Quantity  int    `json:"quantity"`
+	ProductID string `json:"product_id" validate:"required,uuid"`
+	Quantity  int    `json:"quantity" validate:"gt=0"`
 }
 
 type reserveRequest struct {
-	OrderID string               `json:"order_id"`
-	Items   []reserveItemRequest `json:"items"`
+	OrderID string               `json:"order_id" validate:"required,uuid"`
+	Items   []reserveItemRequest `json:"items" validate:"min=1"`
 }
 
 type reservedItemResponse struct {
@@ -52,37 +53,19 @@ type reserveResponse struct {
 // Reserve handles POST /api/v1/inventory/reservations.
 func (h *ReservationsHandler) Reserve(w http.ResponseWriter, r *http.Request) {
 	var req reserveRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeError(w, apperrors.NewBadRequest("invalid request body"))
-		return
-	}
-
-	orderID, err := uuid.Parse(req.OrderID)
-	if err != nil {
-		h.writeError(w, apperrors.NewValidationError("order_id", "must be a valid uuid"))
-		return
-	}
-	if len(req.Items) == 0 {
-		h.writeError(w, apperrors.NewValidationError("items", "must contain at least one item"))
+	if err := validation.DecodeJSON(w, r, &req); err != nil {
+		h.writeError(w, r, err)
 		return
 	}
 
+	orderID := uuid.MustParse(req.OrderID)
 	items := make([]domain.ReserveItem, len(req.Items))
 	for i, item := range req.Items {
-		productID, err := uuid.Parse(item.ProductID)
-		if err != nil {
-			h.writeError(w, apperrors.NewValidationError("items.product_id", "must be a valid uuid"))
-			return
-		}
-		if item.Quantity <= 0 {
-			h.writeError(w, apperrors.NewValidationError("items.quantity", "must be greater than zero"))
-			return
-		}
-		items[i] = domain.ReserveItem{ProductID: productID, Quantity: item.Quantity}
+		items[i] = domain.ReserveItem{ProductID: uuid.MustParse(item.ProductID), Quantity: item.Quantity}
 	}
 
 	if err := h.stock.Reserve(r.Context(), orderID, items); err != nil {
-		h.writeError(w, err)
+		h.writeError(w, r, err)
 		return
 	}
 
@@ -97,12 +80,12 @@ func (h *ReservationsHandler) Reserve(w http.ResponseWriter, r *http.Request) {
 func (h *ReservationsHandler) Release(w http.ResponseWriter, r *http.Request) {
 	orderID, err := uuid.Parse(r.PathValue("order_id"))
 	if err != nil {
-		h.writeError(w, apperrors.NewBadRequest("invalid order id"))
+		h.writeError(w, r, apperrors.NewBadRequest("invalid order id"))
 		return
 	}
 
 	if err := h.stock.Release(r.Context(), orderID); err != nil {
-		h.writeError(w, err)
+		h.writeError(w, r, err)
 		return
 	}
 
@@ -117,11 +100,13 @@ func (h *ReservationsHandler) writeJSON(w http.ResponseWriter, status int, body
 	}
 }
 
-func (h *ReservationsHandler) writeError(w http.ResponseWriter, err error) {
+func (h *ReservationsHandler) writeError(w http.ResponseWriter, r *http.Request, err error) {
 	var appErr *apperrors.AppError
 	if !stderrors.As(err, &appErr) {
 		h.logger.Error("unexpected error", zap.Error(err))
 		appErr = apperrors.NewInternalServerError("internal server error")
 	}
-	h.writeJSON(w, appErr.HTTPCode, appErr)
+	if err := apperrors.WriteProblem(w, r, appErr); err != nil {
+		h.logger.Error("failed to encode error response", zap.Error(err))
+	}
 }
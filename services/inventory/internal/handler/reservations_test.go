@@ -101,6 +101,13 @@ func TestReservationsHandler_Reserve(t *testing.T) {
 			wantStatus: http.StatusBadRequest,
 			wantCode:   "VALIDATION_ERROR",
 		},
+		{
+			name:       "unknown field",
+			body:       `{"order_id":"` + uuid.New().String() + `","items":[{"product_id":"` + productID.String() + `","quantity":1,"warehouse":"A"}]}`,
+			repo:       &fakeStockRepository{},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "VALIDATION_ERROR",
+		},
 		{
 			name:       "insufficient inventory",
 			body:       validBody,
@@ -137,9 +144,9 @@ func TestReservationsHandler_Reserve(t *testing.T) {
 				return
 			}
 
-			appErr := decodeAppError(t, w.Body.Bytes())
-			if appErr.Code != tt.wantCode {
-				t.Errorf("Code = %v, want %v", appErr.Code, tt.wantCode)
+			problem := decodeProblem(t, w)
+			if problem.Code != tt.wantCode {
+				t.Errorf("Code = %v, want %v", problem.Code, tt.wantCode)
 			}
 		})
 	}
@@ -189,9 +196,9 @@ func TestReservationsHandler_Release(t *testing.T) {
 			}
 
 			if tt.wantStatus != http.StatusNoContent {
-				appErr := decodeAppError(t, w.Body.Bytes())
-				if appErr.Code != tt.wantCode {
-					t.Errorf("Code = %v, want %v", appErr.Code, tt.wantCode)
+				problem := decodeProblem(t, w)
+				if problem.Code != tt.wantCode {
+					t.Errorf("Code = %v, want %v", problem.Code, tt.wantCode)
 				}
 			}
 		})
@@ -8,15 +8,22 @@ import (
 	"fmt"
 
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/inventory/internal/domain"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/database"
 	apperrors "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/errors"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+// productsReadKey is the read-your-writes key for the catalog as a whole: a created or updated
+// product can move onto any List page.
+const productsReadKey = "products"
+
 const productColumns = `id, name, description, sku, category, brand, price_cents, cost_cents, currency, is_active, created_at, updated_at, version`
 
 // ProductRepository stores and retrieves products in postgres.
 type ProductRepository struct {
-	db *sql.DB
+	db    *sql.DB
+	reads database.ReadRouter
 }
 
 // NewProductRepository builds a repository backed by the given database handle.
@@ -24,6 +31,27 @@ func NewProductRepository(db *sql.DB) *ProductRepository {
 	return &ProductRepository{db: db}
 }
 
+// SetReadRouter lets List read from a replica through router, so catalog browsing does not
+// compete with reservations on the primary. Create and Update keep List on the primary for the
+// read-your-writes window. Passing nil reads everything from the primary.
+func (r *ProductRepository) SetReadRouter(router database.ReadRouter) {
+	r.reads = router
+}
+
+// reader returns where List reads.
+func (r *ProductRepository) reader(ctx context.Context) database.Queryer {
+	if r.reads == nil {
+		return r.db
+	}
+	return r.reads.Reader(ctx, productsReadKey)
+}
+
+func (r *ProductRepository) noteWrite() {
+	if r.reads != nil {
+		r.reads.NoteWrite(productsReadKey)
+	}
+}
+
 // GetByID returns the product with the given id.
 func (r *ProductRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Product, error) {
 	product, err := r.scanProduct(r.db.QueryRowContext(ctx, `
@@ -52,9 +80,40 @@ func (r *ProductRepository) GetBySKU(ctx context.Context, sku string) (*domain.P
 	return product, nil
 }
 
+// GetByIDs returns the products with the given ids, in no particular order. An id with no product
+// is left out rather than reported, so the caller can tell which ones are missing. It always
+// reads the primary: callers use it to price orders and need the current row.
+func (r *ProductRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*domain.Product, error) {
+	raw := make([]string, len(ids))
+	for i, id := range ids {
+		raw[i] = id.String()
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+productColumns+` FROM products WHERE id = ANY($1::uuid[])
+	`, pq.Array(raw))
+	if err != nil {
+		return nil, fmt.Errorf("select products: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	products := make([]*domain.Product, 0, len(ids))
+	for rows.Next() {
+		product, err := r.scanProduct(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan product: %w", err)
+		}
+		products = append(products, product)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate products: %w", err)
+	}
+	return products, nil
+}
+
 // List returns a page of products, optionally narrowed by category and active status.
 func (r *ProductRepository) List(ctx context.Context, category string, activeOnly bool, limit, offset int) ([]*domain.Product, error) {
-	rows, err := r.db.QueryContext(ctx, `
+	rows, err := r.reader(ctx).QueryContext(ctx, `
 		SELECT `+productColumns+` FROM products
 		WHERE ($1 = '' OR category = $1) AND (NOT $2 OR is_active = true)
 		ORDER BY created_at DESC
@@ -91,6 +150,7 @@ func (r *ProductRepository) Create(ctx context.Context, product *domain.Product)
 	if err != nil {
 		return fmt.Errorf("insert product: %w", err)
 	}
+	r.noteWrite()
 	return nil
 }
 
@@ -115,6 +175,7 @@ func (r *ProductRepository) Update(ctx context.Context, product *domain.Product)
 	if rows == 0 {
 		return apperrors.NewConflict(fmt.Sprintf("product %s was modified concurrently", product.ID))
 	}
+	r.noteWrite()
 	return nil
 }
 
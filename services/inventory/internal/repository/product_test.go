@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"regexp"
 	"testing"
 	"time"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/inventory/internal/domain"
 	apperrors "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/errors"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 var productColumnNames = []string{
@@ -361,3 +363,29 @@ func TestProductRepository_Update(t *testing.T) {
 		}
 	})
 }
+
+func TestProductRepository_GetByIDs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	want := newTestProduct()
+	missing := uuid.New()
+	mock.ExpectQuery(regexp.QuoteMeta("FROM products WHERE id = ANY($1::uuid[])")).
+		WithArgs(pq.Array([]string{want.ID.String(), missing.String()})).
+		WillReturnRows(productRow(want))
+
+	repo := NewProductRepository(db)
+	got, err := repo.GetByIDs(context.Background(), []uuid.UUID{want.ID, missing})
+	if err != nil {
+		t.Fatalf("GetByIDs() error = %v", err)
+	}
+	if len(got) != 1 || got[0].ID != want.ID || got[0].PriceCents != want.PriceCents {
+		t.Errorf("GetByIDs() = %+v, want only %v", got, want.ID)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
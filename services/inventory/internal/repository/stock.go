@@ -8,6 +8,7 @@ import (
 	"fmt"
 
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/inventory/internal/domain"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/database"
 	apperrors "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/errors"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/events"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/outbox"
@@ -47,102 +48,83 @@ func (r *StockRepository) GetByProductID(ctx context.Context, productID uuid.UUI
 // that is already reserved is a no-op for that item; if every item is a duplicate, no event is
 // enqueued.
 func (r *StockRepository) Reserve(ctx context.Context, orderID uuid.UUID, items []domain.ReserveItem) error {
-	tx, err := r.db.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("begin transaction: %w", err)
-	}
-	defer func() { _ = tx.Rollback() }()
+	return database.WithTx(ctx, r.db, func(ctx context.Context, tx *sql.Tx) error {
+		var reservedItems []domain.ReserveItem
+		for _, item := range items {
+			duplicate, err := r.hasReservedRow(ctx, tx, orderID, item.ProductID)
+			if err != nil {
+				return err
+			}
+			if duplicate {
+				continue
+			}
 
-	var reservedItems []domain.ReserveItem
-	for _, item := range items {
-		duplicate, err := r.hasReservedRow(ctx, tx, orderID, item.ProductID)
-		if err != nil {
-			return err
-		}
-		if duplicate {
-			continue
-		}
+			result, err := tx.ExecContext(ctx, `
+				UPDATE inventory
+				SET quantity_available = quantity_available - $1, quantity_reserved = quantity_reserved + $1
+				WHERE product_id = $2 AND quantity_available >= $1
+			`, item.Quantity, item.ProductID)
+			if err != nil {
+				return fmt.Errorf("update inventory: %w", err)
+			}
 
-		result, err := tx.ExecContext(ctx, `
-			UPDATE inventory
-			SET quantity_available = quantity_available - $1, quantity_reserved = quantity_reserved + $1
-			WHERE product_id = $2 AND quantity_available >= $1
-		`, item.Quantity, item.ProductID)
-		if err != nil {
-			return fmt.Errorf("update inventory: %w", err)
-		}
+			rows, err := result.RowsAffected()
+			if err != nil {
+				return fmt.Errorf("read rows affected: %w", err)
+			}
+			if rows == 0 {
+				available, err := r.availableQuantity(ctx, tx, item.ProductID)
+				if err != nil {
+					return err
+				}
+				return apperrors.NewInsufficientInventory(item.ProductID.String(), item.Quantity, available)
+			}
 
-		rows, err := result.RowsAffected()
-		if err != nil {
-			return fmt.Errorf("read rows affected: %w", err)
-		}
-		if rows == 0 {
-			available, err := r.availableQuantity(ctx, tx, item.ProductID)
+			reservation, err := domain.NewReservation(orderID, item.ProductID, item.Quantity)
 			if err != nil {
 				return err
 			}
-			return apperrors.NewInsufficientInventory(item.ProductID.String(), item.Quantity, available)
-		}
 
-		reservation, err := domain.NewReservation(orderID, item.ProductID, item.Quantity)
-		if err != nil {
-			return err
-		}
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO inventory_reservations (id, order_id, product_id, quantity, status, created_at, updated_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7)
+			`, reservation.ID, reservation.OrderID, reservation.ProductID, reservation.Quantity,
+				string(reservation.Status), reservation.CreatedAt, reservation.UpdatedAt); err != nil {
+				return fmt.Errorf("insert reservation: %w", err)
+			}
 
-		if _, err := tx.ExecContext(ctx, `
-			INSERT INTO inventory_reservations (id, order_id, product_id, quantity, status, created_at, updated_at)
-			VALUES ($1, $2, $3, $4, $5, $6, $7)
-		`, reservation.ID, reservation.OrderID, reservation.ProductID, reservation.Quantity,
-			string(reservation.Status), reservation.CreatedAt, reservation.UpdatedAt); err != nil {
-			return fmt.Errorf("insert reservation: %w", err)
-		}
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO inventory_movements (id, product_id, movement_type, quantity, reference_id, reference_type)
+				VALUES ($1, $2, 'reservation', $3, $4, 'order')
+			`, uuid.New(), item.ProductID, -item.Quantity, orderID); err != nil {
+				return fmt.Errorf("insert movement: %w", err)
+			}
 
-		if _, err := tx.ExecContext(ctx, `
-			INSERT INTO inventory_movements (id, product_id, movement_type, quantity, reference_id, reference_type)
-			VALUES ($1, $2, 'reservation', $3, $4, 'order')
-		`, uuid.New(), item.ProductID, -item.Quantity, orderID); err != nil {
-			return fmt.Errorf("insert movement: %w", err)
+			reservedItems = append(reservedItems, item)
 		}
 
-		reservedItems = append(reservedItems, item)
-	}
-
-	if len(reservedItems) > 0 {
-		if err := r.enqueueEvent(ctx, tx, events.EventTypeInventoryReserved, orderID, reservedItems); err != nil {
-			return err
+		if len(reservedItems) > 0 {
+			if err := r.enqueueEvent(ctx, tx, events.EventTypeInventoryReserved, orderID, reservedItems); err != nil {
+				return err
+			}
 		}
-	}
-
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("commit transaction: %w", err)
-	}
-	return nil
+		return nil
+	})
 }
 
 // Release returns every reserved item of an order to available stock, in one transaction, and
 // enqueues inventory.released. An order with no active reservations is a no-op.
 func (r *StockRepository) Release(ctx context.Context, orderID uuid.UUID) error {
-	tx, err := r.db.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("begin transaction: %w", err)
-	}
-	defer func() { _ = tx.Rollback() }()
-
-	items, err := r.release(ctx, tx, orderID)
-	if err != nil {
-		return err
-	}
-
-	if len(items) > 0 {
-		if err := r.enqueueEvent(ctx, tx, events.EventTypeInventoryReleased, orderID, items); err != nil {
+	return database.WithTx(ctx, r.db, func(ctx context.Context, tx *sql.Tx) error {
+		items, err := r.release(ctx, tx, orderID)
+		if err != nil {
 			return err
 		}
-	}
-
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("commit transaction: %w", err)
-	}
-	return nil
+		if len(items) == 0 {
+			return nil
+		}
+		return r.enqueueEvent(ctx, tx, events.EventTypeInventoryReleased, orderID, items)
+	})
 }
 
 // ReleaseInTx releases every reserved item of an order within tx, owned by the caller, so it can
@@ -13,9 +13,11 @@ import (
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/cache"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/database"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/events"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/featureflags"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/httpserver"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/metrics"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/middleware"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/tlsconfig"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
@@ -38,6 +40,19 @@ type Options struct {
 	// CacheMetrics is optional: a nil value means product cache reads are not recorded as hits
 	// or misses.
 	CacheMetrics *cache.Metrics
+	// Cache is optional: when set, product reads go through it instead of a cache built from
+	// Redis, so the caller can share one local tier with the cache invalidation consumer.
+	// CacheMetrics is not applied to it.
+	Cache *cache.Cache
+	// CacheEnabled is optional: when set, it is asked on every product read whether to go through
+	// the cache, so caching can be rolled out per user or switched off without a restart. Nil
+	// sends every read through the cache.
+	CacheEnabled func(ctx context.Context) bool
+	// Startup is optional: when set, /health/startup reports it, typically the lifecycle
+	// manager's StartupCheck.
+	Startup httpserver.Check
+	// TLS is optional: when set, the server serves HTTPS with it (see httpserver.Options.TLS).
+	TLS *tlsconfig.Reloader
 }
 
 // New builds the inventory HTTP server: health checks, metrics and the shared middleware chain.
@@ -47,37 +62,52 @@ func New(opts Options) *Server {
 		registerer = prometheus.DefaultRegisterer
 	}
 
-	checks := make(map[string]httpserver.Check)
+	dependencies := make(map[string]httpserver.Dependency)
 	if opts.DB != nil {
-		checks["database"] = opts.DB.PingContext
+		dependencies["database"] = httpserver.Dependency{Check: opts.DB.PingContext}
 	}
 	if opts.Redis != nil {
-		checks["redis"] = func(ctx context.Context) error { return opts.Redis.Ping(ctx).Err() }
+		// Reads fall back to the database while Redis is down, so losing it only degrades the
+		// service.
+		dependencies["redis"] = httpserver.Dependency{
+			Check: opts.Redis.Healthy,
+			Tier:  httpserver.NonCritical,
+		}
 	}
 	if len(opts.Config.Kafka.Brokers) > 0 {
 		brokers := opts.Config.Kafka.Brokers
-		checks["kafka"] = func(ctx context.Context) error { return events.Healthy(ctx, brokers) }
+		dependencies["kafka"] = httpserver.Dependency{
+			Check: func(ctx context.Context) error { return events.Healthy(ctx, brokers) },
+		}
 	}
 
 	mux := http.NewServeMux()
-	httpserver.NewHealthHandlers(opts.Config.Service.Name, checks).Register(mux)
+	health := httpserver.NewTieredHealthHandlers(opts.Config.Service.Name, dependencies)
+	health.SetStartupCheck(opts.Startup)
+	health.Register(mux)
 
 	if opts.DB != nil {
 		stockRepo := repository.NewStockRepository(opts.DB.DB)
 
 		var productCache service.ProductCache
-		if opts.Redis != nil {
+		if opts.Cache != nil {
+			productCache = opts.Cache
+		} else if opts.Redis != nil {
 			c := cache.New(opts.Redis, 0)
 			if opts.CacheMetrics != nil {
 				c.SetMetrics(opts.CacheMetrics, "product")
 			}
 			productCache = c
 		}
-		productService := service.NewProductService(repository.NewProductRepository(opts.DB.DB), productCache)
+		productRepo := repository.NewProductRepository(opts.DB.DB)
+		productRepo.SetReadRouter(opts.DB)
+		productService := service.NewProductService(productRepo, productCache)
+		productService.SetCacheEnabled(opts.CacheEnabled)
 
 		productsHandler := handler.NewProductsHandler(productService, stockRepo, opts.Logger)
 		mux.HandleFunc("GET /api/v1/products", productsHandler.List)
 		mux.HandleFunc("GET /api/v1/products/{id}", productsHandler.Get)
+		mux.HandleFunc("POST /api/v1/products/batch", productsHandler.Batch)
 		mux.HandleFunc("GET /api/v1/inventory/{product_id}", productsHandler.Inventory)
 
 		reservationsHandler := handler.NewReservationsHandler(stockRepo, opts.Logger)
@@ -90,6 +120,7 @@ func New(opts Options) *Server {
 		middleware.Recovery(opts.Logger),
 		middleware.RequestID,
 		middleware.Logging(opts.Logger),
+		featureflags.Middleware,
 	)
 	wrappedHandler := chain(httpMetrics.Middleware(mux))
 	// otelhttp.NewHandler opens a server span for every request; the span name uses the route
@@ -109,6 +140,7 @@ func New(opts Options) *Server {
 		Addr:    opts.Config.Server.Host + ":" + opts.Config.Server.Port,
 		Handler: outer,
 		Logger:  opts.Logger,
+		TLS:     opts.TLS,
 	})
 
 	return &Server{runtime: runtime}
@@ -80,7 +80,7 @@ func TestNew_WithDatabaseAndRedis(t *testing.T) {
 		Logger:       zaptest.NewLogger(t),
 		Metrics:      prometheus.NewRegistry(),
 		DB:           &database.DB{DB: db},
-		Redis:        &database.RedisClient{Client: client},
+		Redis:        &database.RedisClient{UniversalClient: client},
 		CacheMetrics: cache.NewMetrics(prometheus.NewRegistry()),
 	})
 
@@ -131,7 +131,7 @@ func TestServer_HealthEndpoints_RedisReachable(t *testing.T) {
 		Config:  cfg,
 		Logger:  zaptest.NewLogger(t),
 		Metrics: prometheus.NewRegistry(),
-		Redis:   &database.RedisClient{Client: client},
+		Redis:   &database.RedisClient{UniversalClient: client},
 	})
 
 	req := httptest.NewRequest(http.MethodGet, "/health/ready", nil)
@@ -155,15 +155,27 @@ func TestServer_HealthEndpoints_RedisUnreachable(t *testing.T) {
 		Config:  cfg,
 		Logger:  zaptest.NewLogger(t),
 		Metrics: prometheus.NewRegistry(),
-		Redis:   &database.RedisClient{Client: client},
+		Redis:   &database.RedisClient{UniversalClient: client},
 	})
 
 	req := httptest.NewRequest(http.MethodGet, "/health/ready", nil)
 	w := httptest.NewRecorder()
 	srv.Handler().ServeHTTP(w, req)
 
-	if w.Code != http.StatusServiceUnavailable {
-		t.Fatalf("expected status %d, got %d (body=%s)", http.StatusServiceUnavailable, w.Code, w.Body.String())
+	// Redis only backs the product cache, so losing it degrades the service without taking it
+	// out of rotation.
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d (body=%s)", http.StatusOK, w.Code, w.Body.String())
+	}
+	var status httpserver.HealthStatus
+	if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if status.Status != httpserver.StatusDegraded {
+		t.Errorf("expected status %q, got %q", httpserver.StatusDegraded, status.Status)
+	}
+	if status.Details["redis"] == "" {
+		t.Errorf("expected a failure detail for redis, got %v", status.Details)
 	}
 }
 
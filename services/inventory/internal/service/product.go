@@ -2,20 +2,23 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"net/url"
 	"time"
 
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/inventory/internal/domain"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/cache"
 	"github.com/google/uuid"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
-// productCacheTTL is how long a cached product read stays valid for the fast cache-aside path.
-const productCacheTTL = 5 * time.Minute
-
-// productFallbackTTL is how long a product stays available as a stale fallback once the database
-// fails, well past productCacheTTL so it survives the normal cache-aside entry expiring.
-const productFallbackTTL = 24 * time.Hour
+// productCacheOptions keeps a cached product fresh for five minutes and, once the database fails,
+// keeps serving it stale for up to a day.
+var productCacheOptions = cache.LoadOptions{
+	TTL:      5 * time.Minute,
+	StaleTTL: 24 * time.Hour,
+}
 
 // productCacheFallbackTotal counts product reads served from a stale cache entry after the
 // database failed. It is registered once per process and shared by every ProductService.
@@ -24,23 +27,36 @@ var productCacheFallbackTotal = promauto.NewCounter(prometheus.CounterOpts{
 	Help: "Total number of product reads served from a stale cache entry after a database error.",
 })
 
+// ProductListCacheTag tags every cached List page. The cache invalidation consumer drops them all
+// on product.updated, since an update can move a product onto or off any page (its category or
+// active flag changed), not just change the page it was on.
+const ProductListCacheTag = "product-list"
+
+// productListCacheOptions keeps List pages for a minute. The short TTL bounds how much of the
+// unbounded filter and pagination key space can build up in Redis.
+var productListCacheOptions = cache.LoadOptions{
+	TTL:  time.Minute,
+	Tags: []string{ProductListCacheTag},
+}
+
 // ProductRepository is the persistence port the product service reads through.
 type ProductRepository interface {
 	GetByID(ctx context.Context, id uuid.UUID) (*domain.Product, error)
+	GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*domain.Product, error)
 	List(ctx context.Context, category string, activeOnly bool, limit, offset int) ([]*domain.Product, error)
 }
 
-// ProductCache is the cache-aside port GetByID reads and fills. A nil ProductCache disables
+// ProductCache is the read-through port GetByID and List read and fill. A nil ProductCache disables
 // caching, which is how the service degrades when Redis is unavailable at startup.
 type ProductCache interface {
-	GetJSON(ctx context.Context, key string, dest any) (bool, error)
-	SetJSON(ctx context.Context, key string, value any, ttl time.Duration) error
+	GetOrLoad(ctx context.Context, key string, dest any, opts cache.LoadOptions, load cache.LoadFunc) (bool, error)
 }
 
 // ProductService reads products through a cache-aside layer in front of repo.
 type ProductService struct {
-	repo  ProductRepository
-	cache ProductCache
+	repo         ProductRepository
+	cache        ProductCache
+	cacheEnabled func(ctx context.Context) bool
 }
 
 // NewProductService builds a ProductService backed by repo. cache may be nil, in which case
@@ -49,60 +65,73 @@ func NewProductService(repo ProductRepository, cache ProductCache) *ProductServi
 	return &ProductService{repo: repo, cache: cache}
 }
 
+// SetCacheEnabled makes GetByID and List ask enabled, on every read, whether to go through the
+// cache, so caching can be rolled out per user or switched off without a restart. Passing nil
+// sends every read through the cache.
+func (s *ProductService) SetCacheEnabled(enabled func(ctx context.Context) bool) {
+	s.cacheEnabled = enabled
+}
+
+// useCache reports whether a read on ctx goes through the cache.
+func (s *ProductService) useCache(ctx context.Context) bool {
+	return s.cache != nil && (s.cacheEnabled == nil || s.cacheEnabled(ctx))
+}
+
 // GetByID returns the product with the given id, and whether it was served stale. When a cache is
-// configured, it is read first and filled on a miss; a cache error falls back to the repository
-// rather than failing the read. When the repository itself fails, GetByID falls back to the
-// longer-lived fallback entry for id, if one was ever written, and reports it as stale instead of
-// failing the read; the repository error is returned as-is when there is nothing to fall back to
-// or the cache itself is unavailable.
+// configured and enabled for ctx, the read goes through it: concurrent misses share one
+// repository read, and a repository failure is answered from the expired cache entry, reported as
+// stale, while it is still within productCacheOptions.StaleTTL. The repository error is returned
+// as-is when there is nothing to fall back to.
 func (s *ProductService) GetByID(ctx context.Context, id uuid.UUID) (*domain.Product, bool, error) {
-	if s.cache == nil {
+	if !s.useCache(ctx) {
 		product, err := s.repo.GetByID(ctx, id)
 		return product, false, err
 	}
 
-	key := productCacheKey(id)
-
-	var cached domain.Product
-	if hit, err := s.cache.GetJSON(ctx, key, &cached); err == nil && hit {
-		return &cached, false, nil
-	}
-
-	product, err := s.repo.GetByID(ctx, id)
+	var product domain.Product
+	stale, err := s.cache.GetOrLoad(ctx, productCacheKey(id), &product, productCacheOptions, func(ctx context.Context) (any, error) {
+		return s.repo.GetByID(ctx, id)
+	})
 	if err != nil {
-		if stale, ok := s.staleFromCache(ctx, id); ok {
-			productCacheFallbackTotal.Inc()
-			return stale, true, nil
-		}
 		return nil, false, err
 	}
-
-	_ = s.cache.SetJSON(ctx, key, product, productCacheTTL)
-	_ = s.cache.SetJSON(ctx, productFallbackCacheKey(id), product, productFallbackTTL)
-	return product, false, nil
+	if stale {
+		productCacheFallbackTotal.Inc()
+	}
+	return &product, stale, nil
 }
 
-// staleFromCache looks up the long-lived fallback entry for id, used once the database itself has
-// failed and the fast cache-aside entry has already expired or was never populated.
-func (s *ProductService) staleFromCache(ctx context.Context, id uuid.UUID) (*domain.Product, bool) {
-	var cached domain.Product
-	hit, err := s.cache.GetJSON(ctx, productFallbackCacheKey(id), &cached)
-	if err != nil || !hit {
-		return nil, false
-	}
-	return &cached, true
+// GetByIDs returns the products with the given ids, leaving out any that do not exist. It bypasses
+// the cache: the order service prices orders from it, and a cached price can be up to
+// productCacheOptions.TTL behind the catalog.
+func (s *ProductService) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*domain.Product, error) {
+	return s.repo.GetByIDs(ctx, ids)
 }
 
-// List returns a page of products. List reads are not cached, since the pagination and filter
-// combinations make the key space unbounded.
+// List returns a page of products. When a cache is configured and enabled for ctx, each
+// combination of filters and pagination is cached as its own page under ProductListCacheTag; a
+// failing repository is not answered with a stale page.
 func (s *ProductService) List(ctx context.Context, category string, activeOnly bool, limit, offset int) ([]*domain.Product, error) {
-	return s.repo.List(ctx, category, activeOnly, limit, offset)
+	if !s.useCache(ctx) {
+		return s.repo.List(ctx, category, activeOnly, limit, offset)
+	}
+
+	var products []*domain.Product
+	key := productListCacheKey(category, activeOnly, limit, offset)
+	if _, err := s.cache.GetOrLoad(ctx, key, &products, productListCacheOptions, func(ctx context.Context) (any, error) {
+		return s.repo.List(ctx, category, activeOnly, limit, offset)
+	}); err != nil {
+		return nil, err
+	}
+	return products, nil
 }
 
 func productCacheKey(id uuid.UUID) string {
 	return "product:" + id.String()
 }
 
-func productFallbackCacheKey(id uuid.UUID) string {
-	return "product:" + id.String() + ":fallback"
+// productListCacheKey escapes category so a category containing ":" cannot collide with another
+// filter combination.
+func productListCacheKey(category string, activeOnly bool, limit, offset int) string {
+	return fmt.Sprintf("products:list:%s:%t:%d:%d", url.QueryEscape(category), activeOnly, limit, offset)
 }
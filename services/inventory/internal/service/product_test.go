@@ -4,10 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"slices"
 	"testing"
 	"time"
 
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/inventory/internal/domain"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/cache"
 	"github.com/google/uuid"
 	"github.com/prometheus/client_golang/prometheus/testutil"
 )
@@ -22,6 +24,7 @@ type fakeProductRepository struct {
 
 	listResult   []*domain.Product
 	listErr      error
+	listCalls    int
 	lastCategory string
 	lastActive   bool
 	lastLimit    int
@@ -36,49 +39,70 @@ func (f *fakeProductRepository) GetByID(_ context.Context, _ uuid.UUID) (*domain
 	return f.product, nil
 }
 
+func (f *fakeProductRepository) GetByIDs(_ context.Context, ids []uuid.UUID) ([]*domain.Product, error) {
+	f.getCalls++
+	if f.getErr != nil {
+		return nil, f.getErr
+	}
+	if f.product == nil || !slices.Contains(ids, f.product.ID) {
+		return []*domain.Product{}, nil
+	}
+	return []*domain.Product{f.product}, nil
+}
+
 func (f *fakeProductRepository) List(_ context.Context, category string, activeOnly bool, limit, offset int) ([]*domain.Product, error) {
 	f.lastCategory = category
 	f.lastActive = activeOnly
 	f.lastLimit = limit
 	f.lastOffset = offset
+	f.listCalls++
 	if f.listErr != nil {
 		return nil, f.listErr
 	}
 	return f.listResult, nil
 }
 
-// fakeProductCache is an in-memory ProductCache test double.
+// fakeProductCache is an in-memory ProductCache test double. Entries in fresh are served without
+// loading; entries in stale are only returned when the load fails, mirroring cache.GetOrLoad's
+// stale-if-error behavior.
 type fakeProductCache struct {
-	store  map[string][]byte
-	getErr error
-	setErr error
+	fresh    map[string][]byte
+	stale    map[string][]byte
+	lastOpts cache.LoadOptions
 }
 
 func newFakeProductCache() *fakeProductCache {
-	return &fakeProductCache{store: make(map[string][]byte)}
+	return &fakeProductCache{fresh: make(map[string][]byte), stale: make(map[string][]byte)}
 }
 
-func (f *fakeProductCache) GetJSON(_ context.Context, key string, dest any) (bool, error) {
-	if f.getErr != nil {
-		return false, f.getErr
-	}
-	raw, ok := f.store[key]
-	if !ok {
-		return false, nil
+func (f *fakeProductCache) GetOrLoad(ctx context.Context, key string, dest any, opts cache.LoadOptions, load cache.LoadFunc) (bool, error) {
+	f.lastOpts = opts
+	if raw, ok := f.fresh[key]; ok {
+		return false, json.Unmarshal(raw, dest)
 	}
-	return true, json.Unmarshal(raw, dest)
-}
 
-func (f *fakeProductCache) SetJSON(_ context.Context, key string, value any, _ time.Duration) error {
-	if f.setErr != nil {
-		return f.setErr
+	value, err := load(ctx)
+	if err != nil {
+		if raw, ok := f.stale[key]; ok {
+			return true, json.Unmarshal(raw, dest)
+		}
+		return false, err
 	}
+
 	data, err := json.Marshal(value)
 	if err != nil {
-		return err
+		return false, err
+	}
+	f.fresh[key] = data
+	return false, json.Unmarshal(data, dest)
+}
+
+// expire moves every fresh entry into the stale set, as if its TTL had passed.
+func (f *fakeProductCache) expire() {
+	for key, raw := range f.fresh {
+		f.stale[key] = raw
+		delete(f.fresh, key)
 	}
-	f.store[key] = data
-	return nil
 }
 
 func newTestProduct() *domain.Product {
@@ -137,6 +161,26 @@ func TestProductService_GetByID_NilCacheAlwaysReadsRepository(t *testing.T) {
 	}
 }
 
+func TestProductService_CacheDisabledForRequestReadsRepository(t *testing.T) {
+	product := newTestProduct()
+	repo := &fakeProductRepository{product: product, listResult: []*domain.Product{product}}
+	svc := NewProductService(repo, newFakeProductCache())
+	svc.SetCacheEnabled(func(context.Context) bool { return false })
+
+	for range 2 {
+		if _, _, err := svc.GetByID(context.Background(), product.ID); err != nil {
+			t.Fatalf("GetByID() error = %v", err)
+		}
+		if _, err := svc.List(context.Background(), "", true, 10, 0); err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+	}
+
+	if repo.getCalls != 2 || repo.listCalls != 2 {
+		t.Errorf("repository calls = %d GetByID, %d List, want 2 each (cache disabled for the request)", repo.getCalls, repo.listCalls)
+	}
+}
+
 func TestProductService_GetByID_RepositoryError(t *testing.T) {
 	repo := &fakeProductRepository{getErr: errTestProductRepository}
 	svc := NewProductService(repo, newFakeProductCache())
@@ -146,46 +190,16 @@ func TestProductService_GetByID_RepositoryError(t *testing.T) {
 	}
 }
 
-func TestProductService_GetByID_CacheErrorFallsBackToRepository(t *testing.T) {
+func TestProductService_GetByID_RepositoryErrorFallsBackToStaleCache(t *testing.T) {
 	product := newTestProduct()
 	repo := &fakeProductRepository{product: product}
 	cache := newFakeProductCache()
-	cache.getErr = errors.New("redis unavailable")
 	svc := NewProductService(repo, cache)
 
-	got, stale, err := svc.GetByID(context.Background(), product.ID)
-	if err != nil {
-		t.Fatalf("GetByID() error = %v, want nil", err)
-	}
-	if got.ID != product.ID {
-		t.Errorf("GetByID() ID = %v, want %v", got.ID, product.ID)
-	}
-	if stale {
-		t.Error("GetByID() stale = true, want false")
-	}
-	if repo.getCalls != 1 {
-		t.Errorf("repository GetByID calls = %d, want 1", repo.getCalls)
-	}
-}
-
-// warmUpThenExpireFastEntry populates both the fast cache-aside entry and the long-lived fallback
-// entry for product through a successful read, then removes only the fast entry, simulating it
-// having expired while the fallback entry, written with a much longer TTL, survives.
-func warmUpThenExpireFastEntry(t *testing.T, svc *ProductService, cache *fakeProductCache, product *domain.Product) {
-	t.Helper()
 	if _, _, err := svc.GetByID(context.Background(), product.ID); err != nil {
 		t.Fatalf("GetByID() warm-up call error = %v", err)
 	}
-	delete(cache.store, productCacheKey(product.ID))
-}
-
-func TestProductService_GetByID_RepositoryErrorFallsBackToStaleCache(t *testing.T) {
-	product := newTestProduct()
-	repo := &fakeProductRepository{product: product}
-	cache := newFakeProductCache()
-	svc := NewProductService(repo, cache)
-
-	warmUpThenExpireFastEntry(t, svc, cache, product)
+	cache.expire()
 	repo.getErr = errTestProductRepository
 
 	got, stale, err := svc.GetByID(context.Background(), product.ID)
@@ -206,7 +220,10 @@ func TestProductService_GetByID_RepositoryErrorRecordsFallbackMetric(t *testing.
 	cache := newFakeProductCache()
 	svc := NewProductService(repo, cache)
 
-	warmUpThenExpireFastEntry(t, svc, cache, product)
+	if _, _, err := svc.GetByID(context.Background(), product.ID); err != nil {
+		t.Fatalf("GetByID() warm-up call error = %v", err)
+	}
+	cache.expire()
 	repo.getErr = errTestProductRepository
 
 	before := testutil.ToFloat64(productCacheFallbackTotal)
@@ -221,18 +238,53 @@ func TestProductService_GetByID_RepositoryErrorRecordsFallbackMetric(t *testing.
 	}
 }
 
+func TestProductService_GetByID_KeepsStaleEntriesForADay(t *testing.T) {
+	product := newTestProduct()
+	cache := newFakeProductCache()
+	svc := NewProductService(&fakeProductRepository{product: product}, cache)
+
+	if _, _, err := svc.GetByID(context.Background(), product.ID); err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if cache.lastOpts.TTL != 5*time.Minute || cache.lastOpts.StaleTTL != 24*time.Hour {
+		t.Errorf("GetOrLoad() options = %+v, want TTL 5m and StaleTTL 24h", cache.lastOpts)
+	}
+}
+
 func TestProductService_GetByID_RepositoryErrorWithNothingCachedReturnsRepositoryError(t *testing.T) {
 	repo := &fakeProductRepository{getErr: errTestProductRepository}
 	cache := newFakeProductCache()
 	svc := NewProductService(repo, cache)
 
-	// The normal cache-aside read above already misses (nothing was ever cached for this id), so
-	// there is no stale value to fall back to.
+	// Nothing was ever cached for this id, so there is no stale value to fall back to.
 	if _, stale, err := svc.GetByID(context.Background(), uuid.New()); !errors.Is(err, errTestProductRepository) || stale {
 		t.Errorf("GetByID() = (stale=%v, err=%v), want (stale=false, err=%v)", stale, err, errTestProductRepository)
 	}
 }
 
+func TestProductService_GetByIDs_BypassesCache(t *testing.T) {
+	product := newTestProduct()
+	repo := &fakeProductRepository{product: product}
+	cache := newFakeProductCache()
+	svc := NewProductService(repo, cache)
+
+	for i := 0; i < 2; i++ {
+		products, err := svc.GetByIDs(context.Background(), []uuid.UUID{product.ID, uuid.New()})
+		if err != nil {
+			t.Fatalf("GetByIDs() error = %v", err)
+		}
+		if len(products) != 1 || products[0].ID != product.ID {
+			t.Fatalf("GetByIDs() = %v, want only %v", products, product.ID)
+		}
+	}
+	if repo.getCalls != 2 {
+		t.Errorf("repository calls = %d, want 2: prices must not be served from the cache", repo.getCalls)
+	}
+	if len(cache.fresh) != 0 {
+		t.Errorf("cache entries = %d, want 0", len(cache.fresh))
+	}
+}
+
 func TestProductService_List_DelegatesToRepository(t *testing.T) {
 	want := []*domain.Product{newTestProduct()}
 	repo := &fakeProductRepository{listResult: want}
@@ -250,3 +302,54 @@ func TestProductService_List_DelegatesToRepository(t *testing.T) {
 			repo.lastCategory, repo.lastActive, repo.lastLimit, repo.lastOffset)
 	}
 }
+
+func TestProductService_List_SecondReadOfSamePageIsCached(t *testing.T) {
+	repo := &fakeProductRepository{listResult: []*domain.Product{newTestProduct()}}
+	cache := newFakeProductCache()
+	svc := NewProductService(repo, cache)
+
+	for range 2 {
+		if _, err := svc.List(context.Background(), "gadgets", true, 5, 10); err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+	}
+	if repo.listCalls != 1 {
+		t.Errorf("repository List calls = %d, want 1 (second read should be served from cache)", repo.listCalls)
+	}
+
+	if _, err := svc.List(context.Background(), "gadgets", true, 5, 15); err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if repo.listCalls != 2 {
+		t.Errorf("repository List calls = %d, want 2 (a different page is cached separately)", repo.listCalls)
+	}
+}
+
+func TestProductService_List_TagsPagesForInvalidation(t *testing.T) {
+	cache := newFakeProductCache()
+	svc := NewProductService(&fakeProductRepository{}, cache)
+
+	if _, err := svc.List(context.Background(), "", false, 20, 0); err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(cache.lastOpts.Tags) != 1 || cache.lastOpts.Tags[0] != ProductListCacheTag {
+		t.Errorf("GetOrLoad() tags = %v, want [%s]", cache.lastOpts.Tags, ProductListCacheTag)
+	}
+}
+
+func TestProductService_List_RepositoryError(t *testing.T) {
+	repo := &fakeProductRepository{listErr: errTestProductRepository}
+	svc := NewProductService(repo, newFakeProductCache())
+
+	if _, err := svc.List(context.Background(), "", false, 20, 0); !errors.Is(err, errTestProductRepository) {
+		t.Errorf("List() error = %v, want %v", err, errTestProductRepository)
+	}
+}
+
+func TestProductListCacheKey_EscapesCategory(t *testing.T) {
+	a := productListCacheKey("a:true", false, 1, 2)
+	b := productListCacheKey("a", true, 1, 2)
+	if a == b {
+		t.Errorf("productListCacheKey() collided for different filters: %q", a)
+	}
+}
@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS update_feature_flags_updated_at ON feature_flags;
+DROP TABLE feature_flags;
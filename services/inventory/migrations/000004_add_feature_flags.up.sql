@@ -0,0 +1,15 @@
+-- Feature flags for the postgres backend of the shared featureflags package. A flag missing here
+-- keeps the default the service declares for it.
+CREATE TABLE feature_flags (
+    key VARCHAR(100) PRIMARY KEY,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    percentage SMALLINT NOT NULL DEFAULT 100 CHECK (percentage BETWEEN 0 AND 100),
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE TRIGGER update_feature_flags_updated_at
+    BEFORE UPDATE ON feature_flags
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
@@ -2,9 +2,7 @@ package main
 
 import (
 	"context"
-	stderrors "errors"
 	"log"
-	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -20,9 +18,14 @@ import (
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/cache"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/database"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/events"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/featureflags"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/httpclient"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/httpserver"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/lifecycle"
 	sharedlogger "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/logger"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/metrics"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/outbox"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/tlsconfig"
 	sharedtracing "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/tracing"
 	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
@@ -37,7 +40,22 @@ const cacheConsumerGroupID = "order-cache"
 // the order service has no dedicated config field for it yet.
 const defaultRedisPoolSize = 10
 
+// Feature flags, both on by default. flagCache is checked on every read, so turning it off takes
+// effect straight away; flagCacheInvalidationConsumer is read at startup, so turning it off takes
+// effect on the next restart.
+const (
+	flagCache                     = "cache.enabled"
+	flagCacheInvalidationConsumer = "cache.invalidation_consumer"
+)
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+		return
+	}
+
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
@@ -49,11 +67,22 @@ func main() {
 		Service:     cfg.Service.Name,
 		Version:     cfg.Service.Version,
 		OutputPaths: cfg.Logger.OutputPaths,
+		Sampling: &sharedlogger.SamplingConfig{
+			Initial:    cfg.Logger.Sampling.Initial,
+			Thereafter: cfg.Logger.Sampling.Thereafter,
+			Tick:       cfg.Logger.Sampling.Tick,
+		},
+		Redaction: &sharedlogger.RedactionConfig{
+			Mode:    cfg.Logger.Redaction.Mode,
+			Fields:  cfg.Logger.Redaction.Fields,
+			HashKey: cfg.Logger.Redaction.HashKey.Value(),
+		},
 	})
 	if err != nil {
 		log.Fatalf("Failed to initialize logger: %v", err)
 	}
 	defer func() { _ = appLogger.Sync() }()
+	prometheus.MustRegister(metrics.NewLoggerMetrics(appLogger, cfg.Service.Name))
 
 	appLogger.Info("Starting order service",
 		zap.String("host", cfg.Server.Host),
@@ -68,16 +97,32 @@ func main() {
 	if err != nil {
 		appLogger.Fatal("Failed to initialize tracing", zap.Error(err))
 	}
-	defer func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		if err := shutdownTracing(ctx); err != nil {
-			appLogger.Error("Failed to shut down tracing", zap.Error(err))
-		}
-	}()
+
+	// Components are stopped in the reverse of the order they start in. Tracing is registered
+	// first so that spans from the rest of the shutdown are still exported.
+	lc := lifecycle.New(appLogger.Logger)
+	lc.MustRegister(lifecycle.Component{Name: "tracing", Stop: shutdownTracing, Timeout: 5 * time.Second})
+
+	// The admin listener starts right after tracing and so stops just before it: pprof and the
+	// log level stay reachable while the rest of the service shuts down.
+	var admin *httpserver.AdminServer
+	if cfg.Server.AdminPort != "" {
+		admin = httpserver.NewAdmin(httpserver.AdminOptions{
+			Addr:     cfg.Server.Host + ":" + cfg.Server.AdminPort,
+			Logger:   appLogger.Logger,
+			LogLevel: appLogger,
+			Service:  cfg.Service.Name,
+			Version:  cfg.Service.Version,
+		})
+		lc.MustRegister(lifecycle.Component{
+			Name: "admin-http",
+			Run:  func(context.Context) error { return admin.Start() },
+			Stop: admin.Stop,
+		})
+	}
 
 	db, err := database.NewPostgresConnection(database.PostgresConfig{
-		URL:          cfg.DatabaseURL,
+		URL:          cfg.DatabaseURL.Value(),
 		MaxOpenConns: cfg.DatabasePool.MaxOpenConns,
 		MaxIdleConns: cfg.DatabasePool.MaxIdleConns,
 		MaxLifetime:  cfg.DatabasePool.MaxLifetime,
@@ -85,28 +130,142 @@ func main() {
 	if err != nil {
 		appLogger.Fatal("Failed to connect to database", zap.Error(err))
 	}
-	defer func() { _ = db.Close() }()
+	lc.MustRegister(lifecycle.Component{Name: "database", Stop: lifecycle.Close(db)})
+	if cfg.Migrations.OnStartup {
+		if err := migrateOnStartup(db, cfg.Migrations.Timeout, appLogger.Logger); err != nil {
+			appLogger.Fatal("Failed to apply database migrations", zap.Error(err))
+		}
+	}
 	prometheus.MustRegister(metrics.NewDatabaseMetrics(db.DB, cfg.Service.Name))
+	db.SetQueryMetrics(database.NewQueryMetrics(prometheus.DefaultRegisterer))
+	database.SetTxMetrics(database.NewTxMetrics(prometheus.DefaultRegisterer))
+	db.SetSlowQueryLog(appLogger.Logger, cfg.DatabasePool.SlowQueryThreshold)
+	db.SetRoutingMetrics(database.NewRoutingMetrics(prometheus.DefaultRegisterer))
+	prometheus.MustRegister(database.NewPoolMetrics(db))
+	if cfg.DatabaseReplica.Enabled() {
+		// A missing replica is not fatal: every read simply stays on the primary.
+		if err := db.OpenReplica(database.ReplicaConfig{
+			URL:                  cfg.DatabaseReplica.URL.Value(),
+			MaxOpenConns:         cfg.DatabasePool.MaxOpenConns,
+			MaxIdleConns:         cfg.DatabasePool.MaxIdleConns,
+			MaxLifetime:          cfg.DatabasePool.MaxLifetime,
+			MaxLag:               cfg.DatabaseReplica.MaxLag,
+			LagCheckInterval:     cfg.DatabaseReplica.LagCheckInterval,
+			ReadYourWritesWindow: cfg.DatabaseReplica.ReadYourWritesWindow,
+		}); err != nil {
+			appLogger.Warn("Failed to connect to the database replica, reading from the primary", zap.Error(err))
+		} else {
+			lc.MustRegister(lifecycle.Component{
+				Name:      "database-replica",
+				DependsOn: []string{"database"},
+				Run: func(ctx context.Context) error {
+					db.MonitorReplica(ctx, appLogger.Logger)
+					return nil
+				},
+			})
+		}
+	}
+
+	flagSource, err := featureflags.NewSource(cfg.FeatureFlags.Backend, cfg.FeatureFlags.Path, db.DB)
+	if err != nil {
+		appLogger.Fatal("Failed to set up feature flags", zap.Error(err))
+	}
+	flags := featureflags.New(flagSource, appLogger.Logger,
+		featureflags.Default(flagCache, true, "Cache order reads in Redis; checked on every read, so it can be rolled out per user"),
+		featureflags.Default(flagCacheInvalidationConsumer, true, "Run the Kafka consumer that evicts cached orders on status changes"),
+	)
+	if err := flags.Refresh(context.Background()); err != nil {
+		appLogger.Warn("Failed to load feature flags, starting on defaults", zap.Error(err))
+	}
+	if admin != nil {
+		featureflags.NewHandler(flags, appLogger.Logger).Register(admin.Mux())
+	}
+	if cfg.FeatureFlags.Backend != featureflags.BackendNone {
+		lc.MustRegister(lifecycle.Component{
+			Name:      "feature-flags",
+			DependsOn: []string{"database"},
+			Run: func(ctx context.Context) error {
+				flags.Run(ctx, cfg.FeatureFlags.RefreshInterval)
+				return nil
+			},
+		})
+	}
 
 	redisClient, err := database.NewRedisConnection(database.RedisConfig{
-		URL:      cfg.Redis.URL,
-		PoolSize: defaultRedisPoolSize,
+		Mode:             cfg.Redis.Mode,
+		URL:              cfg.Redis.URL,
+		Addrs:            cfg.Redis.Addrs,
+		MasterName:       cfg.Redis.MasterName,
+		Password:         cfg.Redis.Password.Value(),
+		SentinelPassword: cfg.Redis.SentinelPassword.Value(),
+		PoolSize:         defaultRedisPoolSize,
 	})
 	if err != nil {
 		appLogger.Warn("Failed to connect to Redis, starting without a cache", zap.Error(err))
 		redisClient = nil
 	} else {
-		defer func() { _ = redisClient.Close() }()
+		lc.MustRegister(lifecycle.Component{Name: "redis", Stop: lifecycle.Close(redisClient)})
 	}
 
 	cacheMetrics := cache.NewMetrics(prometheus.DefaultRegisterer)
 
+	// One order cache is shared by the HTTP handlers and the invalidation consumer, so the
+	// consumer's deletes evict the same local tier the handlers read from.
+	var orderCache *cache.Cache
+	if redisClient != nil {
+		orderCache = cache.New(redisClient, 0)
+		orderCache.SetMetrics(cacheMetrics, "order")
+		orderCache.EnableLocal(cache.LocalOptions{MaxEntries: cfg.LocalCache.MaxEntries, TTL: cfg.LocalCache.TTL})
+	}
+
+	// One transport is shared by the inventory and payment clients.
+	clientTLS, err := tlsconfig.Load(cfg.InternalTLS, appLogger.Logger)
+	if err != nil {
+		appLogger.Fatal("Failed to load internal TLS files", zap.Error(err))
+	}
+	if clientTLS != nil {
+		lc.MustRegister(lifecycle.Component{
+			Name: "internal-tls",
+			Run: func(ctx context.Context) error {
+				clientTLS.Run(ctx)
+				return nil
+			},
+		})
+	}
+	clientTransport := httpclient.NewTransport(clientTLS)
+
+	serverTLS, err := tlsconfig.Load(cfg.Server.TLS, appLogger.Logger)
+	if err != nil {
+		appLogger.Fatal("Failed to load server TLS files", zap.Error(err))
+	}
+
+	inventoryClient := client.NewInventoryClient(cfg.InventoryServiceURL, cfg.InventoryClient.Timeout)
+	paymentClient := client.NewPaymentClient(cfg.PaymentServiceURL, cfg.PaymentClient.Timeout)
+	inventoryClient.SetTransport(clientTransport)
+	paymentClient.SetTransport(clientTransport)
+
+	// One order service backs the HTTP API, the payments consumer and the saga loops, so they
+	// share its saga metrics, its cache and the clients' circuit breakers.
+	orderRepo := repository.NewOrderRepository(db.DB)
+	orderRepo.SetReadRouter(db)
+	sagaRepo := repository.NewSagaRepository(db.DB)
+	orderService := service.NewOrderService(orderRepo, db.DB, sagaRepo, inventoryClient, paymentClient)
+	if orderCache != nil {
+		orderService.SetCache(orderCache)
+	}
+	orderService.SetCacheEnabled(func(ctx context.Context) bool { return flags.EnabledForUser(ctx, flagCache) })
+	sagaMetrics := saga.NewMetrics(prometheus.DefaultRegisterer)
+	orderService.SetSagaMetrics(sagaMetrics)
+
 	srv := server.New(server.Options{
-		Config:       cfg,
-		Logger:       appLogger.Logger,
-		DB:           db,
-		Redis:        redisClient,
-		CacheMetrics: cacheMetrics,
+		Config:    cfg,
+		Logger:    appLogger.Logger,
+		DB:        db,
+		Redis:     redisClient,
+		Orders:    orderService,
+		Inventory: inventoryClient,
+		Startup:   lc.StartupCheck,
+		TLS:       serverTLS,
 	})
 
 	kafkaMetrics := events.NewKafkaMetrics(prometheus.DefaultRegisterer)
@@ -115,82 +274,122 @@ func main() {
 	publisher.SetMetrics(kafkaMetrics)
 	relay := outbox.NewRelay(db.DB, publisher, appLogger.Logger, cfg.Outbox.RelayInterval, cfg.Outbox.RelayBatchSize)
 	relay.SetMetrics(kafkaMetrics)
-	relay.Start(context.Background())
+	lc.MustRegister(lifecycle.Component{Name: "kafka-publisher", Stop: lifecycle.Close(publisher)})
+	lc.MustRegister(lifecycle.Component{
+		Name:      "outbox-relay",
+		DependsOn: []string{"database", "kafka-publisher"},
+		Start: func(context.Context) error {
+			relay.Start(context.Background())
+			return nil
+		},
+		// Everything that enqueues has stopped by now, so a final flush sends what is left
+		// before the publisher closes.
+		Stop: func(ctx context.Context) error {
+			relay.Stop()
+			return relay.Flush(ctx)
+		},
+	})
 
-	inventoryClient := client.NewInventoryClient(cfg.InventoryServiceURL, cfg.InventoryClient.Timeout)
-	paymentClient := client.NewPaymentClient(cfg.PaymentServiceURL, cfg.PaymentClient.Timeout)
-	orderService := service.NewOrderService(
-		repository.NewOrderRepository(db.DB), db.DB, repository.NewSagaRepository(db.DB), inventoryClient, paymentClient)
-	orderService.SetSagaMetrics(saga.NewMetrics(prometheus.DefaultRegisterer))
+	if cfg.SagaWatchdog.Interval > 0 {
+		watchdog := saga.NewWatchdog(sagaRepo, orderService, map[saga.State]time.Duration{
+			saga.StateAwaitingPayment: cfg.SagaWatchdog.AwaitingPaymentTimeout,
+		}, cfg.SagaWatchdog.BatchSize, cfg.CompensationRecovery.BaseDelay, appLogger.Logger)
+		watchdog.SetMetrics(sagaMetrics)
+		lc.MustRegister(lifecycle.Component{
+			Name:      "saga-watchdog",
+			DependsOn: []string{"database", "outbox-relay"},
+			Run: func(ctx context.Context) error {
+				watchdog.Run(ctx, cfg.SagaWatchdog.Interval)
+				return nil
+			},
+		})
+	}
+	if cfg.CompensationRecovery.Interval > 0 {
+		recovery := saga.NewRecovery(sagaRepo, orderService, saga.RetryPolicy{
+			MaxAttempts: cfg.CompensationRecovery.MaxAttempts,
+			BaseDelay:   cfg.CompensationRecovery.BaseDelay,
+			MaxDelay:    cfg.CompensationRecovery.MaxDelay,
+		}, cfg.CompensationRecovery.BatchSize, appLogger.Logger)
+		recovery.SetMetrics(sagaMetrics)
+		lc.MustRegister(lifecycle.Component{
+			Name:      "compensation-recovery",
+			DependsOn: []string{"database", "outbox-relay"},
+			Run: func(ctx context.Context) error {
+				recovery.Run(ctx, cfg.CompensationRecovery.Interval)
+				return nil
+			},
+		})
+	}
 	processedStore := events.NewProcessedStore(db.DB)
 	paymentsSubscriber := events.NewSubscriber(events.KafkaConfig{
-		Brokers:  cfg.Kafka.Brokers,
-		GroupID:  cfg.Kafka.GroupID,
-		DLQTopic: events.DLQTopic(events.PaymentsTopic),
+		Brokers:     cfg.Kafka.Brokers,
+		GroupID:     cfg.Kafka.GroupID,
+		DLQTopic:    events.DLQTopic(events.PaymentsTopic),
+		LogPayloads: cfg.Kafka.LogPayloads,
 	}, events.PaymentsTopic, appLogger.Logger)
 	paymentsSubscriber.SetMetrics(kafkaMetrics)
 	paymentsConsumer := consumer.NewPaymentsConsumer(paymentsSubscriber, db.DB, processedStore, orderService, appLogger)
 
-	consumerCtx, stopConsumer := context.WithCancel(context.Background())
-	go func() {
-		if err := paymentsConsumer.Start(consumerCtx); err != nil && !stderrors.Is(err, context.Canceled) {
-			appLogger.Error("payments consumer stopped", zap.Error(err))
-		}
-	}()
+	lc.MustRegister(lifecycle.Component{Name: "payments-subscriber", Stop: lifecycle.Close(paymentsSubscriber)})
+	lc.MustRegister(lifecycle.Component{
+		Name:      "payments-consumer",
+		DependsOn: []string{"database", "outbox-relay", "payments-subscriber"},
+		Run:       paymentsConsumer.Start,
+	})
 
-	var cacheSubscriber *events.Subscriber
-	if redisClient != nil {
-		orderCache := cache.New(redisClient, 0)
-		cacheSubscriber = events.NewSubscriber(events.KafkaConfig{
-			Brokers:  cfg.Kafka.Brokers,
-			GroupID:  cacheConsumerGroupID,
-			DLQTopic: events.DLQTopic(events.OrdersTopic),
+	switch {
+	case redisClient == nil:
+		appLogger.Warn("Redis unavailable, skipping the order cache invalidation consumer")
+	case !flags.Enabled(flagCacheInvalidationConsumer):
+		appLogger.Warn("Order cache invalidation consumer disabled by feature flag",
+			zap.String("flag", flagCacheInvalidationConsumer))
+	default:
+		cacheSubscriber := events.NewSubscriber(events.KafkaConfig{
+			Brokers:     cfg.Kafka.Brokers,
+			GroupID:     cacheConsumerGroupID,
+			DLQTopic:    events.DLQTopic(events.OrdersTopic),
+			LogPayloads: cfg.Kafka.LogPayloads,
 		}, events.OrdersTopic, appLogger.Logger)
 		cacheSubscriber.SetMetrics(kafkaMetrics)
 		cacheConsumer := consumer.NewCacheConsumer(cacheSubscriber, orderCache, appLogger.Logger)
 
-		go func() {
-			if err := cacheConsumer.Start(consumerCtx); err != nil && !stderrors.Is(err, context.Canceled) {
-				appLogger.Error("cache consumer stopped", zap.Error(err))
-			}
-		}()
-	} else {
-		appLogger.Warn("Redis unavailable, skipping the order cache invalidation consumer")
+		lc.MustRegister(lifecycle.Component{Name: "cache-subscriber", Stop: lifecycle.Close(cacheSubscriber)})
+		lc.MustRegister(lifecycle.Component{
+			Name:      "cache-consumer",
+			DependsOn: []string{"redis", "cache-subscriber"},
+			Run:       cacheConsumer.Start,
+		})
 	}
-
-	done := make(chan os.Signal, 1)
-	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	go func() {
-		if err := srv.Start(); err != nil && err != http.ErrServerClosed {
-			appLogger.Fatal("Server failed to start", zap.Error(err))
-		}
-	}()
-
-	<-done
-	appLogger.Info("Order service is shutting down...")
-
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	if err := srv.Stop(ctx); err != nil {
-		appLogger.Error("Server forced to shutdown", zap.Error(err))
-	} else {
-		appLogger.Info("Order service stopped gracefully")
+	if orderCache != nil {
+		lc.MustRegister(lifecycle.Component{
+			Name:      "cache-invalidations",
+			DependsOn: []string{"redis"},
+			Run: func(ctx context.Context) error {
+				orderCache.ListenInvalidations(ctx)
+				return nil
+			},
+		})
 	}
 
-	stopConsumer()
-	if err := paymentsSubscriber.Close(); err != nil {
-		appLogger.Error("Failed to close payments subscriber", zap.Error(err))
-	}
-	if cacheSubscriber != nil {
-		if err := cacheSubscriber.Close(); err != nil {
-			appLogger.Error("Failed to close cache subscriber", zap.Error(err))
-		}
+	// The HTTP server starts last and so stops first: no request is accepted once the consumers
+	// and the outbox relay behind it begin shutting down.
+	httpDeps := []string{"database", "outbox-relay"}
+	if redisClient != nil {
+		httpDeps = append(httpDeps, "redis")
 	}
+	lc.MustRegister(lifecycle.Component{
+		Name:      "http",
+		DependsOn: httpDeps,
+		Run:       func(context.Context) error { return srv.Start() },
+		Stop:      srv.Stop,
+		Timeout:   20 * time.Second,
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	relay.Stop()
-	if err := publisher.Close(); err != nil {
-		appLogger.Error("Failed to close kafka publisher", zap.Error(err))
+	if err := lc.Run(ctx); err != nil {
+		appLogger.Fatal("Order service stopped with errors", zap.Error(err))
 	}
+	appLogger.Info("Order service stopped gracefully")
 }
@@ -62,12 +62,24 @@ type ReserveItem struct {
 	Quantity  int
 }
 
-// InventoryClient reserves and releases stock through the inventory service's HTTP API.
+// Product is the catalog snapshot of a product an order is priced from.
+type Product struct {
+	ID         uuid.UUID
+	Name       string
+	SKU        string
+	PriceCents int64
+	Currency   string
+	IsActive   bool
+}
+
+// InventoryClient reserves and releases stock, and looks up the products being ordered, through
+// the inventory service's HTTP API.
 type InventoryClient struct {
-	baseURL        string
-	httpClient     *http.Client
-	reserveBreaker *resilience.Breaker
-	releaseBreaker *resilience.Breaker
+	baseURL         string
+	httpClient      *http.Client
+	reserveBreaker  *resilience.Breaker
+	releaseBreaker  *resilience.Breaker
+	productsBreaker *resilience.Breaker
 }
 
 // NewInventoryClient builds an InventoryClient talking to baseURL, bounding every request by
@@ -75,13 +87,20 @@ type InventoryClient struct {
 // inventory service.
 func NewInventoryClient(baseURL string, timeout time.Duration) *InventoryClient {
 	return &InventoryClient{
-		baseURL:        strings.TrimRight(baseURL, "/"),
-		httpClient:     &http.Client{Timeout: timeout, Transport: otelhttp.NewTransport(http.DefaultTransport)},
-		reserveBreaker: newBreaker("inventory_reserve"),
-		releaseBreaker: newBreaker("inventory_release"),
+		baseURL:         strings.TrimRight(baseURL, "/"),
+		httpClient:      &http.Client{Timeout: timeout, Transport: otelhttp.NewTransport(http.DefaultTransport)},
+		reserveBreaker:  newBreaker("inventory_reserve"),
+		releaseBreaker:  newBreaker("inventory_release"),
+		productsBreaker: newBreaker("inventory_products"),
 	}
 }
 
+// SetTransport makes the client send its requests through transport, such as the shared TLS
+// transport from httpclient.NewTransport. Requests keep opening client spans.
+func (c *InventoryClient) SetTransport(transport http.RoundTripper) {
+	c.httpClient.Transport = otelhttp.NewTransport(transport)
+}
+
 type reserveItemRequest struct {
 	ProductID string `json:"product_id"`
 	Quantity  int    `json:"quantity"`
@@ -109,7 +128,7 @@ func (c *InventoryClient) Reserve(ctx context.Context, orderID uuid.UUID, items
 	}
 
 	_, execErr := resilience.Execute(c.reserveBreaker, func() (struct{}, error) {
-		return struct{}{}, c.do(ctx, http.MethodPost, "/api/v1/inventory/reservations", body)
+		return struct{}{}, c.do(ctx, http.MethodPost, "/api/v1/inventory/reservations", body, nil)
 	})
 	return wrapBreakerOpen(execErr, "INVENTORY_SERVICE_UNAVAILABLE", "inventory service circuit breaker is open")
 }
@@ -127,13 +146,69 @@ func (c *InventoryClient) Release(ctx context.Context, orderID uuid.UUID) error
 
 	err := resilience.Retry(ctx, retryCfg, func() error {
 		_, execErr := resilience.Execute(c.releaseBreaker, func() (struct{}, error) {
-			return struct{}{}, c.do(ctx, http.MethodDelete, "/api/v1/inventory/reservations/"+orderID.String(), nil)
+			return struct{}{}, c.do(ctx, http.MethodDelete, "/api/v1/inventory/reservations/"+orderID.String(), nil, nil)
 		})
 		return execErr
 	})
 	return wrapBreakerOpen(err, "INVENTORY_SERVICE_UNAVAILABLE", "inventory service circuit breaker is open")
 }
 
+type productsRequest struct {
+	IDs []string `json:"ids"`
+}
+
+type productResponse struct {
+	ID         uuid.UUID `json:"id"`
+	Name       string    `json:"name"`
+	SKU        string    `json:"sku"`
+	PriceCents int64     `json:"price_cents"`
+	Currency   string    `json:"currency"`
+	IsActive   bool      `json:"is_active"`
+}
+
+type productsResponse struct {
+	Products []productResponse `json:"products"`
+}
+
+// Products looks up the current catalog entry of every product in ids, keyed by id. A product the
+// inventory service does not know is missing from the map. The lookup only reads, so it is
+// retried on transient failures as well as guarded by a circuit breaker.
+func (c *InventoryClient) Products(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]Product, error) {
+	rawIDs := make([]string, len(ids))
+	for i, id := range ids {
+		rawIDs[i] = id.String()
+	}
+
+	body, err := json.Marshal(productsRequest{IDs: rawIDs})
+	if err != nil {
+		return nil, fmt.Errorf("marshal products request: %w", err)
+	}
+
+	retryCfg := resilience.RetryConfig{
+		MaxAttempts: retryMaxAttempts,
+		BaseDelay:   retryBaseDelay,
+		MaxDelay:    retryMaxDelay,
+		Retryable:   isRetryableInventoryError,
+	}
+
+	var resp productsResponse
+	err = resilience.Retry(ctx, retryCfg, func() error {
+		_, execErr := resilience.Execute(c.productsBreaker, func() (struct{}, error) {
+			return struct{}{}, c.do(ctx, http.MethodPost, "/api/v1/products/batch", body, &resp)
+		})
+		return execErr
+	})
+	if err != nil {
+		return nil, wrapBreakerOpen(err, "INVENTORY_SERVICE_UNAVAILABLE", "inventory service circuit breaker is open")
+	}
+
+	products := make(map[uuid.UUID]Product, len(resp.Products))
+	for _, p := range resp.Products {
+		products[p.ID] = Product(p)
+	}
+	return products, nil
+}
+
 // isRetryableInventoryError reports whether a failed inventory call is worth retrying: a
 // transport failure or a server-side error might succeed next time, a rejected request or an
 // open breaker will not.
@@ -149,8 +224,9 @@ func isRetryableInventoryError(err error) bool {
 }
 
 // do issues an HTTP request against the inventory service and turns a non-2xx response, or a
-// transport failure, into an *errors.AppError.
-func (c *InventoryClient) do(ctx context.Context, method, path string, body []byte) error {
+// transport failure, into an *errors.AppError. A successful response is decoded into dest unless
+// dest is nil.
+func (c *InventoryClient) do(ctx context.Context, method, path string, body []byte, dest any) error {
 	var reader io.Reader
 	if body != nil {
 		reader = bytes.NewReader(body)
@@ -170,10 +246,16 @@ func (c *InventoryClient) do(ctx context.Context, method, path string, body []by
 	}
 	defer func() { _ = resp.Body.Close() }()
 
-	if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return errorFromResponse(resp)
+	}
+	if dest == nil {
 		return nil
 	}
-	return errorFromResponse(resp)
+	if err := json.NewDecoder(resp.Body).Decode(dest); err != nil {
+		return fmt.Errorf("decode inventory response: %w", err)
+	}
+	return nil
 }
 
 // transportError maps a failure to reach the inventory service to an *errors.AppError, telling a
@@ -196,16 +278,10 @@ func transportError(err error) error {
 	}
 }
 
-type errorBody struct {
-	Code    string `json:"code"`
-	Message string `json:"message"`
-	Details string `json:"details"`
-}
-
-// errorFromResponse rebuilds the *errors.AppError the inventory service reported, falling back to
-// a generic one when the body is not the expected shape.
+// errorFromResponse rebuilds the *errors.AppError from the problem document the inventory service
+// reported, falling back to a generic one when the body is not a problem document.
 func errorFromResponse(resp *http.Response) error {
-	var body errorBody
+	var body apperrors.Problem
 	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Code == "" {
 		return &apperrors.AppError{
 			Code:     "INVENTORY_ERROR",
@@ -213,10 +289,7 @@ func errorFromResponse(resp *http.Response) error {
 			HTTPCode: resp.StatusCode,
 		}
 	}
-	return &apperrors.AppError{
-		Code:     body.Code,
-		Message:  body.Message,
-		Details:  body.Details,
-		HTTPCode: resp.StatusCode,
-	}
+	appErr := apperrors.FromProblem(body)
+	appErr.HTTPCode = resp.StatusCode
+	return appErr
 }
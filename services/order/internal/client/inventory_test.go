@@ -2,6 +2,7 @@ package client
 
 import (
 	"context"
+	"encoding/json"
 	stderrors "errors"
 	"net/http"
 	"net/http/httptest"
@@ -38,9 +39,9 @@ func TestInventoryClient_Reserve(t *testing.T) {
 
 	t.Run("insufficient inventory", func(t *testing.T) {
 		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
-			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Type", "application/problem+json")
 			w.WriteHeader(http.StatusConflict)
-			_, _ = w.Write([]byte(`{"code":"INSUFFICIENT_INVENTORY","message":"Insufficient inventory","details":"Product x: requested 2, available 1"}`))
+			_, _ = w.Write([]byte(`{"type":"https://eventflow-commerce.dev/problems/insufficient-inventory","title":"Insufficient inventory","status":409,"detail":"Insufficient inventory: Product x: requested 2, available 1","code":"INSUFFICIENT_INVENTORY"}`))
 		}))
 		defer srv.Close()
 
@@ -123,6 +124,24 @@ func TestInventoryClient_Reserve(t *testing.T) {
 	})
 }
 
+func TestInventoryClient_SetTransport_ReachesTLSServer(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	c := NewInventoryClient(srv.URL, time.Second)
+	if err := c.Reserve(context.Background(), uuid.New(), testItems()); err == nil {
+		t.Fatal("Reserve() over TLS without a trusted transport succeeded, want a certificate error")
+	}
+
+	c = NewInventoryClient(srv.URL, time.Second)
+	c.SetTransport(srv.Client().Transport)
+	if err := c.Reserve(context.Background(), uuid.New(), testItems()); err != nil {
+		t.Fatalf("Reserve() error = %v", err)
+	}
+}
+
 func TestInventoryClient_Release(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		orderID := uuid.New()
@@ -144,9 +163,9 @@ func TestInventoryClient_Release(t *testing.T) {
 		var calls int32
 		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 			atomic.AddInt32(&calls, 1)
-			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Type", "application/problem+json")
 			w.WriteHeader(http.StatusBadRequest)
-			_, _ = w.Write([]byte(`{"code":"BAD_REQUEST","message":"invalid order id"}`))
+			_, _ = w.Write([]byte(`{"type":"https://eventflow-commerce.dev/problems/bad-request","title":"Bad request","status":400,"detail":"invalid order id","code":"BAD_REQUEST"}`))
 		}))
 		defer srv.Close()
 
@@ -187,6 +206,70 @@ func TestInventoryClient_Release(t *testing.T) {
 	})
 }
 
+func TestInventoryClient_Products(t *testing.T) {
+	t.Run("returns the catalog entries keyed by id", func(t *testing.T) {
+		known, unknown := uuid.New(), uuid.New()
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost || r.URL.Path != "/api/v1/products/batch" {
+				t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			}
+			var req productsRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.IDs) != 2 {
+				t.Errorf("request ids = %v (err=%v), want 2", req.IDs, err)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"products":[{"id":"` + known.String() + `","name":"Widget","sku":"WID-1","price_cents":2999,"currency":"USD","is_active":true}]}`))
+		}))
+		defer srv.Close()
+
+		c := NewInventoryClient(srv.URL, time.Second)
+		products, err := c.Products(context.Background(), []uuid.UUID{known, unknown})
+		if err != nil {
+			t.Fatalf("Products() error = %v", err)
+		}
+		want := Product{ID: known, Name: "Widget", SKU: "WID-1", PriceCents: 2999, Currency: "USD", IsActive: true}
+		if got := products[known]; got != want {
+			t.Errorf("Products()[known] = %+v, want %+v", got, want)
+		}
+		if _, ok := products[unknown]; ok {
+			t.Error("Products() has an entry for an unknown id")
+		}
+	})
+
+	t.Run("retries a server error then succeeds", func(t *testing.T) {
+		var calls int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			if atomic.AddInt32(&calls, 1) < 2 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			_, _ = w.Write([]byte(`{"products":[]}`))
+		}))
+		defer srv.Close()
+
+		c := NewInventoryClient(srv.URL, time.Second)
+		if _, err := c.Products(context.Background(), []uuid.UUID{uuid.New()}); err != nil {
+			t.Fatalf("Products() error = %v", err)
+		}
+		if got := atomic.LoadInt32(&calls); got != 2 {
+			t.Errorf("calls = %d, want 2", got)
+		}
+	})
+
+	t.Run("malformed body", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte(`not json`))
+		}))
+		defer srv.Close()
+
+		c := NewInventoryClient(srv.URL, time.Second)
+		_, err := c.Products(context.Background(), []uuid.UUID{uuid.New()})
+		if err == nil || !strings.Contains(err.Error(), "decode inventory response") {
+			t.Errorf("Products() error = %v, want a decode error", err)
+		}
+	})
+}
+
 func TestInventoryClient_Release_BuildRequestError(t *testing.T) {
 	// A control character in the base URL makes http.NewRequestWithContext fail, which is also
 	// the only way to drive isRetryableInventoryError through its default, non-AppError branch.
@@ -32,14 +32,20 @@ func NewPaymentClient(baseURL string, timeout time.Duration) *PaymentClient {
 	}
 }
 
+// SetTransport makes the client send its requests through transport, such as the shared TLS
+// transport from httpclient.NewTransport.
+func (c *PaymentClient) SetTransport(transport http.RoundTripper) {
+	c.httpClient.Transport = transport
+}
+
 type refundRequest struct {
 	Reason string `json:"reason"`
 }
 
-// Refund asks the payment service to refund paymentID. A payment that is not currently completed
-// comes back as an *errors.AppError with HTTP status 409. A refund is idempotent from the payment
-// service's point of view, so Refund is both guarded by a circuit breaker and retried on
-// transient failures.
+// Refund asks the payment service to refund paymentID. A payment that was already refunded
+// succeeds again without a second refund; one neither completed nor refunded comes back as an
+// *errors.AppError with HTTP status 409. A refund is idempotent from the payment service's point of
+// view, so Refund is both guarded by a circuit breaker and retried on transient failures.
 func (c *PaymentClient) Refund(ctx context.Context, paymentID uuid.UUID, reason string) error {
 	body, err := json.Marshal(refundRequest{Reason: reason})
 	if err != nil {
@@ -118,10 +124,10 @@ func paymentTransportError(err error) error {
 	}
 }
 
-// paymentErrorFromResponse rebuilds the *errors.AppError the payment service reported, falling
-// back to a generic one when the body is not the expected shape.
+// paymentErrorFromResponse rebuilds the *errors.AppError from the problem document the payment
+// service reported, falling back to a generic one when the body is not a problem document.
 func paymentErrorFromResponse(resp *http.Response) error {
-	var body errorBody
+	var body apperrors.Problem
 	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Code == "" {
 		return &apperrors.AppError{
 			Code:     "PAYMENT_ERROR",
@@ -129,10 +135,7 @@ func paymentErrorFromResponse(resp *http.Response) error {
 			HTTPCode: resp.StatusCode,
 		}
 	}
-	return &apperrors.AppError{
-		Code:     body.Code,
-		Message:  body.Message,
-		Details:  body.Details,
-		HTTPCode: resp.StatusCode,
-	}
+	appErr := apperrors.FromProblem(body)
+	appErr.HTTPCode = resp.StatusCode
+	return appErr
 }
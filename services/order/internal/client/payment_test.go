@@ -37,9 +37,9 @@ func TestPaymentClient_Refund(t *testing.T) {
 		var calls int32
 		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 			atomic.AddInt32(&calls, 1)
-			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Type", "application/problem+json")
 			w.WriteHeader(http.StatusConflict)
-			_, _ = w.Write([]byte(`{"code":"CONFLICT","message":"payment is not completed"}`))
+			_, _ = w.Write([]byte(`{"type":"https://eventflow-commerce.dev/problems/conflict","title":"Conflict","status":409,"detail":"payment is not completed","code":"CONFLICT"}`))
 		}))
 		defer srv.Close()
 
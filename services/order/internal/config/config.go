@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"net/url"
 	"time"
@@ -13,6 +14,9 @@ type DatabasePoolConfig struct {
 	MaxOpenConns int           `mapstructure:"max_open_conns"`
 	MaxIdleConns int           `mapstructure:"max_idle_conns"`
 	MaxLifetime  time.Duration `mapstructure:"max_lifetime"`
+	// SlowQueryThreshold logs a warning for queries that take at least this long; 0 turns the
+	// slow query log off.
+	SlowQueryThreshold time.Duration `mapstructure:"slow_query_threshold"`
 }
 
 // OutboxConfig sizes the outbox relay poll loop.
@@ -21,6 +25,30 @@ type OutboxConfig struct {
 	RelayBatchSize int           `mapstructure:"relay_batch_size"`
 }
 
+// SagaWatchdogConfig controls the loop that compensates sagas stuck in a state past its deadline.
+type SagaWatchdogConfig struct {
+	// Interval is how often the watchdog sweeps; 0 turns the watchdog off.
+	Interval  time.Duration `mapstructure:"interval"`
+	BatchSize int           `mapstructure:"batch_size"`
+	// AwaitingPaymentTimeout is how long a saga may wait for its payment result before it is
+	// compensated; 0 never times it out. stock_reserved needs no timeout because a saga only passes
+	// through it inside the transaction that moves it on to awaiting_payment.
+	AwaitingPaymentTimeout time.Duration `mapstructure:"awaiting_payment_timeout"`
+}
+
+// CompensationRecoveryConfig controls the loop that retries saga compensations a release or refund
+// failure left unfinished.
+type CompensationRecoveryConfig struct {
+	// Interval is how often the worker sweeps; 0 turns the worker off.
+	Interval  time.Duration `mapstructure:"interval"`
+	BatchSize int           `mapstructure:"batch_size"`
+	// MaxAttempts is how many retries a saga gets before it is escalated to failed.
+	MaxAttempts int `mapstructure:"max_attempts"`
+	// BaseDelay is the wait before the first retry, doubled for each one after it up to MaxDelay.
+	BaseDelay time.Duration `mapstructure:"base_delay"`
+	MaxDelay  time.Duration `mapstructure:"max_delay"`
+}
+
 // InventoryClientConfig controls the HTTP client used to reserve and release stock synchronously.
 type InventoryClientConfig struct {
 	Timeout time.Duration `mapstructure:"timeout"`
@@ -37,17 +65,25 @@ type Config struct {
 	DatabasePool DatabasePoolConfig    `mapstructure:"database_pool"`
 	// DatabaseURL is the raw connection string used to open the pool; Database
 	// above holds the same information split into fields for validation.
-	DatabaseURL         string                `mapstructure:"-"`
-	Redis               config.RedisConfig    `mapstructure:"redis"`
-	Kafka               config.KafkaConfig    `mapstructure:"kafka"`
-	Outbox              OutboxConfig          `mapstructure:"outbox"`
-	Jaeger              config.JaegerConfig   `mapstructure:"jaeger"`
-	Logger              config.LoggerConfig   `mapstructure:"logger"`
-	Service             config.ServiceConfig  `mapstructure:"service"`
-	InventoryServiceURL string                `mapstructure:"inventory_service_url"`
-	InventoryClient     InventoryClientConfig `mapstructure:"inventory_client"`
-	PaymentServiceURL   string                `mapstructure:"payment_service_url"`
-	PaymentClient       PaymentClientConfig   `mapstructure:"payment_client"`
+	DatabaseURL          config.Secret                `mapstructure:"-"`
+	DatabaseReplica      config.DatabaseReplicaConfig `mapstructure:"database_replica"`
+	Redis                config.RedisConfig           `mapstructure:"redis"`
+	LocalCache           config.LocalCacheConfig      `mapstructure:"local_cache"`
+	Kafka                config.KafkaConfig           `mapstructure:"kafka"`
+	Outbox               OutboxConfig                 `mapstructure:"outbox"`
+	SagaWatchdog         SagaWatchdogConfig           `mapstructure:"saga_watchdog"`
+	CompensationRecovery CompensationRecoveryConfig   `mapstructure:"compensation_recovery"`
+	FeatureFlags         config.FeatureFlagsConfig    `mapstructure:"feature_flags"`
+	Jaeger               config.JaegerConfig          `mapstructure:"jaeger"`
+	Logger               config.LoggerConfig          `mapstructure:"logger"`
+	Migrations           config.MigrationsConfig      `mapstructure:"migrations"`
+	Service              config.ServiceConfig         `mapstructure:"service"`
+	InventoryServiceURL  string                       `mapstructure:"inventory_service_url"`
+	InventoryClient      InventoryClientConfig        `mapstructure:"inventory_client"`
+	PaymentServiceURL    string                       `mapstructure:"payment_service_url"`
+	PaymentClient        PaymentClientConfig          `mapstructure:"payment_client"`
+	// InternalTLS is shared by the inventory and payment clients when their URLs are https://.
+	InternalTLS config.TLSConfig `mapstructure:"internal_tls"`
 }
 
 func LoadConfig() (*Config, error) {
@@ -55,18 +91,58 @@ func LoadConfig() (*Config, error) {
 
 	loader := config.New("order")
 	loader.SetDefault("server.host", "0.0.0.0")
+	loader.SetDefault("server.admin_port", "9091")
+	loader.SetDefault("internal_tls.cert_file", "")
+	loader.SetDefault("internal_tls.key_file", "")
+	loader.SetDefault("internal_tls.ca_file", "")
+	loader.SetDefault("internal_tls.server_name", "")
+	loader.SetDefault("internal_tls.reload_interval", "1m")
+	loader.SetDefault("server.tls.cert_file", "")
+	loader.SetDefault("server.tls.key_file", "")
+	loader.SetDefault("server.tls.ca_file", "")
+	loader.SetDefault("server.tls.reload_interval", "1m")
 	loader.SetDefault("kafka.group_id", "order-service")
 	loader.SetDefault("kafka.dlq_topic", "orders.events.dlq")
+	loader.SetDefault("kafka.log_payloads", false)
 	loader.SetDefault("logger.level", "info")
 	loader.SetDefault("logger.environment", "development")
 	loader.SetDefault("logger.output_paths", []string{"stdout"})
+	loader.SetDefault("logger.sampling.initial", 100)
+	loader.SetDefault("logger.sampling.thereafter", 100)
+	loader.SetDefault("logger.sampling.tick", "1s")
+	loader.SetDefault("logger.redaction.mode", "mask")
+	loader.SetDefault("logger.redaction.fields", []string{})
+	loader.SetDefault("logger.redaction.hash_key", "")
 	loader.SetDefault("service.name", "order")
 	loader.SetDefault("service.version", "1.0.0")
 	loader.SetDefault("database_pool.max_open_conns", 25)
 	loader.SetDefault("database_pool.max_idle_conns", 5)
 	loader.SetDefault("database_pool.max_lifetime", "5m")
+	loader.SetDefault("database_pool.slow_query_threshold", "200ms")
+	loader.SetDefault("migrations.on_startup", false)
+	loader.SetDefault("migrations.timeout", "2m")
+	loader.SetDefault("redis.mode", "standalone")
+	loader.SetDefault("redis.addrs", []string{})
+	loader.SetDefault("redis.master_name", "")
+	loader.SetDefault("database_replica.url", "")
+	loader.SetDefault("database_replica.max_lag", "5s")
+	loader.SetDefault("database_replica.lag_check_interval", "1s")
+	loader.SetDefault("database_replica.read_your_writes_window", "5s")
+	loader.SetDefault("local_cache.max_entries", 10000)
+	loader.SetDefault("local_cache.ttl", "10s")
 	loader.SetDefault("outbox.relay_interval", "1s")
+	loader.SetDefault("feature_flags.backend", "")
+	loader.SetDefault("feature_flags.path", "")
+	loader.SetDefault("feature_flags.refresh_interval", "30s")
 	loader.SetDefault("outbox.relay_batch_size", 100)
+	loader.SetDefault("saga_watchdog.interval", "30s")
+	loader.SetDefault("saga_watchdog.batch_size", 50)
+	loader.SetDefault("saga_watchdog.awaiting_payment_timeout", "15m")
+	loader.SetDefault("compensation_recovery.interval", "30s")
+	loader.SetDefault("compensation_recovery.batch_size", 50)
+	loader.SetDefault("compensation_recovery.max_attempts", 8)
+	loader.SetDefault("compensation_recovery.base_delay", "30s")
+	loader.SetDefault("compensation_recovery.max_delay", "30m")
 	loader.SetDefault("inventory_client.timeout", "5s")
 	loader.SetDefault("payment_client.timeout", "5s")
 
@@ -80,6 +156,21 @@ func LoadConfig() (*Config, error) {
 	if err := loader.BindEnv("redis.url", "REDIS_URL"); err != nil {
 		return nil, fmt.Errorf("failed to bind redis.url: %w", err)
 	}
+	if err := loader.BindEnv("redis.mode", "REDIS_MODE"); err != nil {
+		return nil, fmt.Errorf("failed to bind redis.mode: %w", err)
+	}
+	if err := loader.BindEnv("redis.addrs", "REDIS_ADDRS"); err != nil {
+		return nil, fmt.Errorf("failed to bind redis.addrs: %w", err)
+	}
+	if err := loader.BindEnv("redis.master_name", "REDIS_MASTER_NAME"); err != nil {
+		return nil, fmt.Errorf("failed to bind redis.master_name: %w", err)
+	}
+	if err := loader.BindEnv("redis.password", "REDIS_PASSWORD"); err != nil {
+		return nil, fmt.Errorf("failed to bind redis.password: %w", err)
+	}
+	if err := loader.BindEnv("redis.sentinel_password", "REDIS_SENTINEL_PASSWORD"); err != nil {
+		return nil, fmt.Errorf("failed to bind redis.sentinel_password: %w", err)
+	}
 	if err := loader.BindEnv("kafka.brokers", "KAFKA_BROKERS"); err != nil {
 		return nil, fmt.Errorf("failed to bind kafka.brokers: %w", err)
 	}
@@ -101,23 +192,29 @@ func LoadConfig() (*Config, error) {
 		return nil, err
 	}
 
-	// Get the database URL string directly from viper
-	dbURLString := loader.GetString("database.url")
+	// Get the database URL string directly from viper; it may be a secret reference
+	dbURL, err := loader.Secret("database.url")
+	if err != nil {
+		return nil, err
+	}
+	dbURLString := dbURL.Value()
 	if dbURLString == "" {
 		return nil, fmt.Errorf("ORDER_DATABASE_URL environment variable is not set")
 	}
-	cfg.DatabaseURL = dbURLString
+	cfg.DatabaseURL = dbURL
 
 	parsedURL, err := url.Parse(dbURLString)
 	if err != nil {
-		return nil, fmt.Errorf("invalid ORDER_DATABASE_URL: %w", err)
+		// url.Error repeats the URL, password included, so only its cause is reported.
+		return nil, fmt.Errorf("invalid ORDER_DATABASE_URL: %w", errors.Unwrap(err))
 	}
 
 	// Populate DatabaseConfig fields from parsed URL
 	cfg.Database.Host = parsedURL.Hostname()
 	cfg.Database.Port = parsedURL.Port()
 	cfg.Database.User = parsedURL.User.Username()
-	cfg.Database.Password, _ = parsedURL.User.Password()
+	password, _ := parsedURL.User.Password()
+	cfg.Database.Password = config.Secret(password)
 	cfg.Database.DBName = parsedURL.Path[1:] // Remove leading slash
 	cfg.Database.SSLMode = parsedURL.Query().Get("sslmode")
 
@@ -134,10 +231,57 @@ func (c *Config) Validate() error {
 	if c.Server.Port == "" {
 		return fmt.Errorf("ORDER_SERVER_PORT (or ORDER_SERVICE_PORT) environment variable is not set")
 	}
+	if err := c.Server.Validate(); err != nil {
+		return fmt.Errorf("server configuration invalid: %w", err)
+	}
+	if err := c.Migrations.Validate(); err != nil {
+		return fmt.Errorf("migrations configuration invalid: %w", err)
+	}
+	if err := c.DatabaseReplica.Validate(); err != nil {
+		return fmt.Errorf("database replica configuration invalid: %w", err)
+	}
+	if err := c.InternalTLS.Validate(); err != nil {
+		return fmt.Errorf("internal TLS configuration invalid: %w", err)
+	}
 
 	// Validation for Redis
-	if c.Redis.URL == "" {
-		return fmt.Errorf("REDIS_URL environment variable is not set")
+	if err := c.Redis.Validate(); err != nil {
+		return fmt.Errorf("redis configuration invalid: %w", err)
+	}
+	if c.LocalCache.MaxEntries < 0 {
+		return fmt.Errorf("ORDER_LOCAL_CACHE_MAX_ENTRIES must not be negative")
+	}
+	if c.LocalCache.MaxEntries > 0 && c.LocalCache.TTL <= 0 {
+		return fmt.Errorf("ORDER_LOCAL_CACHE_TTL must be positive when the local cache is enabled")
+	}
+
+	if c.SagaWatchdog.Interval < 0 {
+		return fmt.Errorf("ORDER_SAGA_WATCHDOG_INTERVAL must not be negative")
+	}
+	if c.SagaWatchdog.Interval > 0 && c.SagaWatchdog.BatchSize <= 0 {
+		return fmt.Errorf("ORDER_SAGA_WATCHDOG_BATCH_SIZE must be positive when the saga watchdog is enabled")
+	}
+	if c.SagaWatchdog.AwaitingPaymentTimeout < 0 {
+		return fmt.Errorf("ORDER_SAGA_WATCHDOG_AWAITING_PAYMENT_TIMEOUT must not be negative")
+	}
+
+	if c.CompensationRecovery.Interval < 0 {
+		return fmt.Errorf("ORDER_COMPENSATION_RECOVERY_INTERVAL must not be negative")
+	}
+	if c.CompensationRecovery.Interval > 0 {
+		if c.CompensationRecovery.BatchSize <= 0 {
+			return fmt.Errorf("ORDER_COMPENSATION_RECOVERY_BATCH_SIZE must be positive when compensation recovery is enabled")
+		}
+		if c.CompensationRecovery.MaxAttempts <= 0 {
+			return fmt.Errorf("ORDER_COMPENSATION_RECOVERY_MAX_ATTEMPTS must be positive when compensation recovery is enabled")
+		}
+		if c.CompensationRecovery.BaseDelay <= 0 || c.CompensationRecovery.MaxDelay < c.CompensationRecovery.BaseDelay {
+			return fmt.Errorf("ORDER_COMPENSATION_RECOVERY_BASE_DELAY must be positive and no longer than ORDER_COMPENSATION_RECOVERY_MAX_DELAY")
+		}
+	}
+
+	if err := c.FeatureFlags.Validate(); err != nil {
+		return fmt.Errorf("feature flag configuration invalid: %w", err)
 	}
 
 	// Validation for Kafka
@@ -8,7 +8,9 @@ import (
 	"go.uber.org/zap"
 )
 
-// OrderCache is the invalidation port the cache consumer deletes keys through.
+// OrderCache is the invalidation port the cache consumer deletes keys through. Only one replica
+// in the consumer group sees each event, so Delete must evict the keys from Redis and from every
+// replica's local tier, as cache.Cache.Delete does.
 type OrderCache interface {
 	Delete(ctx context.Context, keys ...string) error
 }
@@ -6,6 +6,7 @@ import (
 	"database/sql"
 	"fmt"
 
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/database"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/events"
 	sharedlogger "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/logger"
 	"github.com/google/uuid"
@@ -14,7 +15,7 @@ import (
 
 // OrderService is the port the payments consumer uses to react to payment results.
 type OrderService interface {
-	ConfirmPayment(ctx context.Context, tx *sql.Tx, orderID uuid.UUID) error
+	ConfirmPayment(ctx context.Context, tx *sql.Tx, orderID, paymentID uuid.UUID) error
 	FailPayment(ctx context.Context, tx *sql.Tx, orderID uuid.UUID) error
 }
 
@@ -57,34 +58,23 @@ func (c *PaymentsConsumer) handle(ctx context.Context, event events.Event) error
 		return nil
 	}
 
-	tx, err := c.db.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("begin transaction: %w", err)
-	}
-	defer func() { _ = tx.Rollback() }()
-
-	processed, err := c.processed.MarkProcessed(ctx, tx, event.ID, event.Type)
-	if err != nil {
-		return err
-	}
-	if !processed {
-		return tx.Commit() // already handled, redelivery is a no-op
-	}
-
-	switch event.Type {
-	case events.EventTypePaymentProcessed:
-		err = c.orders.ConfirmPayment(ctx, tx, orderID)
-	case events.EventTypePaymentFailed:
-		err = c.orders.FailPayment(ctx, tx, orderID)
-	}
-	if err != nil {
-		return err
-	}
+	return database.WithTx(ctx, c.db, func(ctx context.Context, tx *sql.Tx) error {
+		processed, err := c.processed.MarkProcessed(ctx, tx, event.ID, event.Type)
+		if err != nil {
+			return err
+		}
+		if !processed {
+			return nil // already handled, redelivery is a no-op
+		}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("commit transaction: %w", err)
-	}
-	return nil
+		switch event.Type {
+		case events.EventTypePaymentProcessed:
+			return c.orders.ConfirmPayment(ctx, tx, orderID, paymentIDFromEvent(event))
+		case events.EventTypePaymentFailed:
+			return c.orders.FailPayment(ctx, tx, orderID)
+		}
+		return nil
+	})
 }
 
 func orderIDFromEvent(event events.Event) (uuid.UUID, error) {
@@ -98,3 +88,15 @@ func orderIDFromEvent(event events.Event) (uuid.UUID, error) {
 	}
 	return uuid.Parse(str)
 }
+
+// paymentIDFromEvent returns the payment_id event carries, or uuid.Nil if it carries none or an
+// invalid one. The payment is only needed to refund it later, so its absence does not stop the
+// order from being confirmed.
+func paymentIDFromEvent(event events.Event) uuid.UUID {
+	str, _ := event.Data["payment_id"].(string)
+	id, err := uuid.Parse(str)
+	if err != nil {
+		return uuid.Nil
+	}
+	return id
+}
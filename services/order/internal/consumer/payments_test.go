@@ -18,14 +18,16 @@ var errTestOrderService = errors.New("order service failure")
 
 // fakeOrderService is an in-memory OrderService test double.
 type fakeOrderService struct {
-	confirmCalls []uuid.UUID
-	failCalls    []uuid.UUID
-	confirmErr   error
-	failErr      error
+	confirmCalls   []uuid.UUID
+	confirmPayment uuid.UUID
+	failCalls      []uuid.UUID
+	confirmErr     error
+	failErr        error
 }
 
-func (f *fakeOrderService) ConfirmPayment(_ context.Context, _ *sql.Tx, orderID uuid.UUID) error {
+func (f *fakeOrderService) ConfirmPayment(_ context.Context, _ *sql.Tx, orderID, paymentID uuid.UUID) error {
 	f.confirmCalls = append(f.confirmCalls, orderID)
+	f.confirmPayment = paymentID
 	return f.confirmErr
 }
 
@@ -96,8 +98,9 @@ func TestPaymentsConsumer_Handle(t *testing.T) {
 		}
 		defer func() { _ = db.Close() }()
 
-		orderID := uuid.New()
+		orderID, paymentID := uuid.New(), uuid.New()
 		event := newPaymentEvent(events.EventTypePaymentProcessed, orderID.String())
+		event.Data["payment_id"] = paymentID.String()
 
 		mock.ExpectBegin()
 		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO processed_events")).
@@ -114,6 +117,9 @@ func TestPaymentsConsumer_Handle(t *testing.T) {
 		if len(orders.confirmCalls) != 1 || orders.confirmCalls[0] != orderID {
 			t.Errorf("confirmCalls = %v, want [%v]", orders.confirmCalls, orderID)
 		}
+		if orders.confirmPayment != paymentID {
+			t.Errorf("confirmed with payment %v, want %v", orders.confirmPayment, paymentID)
+		}
 		if len(orders.failCalls) != 0 {
 			t.Errorf("failCalls = %v, want none", orders.failCalls)
 		}
@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"regexp"
+
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/validation"
+)
+
+// Address is a postal address an order ships or is billed to. It is stored as JSON in the orders
+// table and carried on order events as-is, hence the tags.
+type Address struct {
+	Name       string `json:"name"`
+	Line1      string `json:"line1"`
+	Line2      string `json:"line2,omitempty"`
+	City       string `json:"city"`
+	Region     string `json:"region,omitempty"`
+	PostalCode string `json:"postal_code,omitempty"`
+	// Country is an ISO 3166-1 alpha-2 code, such as "US".
+	Country string `json:"country"`
+}
+
+var countryCodePattern = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// postalCodePatterns are the postal code formats of the countries orders are commonly shipped to.
+// A country listed here requires a postal code in its format; any other country accepts whatever
+// postal code it is given, including none.
+var postalCodePatterns = map[string]*regexp.Regexp{
+	"AU": regexp.MustCompile(`^\d{4}$`),
+	"BR": regexp.MustCompile(`^\d{5}-?\d{3}$`),
+	"CA": regexp.MustCompile(`^(?i)[A-Z]\d[A-Z] ?\d[A-Z]\d$`),
+	"CN": regexp.MustCompile(`^\d{6}$`),
+	"DE": regexp.MustCompile(`^\d{5}$`),
+	"ES": regexp.MustCompile(`^\d{5}$`),
+	"FR": regexp.MustCompile(`^\d{5}$`),
+	"GB": regexp.MustCompile(`^(?i)[A-Z]{1,2}\d[A-Z\d]? ?\d[A-Z]{2}$`),
+	"IN": regexp.MustCompile(`^\d{6}$`),
+	"IT": regexp.MustCompile(`^\d{5}$`),
+	"JP": regexp.MustCompile(`^\d{3}-?\d{4}$`),
+	"NL": regexp.MustCompile(`^(?i)\d{4} ?[A-Z]{2}$`),
+	"PL": regexp.MustCompile(`^\d{2}-\d{3}$`),
+	"RU": regexp.MustCompile(`^\d{6}$`),
+	"SE": regexp.MustCompile(`^\d{3} ?\d{2}$`),
+	"US": regexp.MustCompile(`^\d{5}(-\d{4})?$`),
+}
+
+// check records every invalid field of a, each under prefix, such as "shipping_address.city".
+func (a Address) check(prefix string, violations *validation.Violations) {
+	field := func(name string) string { return prefix + "." + name }
+
+	violations.Check(a.Name != "", field("name"), "must not be empty")
+	violations.Check(a.Line1 != "", field("line1"), "must not be empty")
+	violations.Check(a.City != "", field("city"), "must not be empty")
+	if !countryCodePattern.MatchString(a.Country) {
+		violations.Add(field("country"), "must be an ISO 3166-1 alpha-2 country code")
+		return
+	}
+
+	pattern, ok := postalCodePatterns[a.Country]
+	if !ok {
+		return
+	}
+	switch {
+	case a.PostalCode == "":
+		violations.Add(field("postal_code"), "must not be empty in "+a.Country)
+	case !pattern.MatchString(a.PostalCode):
+		violations.Add(field("postal_code"), "is not a valid postal code in "+a.Country)
+	}
+}
@@ -0,0 +1,94 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/errors"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/validation"
+	"github.com/google/uuid"
+)
+
+func testAddress(country, postalCode string) Address {
+	return Address{Name: "Ada Lovelace", Line1: "1 Main St", City: "Springfield", Country: country, PostalCode: postalCode}
+}
+
+func TestAddress_Check(t *testing.T) {
+	tests := []struct {
+		name       string
+		address    Address
+		wantFields []string
+	}{
+		{"US zip", testAddress("US", "62704"), nil},
+		{"US zip+4", testAddress("US", "62704-1234"), nil},
+		{"GB postcode", testAddress("GB", "SW1A 1AA"), nil},
+		{"CA postal code in lower case", testAddress("CA", "k1a 0b1"), nil},
+		{"country without a known format accepts no postal code", testAddress("HK", ""), nil},
+		{"country without a known format accepts any postal code", testAddress("NZ", "whatever"), nil},
+		{"US zip with letters", testAddress("US", "6270A"), []string{"addr.postal_code"}},
+		{"DE postal code too short", testAddress("DE", "1011"), []string{"addr.postal_code"}},
+		{"postal code required where known", testAddress("FR", ""), []string{"addr.postal_code"}},
+		{"lower-case country", testAddress("us", "62704"), []string{"addr.country"}},
+		{"three-letter country", testAddress("USA", "62704"), []string{"addr.country"}},
+		{"missing fields", Address{Country: "US", PostalCode: "62704"}, []string{"addr.name", "addr.line1", "addr.city"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var violations validation.Violations
+			tt.address.check("addr", &violations)
+
+			var fields []string
+			for _, v := range violations {
+				fields = append(fields, v.Field)
+			}
+			if strings.Join(fields, ",") != strings.Join(tt.wantFields, ",") {
+				t.Errorf("check() fields = %v, want %v", fields, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestOrder_SetAddresses(t *testing.T) {
+	t.Run("records both addresses", func(t *testing.T) {
+		order := &Order{ID: uuid.New(), Status: StatusPending}
+		shipping, billing := testAddress("US", "62704"), testAddress("GB", "SW1A 1AA")
+		if err := order.SetAddresses(&shipping, &billing); err != nil {
+			t.Fatalf("SetAddresses() error = %v", err)
+		}
+		if order.ShippingAddress == nil || *order.ShippingAddress != shipping {
+			t.Errorf("ShippingAddress = %v, want %v", order.ShippingAddress, shipping)
+		}
+		if order.BillingAddress == nil || *order.BillingAddress != billing {
+			t.Errorf("BillingAddress = %v, want %v", order.BillingAddress, billing)
+		}
+	})
+
+	t.Run("reports both addresses at once and records neither", func(t *testing.T) {
+		order := &Order{ID: uuid.New(), Status: StatusPending}
+		shipping, billing := testAddress("US", "bad"), testAddress("", "")
+		err := order.SetAddresses(&shipping, &billing)
+
+		appErr, ok := err.(*errors.AppError)
+		if !ok {
+			t.Fatalf("SetAddresses() error = %v, want *AppError", err)
+		}
+		var fields []string
+		for _, f := range appErr.Fields {
+			fields = append(fields, f.Field)
+		}
+		want := []string{"shipping_address.postal_code", "billing_address.country"}
+		if strings.Join(fields, ",") != strings.Join(want, ",") {
+			t.Errorf("SetAddresses() fields = %v, want %v", fields, want)
+		}
+		if order.ShippingAddress != nil || order.BillingAddress != nil {
+			t.Error("addresses were recorded despite being invalid")
+		}
+	})
+
+	t.Run("only while pending", func(t *testing.T) {
+		order := &Order{ID: uuid.New(), Status: StatusConfirmed}
+		shipping := testAddress("US", "62704")
+		assertOrderAlreadyProcessed(t, order.SetAddresses(&shipping, nil))
+	})
+}
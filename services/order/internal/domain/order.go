@@ -2,9 +2,11 @@
 package domain
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/errors"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/validation"
 	"github.com/google/uuid"
 )
 
@@ -41,21 +43,33 @@ type Order struct {
 	TotalAmountCents int64
 	Currency         string
 	Items            []OrderItem
-	CreatedAt        time.Time
-	UpdatedAt        time.Time
-	Version          int
+	// ShippingAddress and BillingAddress are nil when the customer did not give one.
+	ShippingAddress *Address
+	BillingAddress  *Address
+	// Carrier and TrackingNumber identify the shipment once the order is shipped.
+	Carrier        string
+	TrackingNumber string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Version        int
 }
 
-// NewOrder builds a pending order from its line items, computing per-item and order totals.
+// NewOrder builds a pending order from its line items, computing per-item and order totals. Every
+// invalid field is reported at once, item fields by their path such as "items[2].quantity".
 func NewOrder(customerID uuid.UUID, items []OrderItem, currency string) (*Order, error) {
-	if customerID == uuid.Nil {
-		return nil, errors.NewValidationError("customer_id", "must not be empty")
-	}
-	if len(items) == 0 {
-		return nil, errors.NewValidationError("items", "must contain at least one item")
+	var violations validation.Violations
+	violations.Check(customerID != uuid.Nil, "customer_id", "must not be empty")
+	violations.Check(len(items) > 0, "items", "must contain at least one item")
+	violations.Check(currency != "", "currency", "must not be empty")
+
+	for i, item := range items {
+		violations.Check(item.ProductID != uuid.Nil, fmt.Sprintf("items[%d].product_id", i), "must not be empty")
+		violations.Check(item.ProductName != "", fmt.Sprintf("items[%d].product_name", i), "must not be empty")
+		violations.Check(item.Quantity > 0, fmt.Sprintf("items[%d].quantity", i), "must be greater than zero")
+		violations.Check(item.UnitPriceCents >= 0, fmt.Sprintf("items[%d].unit_price_cents", i), "must not be negative")
 	}
-	if currency == "" {
-		return nil, errors.NewValidationError("currency", "must not be empty")
+	if err := violations.Err(); err != nil {
+		return nil, err
 	}
 
 	now := time.Now().UTC()
@@ -63,19 +77,6 @@ func NewOrder(customerID uuid.UUID, items []OrderItem, currency string) (*Order,
 	var totalCents int64
 
 	for i, item := range items {
-		if item.ProductID == uuid.Nil {
-			return nil, errors.NewValidationError("product_id", "must not be empty")
-		}
-		if item.ProductName == "" {
-			return nil, errors.NewValidationError("product_name", "must not be empty")
-		}
-		if item.Quantity <= 0 {
-			return nil, errors.NewValidationError("quantity", "must be greater than zero")
-		}
-		if item.UnitPriceCents < 0 {
-			return nil, errors.NewValidationError("unit_price_cents", "must not be negative")
-		}
-
 		item.ID = uuid.New()
 		item.TotalPriceCents = item.UnitPriceCents * int64(item.Quantity)
 		orderItems[i] = item
@@ -95,6 +96,29 @@ func NewOrder(customerID uuid.UUID, items []OrderItem, currency string) (*Order,
 	}, nil
 }
 
+// SetAddresses records where a pending order ships and is billed to. Either may be nil. Every
+// invalid field of both is reported at once, under "shipping_address" and "billing_address".
+func (o *Order) SetAddresses(shipping, billing *Address) error {
+	if o.Status != StatusPending {
+		return errors.NewOrderAlreadyProcessed(o.ID.String())
+	}
+
+	var violations validation.Violations
+	if shipping != nil {
+		shipping.check("shipping_address", &violations)
+	}
+	if billing != nil {
+		billing.check("billing_address", &violations)
+	}
+	if err := violations.Err(); err != nil {
+		return err
+	}
+
+	o.ShippingAddress = shipping
+	o.BillingAddress = billing
+	return nil
+}
+
 // MarkPendingPayment moves a pending order into pending_payment.
 func (o *Order) MarkPendingPayment() error {
 	return o.transition(StatusPending, StatusPendingPayment)
@@ -110,15 +134,44 @@ func (o *Order) Fail() error {
 	return o.transition(StatusPendingPayment, StatusPaymentFailed)
 }
 
-// Cancel moves a pending or pending_payment order into cancelled.
+// Cancel moves a pending, pending_payment or confirmed order into cancelled. An order that has
+// moved on to fulfilment can no longer be cancelled.
 func (o *Order) Cancel() error {
-	if o.Status != StatusPending && o.Status != StatusPendingPayment {
+	if o.Status != StatusPending && o.Status != StatusPendingPayment && o.Status != StatusConfirmed {
 		return errors.NewOrderAlreadyProcessed(o.ID.String())
 	}
 	o.Status = StatusCancelled
 	return nil
 }
 
+// StartProcessing moves a confirmed order into processing, once the warehouse starts preparing it.
+func (o *Order) StartProcessing() error {
+	return o.transition(StatusConfirmed, StatusProcessing)
+}
+
+// Ship moves a processing order into shipped, recording the carrier and tracking number it left
+// with. Both are required.
+func (o *Order) Ship(carrier, trackingNumber string) error {
+	var violations validation.Violations
+	violations.Check(carrier != "", "carrier", "must not be empty")
+	violations.Check(trackingNumber != "", "tracking_number", "must not be empty")
+	if err := violations.Err(); err != nil {
+		return err
+	}
+
+	if err := o.transition(StatusProcessing, StatusShipped); err != nil {
+		return err
+	}
+	o.Carrier = carrier
+	o.TrackingNumber = trackingNumber
+	return nil
+}
+
+// Deliver moves a shipped order into delivered. Delivered is terminal: no transition leaves it.
+func (o *Order) Deliver() error {
+	return o.transition(StatusShipped, StatusDelivered)
+}
+
 func (o *Order) transition(from, to Status) error {
 	if o.Status != from {
 		return errors.NewOrderAlreadyProcessed(o.ID.String())
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/errors"
@@ -62,6 +63,28 @@ func TestNewOrder(t *testing.T) {
 	}
 }
 
+func TestNewOrder_ReportsEveryInvalidField(t *testing.T) {
+	items := []OrderItem{
+		{ProductID: uuid.New(), ProductName: "Widget", Quantity: 1, UnitPriceCents: 100},
+		{ProductID: uuid.New(), Quantity: 0, UnitPriceCents: -1},
+	}
+
+	_, err := NewOrder(uuid.New(), items, "")
+
+	appErr, ok := err.(*errors.AppError)
+	if !ok {
+		t.Fatalf("NewOrder() error = %v, want *AppError", err)
+	}
+	var fields []string
+	for _, f := range appErr.Fields {
+		fields = append(fields, f.Field)
+	}
+	want := []string{"currency", "items[1].product_name", "items[1].quantity", "items[1].unit_price_cents"}
+	if strings.Join(fields, ",") != strings.Join(want, ",") {
+		t.Errorf("NewOrder() fields = %v, want %v", fields, want)
+	}
+}
+
 func TestOrder_Transitions(t *testing.T) {
 	allStatuses := []Status{
 		StatusPending, StatusPendingPayment, StatusPaymentFailed, StatusConfirmed,
@@ -77,6 +100,9 @@ func TestOrder_Transitions(t *testing.T) {
 		{"MarkPendingPayment", (*Order).MarkPendingPayment, StatusPending, StatusPendingPayment},
 		{"Confirm", (*Order).Confirm, StatusPendingPayment, StatusConfirmed},
 		{"Fail", (*Order).Fail, StatusPendingPayment, StatusPaymentFailed},
+		{"StartProcessing", (*Order).StartProcessing, StatusConfirmed, StatusProcessing},
+		{"Ship", func(o *Order) error { return o.Ship("DHL", "JD0001") }, StatusProcessing, StatusShipped},
+		{"Deliver", (*Order).Deliver, StatusShipped, StatusDelivered},
 	}
 
 	for _, tt := range singleSourceTests {
@@ -104,7 +130,7 @@ func TestOrder_Transitions(t *testing.T) {
 			order := &Order{ID: uuid.New(), Status: from}
 			err := order.Cancel()
 
-			if from != StatusPending && from != StatusPendingPayment {
+			if from != StatusPending && from != StatusPendingPayment && from != StatusConfirmed {
 				assertOrderAlreadyProcessed(t, err)
 				return
 			}
@@ -118,6 +144,30 @@ func TestOrder_Transitions(t *testing.T) {
 	}
 }
 
+func TestOrder_Ship(t *testing.T) {
+	t.Run("records the shipment", func(t *testing.T) {
+		order := &Order{ID: uuid.New(), Status: StatusProcessing}
+		if err := order.Ship("DHL", "JD0001"); err != nil {
+			t.Fatalf("Ship() error = %v", err)
+		}
+		if order.Carrier != "DHL" || order.TrackingNumber != "JD0001" {
+			t.Errorf("shipment = %q/%q, want DHL/JD0001", order.Carrier, order.TrackingNumber)
+		}
+	})
+
+	t.Run("requires a carrier and tracking number", func(t *testing.T) {
+		order := &Order{ID: uuid.New(), Status: StatusProcessing}
+		err := order.Ship("", "")
+		appErr, ok := err.(*errors.AppError)
+		if !ok || appErr.Code != "VALIDATION_ERROR" {
+			t.Fatalf("Ship() error = %v, want VALIDATION_ERROR", err)
+		}
+		if order.Status != StatusProcessing {
+			t.Errorf("Status = %v, want %v", order.Status, StatusProcessing)
+		}
+	})
+}
+
 func assertOrderAlreadyProcessed(t *testing.T, err error) {
 	t.Helper()
 
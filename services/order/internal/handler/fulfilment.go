@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"slices"
+
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/order/internal/domain"
+	apperrors "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/errors"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/validation"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Roles, as the API Gateway forwards them in X-User-Role from the caller's JWT, allowed to drive
+// an order through fulfilment.
+const (
+	RoleWarehouse = "warehouse"
+	RoleAdmin     = "admin"
+)
+
+var fulfilmentRoles = []string{RoleWarehouse, RoleAdmin}
+
+// OrderFulfiller is the port the fulfilment handler uses to move a confirmed order through
+// processing, shipped and delivered.
+type OrderFulfiller interface {
+	StartProcessing(ctx context.Context, orderID uuid.UUID) (*domain.Order, error)
+	Ship(ctx context.Context, orderID uuid.UUID, carrier, trackingNumber string) (*domain.Order, error)
+	Deliver(ctx context.Context, orderID uuid.UUID) (*domain.Order, error)
+}
+
+// FulfilmentHandler serves the staff-only order fulfilment endpoints. Unlike the customer
+// endpoints, they act on any order, so they are guarded by role rather than ownership.
+type FulfilmentHandler struct {
+	responder
+
+	orders OrderFulfiller
+}
+
+// NewFulfilmentHandler builds a FulfilmentHandler backed by orders.
+func NewFulfilmentHandler(orders OrderFulfiller, logger *zap.Logger) *FulfilmentHandler {
+	return &FulfilmentHandler{responder: responder{logger: logger}, orders: orders}
+}
+
+type shipOrderRequest struct {
+	Carrier        string `json:"carrier" validate:"required,max=100"`
+	TrackingNumber string `json:"tracking_number" validate:"required,max=100"`
+}
+
+// StartProcessing handles POST /api/v1/orders/{id}/processing, moving a confirmed order into
+// processing.
+func (h *FulfilmentHandler) StartProcessing(w http.ResponseWriter, r *http.Request) {
+	h.transition(w, r, func(orderID uuid.UUID) (*domain.Order, error) {
+		return h.orders.StartProcessing(r.Context(), orderID)
+	})
+}
+
+// Ship handles POST /api/v1/orders/{id}/ship, moving a processing order into shipped with the
+// carrier and tracking number in the body.
+func (h *FulfilmentHandler) Ship(w http.ResponseWriter, r *http.Request) {
+	h.transition(w, r, func(orderID uuid.UUID) (*domain.Order, error) {
+		var req shipOrderRequest
+		if err := validation.DecodeJSON(w, r, &req); err != nil {
+			return nil, err
+		}
+		return h.orders.Ship(r.Context(), orderID, req.Carrier, req.TrackingNumber)
+	})
+}
+
+// Deliver handles POST /api/v1/orders/{id}/deliver, moving a shipped order into delivered.
+func (h *FulfilmentHandler) Deliver(w http.ResponseWriter, r *http.Request) {
+	h.transition(w, r, func(orderID uuid.UUID) (*domain.Order, error) {
+		return h.orders.Deliver(r.Context(), orderID)
+	})
+}
+
+// transition checks the caller's role and the order id, then runs apply and answers 200 with the
+// updated order. A transition the order's status does not allow answers 409.
+func (h *FulfilmentHandler) transition(w http.ResponseWriter, r *http.Request, apply func(orderID uuid.UUID) (*domain.Order, error)) {
+	if err := requireRole(r, fulfilmentRoles...); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	orderID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		h.writeError(w, r, apperrors.NewBadRequest("invalid order id"))
+		return
+	}
+
+	order, err := apply(orderID)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, newOrderResponse(order))
+}
+
+// requireRole checks that X-User-Role is one of roles. A request without the header is
+// unauthenticated; one with any other role is forbidden.
+func requireRole(r *http.Request, roles ...string) error {
+	role := r.Header.Get("X-User-Role")
+	if role == "" {
+		return apperrors.NewUnauthorized("X-User-Role header is required")
+	}
+	if !slices.Contains(roles, role) {
+		return apperrors.NewForbidden("role " + role + " may not change an order's fulfilment status")
+	}
+	return nil
+}
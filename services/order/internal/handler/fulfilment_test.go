@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/order/internal/domain"
+	apperrors "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/errors"
+	"github.com/google/uuid"
+	"go.uber.org/zap/zaptest"
+)
+
+// fakeOrderFulfiller is an in-memory OrderFulfiller test double that applies each transition to
+// an order in the status it starts from.
+type fakeOrderFulfiller struct {
+	from  domain.Status
+	calls []string
+}
+
+func (f *fakeOrderFulfiller) apply(orderID uuid.UUID, call string, transition func(*domain.Order) error) (*domain.Order, error) {
+	f.calls = append(f.calls, call)
+	order := &domain.Order{ID: orderID, Status: f.from, Currency: "USD"}
+	if err := transition(order); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+func (f *fakeOrderFulfiller) StartProcessing(_ context.Context, orderID uuid.UUID) (*domain.Order, error) {
+	return f.apply(orderID, "processing", (*domain.Order).StartProcessing)
+}
+
+func (f *fakeOrderFulfiller) Ship(_ context.Context, orderID uuid.UUID, carrier, trackingNumber string) (*domain.Order, error) {
+	return f.apply(orderID, "ship", func(o *domain.Order) error { return o.Ship(carrier, trackingNumber) })
+}
+
+func (f *fakeOrderFulfiller) Deliver(_ context.Context, orderID uuid.UUID) (*domain.Order, error) {
+	return f.apply(orderID, "deliver", (*domain.Order).Deliver)
+}
+
+func newFulfilmentMux(t *testing.T, orders OrderFulfiller) *http.ServeMux {
+	t.Helper()
+	h := NewFulfilmentHandler(orders, zaptest.NewLogger(t))
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/orders/{id}/processing", h.StartProcessing)
+	mux.HandleFunc("POST /api/v1/orders/{id}/ship", h.Ship)
+	mux.HandleFunc("POST /api/v1/orders/{id}/deliver", h.Deliver)
+	return mux
+}
+
+func TestFulfilmentHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		action     string
+		role       string
+		orderID    string
+		body       string
+		from       domain.Status
+		wantStatus int
+		wantCode   string
+		wantOrder  domain.Status
+	}{
+		{
+			name:       "warehouse starts processing",
+			action:     "processing",
+			role:       RoleWarehouse,
+			from:       domain.StatusConfirmed,
+			wantStatus: http.StatusOK,
+			wantOrder:  domain.StatusProcessing,
+		},
+		{
+			name:       "admin ships with a tracking number",
+			action:     "ship",
+			role:       RoleAdmin,
+			body:       `{"carrier":"DHL","tracking_number":"JD0001"}`,
+			from:       domain.StatusProcessing,
+			wantStatus: http.StatusOK,
+			wantOrder:  domain.StatusShipped,
+		},
+		{
+			name:       "warehouse delivers",
+			action:     "deliver",
+			role:       RoleWarehouse,
+			from:       domain.StatusShipped,
+			wantStatus: http.StatusOK,
+			wantOrder:  domain.StatusDelivered,
+		},
+		{
+			name:       "shipping needs a carrier and tracking number",
+			action:     "ship",
+			role:       RoleWarehouse,
+			body:       `{"carrier":"DHL"}`,
+			from:       domain.StatusProcessing,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "VALIDATION_ERROR",
+		},
+		{
+			name:       "delivered is terminal",
+			action:     "processing",
+			role:       RoleWarehouse,
+			from:       domain.StatusDelivered,
+			wantStatus: http.StatusConflict,
+			wantCode:   "ORDER_ALREADY_PROCESSED",
+		},
+		{
+			name:       "customer role is forbidden",
+			action:     "deliver",
+			role:       "customer",
+			from:       domain.StatusShipped,
+			wantStatus: http.StatusForbidden,
+			wantCode:   "FORBIDDEN",
+		},
+		{
+			name:       "missing role",
+			action:     "deliver",
+			from:       domain.StatusShipped,
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   "UNAUTHORIZED",
+		},
+		{
+			name:       "invalid order id",
+			action:     "deliver",
+			role:       RoleAdmin,
+			orderID:    "not-a-uuid",
+			from:       domain.StatusShipped,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "BAD_REQUEST",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orders := &fakeOrderFulfiller{from: tt.from}
+			mux := newFulfilmentMux(t, orders)
+
+			orderID := tt.orderID
+			if orderID == "" {
+				orderID = uuid.New().String()
+			}
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/orders/"+orderID+"/"+tt.action, strings.NewReader(tt.body))
+			if tt.role != "" {
+				req.Header.Set("X-User-Role", tt.role)
+			}
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body=%s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				if problem := decodeProblem(t, w); problem.Code != tt.wantCode {
+					t.Errorf("Code = %v, want %v", problem.Code, tt.wantCode)
+				}
+				if tt.wantStatus == http.StatusForbidden || tt.wantStatus == http.StatusUnauthorized {
+					if len(orders.calls) != 0 {
+						t.Errorf("calls = %v, want none without an allowed role", orders.calls)
+					}
+				}
+				return
+			}
+
+			var got orderResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if got.Status != string(tt.wantOrder) {
+				t.Errorf("Status = %v, want %v", got.Status, tt.wantOrder)
+			}
+			if tt.action == "ship" && (got.Carrier != "DHL" || got.TrackingNumber != "JD0001") {
+				t.Errorf("shipment = %q/%q, want DHL/JD0001", got.Carrier, got.TrackingNumber)
+			}
+		})
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("X-User-Role", RoleAdmin)
+	if err := requireRole(req, RoleWarehouse, RoleAdmin); err != nil {
+		t.Errorf("requireRole(admin) error = %v, want nil", err)
+	}
+
+	req.Header.Set("X-User-Role", "Admin")
+	appErr, ok := requireRole(req, RoleWarehouse, RoleAdmin).(*apperrors.AppError)
+	if !ok || appErr.Code != "FORBIDDEN" {
+		t.Errorf("requireRole(Admin) = %v, want FORBIDDEN: roles are matched exactly", appErr)
+	}
+}
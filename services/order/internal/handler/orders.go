@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	stderrors "errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
@@ -12,6 +13,7 @@ import (
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/order/internal/client"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/order/internal/domain"
 	apperrors "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/errors"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/validation"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
@@ -19,6 +21,10 @@ import (
 const (
 	defaultListLimit = 20
 	maxListLimit     = 100
+
+	// defaultCurrency is the currency of an order that names neither a currency nor a product
+	// the catalog knows; the unknown product then fails validation anyway.
+	defaultCurrency = "USD"
 )
 
 // OrderRepository is the persistence port the orders handler depends on.
@@ -35,36 +41,84 @@ type InventoryReserver interface {
 	Release(ctx context.Context, orderID uuid.UUID) error
 }
 
+// ProductCatalog is the port the orders handler prices an order's items from. Products returns
+// the current catalog entry of each product in ids that exists, keyed by id.
+type ProductCatalog interface {
+	Products(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]client.Product, error)
+}
+
 // OrderTransitioner is the port the orders handler uses to move a freshly created order into
-// pending_payment once its stock is reserved.
+// pending_payment once its stock is reserved, and to cancel an order for its customer.
 type OrderTransitioner interface {
 	MarkPendingPaymentAfterCreate(ctx context.Context, orderID uuid.UUID) error
+	CancelByCustomer(ctx context.Context, orderID, customerID uuid.UUID, reason string) (*domain.Order, error)
 }
 
 // OrdersHandler serves the order HTTP endpoints.
 type OrdersHandler struct {
+	responder
+
 	repo      OrderRepository
+	catalog   ProductCatalog
 	inventory InventoryReserver
 	orders    OrderTransitioner
-	logger    *zap.Logger
 }
 
-// NewOrdersHandler builds an OrdersHandler backed by repo, inventory and orders.
-func NewOrdersHandler(repo OrderRepository, inventory InventoryReserver, orders OrderTransitioner, logger *zap.Logger) *OrdersHandler {
-	return &OrdersHandler{repo: repo, inventory: inventory, orders: orders, logger: logger}
+// responder writes JSON responses and problem documents, logging what cannot be written.
+type responder struct {
+	logger *zap.Logger
 }
 
+// NewOrdersHandler builds an OrdersHandler backed by repo, catalog, inventory and orders.
+func NewOrdersHandler(repo OrderRepository, catalog ProductCatalog, inventory InventoryReserver, orders OrderTransitioner, logger *zap.Logger) *OrdersHandler {
+	return &OrdersHandler{responder: responder{logger: logger}, repo: repo, catalog: catalog, inventory: inventory, orders: orders}
+}
+
+// createOrderItemRequest names a product and a quantity. The name, SKU and price are optional and
+// only checked against the catalog: the order is always priced from the catalog.
 type createOrderItemRequest struct {
-	ProductID      string `json:"product_id"`
-	ProductName    string `json:"product_name"`
-	ProductSKU     string `json:"product_sku"`
-	Quantity       int    `json:"quantity"`
-	UnitPriceCents int64  `json:"unit_price_cents"`
+	ProductID      string `json:"product_id" validate:"required,uuid"`
+	ProductName    string `json:"product_name" validate:"max=255"`
+	ProductSKU     string `json:"product_sku" validate:"max=100"`
+	Quantity       int    `json:"quantity" validate:"gt=0"`
+	UnitPriceCents *int64 `json:"unit_price_cents" validate:"min=0"`
+}
+
+// addressBody is an address as the API accepts and returns it. Country-specific rules, such as
+// the postal code format, are the domain's.
+type addressBody struct {
+	Name       string `json:"name" validate:"required,max=255"`
+	Line1      string `json:"line1" validate:"required,max=255"`
+	Line2      string `json:"line2,omitempty" validate:"max=255"`
+	City       string `json:"city" validate:"required,max=100"`
+	Region     string `json:"region,omitempty" validate:"max=100"`
+	PostalCode string `json:"postal_code,omitempty" validate:"max=20"`
+	Country    string `json:"country" validate:"required,len=2"`
+}
+
+func (a *addressBody) toDomain() *domain.Address {
+	if a == nil {
+		return nil
+	}
+	address := domain.Address(*a)
+	return &address
+}
+
+func newAddressBody(a *domain.Address) *addressBody {
+	if a == nil {
+		return nil
+	}
+	body := addressBody(*a)
+	return &body
 }
 
+// createOrderRequest is the body of POST /api/v1/orders. Items is capped at 100 because the order
+// is priced with a single inventory batch lookup, which accepts at most 100 product ids.
 type createOrderRequest struct {
-	Items    []createOrderItemRequest `json:"items"`
-	Currency string                   `json:"currency"`
+	Items           []createOrderItemRequest `json:"items" validate:"min=1,max=100"`
+	Currency        string                   `json:"currency" validate:"len=3"`
+	ShippingAddress *addressBody             `json:"shipping_address"`
+	BillingAddress  *addressBody             `json:"billing_address"`
 }
 
 type orderItemResponse struct {
@@ -84,6 +138,10 @@ type orderResponse struct {
 	TotalAmountCents int64               `json:"total_amount_cents"`
 	Currency         string              `json:"currency"`
 	Items            []orderItemResponse `json:"items"`
+	ShippingAddress  *addressBody        `json:"shipping_address,omitempty"`
+	BillingAddress   *addressBody        `json:"billing_address,omitempty"`
+	Carrier          string              `json:"carrier,omitempty"`
+	TrackingNumber   string              `json:"tracking_number,omitempty"`
 	CreatedAt        time.Time           `json:"created_at"`
 	UpdatedAt        time.Time           `json:"updated_at"`
 	Version          int                 `json:"version"`
@@ -110,6 +168,10 @@ func newOrderResponse(o *domain.Order) orderResponse {
 		TotalAmountCents: o.TotalAmountCents,
 		Currency:         o.Currency,
 		Items:            items,
+		ShippingAddress:  newAddressBody(o.ShippingAddress),
+		BillingAddress:   newAddressBody(o.BillingAddress),
+		Carrier:          o.Carrier,
+		TrackingNumber:   o.TrackingNumber,
 		CreatedAt:        o.CreatedAt,
 		UpdatedAt:        o.UpdatedAt,
 		Version:          o.Version,
@@ -121,45 +183,38 @@ type createOrderResponse struct {
 	Status  string `json:"status"`
 }
 
-// Create handles POST /api/v1/orders. It reserves stock for the order synchronously before
-// accepting it: a shortage answers 409 and creates nothing. Once stock is reserved, the order is
+// Create handles POST /api/v1/orders. It prices every item from the inventory catalog, rejecting
+// unknown or inactive products and any name, SKU, price or currency the caller sent that does not
+// match the catalog. It then reserves stock for the order synchronously before accepting it: a
+// shortage answers 409 and creates nothing. Once stock is reserved, the order is
 // saved and moved to pending_payment, which enqueues order.ready_for_payment for the payment saga
 // to pick up, and the call returns 202 with the order id.
 func (h *OrdersHandler) Create(w http.ResponseWriter, r *http.Request) {
 	customerID, err := customerIDFromHeader(r)
 	if err != nil {
-		h.writeError(w, err)
+		h.writeError(w, r, err)
 		return
 	}
 
 	var req createOrderRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeError(w, apperrors.NewBadRequest("invalid request body"))
+	if err := validation.DecodeJSON(w, r, &req); err != nil {
+		h.writeError(w, r, err)
 		return
 	}
-	if req.Currency == "" {
-		req.Currency = "USD"
-	}
 
-	items := make([]domain.OrderItem, len(req.Items))
-	for i, item := range req.Items {
-		productID, err := uuid.Parse(item.ProductID)
-		if err != nil {
-			h.writeError(w, apperrors.NewValidationError("items.product_id", "must be a valid uuid"))
-			return
-		}
-		items[i] = domain.OrderItem{
-			ProductID:      productID,
-			ProductName:    item.ProductName,
-			ProductSKU:     item.ProductSKU,
-			Quantity:       item.Quantity,
-			UnitPriceCents: item.UnitPriceCents,
-		}
+	items, currency, err := h.priceItems(r.Context(), req)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
 	}
 
-	order, err := domain.NewOrder(customerID, items, req.Currency)
+	order, err := domain.NewOrder(customerID, items, currency)
 	if err != nil {
-		h.writeError(w, err)
+		h.writeError(w, r, err)
+		return
+	}
+	if err := order.SetAddresses(req.ShippingAddress.toDomain(), req.BillingAddress.toDomain()); err != nil {
+		h.writeError(w, r, err)
 		return
 	}
 
@@ -168,7 +223,7 @@ func (h *OrdersHandler) Create(w http.ResponseWriter, r *http.Request) {
 		reserveItems[i] = client.ReserveItem{ProductID: item.ProductID, Quantity: item.Quantity}
 	}
 	if err := h.inventory.Reserve(r.Context(), order.ID, reserveItems); err != nil {
-		h.writeError(w, err)
+		h.writeError(w, r, err)
 		return
 	}
 
@@ -177,40 +232,135 @@ func (h *OrdersHandler) Create(w http.ResponseWriter, r *http.Request) {
 			h.logger.Error("failed to release reservation for an order that was not saved",
 				zap.String("order_id", order.ID.String()), zap.Error(releaseErr))
 		}
-		h.writeError(w, err)
+		h.writeError(w, r, err)
 		return
 	}
 
 	if err := h.orders.MarkPendingPaymentAfterCreate(r.Context(), order.ID); err != nil {
-		h.writeError(w, err)
+		h.writeError(w, r, err)
 		return
 	}
 
 	h.writeJSON(w, http.StatusAccepted, createOrderResponse{OrderID: order.ID.String(), Status: string(domain.StatusPendingPayment)})
 }
 
+// priceItems builds the order items of req from the catalog entries of their products and returns
+// them with the order's currency. Without a currency in req, the order takes the currency of its
+// first product, so a mix of currencies is still rejected.
+func (h *OrdersHandler) priceItems(ctx context.Context, req createOrderRequest) ([]domain.OrderItem, string, error) {
+	ids := make([]uuid.UUID, len(req.Items))
+	for i, item := range req.Items {
+		ids[i] = uuid.MustParse(item.ProductID)
+	}
+
+	products, err := h.catalog.Products(ctx, ids)
+	if err != nil {
+		return nil, "", err
+	}
+
+	currency := req.Currency
+	if currency == "" {
+		currency = defaultCurrency
+		if product, ok := products[ids[0]]; ok {
+			currency = product.Currency
+		}
+	}
+
+	var violations validation.Violations
+	items := make([]domain.OrderItem, len(req.Items))
+	for i, item := range req.Items {
+		field := func(name string) string { return fmt.Sprintf("items[%d].%s", i, name) }
+
+		product, ok := products[ids[i]]
+		if !ok {
+			violations.Add(field("product_id"), "does not exist")
+			continue
+		}
+		if !product.IsActive {
+			violations.Add(field("product_id"), "is not available for sale")
+			continue
+		}
+		violations.Check(product.Currency == currency, field("product_id"),
+			fmt.Sprintf("is priced in %s, not %s", product.Currency, currency))
+		violations.Check(item.ProductName == "" || item.ProductName == product.Name, field("product_name"),
+			fmt.Sprintf("does not match the catalog name %q", product.Name))
+		violations.Check(item.ProductSKU == "" || item.ProductSKU == product.SKU, field("product_sku"),
+			fmt.Sprintf("does not match the catalog SKU %q", product.SKU))
+		violations.Check(item.UnitPriceCents == nil || *item.UnitPriceCents == product.PriceCents, field("unit_price_cents"),
+			fmt.Sprintf("does not match the current price of %d", product.PriceCents))
+
+		items[i] = domain.OrderItem{
+			ProductID:      product.ID,
+			ProductName:    product.Name,
+			ProductSKU:     product.SKU,
+			Quantity:       item.Quantity,
+			UnitPriceCents: product.PriceCents,
+		}
+	}
+	if err := violations.Err(); err != nil {
+		return nil, "", err
+	}
+	return items, currency, nil
+}
+
 // Get handles GET /api/v1/orders/{id}.
 func (h *OrdersHandler) Get(w http.ResponseWriter, r *http.Request) {
 	customerID, err := customerIDFromHeader(r)
 	if err != nil {
-		h.writeError(w, err)
+		h.writeError(w, r, err)
 		return
 	}
 
 	orderID, err := uuid.Parse(r.PathValue("id"))
 	if err != nil {
-		h.writeError(w, apperrors.NewBadRequest("invalid order id"))
+		h.writeError(w, r, apperrors.NewBadRequest("invalid order id"))
 		return
 	}
 
 	order, err := h.repo.GetByID(r.Context(), orderID)
 	if err != nil {
-		h.writeError(w, err)
+		h.writeError(w, r, err)
 		return
 	}
 
 	if order.CustomerID != customerID {
-		h.writeError(w, apperrors.NewForbidden("order does not belong to the current user"))
+		h.writeError(w, r, apperrors.NewForbidden("order does not belong to the current user"))
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, newOrderResponse(order))
+}
+
+type cancelOrderRequest struct {
+	Reason string `json:"reason" validate:"max=255"`
+}
+
+// Cancel handles POST /api/v1/orders/{id}/cancel. The customer may cancel their own order while
+// it awaits payment or once it is confirmed; the body, carrying an optional reason, may be
+// omitted. The reservation is released, and a confirmed order's payment refunded, before the call
+// answers 200 with the cancelled order. An order in any other status answers 409.
+func (h *OrdersHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	customerID, err := customerIDFromHeader(r)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	orderID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		h.writeError(w, r, apperrors.NewBadRequest("invalid order id"))
+		return
+	}
+
+	var req cancelOrderRequest
+	if err := validation.Decode(w, r, &req, validation.DecodeOptions{AllowEmpty: true}); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	order, err := h.orders.CancelByCustomer(r.Context(), orderID, customerID, req.Reason)
+	if err != nil {
+		h.writeError(w, r, err)
 		return
 	}
 
@@ -227,19 +377,19 @@ type listOrdersResponse struct {
 func (h *OrdersHandler) List(w http.ResponseWriter, r *http.Request) {
 	customerID, err := customerIDFromHeader(r)
 	if err != nil {
-		h.writeError(w, err)
+		h.writeError(w, r, err)
 		return
 	}
 
 	limit, offset, err := parsePagination(r)
 	if err != nil {
-		h.writeError(w, err)
+		h.writeError(w, r, err)
 		return
 	}
 
 	orders, err := h.repo.ListByCustomer(r.Context(), customerID, limit, offset)
 	if err != nil {
-		h.writeError(w, err)
+		h.writeError(w, r, err)
 		return
 	}
 
@@ -289,7 +439,7 @@ func customerIDFromHeader(r *http.Request) (uuid.UUID, error) {
 	return id, nil
 }
 
-func (h *OrdersHandler) writeJSON(w http.ResponseWriter, status int, body any) {
+func (h responder) writeJSON(w http.ResponseWriter, status int, body any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	if err := json.NewEncoder(w).Encode(body); err != nil {
@@ -297,11 +447,13 @@ func (h *OrdersHandler) writeJSON(w http.ResponseWriter, status int, body any) {
 	}
 }
 
-func (h *OrdersHandler) writeError(w http.ResponseWriter, err error) {
+func (h responder) writeError(w http.ResponseWriter, r *http.Request, err error) {
 	var appErr *apperrors.AppError
 	if !stderrors.As(err, &appErr) {
 		h.logger.Error("unexpected error", zap.Error(err))
 		appErr = apperrors.NewInternalServerError("internal server error")
 	}
-	h.writeJSON(w, appErr.HTTPCode, appErr)
+	if err := apperrors.WriteProblem(w, r, appErr); err != nil {
+		h.logger.Error("failed to encode error response", zap.Error(err))
+	}
 }
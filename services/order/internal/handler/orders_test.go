@@ -7,6 +7,7 @@ import (
 	stderrors "errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/order/internal/client"
@@ -65,6 +66,33 @@ func (f *fakeOrderRepository) ListByCustomer(_ context.Context, _ uuid.UUID, lim
 	return f.listResult, nil
 }
 
+// fakeProductCatalog is an in-memory ProductCatalog test double.
+type fakeProductCatalog struct {
+	products map[uuid.UUID]client.Product
+	err      error
+}
+
+func newFakeProductCatalog(products ...client.Product) *fakeProductCatalog {
+	f := &fakeProductCatalog{products: make(map[uuid.UUID]client.Product)}
+	for _, p := range products {
+		f.products[p.ID] = p
+	}
+	return f
+}
+
+func (f *fakeProductCatalog) Products(_ context.Context, ids []uuid.UUID) (map[uuid.UUID]client.Product, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	found := make(map[uuid.UUID]client.Product)
+	for _, id := range ids {
+		if p, ok := f.products[id]; ok {
+			found[id] = p
+		}
+	}
+	return found, nil
+}
+
 // fakeInventoryReserver is an in-memory InventoryReserver test double.
 type fakeInventoryReserver struct {
 	reserveErr error
@@ -87,6 +115,9 @@ func (f *fakeInventoryReserver) Release(_ context.Context, orderID uuid.UUID) er
 type fakeOrderTransitioner struct {
 	err   error
 	calls []uuid.UUID
+
+	cancelErr    error
+	cancelReason string
 }
 
 func (f *fakeOrderTransitioner) MarkPendingPaymentAfterCreate(_ context.Context, orderID uuid.UUID) error {
@@ -94,27 +125,42 @@ func (f *fakeOrderTransitioner) MarkPendingPaymentAfterCreate(_ context.Context,
 	return f.err
 }
 
-func newTestMux(t *testing.T, repo OrderRepository, inventory InventoryReserver, orders OrderTransitioner) *http.ServeMux {
+func (f *fakeOrderTransitioner) CancelByCustomer(_ context.Context, orderID, customerID uuid.UUID, reason string) (*domain.Order, error) {
+	f.cancelReason = reason
+	if f.cancelErr != nil {
+		return nil, f.cancelErr
+	}
+	return &domain.Order{ID: orderID, CustomerID: customerID, Status: domain.StatusCancelled, Currency: "USD"}, nil
+}
+
+func newTestMux(t *testing.T, repo OrderRepository, catalog ProductCatalog, inventory InventoryReserver, orders OrderTransitioner) *http.ServeMux {
 	t.Helper()
-	h := NewOrdersHandler(repo, inventory, orders, zaptest.NewLogger(t))
+	h := NewOrdersHandler(repo, catalog, inventory, orders, zaptest.NewLogger(t))
 	mux := http.NewServeMux()
 	mux.HandleFunc("POST /api/v1/orders", h.Create)
 	mux.HandleFunc("GET /api/v1/orders/{id}", h.Get)
+	mux.HandleFunc("POST /api/v1/orders/{id}/cancel", h.Cancel)
 	mux.HandleFunc("GET /api/v1/orders", h.List)
 	return mux
 }
 
-func decodeAppError(t *testing.T, body []byte) apperrors.AppError {
+// decodeProblem decodes the RFC 7807 problem in w, failing the test unless it was served as
+// application/problem+json.
+func decodeProblem(t *testing.T, w *httptest.ResponseRecorder) apperrors.Problem {
 	t.Helper()
-	var appErr apperrors.AppError
-	if err := json.Unmarshal(body, &appErr); err != nil {
+	if got := w.Header().Get("Content-Type"); got != apperrors.ProblemContentType {
+		t.Errorf("Content-Type = %q, want %q", got, apperrors.ProblemContentType)
+	}
+	var problem apperrors.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
 		t.Fatalf("failed to decode error body: %v", err)
 	}
-	return appErr
+	return problem
 }
 
 func TestOrdersHandler_Create(t *testing.T) {
-	validBody := `{"items":[{"product_id":"` + uuid.New().String() + `","product_name":"Widget","quantity":2,"unit_price_cents":999}],"currency":"USD"}`
+	widget := testWidget()
+	validBody := `{"items":[{"product_id":"` + widget.ID.String() + `","product_name":"Widget","quantity":2,"unit_price_cents":999}],"currency":"USD"}`
 
 	tests := []struct {
 		name       string
@@ -175,6 +221,16 @@ func TestOrdersHandler_Create(t *testing.T) {
 			wantStatus: http.StatusBadRequest,
 			wantCode:   "VALIDATION_ERROR",
 		},
+		{
+			name:       "more items than the catalog can price at once",
+			userID:     uuid.New().String(),
+			body:       `{"items":[` + strings.Repeat(`{"product_id":"`+widget.ID.String()+`","quantity":1},`, 100) + `{"product_id":"` + widget.ID.String() + `","quantity":1}],"currency":"USD"}`,
+			repo:       newFakeOrderRepository(),
+			inventory:  &fakeInventoryReserver{},
+			orders:     &fakeOrderTransitioner{},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "VALIDATION_ERROR",
+		},
 		{
 			name:       "invalid item product id",
 			userID:     uuid.New().String(),
@@ -185,6 +241,16 @@ func TestOrdersHandler_Create(t *testing.T) {
 			wantStatus: http.StatusBadRequest,
 			wantCode:   "VALIDATION_ERROR",
 		},
+		{
+			name:       "unknown field",
+			userID:     uuid.New().String(),
+			body:       `{"items":[{"product_id":"` + uuid.New().String() + `","product_name":"Widget","quantity":1,"unit_price_cents":100}],"currency":"USD","discount":50}`,
+			repo:       newFakeOrderRepository(),
+			inventory:  &fakeInventoryReserver{},
+			orders:     &fakeOrderTransitioner{},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "VALIDATION_ERROR",
+		},
 		{
 			name:       "insufficient inventory",
 			userID:     uuid.New().String(),
@@ -219,7 +285,7 @@ func TestOrdersHandler_Create(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mux := newTestMux(t, tt.repo, tt.inventory, tt.orders)
+			mux := newTestMux(t, tt.repo, newFakeProductCatalog(widget), tt.inventory, tt.orders)
 
 			req := httptest.NewRequest(http.MethodPost, "/api/v1/orders", bytes.NewBufferString(tt.body))
 			if tt.userID != "" {
@@ -256,9 +322,9 @@ func TestOrdersHandler_Create(t *testing.T) {
 				return
 			}
 
-			appErr := decodeAppError(t, w.Body.Bytes())
-			if appErr.Code != tt.wantCode {
-				t.Errorf("Code = %v, want %v", appErr.Code, tt.wantCode)
+			problem := decodeProblem(t, w)
+			if problem.Code != tt.wantCode {
+				t.Errorf("Code = %v, want %v", problem.Code, tt.wantCode)
 			}
 
 			if tt.name == "insufficient inventory" && len(tt.repo.saved) != 0 {
@@ -271,6 +337,243 @@ func TestOrdersHandler_Create(t *testing.T) {
 	}
 }
 
+func TestOrdersHandler_Create_ReportsEveryInvalidItemField(t *testing.T) {
+	mux := newTestMux(t, newFakeOrderRepository(), newFakeProductCatalog(), &fakeInventoryReserver{}, &fakeOrderTransitioner{})
+	body := `{"items":[
+		{"product_id":"` + uuid.New().String() + `","product_name":"Widget","quantity":1,"unit_price_cents":100},
+		{"product_id":"not-a-uuid","product_name":"Gadget","quantity":1,"unit_price_cents":100},
+		{"product_id":"` + uuid.New().String() + `","product_name":"","quantity":0,"unit_price_cents":-1}
+	],"currency":"USD"}`
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/orders", bytes.NewBufferString(body))
+	req.Header.Set("X-User-ID", uuid.New().String())
+	w := httptest.NewRecorder()
+
+	mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d (body=%s)", w.Code, http.StatusBadRequest, w.Body.String())
+	}
+	problem := decodeProblem(t, w)
+	var fields []string
+	for _, f := range problem.Errors {
+		fields = append(fields, f.Field)
+	}
+	want := []string{"items[1].product_id", "items[2].quantity", "items[2].unit_price_cents"}
+	if strings.Join(fields, ",") != strings.Join(want, ",") {
+		t.Errorf("errors = %v, want %v", fields, want)
+	}
+}
+
+func testWidget() client.Product {
+	return client.Product{ID: uuid.New(), Name: "Widget", SKU: "WID-1", PriceCents: 999, Currency: "USD", IsActive: true}
+}
+
+func TestOrdersHandler_Create_PricesFromCatalog(t *testing.T) {
+	widget := testWidget()
+	retired := client.Product{ID: uuid.New(), Name: "Gizmo", SKU: "GIZ-1", PriceCents: 500, Currency: "USD"}
+	euroWidget := client.Product{ID: uuid.New(), Name: "Euro Widget", SKU: "EWID-1", PriceCents: 899, Currency: "EUR", IsActive: true}
+	item := func(p client.Product, extra string) string {
+		return `{"product_id":"` + p.ID.String() + `","quantity":2` + extra + `}`
+	}
+
+	tests := []struct {
+		name         string
+		body         string
+		catalog      *fakeProductCatalog
+		wantStatus   int
+		wantCode     string
+		wantFields   []string
+		wantCurrency string
+	}{
+		{
+			name:         "name, sku and price come from the catalog",
+			body:         `{"items":[` + item(widget, "") + `]}`,
+			wantStatus:   http.StatusAccepted,
+			wantCurrency: "USD",
+		},
+		{
+			name:         "order currency defaults to the product's",
+			body:         `{"items":[` + item(euroWidget, "") + `]}`,
+			wantStatus:   http.StatusAccepted,
+			wantCurrency: "EUR",
+		},
+		{
+			name:         "matching details are accepted",
+			body:         `{"items":[` + item(widget, `,"product_name":"Widget","product_sku":"WID-1","unit_price_cents":999`) + `],"currency":"USD"}`,
+			wantStatus:   http.StatusAccepted,
+			wantCurrency: "USD",
+		},
+		{
+			name:       "tampered price",
+			body:       `{"items":[` + item(widget, `,"unit_price_cents":1`) + `]}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "VALIDATION_ERROR",
+			wantFields: []string{"items[0].unit_price_cents"},
+		},
+		{
+			name:       "stale name and sku",
+			body:       `{"items":[` + item(widget, `,"product_name":"Old Widget","product_sku":"WID-0"`) + `]}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "VALIDATION_ERROR",
+			wantFields: []string{"items[0].product_name", "items[0].product_sku"},
+		},
+		{
+			name:       "unknown, inactive and wrong-currency products",
+			body:       `{"items":[` + item(widget, "") + `,` + item(testWidget(), "") + `,` + item(retired, "") + `,` + item(euroWidget, "") + `],"currency":"USD"}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "VALIDATION_ERROR",
+			wantFields: []string{"items[1].product_id", "items[2].product_id", "items[3].product_id"},
+		},
+		{
+			name:       "catalog unavailable",
+			body:       `{"items":[` + item(widget, "") + `]}`,
+			catalog:    &fakeProductCatalog{err: &apperrors.AppError{Code: "INVENTORY_SERVICE_UNAVAILABLE", Message: "down", HTTPCode: http.StatusServiceUnavailable}},
+			wantStatus: http.StatusServiceUnavailable,
+			wantCode:   "INVENTORY_SERVICE_UNAVAILABLE",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			catalog := tt.catalog
+			if catalog == nil {
+				catalog = newFakeProductCatalog(widget, retired, euroWidget)
+			}
+			repo := newFakeOrderRepository()
+			inventory := &fakeInventoryReserver{}
+			mux := newTestMux(t, repo, catalog, inventory, &fakeOrderTransitioner{})
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/orders", bytes.NewBufferString(tt.body))
+			req.Header.Set("X-User-ID", uuid.New().String())
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body=%s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+
+			if tt.wantStatus == http.StatusAccepted {
+				if len(repo.saved) != 1 {
+					t.Fatalf("saved = %d, want 1", len(repo.saved))
+				}
+				order := repo.saved[0]
+				want := widget
+				if tt.wantCurrency == "EUR" {
+					want = euroWidget
+				}
+				got := order.Items[0]
+				if got.ProductName != want.Name || got.ProductSKU != want.SKU || got.UnitPriceCents != want.PriceCents {
+					t.Errorf("item = %s/%s/%d, want %s/%s/%d", got.ProductName, got.ProductSKU, got.UnitPriceCents, want.Name, want.SKU, want.PriceCents)
+				}
+				if order.TotalAmountCents != 2*want.PriceCents {
+					t.Errorf("TotalAmountCents = %d, want %d", order.TotalAmountCents, 2*want.PriceCents)
+				}
+				if order.Currency != tt.wantCurrency {
+					t.Errorf("Currency = %v, want %v", order.Currency, tt.wantCurrency)
+				}
+				return
+			}
+
+			problem := decodeProblem(t, w)
+			if problem.Code != tt.wantCode {
+				t.Errorf("Code = %v, want %v", problem.Code, tt.wantCode)
+			}
+			var fields []string
+			for _, f := range problem.Errors {
+				fields = append(fields, f.Field)
+			}
+			if strings.Join(fields, ",") != strings.Join(tt.wantFields, ",") {
+				t.Errorf("errors = %v, want %v", fields, tt.wantFields)
+			}
+			if len(repo.saved) != 0 || len(inventory.reserved) != 0 {
+				t.Errorf("saved = %d, reserved = %d, want nothing for a rejected order", len(repo.saved), len(inventory.reserved))
+			}
+		})
+	}
+}
+
+func TestOrdersHandler_Create_Addresses(t *testing.T) {
+	widget := testWidget()
+	items := `"items":[{"product_id":"` + widget.ID.String() + `","quantity":1}]`
+	shipping := `{"name":"Ada","line1":"1 Main St","city":"Springfield","postal_code":"62704","country":"US"}`
+
+	tests := []struct {
+		name         string
+		body         string
+		wantStatus   int
+		wantFields   []string
+		wantShipping bool
+		wantBilling  bool
+	}{
+		{
+			name:       "no addresses",
+			body:       `{` + items + `}`,
+			wantStatus: http.StatusAccepted,
+		},
+		{
+			name:         "shipping and billing",
+			body:         `{` + items + `,"shipping_address":` + shipping + `,"billing_address":{"name":"Ada","line1":"10 Downing St","city":"London","postal_code":"SW1A 2AA","country":"GB"}}`,
+			wantStatus:   http.StatusAccepted,
+			wantShipping: true,
+			wantBilling:  true,
+		},
+		{
+			name:       "missing required fields",
+			body:       `{` + items + `,"shipping_address":{"line1":"1 Main St","country":"US"}}`,
+			wantStatus: http.StatusBadRequest,
+			wantFields: []string{"shipping_address.name", "shipping_address.city"},
+		},
+		{
+			name:       "postal code in the wrong format for the country",
+			body:       `{` + items + `,"shipping_address":` + shipping + `,"billing_address":{"name":"Ada","line1":"1 Rue","city":"Paris","postal_code":"SW1A 2AA","country":"FR"}}`,
+			wantStatus: http.StatusBadRequest,
+			wantFields: []string{"billing_address.postal_code"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeOrderRepository()
+			mux := newTestMux(t, repo, newFakeProductCatalog(widget), &fakeInventoryReserver{}, &fakeOrderTransitioner{})
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/orders", bytes.NewBufferString(tt.body))
+			req.Header.Set("X-User-ID", uuid.New().String())
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body=%s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+
+			if tt.wantStatus == http.StatusAccepted {
+				order := repo.saved[0]
+				if (order.ShippingAddress != nil) != tt.wantShipping {
+					t.Errorf("ShippingAddress = %+v, want set = %v", order.ShippingAddress, tt.wantShipping)
+				}
+				if (order.BillingAddress != nil) != tt.wantBilling {
+					t.Errorf("BillingAddress = %+v, want set = %v", order.BillingAddress, tt.wantBilling)
+				}
+				if tt.wantShipping && order.ShippingAddress.PostalCode != "62704" {
+					t.Errorf("ShippingAddress.PostalCode = %v, want 62704", order.ShippingAddress.PostalCode)
+				}
+				return
+			}
+
+			problem := decodeProblem(t, w)
+			var fields []string
+			for _, f := range problem.Errors {
+				fields = append(fields, f.Field)
+			}
+			if strings.Join(fields, ",") != strings.Join(tt.wantFields, ",") {
+				t.Errorf("errors = %v, want %v", fields, tt.wantFields)
+			}
+		})
+	}
+}
+
 func TestOrdersHandler_Get(t *testing.T) {
 	owner := uuid.New()
 	other := uuid.New()
@@ -280,6 +583,7 @@ func TestOrdersHandler_Get(t *testing.T) {
 		Status:           domain.StatusPending,
 		TotalAmountCents: 1998,
 		Currency:         "USD",
+		ShippingAddress:  &domain.Address{Name: "Ada", Line1: "1 Main St", City: "Springfield", PostalCode: "62704", Country: "US"},
 	}
 
 	tests := []struct {
@@ -325,7 +629,7 @@ func TestOrdersHandler_Get(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mux := newTestMux(t, tt.repo, &fakeInventoryReserver{}, &fakeOrderTransitioner{})
+			mux := newTestMux(t, tt.repo, newFakeProductCatalog(), &fakeInventoryReserver{}, &fakeOrderTransitioner{})
 
 			req := httptest.NewRequest(http.MethodGet, "/api/v1/orders/"+tt.orderID, nil)
 			req.Header.Set("X-User-ID", tt.userID)
@@ -345,12 +649,123 @@ func TestOrdersHandler_Get(t *testing.T) {
 				if got.ID != order.ID.String() {
 					t.Errorf("ID = %v, want %v", got.ID, order.ID.String())
 				}
+				if got.ShippingAddress == nil || *got.ShippingAddress.toDomain() != *order.ShippingAddress {
+					t.Errorf("ShippingAddress = %+v, want %+v", got.ShippingAddress, order.ShippingAddress)
+				}
+				if got.BillingAddress != nil {
+					t.Errorf("BillingAddress = %+v, want none", got.BillingAddress)
+				}
+				return
+			}
+
+			problem := decodeProblem(t, w)
+			if problem.Code != tt.wantCode {
+				t.Errorf("Code = %v, want %v", problem.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestOrdersHandler_Cancel(t *testing.T) {
+	tests := []struct {
+		name       string
+		userID     string
+		orderID    string
+		body       string
+		orders     *fakeOrderTransitioner
+		wantStatus int
+		wantCode   string
+		wantReason string
+	}{
+		{
+			name:       "with a reason",
+			userID:     uuid.New().String(),
+			orderID:    uuid.New().String(),
+			body:       `{"reason":"changed_mind"}`,
+			orders:     &fakeOrderTransitioner{},
+			wantStatus: http.StatusOK,
+			wantReason: "changed_mind",
+		},
+		{
+			name:       "without a body",
+			userID:     uuid.New().String(),
+			orderID:    uuid.New().String(),
+			orders:     &fakeOrderTransitioner{},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "missing user id",
+			orderID:    uuid.New().String(),
+			orders:     &fakeOrderTransitioner{},
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   "UNAUTHORIZED",
+		},
+		{
+			name:       "invalid order id",
+			userID:     uuid.New().String(),
+			orderID:    "not-a-uuid",
+			orders:     &fakeOrderTransitioner{},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "BAD_REQUEST",
+		},
+		{
+			name:       "unknown field",
+			userID:     uuid.New().String(),
+			orderID:    uuid.New().String(),
+			body:       `{"why":"changed_mind"}`,
+			orders:     &fakeOrderTransitioner{},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "VALIDATION_ERROR",
+		},
+		{
+			name:       "order already shipped",
+			userID:     uuid.New().String(),
+			orderID:    uuid.New().String(),
+			orders:     &fakeOrderTransitioner{cancelErr: apperrors.NewOrderAlreadyProcessed("order")},
+			wantStatus: http.StatusConflict,
+			wantCode:   "ORDER_ALREADY_PROCESSED",
+		},
+		{
+			name:       "order belongs to another customer",
+			userID:     uuid.New().String(),
+			orderID:    uuid.New().String(),
+			orders:     &fakeOrderTransitioner{cancelErr: apperrors.NewForbidden("order does not belong to the current user")},
+			wantStatus: http.StatusForbidden,
+			wantCode:   "FORBIDDEN",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := newTestMux(t, newFakeOrderRepository(), newFakeProductCatalog(), &fakeInventoryReserver{}, tt.orders)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/orders/"+tt.orderID+"/cancel", strings.NewReader(tt.body))
+			if tt.userID != "" {
+				req.Header.Set("X-User-ID", tt.userID)
+			}
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body=%s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				if problem := decodeProblem(t, w); problem.Code != tt.wantCode {
+					t.Errorf("Code = %v, want %v", problem.Code, tt.wantCode)
+				}
 				return
 			}
 
-			appErr := decodeAppError(t, w.Body.Bytes())
-			if appErr.Code != tt.wantCode {
-				t.Errorf("Code = %v, want %v", appErr.Code, tt.wantCode)
+			var got orderResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if got.Status != string(domain.StatusCancelled) {
+				t.Errorf("Status = %v, want %v", got.Status, domain.StatusCancelled)
+			}
+			if tt.orders.cancelReason != tt.wantReason {
+				t.Errorf("reason = %q, want %q", tt.orders.cancelReason, tt.wantReason)
 			}
 		})
 	}
@@ -405,7 +820,7 @@ func TestOrdersHandler_List(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			repo := &fakeOrderRepository{listResult: []*domain.Order{sampleOrder}}
-			mux := newTestMux(t, repo, &fakeInventoryReserver{}, &fakeOrderTransitioner{})
+			mux := newTestMux(t, repo, newFakeProductCatalog(), &fakeInventoryReserver{}, &fakeOrderTransitioner{})
 
 			req := httptest.NewRequest(http.MethodGet, "/api/v1/orders"+tt.query, nil)
 			req.Header.Set("X-User-ID", uuid.New().String())
@@ -438,9 +853,9 @@ func TestOrdersHandler_List(t *testing.T) {
 				return
 			}
 
-			appErr := decodeAppError(t, w.Body.Bytes())
-			if appErr.Code != tt.wantCode {
-				t.Errorf("Code = %v, want %v", appErr.Code, tt.wantCode)
+			problem := decodeProblem(t, w)
+			if problem.Code != tt.wantCode {
+				t.Errorf("Code = %v, want %v", problem.Code, tt.wantCode)
 			}
 		})
 	}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/order/internal/domain"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/database"
 	apperrors "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/errors"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/events"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/outbox"
@@ -18,6 +19,7 @@ import (
 // OrderRepository stores and retrieves orders in postgres.
 type OrderRepository struct {
 	db     *sql.DB
+	reads  database.ReadRouter
 	outbox *outbox.Store
 }
 
@@ -26,51 +28,78 @@ func NewOrderRepository(db *sql.DB) *OrderRepository {
 	return &OrderRepository{db: db, outbox: outbox.NewStore()}
 }
 
+// SetReadRouter lets ListByCustomer read from a replica through router. Save and UpdateStatus note
+// each write for its customer, so the customer's own next list still sees it. Passing nil reads
+// everything from the primary.
+func (r *OrderRepository) SetReadRouter(router database.ReadRouter) {
+	r.reads = router
+}
+
+// reader returns where to read on behalf of key.
+func (r *OrderRepository) reader(ctx context.Context, key string) database.Queryer {
+	if r.reads == nil {
+		return r.db
+	}
+	return r.reads.Reader(ctx, key)
+}
+
+// orderColumns are the orders columns scanOrder reads, in order.
+const orderColumns = "id, customer_id, status, total_amount_cents, currency, shipping_address, billing_address, carrier, tracking_number, created_at, updated_at, version"
+
+func customerReadKey(customerID uuid.UUID) string {
+	return "customer:" + customerID.String()
+}
+
 // Save writes the order and its items in a single transaction.
 func (r *OrderRepository) Save(ctx context.Context, order *domain.Order) error {
-	tx, err := r.db.BeginTx(ctx, nil)
+	shipping, err := addressJSON(order.ShippingAddress)
 	if err != nil {
-		return fmt.Errorf("begin transaction: %w", err)
+		return fmt.Errorf("marshal shipping address: %w", err)
 	}
-	defer func() { _ = tx.Rollback() }()
-
-	_, err = tx.ExecContext(ctx, `
-		INSERT INTO orders (id, customer_id, status, total_amount_cents, currency, created_at, updated_at, version)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-	`, order.ID, order.CustomerID, string(order.Status), order.TotalAmountCents, order.Currency,
-		order.CreatedAt, order.UpdatedAt, order.Version)
+	billing, err := addressJSON(order.BillingAddress)
 	if err != nil {
-		return fmt.Errorf("insert order: %w", err)
+		return fmt.Errorf("marshal billing address: %w", err)
 	}
 
-	for _, item := range order.Items {
-		_, err = tx.ExecContext(ctx, `
-			INSERT INTO order_items (id, order_id, product_id, product_name, product_sku, quantity, unit_price_cents, total_price_cents)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		`, item.ID, order.ID, item.ProductID, item.ProductName, item.ProductSKU,
-			item.Quantity, item.UnitPriceCents, item.TotalPriceCents)
+	return database.WithTx(ctx, r.db, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO orders (id, customer_id, status, total_amount_cents, currency, shipping_address, billing_address, created_at, updated_at, version)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		`, order.ID, order.CustomerID, string(order.Status), order.TotalAmountCents, order.Currency,
+			shipping, billing, order.CreatedAt, order.UpdatedAt, order.Version)
 		if err != nil {
-			return fmt.Errorf("insert order item: %w", err)
+			return fmt.Errorf("insert order: %w", err)
 		}
-	}
 
-	payload, err := json.Marshal(newOrderCreatedPayload(order))
-	if err != nil {
-		return fmt.Errorf("marshal order.created payload: %w", err)
-	}
-	if err := r.outbox.Enqueue(ctx, tx, outbox.Message{
-		Topic:       events.OrdersTopic,
-		EventType:   events.EventTypeOrderCreated,
-		AggregateID: order.ID.String(),
-		Payload:     payload,
-	}); err != nil {
-		return err
-	}
+		for _, item := range order.Items {
+			_, err = tx.ExecContext(ctx, `
+				INSERT INTO order_items (id, order_id, product_id, product_name, product_sku, quantity, unit_price_cents, total_price_cents)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			`, item.ID, order.ID, item.ProductID, item.ProductName, item.ProductSKU,
+				item.Quantity, item.UnitPriceCents, item.TotalPriceCents)
+			if err != nil {
+				return fmt.Errorf("insert order item: %w", err)
+			}
+		}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("commit transaction: %w", err)
-	}
-	return nil
+		payload, err := json.Marshal(newOrderCreatedPayload(order))
+		if err != nil {
+			return fmt.Errorf("marshal order.created payload: %w", err)
+		}
+		if err := r.outbox.Enqueue(ctx, tx, outbox.Message{
+			Topic:       events.OrdersTopic,
+			EventType:   events.EventTypeOrderCreated,
+			AggregateID: order.ID.String(),
+			Payload:     payload,
+		}); err != nil {
+			return err
+		}
+
+		if r.reads != nil {
+			database.AfterCommit(ctx, func() { r.reads.NoteWrite(customerReadKey(order.CustomerID)) })
+		}
+		return nil
+	})
 }
 
 // orderCreatedPayload is the order.created outbox payload. Money fields are integer minor units
@@ -116,10 +145,13 @@ func newOrderCreatedPayload(order *domain.Order) orderCreatedPayload {
 	}
 }
 
-// GetByID assembles the full order aggregate, including its items.
+// GetByID assembles the full order aggregate, including its items. It always reads from the
+// primary: callers load an order to transition it with its version as the optimistic lock, so a
+// lagging replica would turn every such write into a conflict, and the read is keyed by order
+// rather than by the customer whose writes NoteWrite tracks.
 func (r *OrderRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Order, error) {
 	order, err := r.scanOrder(r.db.QueryRowContext(ctx, `
-		SELECT id, customer_id, status, total_amount_cents, currency, created_at, updated_at, version
+		SELECT `+orderColumns+`
 		FROM orders WHERE id = $1
 	`, id))
 	if stderrors.Is(err, sql.ErrNoRows) {
@@ -140,8 +172,8 @@ func (r *OrderRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Or
 
 // ListByCustomer returns a page of order summaries for the given customer, most recent first.
 func (r *OrderRepository) ListByCustomer(ctx context.Context, customerID uuid.UUID, limit, offset int) ([]*domain.Order, error) {
-	rows, err := r.db.QueryContext(ctx, `
-		SELECT id, customer_id, status, total_amount_cents, currency, created_at, updated_at, version
+	rows, err := r.reader(ctx, customerReadKey(customerID)).QueryContext(ctx, `
+		SELECT `+orderColumns+`
 		FROM orders WHERE customer_id = $1
 		ORDER BY created_at DESC
 		LIMIT $2 OFFSET $3
@@ -169,25 +201,60 @@ func (r *OrderRepository) ListByCustomer(ctx context.Context, customerID uuid.UU
 // UpdateStatus transitions an order's status within tx, using version as an optimistic lock. The
 // caller owns the transaction, so the update can be combined with other writes, such as an
 // outbox message for the transition or an idempotency marker for the event that triggered it.
+// Once that transaction commits, the write is noted for the order's customer, so their next list
+// shows the new status.
 func (r *OrderRepository) UpdateStatus(ctx context.Context, tx *sql.Tx, id uuid.UUID, status domain.Status, expectedVersion int) error {
-	result, err := tx.ExecContext(ctx, `
+	var customerID uuid.UUID
+	err := tx.QueryRowContext(ctx, `
 		UPDATE orders SET status = $1, version = version + 1
 		WHERE id = $2 AND version = $3
-	`, string(status), id, expectedVersion)
+		RETURNING customer_id
+	`, string(status), id, expectedVersion).Scan(&customerID)
+	if stderrors.Is(err, sql.ErrNoRows) {
+		return apperrors.NewConflict(fmt.Sprintf("order %s was modified concurrently", id))
+	}
 	if err != nil {
 		return fmt.Errorf("update order status: %w", err)
 	}
 
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("read rows affected: %w", err)
+	if r.reads != nil {
+		database.AfterCommit(ctx, func() { r.reads.NoteWrite(customerReadKey(customerID)) })
 	}
-	if rows == 0 {
-		return apperrors.NewConflict(fmt.Sprintf("order %s was modified concurrently", id))
+	return nil
+}
+
+// UpdateShipment records the carrier and tracking number of a shipped order within tx, alongside
+// the UpdateStatus call that moved it to shipped.
+func (r *OrderRepository) UpdateShipment(ctx context.Context, tx *sql.Tx, id uuid.UUID, carrier, trackingNumber string) error {
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE orders SET carrier = $1, tracking_number = $2 WHERE id = $3
+	`, carrier, trackingNumber, id); err != nil {
+		return fmt.Errorf("update order shipment: %w", err)
 	}
 	return nil
 }
 
+// addressJSON encodes a for a JSONB column. Without an address it returns an untyped nil, which
+// is stored as NULL; a nil []byte would be sent as an empty, invalid JSON document.
+func addressJSON(a *domain.Address) (any, error) {
+	if a == nil {
+		return nil, nil
+	}
+	return json.Marshal(a)
+}
+
+// parseAddress decodes a JSONB address column, returning nil for NULL.
+func parseAddress(raw []byte) (*domain.Address, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	var a domain.Address
+	if err := json.Unmarshal(raw, &a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
 // rowScanner is satisfied by both sql.Row and sql.Rows.
 type rowScanner interface {
 	Scan(dest ...any) error
@@ -196,16 +263,28 @@ type rowScanner interface {
 func (r *OrderRepository) scanOrder(row rowScanner) (*domain.Order, error) {
 	var order domain.Order
 	var status string
+	var shipping, billing []byte
+	var carrier, trackingNumber sql.NullString
 
 	if err := row.Scan(&order.ID, &order.CustomerID, &status, &order.TotalAmountCents, &order.Currency,
-		&order.CreatedAt, &order.UpdatedAt, &order.Version); err != nil {
+		&shipping, &billing, &carrier, &trackingNumber, &order.CreatedAt, &order.UpdatedAt, &order.Version); err != nil {
 		return nil, err
 	}
+	var err error
+	if order.ShippingAddress, err = parseAddress(shipping); err != nil {
+		return nil, fmt.Errorf("decode shipping address: %w", err)
+	}
+	if order.BillingAddress, err = parseAddress(billing); err != nil {
+		return nil, fmt.Errorf("decode billing address: %w", err)
+	}
 	order.Status = domain.Status(status)
+	order.Carrier = carrier.String
+	order.TrackingNumber = trackingNumber.String
 
 	return &order, nil
 }
 
+// itemsByOrderID reads from the primary, alongside the order GetByID just read there.
 func (r *OrderRepository) itemsByOrderID(ctx context.Context, orderID uuid.UUID) ([]domain.OrderItem, error) {
 	rows, err := r.db.QueryContext(ctx, `
 		SELECT id, product_id, product_name, product_sku, quantity, unit_price_cents, total_price_cents
@@ -10,10 +10,30 @@ import (
 
 	sqlmock "github.com/DATA-DOG/go-sqlmock"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/order/internal/domain"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/database"
 	apperrors "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/errors"
 	"github.com/google/uuid"
 )
 
+// fakeReadRouter sends every read to reader and records the keys it is given.
+type fakeReadRouter struct {
+	reader  *sql.DB
+	readKey string
+	written []string
+}
+
+func (r *fakeReadRouter) Reader(_ context.Context, key string) database.Queryer {
+	r.readKey = key
+	return r.reader
+}
+
+func (r *fakeReadRouter) NoteWrite(key string) {
+	r.written = append(r.written, key)
+}
+
+// orderColumnNames are the columns of orderColumns, for building result rows.
+var orderColumnNames = []string{"id", "customer_id", "status", "total_amount_cents", "currency", "shipping_address", "billing_address", "carrier", "tracking_number", "created_at", "updated_at", "version"}
+
 func newTestOrder() *domain.Order {
 	now := time.Now().UTC()
 	orderID := uuid.New()
@@ -54,7 +74,7 @@ func TestOrderRepository_Save(t *testing.T) {
 		mock.ExpectBegin()
 		mock.ExpectExec("INSERT INTO orders").
 			WithArgs(order.ID, order.CustomerID, string(order.Status), order.TotalAmountCents, order.Currency,
-				order.CreatedAt, order.UpdatedAt, order.Version).
+				nil, nil, order.CreatedAt, order.UpdatedAt, order.Version).
 			WillReturnResult(sqlmock.NewResult(0, 1))
 		mock.ExpectExec("INSERT INTO order_items").
 			WithArgs(order.Items[0].ID, order.ID, order.Items[0].ProductID, order.Items[0].ProductName,
@@ -65,13 +85,18 @@ func TestOrderRepository_Save(t *testing.T) {
 			WillReturnResult(sqlmock.NewResult(0, 1))
 		mock.ExpectCommit()
 
+		router := &fakeReadRouter{}
 		repo := NewOrderRepository(db)
+		repo.SetReadRouter(router)
 		if err := repo.Save(context.Background(), order); err != nil {
 			t.Fatalf("Save() error = %v", err)
 		}
 		if err := mock.ExpectationsWereMet(); err != nil {
 			t.Errorf("unmet expectations: %v", err)
 		}
+		if want := "customer:" + order.CustomerID.String(); len(router.written) != 1 || router.written[0] != want {
+			t.Errorf("Save() noted writes %v, want [%s]", router.written, want)
+		}
 	})
 
 	t.Run("returns error when the transaction fails to begin", func(t *testing.T) {
@@ -114,6 +139,35 @@ func TestOrderRepository_Save(t *testing.T) {
 		}
 	})
 
+	t.Run("stores addresses as JSON", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("sqlmock.New: %v", err)
+		}
+		defer func() { _ = db.Close() }()
+
+		order := newTestOrder()
+		order.ShippingAddress = &domain.Address{Name: "Ada", Line1: "1 Main St", City: "Springfield", PostalCode: "62704", Country: "US"}
+
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO orders").
+			WithArgs(order.ID, order.CustomerID, string(order.Status), order.TotalAmountCents, order.Currency,
+				[]byte(`{"name":"Ada","line1":"1 Main St","city":"Springfield","postal_code":"62704","country":"US"}`), nil,
+				order.CreatedAt, order.UpdatedAt, order.Version).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO order_items").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO outbox_messages")).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		repo := NewOrderRepository(db)
+		if err := repo.Save(context.Background(), order); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet expectations: %v", err)
+		}
+	})
+
 	t.Run("rolls back when item insert fails", func(t *testing.T) {
 		db, mock, err := sqlmock.New()
 		if err != nil {
@@ -196,9 +250,9 @@ func TestOrderRepository_GetByID(t *testing.T) {
 
 		want := newTestOrder()
 
-		orderRows := sqlmock.NewRows([]string{"id", "customer_id", "status", "total_amount_cents", "currency", "created_at", "updated_at", "version"}).
+		orderRows := sqlmock.NewRows(orderColumnNames).
 			AddRow(want.ID.String(), want.CustomerID.String(), string(want.Status), want.TotalAmountCents, want.Currency,
-				want.CreatedAt, want.UpdatedAt, want.Version)
+				nil, nil, nil, nil, want.CreatedAt, want.UpdatedAt, want.Version)
 		mock.ExpectQuery("FROM orders WHERE id").WithArgs(want.ID).WillReturnRows(orderRows)
 
 		itemRows := sqlmock.NewRows([]string{"id", "product_id", "product_name", "product_sku", "quantity", "unit_price_cents", "total_price_cents"}).
@@ -222,6 +276,37 @@ func TestOrderRepository_GetByID(t *testing.T) {
 		}
 	})
 
+	t.Run("decodes addresses", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("sqlmock.New: %v", err)
+		}
+		defer func() { _ = db.Close() }()
+
+		want := newTestOrder()
+		billing := domain.Address{Name: "Ada", Line1: "10 Downing St", City: "London", PostalCode: "SW1A 2AA", Country: "GB"}
+
+		orderRows := sqlmock.NewRows(orderColumnNames).
+			AddRow(want.ID.String(), want.CustomerID.String(), string(want.Status), want.TotalAmountCents, want.Currency,
+				nil, []byte(`{"name":"Ada","line1":"10 Downing St","city":"London","postal_code":"SW1A 2AA","country":"GB"}`),
+				nil, nil, want.CreatedAt, want.UpdatedAt, want.Version)
+		mock.ExpectQuery("FROM orders WHERE id").WithArgs(want.ID).WillReturnRows(orderRows)
+		mock.ExpectQuery("FROM order_items WHERE order_id").WithArgs(want.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "product_name", "product_sku", "quantity", "unit_price_cents", "total_price_cents"}))
+
+		repo := NewOrderRepository(db)
+		got, err := repo.GetByID(context.Background(), want.ID)
+		if err != nil {
+			t.Fatalf("GetByID() error = %v", err)
+		}
+		if got.ShippingAddress != nil {
+			t.Errorf("ShippingAddress = %+v, want nil", got.ShippingAddress)
+		}
+		if got.BillingAddress == nil || *got.BillingAddress != billing {
+			t.Errorf("BillingAddress = %+v, want %+v", got.BillingAddress, billing)
+		}
+	})
+
 	t.Run("returns not found when there is no row", func(t *testing.T) {
 		db, mock, err := sqlmock.New()
 		if err != nil {
@@ -267,9 +352,9 @@ func TestOrderRepository_GetByID(t *testing.T) {
 		defer func() { _ = db.Close() }()
 
 		want := newTestOrder()
-		orderRows := sqlmock.NewRows([]string{"id", "customer_id", "status", "total_amount_cents", "currency", "created_at", "updated_at", "version"}).
+		orderRows := sqlmock.NewRows(orderColumnNames).
 			AddRow(want.ID.String(), want.CustomerID.String(), string(want.Status), want.TotalAmountCents, want.Currency,
-				want.CreatedAt, want.UpdatedAt, want.Version)
+				nil, nil, nil, nil, want.CreatedAt, want.UpdatedAt, want.Version)
 		mock.ExpectQuery("FROM orders WHERE id").WithArgs(want.ID).WillReturnRows(orderRows)
 		mock.ExpectQuery("FROM order_items WHERE order_id").WithArgs(want.ID).WillReturnError(errors.New("boom"))
 
@@ -287,9 +372,9 @@ func TestOrderRepository_GetByID(t *testing.T) {
 		defer func() { _ = db.Close() }()
 
 		want := newTestOrder()
-		orderRows := sqlmock.NewRows([]string{"id", "customer_id", "status", "total_amount_cents", "currency", "created_at", "updated_at", "version"}).
+		orderRows := sqlmock.NewRows(orderColumnNames).
 			AddRow(want.ID.String(), want.CustomerID.String(), string(want.Status), want.TotalAmountCents, want.Currency,
-				want.CreatedAt, want.UpdatedAt, want.Version)
+				nil, nil, nil, nil, want.CreatedAt, want.UpdatedAt, want.Version)
 		mock.ExpectQuery("FROM orders WHERE id").WithArgs(want.ID).WillReturnRows(orderRows)
 
 		itemRows := sqlmock.NewRows([]string{"id", "product_id", "product_name", "product_sku", "quantity", "unit_price_cents", "total_price_cents"}).
@@ -311,9 +396,9 @@ func TestOrderRepository_GetByID(t *testing.T) {
 		defer func() { _ = db.Close() }()
 
 		want := newTestOrder()
-		orderRows := sqlmock.NewRows([]string{"id", "customer_id", "status", "total_amount_cents", "currency", "created_at", "updated_at", "version"}).
+		orderRows := sqlmock.NewRows(orderColumnNames).
 			AddRow(want.ID.String(), want.CustomerID.String(), string(want.Status), want.TotalAmountCents, want.Currency,
-				want.CreatedAt, want.UpdatedAt, want.Version)
+				nil, nil, nil, nil, want.CreatedAt, want.UpdatedAt, want.Version)
 		mock.ExpectQuery("FROM orders WHERE id").WithArgs(want.ID).WillReturnRows(orderRows)
 
 		itemRows := sqlmock.NewRows([]string{"id", "product_id", "product_name", "product_sku", "quantity", "unit_price_cents", "total_price_cents"}).
@@ -338,9 +423,9 @@ func TestOrderRepository_ListByCustomer(t *testing.T) {
 		defer func() { _ = db.Close() }()
 
 		want := newTestOrder()
-		rows := sqlmock.NewRows([]string{"id", "customer_id", "status", "total_amount_cents", "currency", "created_at", "updated_at", "version"}).
+		rows := sqlmock.NewRows(orderColumnNames).
 			AddRow(want.ID.String(), want.CustomerID.String(), string(want.Status), want.TotalAmountCents, want.Currency,
-				want.CreatedAt, want.UpdatedAt, want.Version)
+				nil, nil, nil, nil, want.CreatedAt, want.UpdatedAt, want.Version)
 		mock.ExpectQuery("FROM orders WHERE customer_id").WithArgs(want.CustomerID, 20, 0).WillReturnRows(rows)
 
 		repo := NewOrderRepository(db)
@@ -356,6 +441,36 @@ func TestOrderRepository_ListByCustomer(t *testing.T) {
 		}
 	})
 
+	t.Run("reads where the router sends it", func(t *testing.T) {
+		primary, _, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("sqlmock.New: %v", err)
+		}
+		defer func() { _ = primary.Close() }()
+		replica, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("sqlmock.New: %v", err)
+		}
+		defer func() { _ = replica.Close() }()
+
+		customerID := uuid.New()
+		rows := sqlmock.NewRows(orderColumnNames)
+		mock.ExpectQuery("FROM orders WHERE customer_id").WithArgs(customerID, 20, 0).WillReturnRows(rows)
+
+		router := &fakeReadRouter{reader: replica}
+		repo := NewOrderRepository(primary)
+		repo.SetReadRouter(router)
+		if _, err := repo.ListByCustomer(context.Background(), customerID, 20, 0); err != nil {
+			t.Fatalf("ListByCustomer() error = %v", err)
+		}
+		if want := "customer:" + customerID.String(); router.readKey != want {
+			t.Errorf("ListByCustomer() read with key %q, want %q", router.readKey, want)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet expectations: %v", err)
+		}
+	})
+
 	t.Run("returns an empty slice when there are no orders", func(t *testing.T) {
 		db, mock, err := sqlmock.New()
 		if err != nil {
@@ -364,7 +479,7 @@ func TestOrderRepository_ListByCustomer(t *testing.T) {
 		defer func() { _ = db.Close() }()
 
 		customerID := uuid.New()
-		rows := sqlmock.NewRows([]string{"id", "customer_id", "status", "total_amount_cents", "currency", "created_at", "updated_at", "version"})
+		rows := sqlmock.NewRows(orderColumnNames)
 		mock.ExpectQuery("FROM orders WHERE customer_id").WithArgs(customerID, 20, 0).WillReturnRows(rows)
 
 		repo := NewOrderRepository(db)
@@ -401,9 +516,9 @@ func TestOrderRepository_ListByCustomer(t *testing.T) {
 		defer func() { _ = db.Close() }()
 
 		want := newTestOrder()
-		rows := sqlmock.NewRows([]string{"id", "customer_id", "status", "total_amount_cents", "currency", "created_at", "updated_at", "version"}).
+		rows := sqlmock.NewRows(orderColumnNames).
 			AddRow(want.ID.String(), want.CustomerID.String(), string(want.Status), want.TotalAmountCents, want.Currency,
-				want.CreatedAt, want.UpdatedAt, "not-a-number")
+				nil, nil, nil, nil, want.CreatedAt, want.UpdatedAt, "not-a-number")
 		mock.ExpectQuery("FROM orders WHERE customer_id").WithArgs(want.CustomerID, 20, 0).WillReturnRows(rows)
 
 		repo := NewOrderRepository(db)
@@ -420,9 +535,9 @@ func TestOrderRepository_ListByCustomer(t *testing.T) {
 		defer func() { _ = db.Close() }()
 
 		want := newTestOrder()
-		rows := sqlmock.NewRows([]string{"id", "customer_id", "status", "total_amount_cents", "currency", "created_at", "updated_at", "version"}).
+		rows := sqlmock.NewRows(orderColumnNames).
 			AddRow(want.ID.String(), want.CustomerID.String(), string(want.Status), want.TotalAmountCents, want.Currency,
-				want.CreatedAt, want.UpdatedAt, want.Version).
+				nil, nil, nil, nil, want.CreatedAt, want.UpdatedAt, want.Version).
 			RowError(0, errors.New("boom"))
 		mock.ExpectQuery("FROM orders WHERE customer_id").WithArgs(want.CustomerID, 20, 0).WillReturnRows(rows)
 
@@ -443,9 +558,9 @@ func TestOrderRepository_UpdateStatus(t *testing.T) {
 
 		id := uuid.New()
 		mock.ExpectBegin()
-		mock.ExpectExec("UPDATE orders SET status").
+		mock.ExpectQuery("UPDATE orders SET status").
 			WithArgs(string(domain.StatusConfirmed), id, 1).
-			WillReturnResult(sqlmock.NewResult(0, 1))
+			WillReturnRows(sqlmock.NewRows([]string{"customer_id"}).AddRow(uuid.New()))
 		mock.ExpectCommit()
 
 		tx, err := db.Begin()
@@ -465,40 +580,41 @@ func TestOrderRepository_UpdateStatus(t *testing.T) {
 		}
 	})
 
-	t.Run("returns conflict when the version does not match", func(t *testing.T) {
+	t.Run("notes the write for the customer once the transaction commits", func(t *testing.T) {
 		db, mock, err := sqlmock.New()
 		if err != nil {
 			t.Fatalf("sqlmock.New: %v", err)
 		}
 		defer func() { _ = db.Close() }()
 
-		id := uuid.New()
+		id, customerID := uuid.New(), uuid.New()
 		mock.ExpectBegin()
-		mock.ExpectExec("UPDATE orders SET status").
+		mock.ExpectQuery("UPDATE orders SET status").
 			WithArgs(string(domain.StatusConfirmed), id, 1).
-			WillReturnResult(sqlmock.NewResult(0, 0))
-		mock.ExpectRollback()
-
-		tx, err := db.Begin()
-		if err != nil {
-			t.Fatalf("db.Begin() error = %v", err)
-		}
+			WillReturnRows(sqlmock.NewRows([]string{"customer_id"}).AddRow(customerID))
+		mock.ExpectCommit()
 
+		router := &fakeReadRouter{}
 		repo := NewOrderRepository(db)
-		err = repo.UpdateStatus(context.Background(), tx, id, domain.StatusConfirmed, 1)
-		if err == nil {
-			t.Fatal("expected error, got none")
-		}
-		var appErr *apperrors.AppError
-		if !errors.As(err, &appErr) || appErr.Code != "CONFLICT" {
-			t.Errorf("error = %v, want CONFLICT", err)
+		repo.SetReadRouter(router)
+		err = database.WithTx(context.Background(), db, func(ctx context.Context, tx *sql.Tx) error {
+			if err := repo.UpdateStatus(ctx, tx, id, domain.StatusConfirmed, 1); err != nil {
+				return err
+			}
+			if len(router.written) != 0 {
+				t.Errorf("UpdateStatus() noted writes %v before the commit, want none", router.written)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("WithTx() error = %v", err)
 		}
-		if err := tx.Rollback(); err != nil {
-			t.Fatalf("tx.Rollback() error = %v", err)
+		if want := "customer:" + customerID.String(); len(router.written) != 1 || router.written[0] != want {
+			t.Errorf("UpdateStatus() noted writes %v, want [%s]", router.written, want)
 		}
 	})
 
-	t.Run("returns error on exec failure", func(t *testing.T) {
+	t.Run("returns conflict when the version does not match", func(t *testing.T) {
 		db, mock, err := sqlmock.New()
 		if err != nil {
 			t.Fatalf("sqlmock.New: %v", err)
@@ -507,9 +623,9 @@ func TestOrderRepository_UpdateStatus(t *testing.T) {
 
 		id := uuid.New()
 		mock.ExpectBegin()
-		mock.ExpectExec("UPDATE orders SET status").
+		mock.ExpectQuery("UPDATE orders SET status").
 			WithArgs(string(domain.StatusConfirmed), id, 1).
-			WillReturnError(errors.New("boom"))
+			WillReturnRows(sqlmock.NewRows([]string{"customer_id"}))
 		mock.ExpectRollback()
 
 		tx, err := db.Begin()
@@ -517,16 +633,26 @@ func TestOrderRepository_UpdateStatus(t *testing.T) {
 			t.Fatalf("db.Begin() error = %v", err)
 		}
 
+		router := &fakeReadRouter{}
 		repo := NewOrderRepository(db)
-		if err := repo.UpdateStatus(context.Background(), tx, id, domain.StatusConfirmed, 1); err == nil {
+		repo.SetReadRouter(router)
+		err = repo.UpdateStatus(context.Background(), tx, id, domain.StatusConfirmed, 1)
+		if err == nil {
 			t.Fatal("expected error, got none")
 		}
+		var appErr *apperrors.AppError
+		if !errors.As(err, &appErr) || appErr.Code != "CONFLICT" {
+			t.Errorf("error = %v, want CONFLICT", err)
+		}
+		if len(router.written) != 0 {
+			t.Errorf("UpdateStatus() noted writes %v for a conflict, want none", router.written)
+		}
 		if err := tx.Rollback(); err != nil {
 			t.Fatalf("tx.Rollback() error = %v", err)
 		}
 	})
 
-	t.Run("returns error when reading rows affected fails", func(t *testing.T) {
+	t.Run("returns error on update failure", func(t *testing.T) {
 		db, mock, err := sqlmock.New()
 		if err != nil {
 			t.Fatalf("sqlmock.New: %v", err)
@@ -535,9 +661,9 @@ func TestOrderRepository_UpdateStatus(t *testing.T) {
 
 		id := uuid.New()
 		mock.ExpectBegin()
-		mock.ExpectExec("UPDATE orders SET status").
+		mock.ExpectQuery("UPDATE orders SET status").
 			WithArgs(string(domain.StatusConfirmed), id, 1).
-			WillReturnResult(sqlmock.NewErrorResult(errors.New("boom")))
+			WillReturnError(errors.New("boom"))
 		mock.ExpectRollback()
 
 		tx, err := db.Begin()
@@ -554,3 +680,34 @@ func TestOrderRepository_UpdateStatus(t *testing.T) {
 		}
 	})
 }
+
+func TestOrderRepository_UpdateShipment(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	id := uuid.New()
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE orders SET carrier").
+		WithArgs("DHL", "JD0001", id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("db.Begin() error = %v", err)
+	}
+
+	repo := NewOrderRepository(db)
+	if err := repo.UpdateShipment(context.Background(), tx, id, "DHL", "JD0001"); err != nil {
+		t.Fatalf("UpdateShipment() error = %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("tx.Commit() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/order/internal/saga"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/database"
 	apperrors "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/errors"
 	"github.com/google/uuid"
 )
@@ -82,6 +83,22 @@ func (r *SagaRepository) stateForUpdate(ctx context.Context, tx *sql.Tx, orderID
 	return saga.State(state), nil
 }
 
+// State returns the current state of orderID's saga, reading within the transaction ctx carries if
+// there is one.
+func (r *SagaRepository) State(ctx context.Context, orderID uuid.UUID) (saga.State, error) {
+	var state string
+	err := database.Conn(ctx, r.db).QueryRowContext(ctx, `
+		SELECT state FROM order_sagas WHERE order_id = $1
+	`, orderID).Scan(&state)
+	if stderrors.Is(err, sql.ErrNoRows) {
+		return "", apperrors.NewNotFound("order saga")
+	}
+	if err != nil {
+		return "", fmt.Errorf("select saga state: %w", err)
+	}
+	return saga.State(state), nil
+}
+
 // SetReservationID records the inventory reservation correlated with orderID's saga, within tx.
 func (r *SagaRepository) SetReservationID(ctx context.Context, tx *sql.Tx, orderID, reservationID uuid.UUID) error {
 	if _, err := tx.ExecContext(ctx, `
@@ -102,6 +119,49 @@ func (r *SagaRepository) SetPaymentID(ctx context.Context, tx *sql.Tx, orderID,
 	return nil
 }
 
+// PaymentID returns the payment recorded for orderID's saga, or uuid.Nil if none has been, reading
+// within the transaction ctx carries if there is one.
+func (r *SagaRepository) PaymentID(ctx context.Context, orderID uuid.UUID) (uuid.UUID, error) {
+	var paymentID uuid.NullUUID
+	err := database.Conn(ctx, r.db).QueryRowContext(ctx, `
+		SELECT payment_id FROM order_sagas WHERE order_id = $1
+	`, orderID).Scan(&paymentID)
+	if stderrors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, apperrors.NewNotFound("order saga")
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("select saga payment id: %w", err)
+	}
+	return paymentID.UUID, nil
+}
+
+// SetCompensationReason records why compensation of orderID's saga started, within tx, unless an
+// earlier attempt already recorded a reason, so a retried compensation ends the order the same way.
+func (r *SagaRepository) SetCompensationReason(ctx context.Context, tx *sql.Tx, orderID uuid.UUID, reason string) error {
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE order_sagas SET compensation_reason = COALESCE(compensation_reason, $1) WHERE order_id = $2
+	`, reason, orderID); err != nil {
+		return fmt.Errorf("set saga compensation reason: %w", err)
+	}
+	return nil
+}
+
+// CompensationReason returns why compensation of orderID's saga started, or "" if it has not or
+// started before reasons were recorded.
+func (r *SagaRepository) CompensationReason(ctx context.Context, orderID uuid.UUID) (string, error) {
+	var reason sql.NullString
+	err := database.Conn(ctx, r.db).QueryRowContext(ctx, `
+		SELECT compensation_reason FROM order_sagas WHERE order_id = $1
+	`, orderID).Scan(&reason)
+	if stderrors.Is(err, sql.ErrNoRows) {
+		return "", apperrors.NewNotFound("order saga")
+	}
+	if err != nil {
+		return "", fmt.Errorf("select saga compensation reason: %w", err)
+	}
+	return reason.String, nil
+}
+
 // SetLastError records the most recent compensation failure for orderID's saga, so operators can
 // see why a saga is stuck compensating. It uses its own connection rather than tx, since it is
 // meant to survive the rollback of the transaction that hit the failure.
@@ -114,6 +174,107 @@ func (r *SagaRepository) SetLastError(ctx context.Context, orderID uuid.UUID, me
 	return nil
 }
 
+// ClaimTimedOut moves up to limit sagas that have sat in state since before olderThan to
+// compensating, recording why in last_error, and returns their order ids. Each claimed saga's
+// next_compensation_at is set to leaseUntil, which keeps compensation recovery off it while the
+// watchdog compensates it and hands it over if that attempt fails. Candidates are found
+// through idx_order_sagas_state and locked with FOR UPDATE SKIP LOCKED, so watchdogs on several
+// replicas claim disjoint sagas, and a saga that moved on in the meantime, such as one a payment
+// just landed for, no longer matches and is left alone.
+func (r *SagaRepository) ClaimTimedOut(ctx context.Context, state saga.State, olderThan, leaseUntil time.Time, limit int) ([]uuid.UUID, error) {
+	if err := saga.Transition(state, saga.StateCompensating); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		UPDATE order_sagas SET state = $1, last_error = $2, next_compensation_at = $3
+		WHERE order_id IN (
+			SELECT order_id FROM order_sagas
+			WHERE state = $4 AND updated_at < $5
+			ORDER BY updated_at
+			LIMIT $6
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING order_id
+	`, string(saga.StateCompensating), "timed out in "+string(state), leaseUntil, string(state), olderThan, limit)
+	if err != nil {
+		return nil, fmt.Errorf("claim timed out sagas: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	orderIDs := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var orderID uuid.UUID
+		if err := rows.Scan(&orderID); err != nil {
+			return nil, fmt.Errorf("scan timed out saga: %w", err)
+		}
+		orderIDs = append(orderIDs, orderID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate timed out sagas: %w", err)
+	}
+	return orderIDs, nil
+}
+
+// ClaimCompensationRetries claims up to limit sagas stuck in compensating whose next attempt is
+// due at now: once the saga has sat in compensating for policy.BaseDelay for the first retry, then
+// whenever the previous claim or the watchdog scheduled it. last_error is not required, so a saga
+// whose compensation was cut short before any failure was recorded, such as by a crash, is
+// retried too. Each claim counts the attempt and schedules the
+// next one, policy.BaseDelay doubled once per attempt so far and capped at policy.MaxDelay, which
+// also keeps other workers off the saga while this attempt runs. Rows are locked with FOR UPDATE
+// SKIP LOCKED, so workers on several replicas claim disjoint sagas.
+func (r *SagaRepository) ClaimCompensationRetries(ctx context.Context, now time.Time, policy saga.RetryPolicy, limit int) ([]saga.CompensationRetry, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		UPDATE order_sagas
+		SET compensation_attempts = compensation_attempts + 1,
+			next_compensation_at = $1::timestamptz
+				+ LEAST($2::float8 * POWER(2, compensation_attempts + 1), $3::float8) * INTERVAL '1 second'
+		WHERE order_id IN (
+			SELECT order_id FROM order_sagas
+			WHERE state = $4
+				AND COALESCE(next_compensation_at, updated_at + $2::float8 * INTERVAL '1 second') <= $1::timestamptz
+			ORDER BY COALESCE(next_compensation_at, updated_at)
+			LIMIT $5
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING order_id, compensation_attempts
+	`, now, policy.BaseDelay.Seconds(), policy.MaxDelay.Seconds(), string(saga.StateCompensating), limit)
+	if err != nil {
+		return nil, fmt.Errorf("claim saga compensation retries: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	retries := make([]saga.CompensationRetry, 0)
+	for rows.Next() {
+		var retry saga.CompensationRetry
+		if err := rows.Scan(&retry.OrderID, &retry.Attempt); err != nil {
+			return nil, fmt.Errorf("scan saga compensation retry: %w", err)
+		}
+		retries = append(retries, retry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate saga compensation retries: %w", err)
+	}
+	return retries, nil
+}
+
+// FailCompensation moves orderID's saga from compensating to failed, recording lastError, and
+// reports whether it did. A saga that finished compensating in the meantime is left alone.
+func (r *SagaRepository) FailCompensation(ctx context.Context, orderID uuid.UUID, lastError string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE order_sagas SET state = $1, last_error = $2 WHERE order_id = $3 AND state = $4
+	`, string(saga.StateFailed), lastError, orderID, string(saga.StateCompensating))
+	if err != nil {
+		return false, fmt.Errorf("fail saga compensation: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("read rows affected: %w", err)
+	}
+	return affected > 0, nil
+}
+
 // Get returns the current saga row for orderID.
 func (r *SagaRepository) Get(ctx context.Context, orderID uuid.UUID) (*Saga, error) {
 	var (
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	stderrors "errors"
+	"reflect"
 	"regexp"
 	"testing"
 	"time"
@@ -533,3 +534,245 @@ func TestSagaRepository_Get(t *testing.T) {
 		}
 	})
 }
+
+func TestSagaRepository_State(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	orderID := uuid.New()
+	query := regexp.QuoteMeta("SELECT state FROM order_sagas WHERE order_id = $1")
+	mock.ExpectQuery(query).WithArgs(orderID).
+		WillReturnRows(sqlmock.NewRows([]string{"state"}).AddRow(string(saga.StateCompensating)))
+	mock.ExpectQuery(query).WithArgs(orderID).
+		WillReturnError(sql.ErrNoRows)
+
+	repo := NewSagaRepository(db)
+	got, err := repo.State(context.Background(), orderID)
+	if err != nil || got != saga.StateCompensating {
+		t.Errorf("State() = %v, %v, want %v, nil", got, err, saga.StateCompensating)
+	}
+	_, err = repo.State(context.Background(), orderID)
+	var appErr *apperrors.AppError
+	if !stderrors.As(err, &appErr) || appErr.Code != "NOT_FOUND" {
+		t.Errorf("State() without a saga error = %v, want NOT_FOUND", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestSagaRepository_PaymentID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	orderID, paymentID := uuid.New(), uuid.New()
+	query := regexp.QuoteMeta("SELECT payment_id FROM order_sagas WHERE order_id = $1")
+	mock.ExpectQuery(query).WithArgs(orderID).
+		WillReturnRows(sqlmock.NewRows([]string{"payment_id"}).AddRow(paymentID))
+	mock.ExpectQuery(query).WithArgs(orderID).
+		WillReturnRows(sqlmock.NewRows([]string{"payment_id"}).AddRow(nil))
+	mock.ExpectQuery(query).WithArgs(orderID).
+		WillReturnError(sql.ErrNoRows)
+
+	repo := NewSagaRepository(db)
+	got, err := repo.PaymentID(context.Background(), orderID)
+	if err != nil || got != paymentID {
+		t.Errorf("PaymentID() = %v, %v, want %v, nil", got, err, paymentID)
+	}
+	got, err = repo.PaymentID(context.Background(), orderID)
+	if err != nil || got != uuid.Nil {
+		t.Errorf("PaymentID() before a payment = %v, %v, want uuid.Nil, nil", got, err)
+	}
+	_, err = repo.PaymentID(context.Background(), orderID)
+	var appErr *apperrors.AppError
+	if !stderrors.As(err, &appErr) || appErr.Code != "NOT_FOUND" {
+		t.Errorf("PaymentID() without a saga error = %v, want NOT_FOUND", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestSagaRepository_ClaimTimedOut(t *testing.T) {
+	t.Run("moves the claimed sagas to compensating", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("sqlmock.New: %v", err)
+		}
+		defer func() { _ = db.Close() }()
+
+		now := time.Now()
+		olderThan, leaseUntil := now.Add(-15*time.Minute), now.Add(30*time.Second)
+		first, second := uuid.New(), uuid.New()
+		mock.ExpectQuery(regexp.QuoteMeta("SET state = $1, last_error = $2, next_compensation_at = $3")).
+			WithArgs(string(saga.StateCompensating), "timed out in awaiting_payment", leaseUntil, string(saga.StateAwaitingPayment), olderThan, 50).
+			WillReturnRows(sqlmock.NewRows([]string{"order_id"}).AddRow(first).AddRow(second))
+
+		repo := NewSagaRepository(db)
+		got, err := repo.ClaimTimedOut(context.Background(), saga.StateAwaitingPayment, olderThan, leaseUntil, 50)
+		if err != nil {
+			t.Fatalf("ClaimTimedOut() error = %v", err)
+		}
+		if len(got) != 2 || got[0] != first || got[1] != second {
+			t.Errorf("ClaimTimedOut() = %v, want [%v %v]", got, first, second)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet expectations: %v", err)
+		}
+	})
+
+	t.Run("rejects a state that cannot be compensated", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("sqlmock.New: %v", err)
+		}
+		defer func() { _ = db.Close() }()
+
+		repo := NewSagaRepository(db)
+		if _, err := repo.ClaimTimedOut(context.Background(), saga.StateCompensated, time.Now(), time.Now(), 50); err == nil {
+			t.Error("ClaimTimedOut(compensated) error = nil, want an invalid transition")
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet expectations: %v", err)
+		}
+	})
+
+	t.Run("query failure", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("sqlmock.New: %v", err)
+		}
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta("UPDATE order_sagas")).WillReturnError(stderrors.New("boom"))
+
+		repo := NewSagaRepository(db)
+		if _, err := repo.ClaimTimedOut(context.Background(), saga.StateAwaitingPayment, time.Now(), time.Now(), 50); err == nil {
+			t.Error("ClaimTimedOut() error = nil, want the query failure")
+		}
+	})
+}
+
+func TestSagaRepository_CompensationReason(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	orderID := uuid.New()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE order_sagas SET compensation_reason = COALESCE(compensation_reason, $1) WHERE order_id = $2")).
+		WithArgs("payment_failed", orderID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	query := regexp.QuoteMeta("SELECT compensation_reason FROM order_sagas WHERE order_id = $1")
+	mock.ExpectQuery(query).WithArgs(orderID).
+		WillReturnRows(sqlmock.NewRows([]string{"compensation_reason"}).AddRow("payment_failed"))
+	mock.ExpectQuery(query).WithArgs(orderID).
+		WillReturnRows(sqlmock.NewRows([]string{"compensation_reason"}).AddRow(nil))
+
+	repo := NewSagaRepository(db)
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	if err := repo.SetCompensationReason(context.Background(), tx, orderID, "payment_failed"); err != nil {
+		t.Fatalf("SetCompensationReason() error = %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	if got, err := repo.CompensationReason(context.Background(), orderID); err != nil || got != "payment_failed" {
+		t.Errorf("CompensationReason() = %q, %v, want payment_failed, nil", got, err)
+	}
+	if got, err := repo.CompensationReason(context.Background(), orderID); err != nil || got != "" {
+		t.Errorf("CompensationReason() before one was recorded = %q, %v, want \"\", nil", got, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestSagaRepository_ClaimCompensationRetries(t *testing.T) {
+	policy := saga.RetryPolicy{MaxAttempts: 5, BaseDelay: 30 * time.Second, MaxDelay: 30 * time.Minute}
+
+	t.Run("counts the attempt and returns it", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("sqlmock.New: %v", err)
+		}
+		defer func() { _ = db.Close() }()
+
+		now := time.Now()
+		first, second := uuid.New(), uuid.New()
+		// Sagas are selected on how long they have sat in compensating, not on last_error, so one
+		// whose compensation was cut short before a failure was recorded is retried too.
+		mock.ExpectQuery(`SET compensation_attempts = compensation_attempts \+ 1(?s:.*)WHERE state = \$4\s+AND COALESCE\(next_compensation_at, updated_at`).
+			WithArgs(now, 30.0, 1800.0, string(saga.StateCompensating), 20).
+			WillReturnRows(sqlmock.NewRows([]string{"order_id", "compensation_attempts"}).AddRow(first, 1).AddRow(second, 4))
+
+		repo := NewSagaRepository(db)
+		got, err := repo.ClaimCompensationRetries(context.Background(), now, policy, 20)
+		if err != nil {
+			t.Fatalf("ClaimCompensationRetries() error = %v", err)
+		}
+		want := []saga.CompensationRetry{{OrderID: first, Attempt: 1}, {OrderID: second, Attempt: 4}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ClaimCompensationRetries() = %+v, want %+v", got, want)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet expectations: %v", err)
+		}
+	})
+
+	t.Run("query failure", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("sqlmock.New: %v", err)
+		}
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta("FOR UPDATE SKIP LOCKED")).WillReturnError(stderrors.New("boom"))
+
+		repo := NewSagaRepository(db)
+		if _, err := repo.ClaimCompensationRetries(context.Background(), time.Now(), policy, 20); err == nil {
+			t.Fatal("expected error, got none")
+		}
+	})
+}
+
+func TestSagaRepository_FailCompensation(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	orderID := uuid.New()
+	query := regexp.QuoteMeta("UPDATE order_sagas SET state = $1, last_error = $2 WHERE order_id = $3 AND state = $4")
+	mock.ExpectExec(query).
+		WithArgs(string(saga.StateFailed), "refund failed", orderID, string(saga.StateCompensating)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(query).
+		WithArgs(string(saga.StateFailed), "refund failed", orderID, string(saga.StateCompensating)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	repo := NewSagaRepository(db)
+	if failed, err := repo.FailCompensation(context.Background(), orderID, "refund failed"); err != nil || !failed {
+		t.Errorf("FailCompensation() = %v, %v, want true, nil", failed, err)
+	}
+	if failed, err := repo.FailCompensation(context.Background(), orderID, "refund failed"); err != nil || failed {
+		t.Errorf("FailCompensation() of a saga no longer compensating = %v, %v, want false, nil", failed, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
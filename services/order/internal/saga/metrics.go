@@ -12,6 +12,10 @@ type Metrics struct {
 	completed   prometheus.Counter
 	compensated prometheus.Counter
 	transitions *prometheus.CounterVec
+	timedOut    *prometheus.CounterVec
+	retries     *prometheus.CounterVec
+	failed      prometheus.Counter
+	late        *prometheus.CounterVec
 	duration    prometheus.Histogram
 }
 
@@ -30,6 +34,22 @@ func NewMetrics(registerer prometheus.Registerer) *Metrics {
 			Name: "order_saga_transitions_total",
 			Help: "Total number of order saga state transitions.",
 		}, []string{"from", "to"}),
+		timedOut: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "order_saga_timeouts_total",
+			Help: "Total number of order sagas compensated for staying in a state past its deadline.",
+		}, []string{"state"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "order_saga_compensation_retries_total",
+			Help: "Total number of retried order saga compensations, by outcome.",
+		}, []string{"outcome"}),
+		failed: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "order_saga_compensation_failed_total",
+			Help: "Total number of order sagas escalated to failed after their compensation ran out of retries.",
+		}),
+		late: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "order_saga_late_payments_total",
+			Help: "Total number of payments refunded because they landed on an order saga that could no longer be paid, by saga state.",
+		}, []string{"state"}),
 		duration: prometheus.NewHistogram(prometheus.HistogramOpts{
 			Name:    "order_saga_duration_seconds",
 			Help:    "Duration from order creation to the saga reaching a terminal state, in seconds.",
@@ -37,7 +57,7 @@ func NewMetrics(registerer prometheus.Registerer) *Metrics {
 		}),
 	}
 
-	registerer.MustRegister(m.completed, m.compensated, m.transitions, m.duration)
+	registerer.MustRegister(m.completed, m.compensated, m.transitions, m.timedOut, m.retries, m.failed, m.late, m.duration)
 	return m
 }
 
@@ -59,3 +79,30 @@ func (m *Metrics) RecordCompensated(createdAt time.Time) {
 	m.compensated.Inc()
 	m.duration.Observe(time.Since(createdAt).Seconds())
 }
+
+// RecordTimedOut records a saga the watchdog claimed for compensation after it stayed in state
+// past its deadline.
+func (m *Metrics) RecordTimedOut(state State) {
+	m.timedOut.WithLabelValues(string(state)).Inc()
+}
+
+// RecordCompensationRetry records a retried compensation that succeeded or failed.
+func (m *Metrics) RecordCompensationRetry(succeeded bool) {
+	outcome := "failed"
+	if succeeded {
+		outcome = "succeeded"
+	}
+	m.retries.WithLabelValues(outcome).Inc()
+}
+
+// RecordCompensationFailed records a saga given up on as failed after its last compensation
+// attempt. Any increase needs an operator, so it is alerted on.
+func (m *Metrics) RecordCompensationFailed() {
+	m.failed.Inc()
+}
+
+// RecordLatePayment records a payment that landed on a saga in state, compensating or failed,
+// and is refunded rather than confirming the order.
+func (m *Metrics) RecordLatePayment(state State) {
+	m.late.WithLabelValues(string(state)).Inc()
+}
@@ -69,3 +69,54 @@ func TestNewMetrics_DuplicateRegistrationPanics(t *testing.T) {
 	}()
 	NewMetrics(registry)
 }
+
+func TestMetrics_RecordTimedOut(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	m := NewMetrics(registry)
+
+	m.RecordTimedOut(StateAwaitingPayment)
+	m.RecordTimedOut(StateAwaitingPayment)
+	m.RecordTimedOut(StateStockReserved)
+
+	if got := testutil.ToFloat64(m.timedOut.WithLabelValues(string(StateAwaitingPayment))); got != 2 {
+		t.Errorf("awaiting_payment timeouts = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(m.timedOut.WithLabelValues(string(StateStockReserved))); got != 1 {
+		t.Errorf("stock_reserved timeouts = %v, want 1", got)
+	}
+}
+
+func TestMetrics_RecordCompensationRetry(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	m := NewMetrics(registry)
+
+	m.RecordCompensationRetry(true)
+	m.RecordCompensationRetry(false)
+	m.RecordCompensationRetry(false)
+	m.RecordCompensationFailed()
+
+	if got := testutil.ToFloat64(m.retries.WithLabelValues("succeeded")); got != 1 {
+		t.Errorf("succeeded retries = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.retries.WithLabelValues("failed")); got != 2 {
+		t.Errorf("failed retries = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(m.failed); got != 1 {
+		t.Errorf("compensation failed total = %v, want 1", got)
+	}
+}
+
+func TestMetrics_RecordLatePayment(t *testing.T) {
+	m := NewMetrics(prometheus.NewRegistry())
+
+	m.RecordLatePayment(StateCompensating)
+	m.RecordLatePayment(StateFailed)
+	m.RecordLatePayment(StateFailed)
+
+	if got := testutil.ToFloat64(m.late.WithLabelValues(string(StateCompensating))); got != 1 {
+		t.Errorf("compensating late payments = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.late.WithLabelValues(string(StateFailed))); got != 2 {
+		t.Errorf("failed late payments = %v, want 2", got)
+	}
+}
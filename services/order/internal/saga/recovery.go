@@ -0,0 +1,126 @@
+package saga
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// CompensationRetry is a saga claimed for another compensation attempt. Attempt counts this one,
+// starting at 1.
+type CompensationRetry struct {
+	OrderID uuid.UUID
+	Attempt int
+}
+
+// RetryPolicy bounds how often and how many times a failed compensation is retried. The delay
+// before attempt n is BaseDelay doubled n-1 times, capped at MaxDelay.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// CompensationStore is the port the recovery worker uses to claim and escalate sagas stuck in
+// compensating.
+type CompensationStore interface {
+	// ClaimCompensationRetries claims up to limit sagas whose compensation failed and whose next
+	// attempt is due at now, counting the attempt and scheduling the one after it by policy, so a
+	// saga is only ever claimed by one of several workers running at once.
+	ClaimCompensationRetries(ctx context.Context, now time.Time, policy RetryPolicy, limit int) ([]CompensationRetry, error)
+	// FailCompensation moves a saga still compensating to failed, recording lastError, and
+	// reports whether it did.
+	FailCompensation(ctx context.Context, orderID uuid.UUID, lastError string) (bool, error)
+}
+
+// CompensationRetrier is the port the recovery worker uses to run a saga's remaining compensation
+// steps again.
+type CompensationRetrier interface {
+	RetryCompensation(ctx context.Context, orderID uuid.UUID) error
+}
+
+// Recovery periodically retries the compensation of sagas left in compensating after a release or
+// refund failed, and escalates a saga to failed once it runs out of attempts.
+type Recovery struct {
+	store     CompensationStore
+	retrier   CompensationRetrier
+	policy    RetryPolicy
+	batchSize int
+	logger    *zap.Logger
+	metrics   *Metrics
+}
+
+// NewRecovery builds a Recovery that retries up to batchSize sagas on each sweep under policy.
+func NewRecovery(store CompensationStore, retrier CompensationRetrier, policy RetryPolicy, batchSize int, logger *zap.Logger) *Recovery {
+	return &Recovery{store: store, retrier: retrier, policy: policy, batchSize: batchSize, logger: logger}
+}
+
+// SetMetrics attaches m so retries and escalations are recorded. Passing nil disables metrics.
+func (r *Recovery) SetMetrics(m *Metrics) {
+	r.metrics = m
+}
+
+// Run sweeps for compensations due a retry every interval until ctx is cancelled.
+func (r *Recovery) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.Sweep(ctx)
+		}
+	}
+}
+
+// Sweep claims one batch of sagas due a retry and runs each one's compensation again. A saga whose
+// attempt fails waits for the next one its claim scheduled, unless that was its last, in which
+// case it is moved to failed.
+func (r *Recovery) Sweep(ctx context.Context) {
+	retries, err := r.store.ClaimCompensationRetries(ctx, time.Now(), r.policy, r.batchSize)
+	if err != nil {
+		r.logger.Error("Failed to claim saga compensations to retry", zap.Error(err))
+		return
+	}
+
+	for _, retry := range retries {
+		err := r.retrier.RetryCompensation(ctx, retry.OrderID)
+		if r.metrics != nil {
+			r.metrics.RecordCompensationRetry(err == nil)
+		}
+		if err == nil {
+			r.logger.Info("Recovered saga compensation",
+				zap.String("order_id", retry.OrderID.String()), zap.Int("attempt", retry.Attempt))
+			continue
+		}
+		if retry.Attempt < r.policy.MaxAttempts {
+			r.logger.Warn("Saga compensation retry failed",
+				zap.String("order_id", retry.OrderID.String()), zap.Int("attempt", retry.Attempt), zap.Error(err))
+			continue
+		}
+		r.escalate(ctx, retry, err)
+	}
+}
+
+// escalate moves a saga that used up its attempts to failed, so it stops being retried and an
+// operator is alerted to finish it by hand.
+func (r *Recovery) escalate(ctx context.Context, retry CompensationRetry, cause error) {
+	failed, err := r.store.FailCompensation(ctx, retry.OrderID, cause.Error())
+	if err != nil {
+		r.logger.Error("Failed to escalate saga compensation",
+			zap.String("order_id", retry.OrderID.String()), zap.Error(err))
+		return
+	}
+	if !failed {
+		return
+	}
+	if r.metrics != nil {
+		r.metrics.RecordCompensationFailed()
+	}
+	r.logger.Error("Saga compensation failed after the last attempt, needs manual intervention",
+		zap.String("order_id", retry.OrderID.String()), zap.Int("attempts", retry.Attempt), zap.Error(cause))
+}
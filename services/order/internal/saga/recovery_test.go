@@ -0,0 +1,137 @@
+package saga
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap/zaptest"
+)
+
+// fakeCompensationStore is an in-memory CompensationStore test double.
+type fakeCompensationStore struct {
+	retries  []CompensationRetry
+	claimErr error
+	policies []RetryPolicy
+	limits   []int
+
+	failErr     error
+	notFailable bool
+	failed      []uuid.UUID
+}
+
+func (f *fakeCompensationStore) ClaimCompensationRetries(_ context.Context, _ time.Time, policy RetryPolicy, limit int) ([]CompensationRetry, error) {
+	f.policies = append(f.policies, policy)
+	f.limits = append(f.limits, limit)
+	if f.claimErr != nil {
+		return nil, f.claimErr
+	}
+	return f.retries, nil
+}
+
+func (f *fakeCompensationStore) FailCompensation(_ context.Context, orderID uuid.UUID, _ string) (bool, error) {
+	f.failed = append(f.failed, orderID)
+	if f.failErr != nil {
+		return false, f.failErr
+	}
+	return !f.notFailable, nil
+}
+
+// fakeCompensationRetrier is an in-memory CompensationRetrier test double.
+type fakeCompensationRetrier struct {
+	failFor map[uuid.UUID]error
+	retried []uuid.UUID
+}
+
+func (f *fakeCompensationRetrier) RetryCompensation(_ context.Context, orderID uuid.UUID) error {
+	f.retried = append(f.retried, orderID)
+	return f.failFor[orderID]
+}
+
+var testRetryPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: 30 * time.Second, MaxDelay: 30 * time.Minute}
+
+func TestRecovery_Sweep(t *testing.T) {
+	t.Run("retries every claimed saga and escalates the one out of attempts", func(t *testing.T) {
+		recovered, retryLater, exhausted := uuid.New(), uuid.New(), uuid.New()
+		store := &fakeCompensationStore{retries: []CompensationRetry{
+			{OrderID: recovered, Attempt: 1},
+			{OrderID: retryLater, Attempt: 2},
+			{OrderID: exhausted, Attempt: 3},
+		}}
+		cause := errors.New("payment service unavailable")
+		retrier := &fakeCompensationRetrier{failFor: map[uuid.UUID]error{retryLater: cause, exhausted: cause}}
+		m := NewMetrics(prometheus.NewRegistry())
+		r := NewRecovery(store, retrier, testRetryPolicy, 25, zaptest.NewLogger(t))
+		r.SetMetrics(m)
+
+		r.Sweep(context.Background())
+
+		if !reflect.DeepEqual(store.policies, []RetryPolicy{testRetryPolicy}) || !reflect.DeepEqual(store.limits, []int{25}) {
+			t.Errorf("claims = %+v limits %v, want one with the policy and limit 25", store.policies, store.limits)
+		}
+		if want := []uuid.UUID{recovered, retryLater, exhausted}; !reflect.DeepEqual(retrier.retried, want) {
+			t.Errorf("retried = %v, want %v", retrier.retried, want)
+		}
+		if want := []uuid.UUID{exhausted}; !reflect.DeepEqual(store.failed, want) {
+			t.Errorf("failed = %v, want %v", store.failed, want)
+		}
+		if got := testutil.ToFloat64(m.retries.WithLabelValues("succeeded")); got != 1 {
+			t.Errorf("succeeded retries = %v, want 1", got)
+		}
+		if got := testutil.ToFloat64(m.retries.WithLabelValues("failed")); got != 2 {
+			t.Errorf("failed retries = %v, want 2", got)
+		}
+		if got := testutil.ToFloat64(m.failed); got != 1 {
+			t.Errorf("compensation failed total = %v, want 1", got)
+		}
+	})
+
+	t.Run("does not alert on a saga that finished compensating meanwhile", func(t *testing.T) {
+		orderID := uuid.New()
+		store := &fakeCompensationStore{retries: []CompensationRetry{{OrderID: orderID, Attempt: 3}}, notFailable: true}
+		retrier := &fakeCompensationRetrier{failFor: map[uuid.UUID]error{orderID: errors.New("conflict")}}
+		m := NewMetrics(prometheus.NewRegistry())
+		r := NewRecovery(store, retrier, testRetryPolicy, 25, zaptest.NewLogger(t))
+		r.SetMetrics(m)
+
+		r.Sweep(context.Background())
+
+		if got := testutil.ToFloat64(m.failed); got != 0 {
+			t.Errorf("compensation failed total = %v, want 0", got)
+		}
+	})
+
+	t.Run("a failed claim retries nothing", func(t *testing.T) {
+		retrier := &fakeCompensationRetrier{}
+		store := &fakeCompensationStore{claimErr: errors.New("connection refused")}
+		r := NewRecovery(store, retrier, testRetryPolicy, 25, zaptest.NewLogger(t))
+
+		r.Sweep(context.Background())
+
+		if len(retrier.retried) != 0 {
+			t.Errorf("retried = %v, want none", retrier.retried)
+		}
+	})
+}
+
+func TestRecovery_RunStopsWhenContextIsCancelled(t *testing.T) {
+	r := NewRecovery(&fakeCompensationStore{}, &fakeCompensationRetrier{}, testRetryPolicy, 25, zaptest.NewLogger(t))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.Run(ctx, time.Millisecond)
+		close(done)
+	}()
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run() did not return after ctx was cancelled")
+	}
+}
@@ -25,7 +25,8 @@ var graph = map[State][]State{
 	StateStockReserved:   {StateAwaitingPayment, StateCompensating},
 	StateAwaitingPayment: {StatePaid, StateCompensating},
 	StatePaid:            {StateCompleted, StateCompensating},
-	StateCompensating:    {StateCompensated},
+	StateCompleted:       {StateCompensating},
+	StateCompensating:    {StateCompensated, StateFailed},
 }
 
 // CanTransition reports whether moving from `from` to `to` is part of the saga's state graph. A
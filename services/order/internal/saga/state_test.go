@@ -13,7 +13,8 @@ func TestTransition(t *testing.T) {
 		StateStockReserved:   {StateAwaitingPayment: true, StateCompensating: true},
 		StateAwaitingPayment: {StatePaid: true, StateCompensating: true},
 		StatePaid:            {StateCompleted: true, StateCompensating: true},
-		StateCompensating:    {StateCompensated: true},
+		StateCompleted:       {StateCompensating: true},
+		StateCompensating:    {StateCompensated: true, StateFailed: true},
 	}
 
 	for _, from := range allStates {
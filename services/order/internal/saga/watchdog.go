@@ -0,0 +1,96 @@
+package saga
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// TimeoutClaimer is the port the watchdog uses to find sagas that have stayed in state since
+// before olderThan. It moves up to limit of them to compensating and returns their order IDs, so a
+// saga is only ever claimed by one of several watchdogs running at once, and keeps compensation
+// recovery off them until leaseUntil.
+type TimeoutClaimer interface {
+	ClaimTimedOut(ctx context.Context, state State, olderThan, leaseUntil time.Time, limit int) ([]uuid.UUID, error)
+}
+
+// TimeoutCompensator is the port the watchdog uses to undo the steps of a claimed saga and cancel
+// its order.
+type TimeoutCompensator interface {
+	CompensateTimedOut(ctx context.Context, orderID uuid.UUID) error
+}
+
+// Watchdog periodically compensates sagas that stayed in a state past its deadline, such as an
+// order whose payment result never arrived and which would otherwise hold its reserved stock
+// forever.
+type Watchdog struct {
+	sagas       TimeoutClaimer
+	compensator TimeoutCompensator
+	deadlines   map[State]time.Duration
+	batchSize   int
+	lease       time.Duration
+	logger      *zap.Logger
+	metrics     *Metrics
+}
+
+// NewWatchdog builds a Watchdog that claims up to batchSize sagas per state on each sweep, a saga
+// being timed out once it has stayed in a state longer than that state's entry in deadlines. A
+// state with no entry, or a zero one, is never timed out. Compensation recovery leaves a claimed
+// saga alone for lease, which should outlast one compensation attempt.
+func NewWatchdog(sagas TimeoutClaimer, compensator TimeoutCompensator, deadlines map[State]time.Duration, batchSize int, lease time.Duration, logger *zap.Logger) *Watchdog {
+	return &Watchdog{sagas: sagas, compensator: compensator, deadlines: deadlines, batchSize: batchSize, lease: lease, logger: logger}
+}
+
+// SetMetrics attaches m so each timed-out saga is recorded. Passing nil disables metrics.
+func (w *Watchdog) SetMetrics(m *Metrics) {
+	w.metrics = m
+}
+
+// Run sweeps for timed-out sagas every interval until ctx is cancelled.
+func (w *Watchdog) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.Sweep(ctx)
+		}
+	}
+}
+
+// Sweep claims and compensates one batch of timed-out sagas for each state with a deadline. A
+// saga whose compensation fails is logged and left in compensating with its last_error set, for
+// the compensation recovery to retry once the lease runs out; the watchdog does not claim it again.
+func (w *Watchdog) Sweep(ctx context.Context) {
+	now := time.Now()
+	for state, deadline := range w.deadlines {
+		if deadline <= 0 {
+			continue
+		}
+
+		orderIDs, err := w.sagas.ClaimTimedOut(ctx, state, now.Add(-deadline), now.Add(w.lease), w.batchSize)
+		if err != nil {
+			w.logger.Error("Failed to claim timed-out sagas", zap.String("state", string(state)), zap.Error(err))
+			continue
+		}
+
+		for _, orderID := range orderIDs {
+			if w.metrics != nil {
+				w.metrics.RecordTimedOut(state)
+			}
+			w.logger.Info("Compensating timed-out saga",
+				zap.String("order_id", orderID.String()),
+				zap.String("state", string(state)),
+				zap.Duration("deadline", deadline))
+			if err := w.compensator.CompensateTimedOut(ctx, orderID); err != nil {
+				w.logger.Warn("Failed to compensate timed-out saga, leaving it compensating",
+					zap.String("order_id", orderID.String()), zap.Error(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package saga
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap/zaptest"
+)
+
+type claimCall struct {
+	state      State
+	olderThan  time.Time
+	leaseUntil time.Time
+	limit      int
+}
+
+// fakeTimeoutClaimer is an in-memory TimeoutClaimer test double.
+type fakeTimeoutClaimer struct {
+	claimed  map[State][]uuid.UUID
+	claimErr error
+	calls    []claimCall
+}
+
+func (f *fakeTimeoutClaimer) ClaimTimedOut(_ context.Context, state State, olderThan, leaseUntil time.Time, limit int) ([]uuid.UUID, error) {
+	f.calls = append(f.calls, claimCall{state, olderThan, leaseUntil, limit})
+	if f.claimErr != nil {
+		return nil, f.claimErr
+	}
+	return f.claimed[state], nil
+}
+
+// fakeTimeoutCompensator is an in-memory TimeoutCompensator test double.
+type fakeTimeoutCompensator struct {
+	failFor     map[uuid.UUID]error
+	compensated []uuid.UUID
+}
+
+func (f *fakeTimeoutCompensator) CompensateTimedOut(_ context.Context, orderID uuid.UUID) error {
+	f.compensated = append(f.compensated, orderID)
+	return f.failFor[orderID]
+}
+
+func TestWatchdog_Sweep(t *testing.T) {
+	t.Run("compensates every claimed saga and records its timeout", func(t *testing.T) {
+		first, second := uuid.New(), uuid.New()
+		claimer := &fakeTimeoutClaimer{claimed: map[State][]uuid.UUID{StateAwaitingPayment: {first, second}}}
+		compensator := &fakeTimeoutCompensator{failFor: map[uuid.UUID]error{first: errors.New("inventory unavailable")}}
+		m := NewMetrics(prometheus.NewRegistry())
+		w := NewWatchdog(claimer, compensator, map[State]time.Duration{StateAwaitingPayment: 15 * time.Minute}, 50, 30*time.Second, zaptest.NewLogger(t))
+		w.SetMetrics(m)
+
+		before := time.Now()
+		w.Sweep(context.Background())
+
+		if len(claimer.calls) != 1 {
+			t.Fatalf("claim calls = %+v, want one", claimer.calls)
+		}
+		call := claimer.calls[0]
+		if call.state != StateAwaitingPayment || call.limit != 50 {
+			t.Errorf("claim call = %+v, want awaiting_payment with limit 50", call)
+		}
+		if cutoff := before.Add(-15 * time.Minute); call.olderThan.Before(cutoff) || call.olderThan.After(time.Now().Add(-15*time.Minute)) {
+			t.Errorf("olderThan = %v, want 15m before the sweep", call.olderThan)
+		}
+		if got := call.leaseUntil.Sub(call.olderThan); got != 15*time.Minute+30*time.Second {
+			t.Errorf("leaseUntil = %v, want 30s after the sweep", call.leaseUntil)
+		}
+		// A failed compensation does not stop the rest of the batch.
+		if want := []uuid.UUID{first, second}; !reflect.DeepEqual(compensator.compensated, want) {
+			t.Errorf("compensated = %v, want %v", compensator.compensated, want)
+		}
+		if got := testutil.ToFloat64(m.timedOut.WithLabelValues(string(StateAwaitingPayment))); got != 2 {
+			t.Errorf("awaiting_payment timeouts = %v, want 2", got)
+		}
+	})
+
+	t.Run("skips states without a deadline", func(t *testing.T) {
+		claimer := &fakeTimeoutClaimer{}
+		deadlines := map[State]time.Duration{StateStockReserved: 0, StateAwaitingPayment: time.Minute}
+		w := NewWatchdog(claimer, &fakeTimeoutCompensator{}, deadlines, 10, time.Minute, zaptest.NewLogger(t))
+
+		w.Sweep(context.Background())
+
+		if len(claimer.calls) != 1 || claimer.calls[0].state != StateAwaitingPayment {
+			t.Errorf("claim calls = %+v, want only awaiting_payment", claimer.calls)
+		}
+	})
+
+	t.Run("a failed claim compensates nothing", func(t *testing.T) {
+		compensator := &fakeTimeoutCompensator{}
+		claimer := &fakeTimeoutClaimer{claimErr: errors.New("connection refused")}
+		w := NewWatchdog(claimer, compensator, map[State]time.Duration{StateAwaitingPayment: time.Minute}, 10, time.Minute, zaptest.NewLogger(t))
+
+		w.Sweep(context.Background())
+
+		if len(compensator.compensated) != 0 {
+			t.Errorf("compensated = %v, want none", compensator.compensated)
+		}
+	})
+}
+
+func TestWatchdog_RunStopsWhenContextIsCancelled(t *testing.T) {
+	w := NewWatchdog(&fakeTimeoutClaimer{}, &fakeTimeoutCompensator{}, nil, 10, time.Minute, zaptest.NewLogger(t))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		w.Run(ctx, time.Millisecond)
+		close(done)
+	}()
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run() did not return after ctx was cancelled")
+	}
+}
@@ -9,14 +9,14 @@ import (
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/order/internal/client"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/order/internal/config"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/order/internal/handler"
-	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/order/internal/repository"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/order/internal/service"
-	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/cache"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/database"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/events"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/featureflags"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/httpserver"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/metrics"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/middleware"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/tlsconfig"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
@@ -34,11 +34,20 @@ type Options struct {
 	Logger  *zap.Logger
 	Metrics prometheus.Registerer
 	DB      *database.DB
-	// Redis is optional: a nil client means the service runs without an order cache.
+	// Redis is optional: when set, readiness reports it as a non-critical dependency.
 	Redis *database.RedisClient
-	// CacheMetrics is optional: a nil value means order cache reads are not recorded as hits or
-	// misses.
-	CacheMetrics *cache.Metrics
+	// Orders is optional: the order routes are registered only when it is set. It is the
+	// OrderService the caller also hands to the consumers and the saga loops, so every path shares
+	// one set of saga metrics, cache and client circuit breakers.
+	Orders *service.OrderService
+	// Inventory is the client the create route prices and reserves stock through. It is required
+	// when Orders is set.
+	Inventory *client.InventoryClient
+	// Startup is optional: when set, /health/startup reports it, typically the lifecycle
+	// manager's StartupCheck.
+	Startup httpserver.Check
+	// TLS is optional: when set, the server serves HTTPS with it (see httpserver.Options.TLS).
+	TLS *tlsconfig.Reloader
 }
 
 // New builds the order HTTP server: health checks, metrics and the shared middleware chain.
@@ -48,36 +57,38 @@ func New(opts Options) *Server {
 		registerer = prometheus.DefaultRegisterer
 	}
 
-	checks := make(map[string]httpserver.Check)
+	dependencies := make(map[string]httpserver.Dependency)
 	if opts.DB != nil {
-		checks["database"] = opts.DB.PingContext
+		dependencies["database"] = httpserver.Dependency{Check: opts.DB.PingContext}
 	}
 	if opts.Redis != nil {
-		checks["redis"] = func(ctx context.Context) error { return opts.Redis.Ping(ctx).Err() }
+		// The order cache is an optimisation; without Redis, reads go straight to Postgres.
+		dependencies["redis"] = httpserver.Dependency{
+			Check: opts.Redis.Healthy,
+			Tier:  httpserver.NonCritical,
+		}
 	}
 	if len(opts.Config.Kafka.Brokers) > 0 {
 		brokers := opts.Config.Kafka.Brokers
-		checks["kafka"] = func(ctx context.Context) error { return events.Healthy(ctx, brokers) }
+		dependencies["kafka"] = httpserver.Dependency{
+			Check: func(ctx context.Context) error { return events.Healthy(ctx, brokers) },
+		}
 	}
 
 	mux := http.NewServeMux()
-	httpserver.NewHealthHandlers(opts.Config.Service.Name, checks).Register(mux)
+	health := httpserver.NewTieredHealthHandlers(opts.Config.Service.Name, dependencies)
+	health.SetStartupCheck(opts.Startup)
+	health.Register(mux)
 
-	if opts.DB != nil {
-		inventoryClient := client.NewInventoryClient(opts.Config.InventoryServiceURL, opts.Config.InventoryClient.Timeout)
-		paymentClient := client.NewPaymentClient(opts.Config.PaymentServiceURL, opts.Config.PaymentClient.Timeout)
-		orderService := service.NewOrderService(
-			repository.NewOrderRepository(opts.DB.DB), opts.DB.DB, repository.NewSagaRepository(opts.DB.DB), inventoryClient, paymentClient)
-		if opts.Redis != nil {
-			orderCache := cache.New(opts.Redis, 0)
-			if opts.CacheMetrics != nil {
-				orderCache.SetMetrics(opts.CacheMetrics, "order")
-			}
-			orderService.SetCache(orderCache)
-		}
-		ordersHandler := handler.NewOrdersHandler(orderService, inventoryClient, orderService, opts.Logger)
+	if opts.Orders != nil {
+		ordersHandler := handler.NewOrdersHandler(opts.Orders, opts.Inventory, opts.Inventory, opts.Orders, opts.Logger)
 		mux.HandleFunc("POST /api/v1/orders", ordersHandler.Create)
 		mux.HandleFunc("GET /api/v1/orders/{id}", ordersHandler.Get)
+		mux.HandleFunc("POST /api/v1/orders/{id}/cancel", ordersHandler.Cancel)
+		fulfilmentHandler := handler.NewFulfilmentHandler(opts.Orders, opts.Logger)
+		mux.HandleFunc("POST /api/v1/orders/{id}/processing", fulfilmentHandler.StartProcessing)
+		mux.HandleFunc("POST /api/v1/orders/{id}/ship", fulfilmentHandler.Ship)
+		mux.HandleFunc("POST /api/v1/orders/{id}/deliver", fulfilmentHandler.Deliver)
 		mux.HandleFunc("GET /api/v1/orders", ordersHandler.List)
 	}
 
@@ -86,6 +97,7 @@ func New(opts Options) *Server {
 		middleware.Recovery(opts.Logger),
 		middleware.RequestID,
 		middleware.Logging(opts.Logger),
+		featureflags.Middleware,
 	)
 	wrappedHandler := chain(httpMetrics.Middleware(mux))
 	// otelhttp.NewHandler opens a server span for every request; the span name uses the route
@@ -105,6 +117,7 @@ func New(opts Options) *Server {
 		Addr:    opts.Config.Server.Host + ":" + opts.Config.Server.Port,
 		Handler: outer,
 		Logger:  opts.Logger,
+		TLS:     opts.TLS,
 	})
 
 	return &Server{runtime: runtime}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"net"
 	"net/http"
@@ -10,8 +11,10 @@ import (
 	"time"
 
 	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/order/internal/client"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/order/internal/config"
-	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/cache"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/order/internal/repository"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/order/internal/service"
 	sharedConfig "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/config"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/database"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/httpserver"
@@ -33,6 +36,15 @@ func newTestServer(t *testing.T) *Server {
 	})
 }
 
+// newTestOrderService builds an OrderService on db whose clients point at addresses nothing
+// listens on, for tests that only check routing.
+func newTestOrderService(db *sql.DB) (*service.OrderService, *client.InventoryClient) {
+	inventory := client.NewInventoryClient("http://127.0.0.1:0", time.Second)
+	payments := client.NewPaymentClient("http://127.0.0.1:0", time.Second)
+	orders := service.NewOrderService(repository.NewOrderRepository(db), db, repository.NewSagaRepository(db), inventory, payments)
+	return orders, inventory
+}
+
 func TestNew(t *testing.T) {
 	srv := newTestServer(t)
 
@@ -167,7 +179,7 @@ func TestServer_StartAndStop(t *testing.T) {
 	}
 }
 
-func TestNew_WithDatabase_RegistersOrderRoutes(t *testing.T) {
+func TestNew_WithOrders_RegistersOrderRoutes(t *testing.T) {
 	db, _, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("sqlmock.New() error = %v", err)
@@ -178,11 +190,14 @@ func TestNew_WithDatabase_RegistersOrderRoutes(t *testing.T) {
 		Server:  sharedConfig.ServerConfig{Host: "127.0.0.1", Port: "0"},
 		Service: sharedConfig.ServiceConfig{Name: "order", Version: "1.0.0"},
 	}
+	orders, inventory := newTestOrderService(db)
 	srv := New(Options{
-		Config:  cfg,
-		Logger:  zaptest.NewLogger(t),
-		Metrics: prometheus.NewRegistry(),
-		DB:      &database.DB{DB: db},
+		Config:    cfg,
+		Logger:    zaptest.NewLogger(t),
+		Metrics:   prometheus.NewRegistry(),
+		DB:        &database.DB{DB: db},
+		Orders:    orders,
+		Inventory: inventory,
 	})
 
 	// customerIDFromHeader rejects the request before it ever reaches the repository, so a bare
@@ -205,7 +220,7 @@ func TestNew_WithDatabase_RegistersOrderRoutes(t *testing.T) {
 
 const uuidLikeSegment = "11111111-1111-1111-1111-111111111111"
 
-func TestNew_WithDatabaseAndRedis_RegistersCacheAndHealthCheck(t *testing.T) {
+func TestNew_WithRedis_RegistersHealthCheck(t *testing.T) {
 	db, _, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("sqlmock.New() error = %v", err)
@@ -219,14 +234,15 @@ func TestNew_WithDatabaseAndRedis_RegistersCacheAndHealthCheck(t *testing.T) {
 		Server:  sharedConfig.ServerConfig{Host: "127.0.0.1", Port: "0"},
 		Service: sharedConfig.ServiceConfig{Name: "order", Version: "1.0.0"},
 	}
-	registry := prometheus.NewRegistry()
+	orders, inventory := newTestOrderService(db)
 	srv := New(Options{
-		Config:       cfg,
-		Logger:       zaptest.NewLogger(t),
-		Metrics:      registry,
-		DB:           &database.DB{DB: db},
-		Redis:        &database.RedisClient{Client: redisClient},
-		CacheMetrics: cache.NewMetrics(registry),
+		Config:    cfg,
+		Logger:    zaptest.NewLogger(t),
+		Metrics:   prometheus.NewRegistry(),
+		DB:        &database.DB{DB: db},
+		Redis:     &database.RedisClient{UniversalClient: redisClient},
+		Orders:    orders,
+		Inventory: inventory,
 	})
 
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/orders/"+uuidLikeSegment, nil)
@@ -237,12 +253,20 @@ func TestNew_WithDatabaseAndRedis_RegistersCacheAndHealthCheck(t *testing.T) {
 		t.Errorf("GET /api/v1/orders/{id} = %d, want %d (missing X-User-ID)", w.Code, http.StatusUnauthorized)
 	}
 
-	// The redis client points at an address nothing listens on, so readiness must report it down.
+	// The redis client points at an address nothing listens on, so readiness must report it down,
+	// but only as degraded: the cache is not critical.
 	readyReq := httptest.NewRequest(http.MethodGet, "/health/ready", nil)
 	readyW := httptest.NewRecorder()
 	srv.Handler().ServeHTTP(readyW, readyReq)
-	if readyW.Code != http.StatusServiceUnavailable {
-		t.Errorf("/health/ready with an unreachable redis = %d, want %d (body=%s)", readyW.Code, http.StatusServiceUnavailable, readyW.Body.String())
+	if readyW.Code != http.StatusOK {
+		t.Errorf("/health/ready with an unreachable redis = %d, want %d (body=%s)", readyW.Code, http.StatusOK, readyW.Body.String())
+	}
+	var status httpserver.HealthStatus
+	if err := json.Unmarshal(readyW.Body.Bytes(), &status); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if status.Status != httpserver.StatusDegraded || status.Checks["redis"].Status != httpserver.StatusUnhealthy {
+		t.Errorf("/health/ready with an unreachable redis = %+v, want degraded with redis unhealthy", status)
 	}
 }
 
@@ -262,6 +286,18 @@ func TestNew_DefaultMetricsRegisterer(t *testing.T) {
 	}
 }
 
+func TestNew_AdminEndpointsNotOnPublicPort(t *testing.T) {
+	srv := newTestServer(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/flags", nil)
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
 func TestRoutePath(t *testing.T) {
 	tests := []struct {
 		path string
@@ -10,13 +10,25 @@ import (
 
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/order/internal/domain"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/order/internal/saga"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/cache"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/database"
+	apperrors "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/errors"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/events"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/outbox"
 	"github.com/google/uuid"
 )
 
-// orderCacheTTL is how long a cached order read stays valid.
-const orderCacheTTL = 60 * time.Second
+// Cancellation reasons recorded on order.cancelled and passed along with refunds.
+const (
+	customerCancellationReason = "customer_request"
+	paymentFailedReason        = "payment_failed"
+	cancelledOrderRefundReason = "order_cancelled"
+	sagaTimeoutReason          = "saga_timeout"
+)
+
+// orderCacheOptions keeps a cached order fresh for a minute. Orders change status often, so an
+// expired order is never served stale.
+var orderCacheOptions = cache.LoadOptions{TTL: 60 * time.Second}
 
 // Repository is the persistence port the order service depends on.
 type Repository interface {
@@ -24,15 +36,15 @@ type Repository interface {
 	GetByID(ctx context.Context, id uuid.UUID) (*domain.Order, error)
 	ListByCustomer(ctx context.Context, customerID uuid.UUID, limit, offset int) ([]*domain.Order, error)
 	UpdateStatus(ctx context.Context, tx *sql.Tx, id uuid.UUID, status domain.Status, expectedVersion int) error
+	UpdateShipment(ctx context.Context, tx *sql.Tx, id uuid.UUID, carrier, trackingNumber string) error
 }
 
-// OrderCache is the cache-aside port GetByID reads and fills for read-only order lookups. A nil
+// OrderCache is the read-through port GetByID reads and fills for read-only order lookups. A nil
 // OrderCache disables caching, which is how the service degrades when Redis is unavailable at
 // startup. Lifecycle transitions read the repository directly instead, so they never act on a
 // stale cached version.
 type OrderCache interface {
-	GetJSON(ctx context.Context, key string, dest any) (bool, error)
-	SetJSON(ctx context.Context, key string, value any, ttl time.Duration) error
+	GetOrLoad(ctx context.Context, key string, dest any, opts cache.LoadOptions, load cache.LoadFunc) (bool, error)
 }
 
 // SagaRepository is the persistence port for the order saga state that accompanies each order
@@ -40,6 +52,11 @@ type OrderCache interface {
 type SagaRepository interface {
 	Start(ctx context.Context, tx *sql.Tx, orderID uuid.UUID) error
 	Transition(ctx context.Context, tx *sql.Tx, orderID uuid.UUID, to saga.State) error
+	State(ctx context.Context, orderID uuid.UUID) (saga.State, error)
+	SetPaymentID(ctx context.Context, tx *sql.Tx, orderID, paymentID uuid.UUID) error
+	PaymentID(ctx context.Context, orderID uuid.UUID) (uuid.UUID, error)
+	SetCompensationReason(ctx context.Context, tx *sql.Tx, orderID uuid.UUID, reason string) error
+	CompensationReason(ctx context.Context, orderID uuid.UUID) (string, error)
 	SetLastError(ctx context.Context, orderID uuid.UUID, message string) error
 }
 
@@ -58,14 +75,15 @@ type PaymentRefunder interface {
 // OrderService applies order lifecycle transitions and records the resulting domain event in the
 // outbox, within a transaction supplied by the caller so it can be combined with other writes.
 type OrderService struct {
-	repo        Repository
-	db          *sql.DB
-	saga        SagaRepository
-	inventory   InventoryReleaser
-	payments    PaymentRefunder
-	outbox      *outbox.Store
-	cache       OrderCache
-	sagaMetrics *saga.Metrics
+	repo         Repository
+	db           *sql.DB
+	saga         SagaRepository
+	inventory    InventoryReleaser
+	payments     PaymentRefunder
+	outbox       *outbox.Store
+	cache        OrderCache
+	cacheEnabled func(ctx context.Context) bool
+	sagaMetrics  *saga.Metrics
 }
 
 // NewOrderService builds an OrderService backed by repo, sagaRepo, inventory and payments. db is
@@ -81,6 +99,13 @@ func (s *OrderService) SetCache(cache OrderCache) {
 	s.cache = cache
 }
 
+// SetCacheEnabled makes GetByID ask enabled, on every read, whether to go through the cache, so
+// caching can be rolled out per user or switched off without a restart. Passing nil sends every
+// read through the cache.
+func (s *OrderService) SetCacheEnabled(enabled func(ctx context.Context) bool) {
+	s.cacheEnabled = enabled
+}
+
 // SetSagaMetrics attaches m so a saga reaching completed or compensated is recorded. Passing nil
 // disables metrics.
 func (s *OrderService) SetSagaMetrics(m *saga.Metrics) {
@@ -99,27 +124,20 @@ func (s *OrderService) ListByCustomer(ctx context.Context, customerID uuid.UUID,
 }
 
 // GetByID returns the order with the given id for a read-only lookup. When a cache is
-// configured, it is read first and filled on a miss; a cache error falls back to the repository
-// rather than failing the read.
+// configured and enabled for ctx, the read goes through it and concurrent misses share one
+// repository read; a cache error falls back to the repository rather than failing the read.
 func (s *OrderService) GetByID(ctx context.Context, id uuid.UUID) (*domain.Order, error) {
-	if s.cache == nil {
+	if s.cache == nil || (s.cacheEnabled != nil && !s.cacheEnabled(ctx)) {
 		return s.repo.GetByID(ctx, id)
 	}
 
-	key := orderCacheKey(id)
-
-	var cached domain.Order
-	if hit, err := s.cache.GetJSON(ctx, key, &cached); err == nil && hit {
-		return &cached, nil
-	}
-
-	order, err := s.repo.GetByID(ctx, id)
-	if err != nil {
+	var order domain.Order
+	if _, err := s.cache.GetOrLoad(ctx, orderCacheKey(id), &order, orderCacheOptions, func(ctx context.Context) (any, error) {
+		return s.repo.GetByID(ctx, id)
+	}); err != nil {
 		return nil, err
 	}
-
-	_ = s.cache.SetJSON(ctx, key, order, orderCacheTTL)
-	return order, nil
+	return &order, nil
 }
 
 func orderCacheKey(id uuid.UUID) string {
@@ -130,16 +148,9 @@ func orderCacheKey(id uuid.UUID) string {
 // order.ready_for_payment in its own transaction. It is used by the create-order handler, which
 // persists the order separately through OrderRepository.Save and has no transaction to join.
 func (s *OrderService) MarkPendingPaymentAfterCreate(ctx context.Context, orderID uuid.UUID) error {
-	tx, err := s.db.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("begin transaction: %w", err)
-	}
-	defer func() { _ = tx.Rollback() }()
-
-	if err := s.MarkPendingPayment(ctx, tx, orderID); err != nil {
-		return err
-	}
-	return tx.Commit()
+	return database.WithTx(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		return s.MarkPendingPayment(ctx, tx, orderID)
+	})
 }
 
 // MarkPendingPayment transitions order to pending_payment and enqueues order.ready_for_payment. Its
@@ -164,20 +175,43 @@ func (s *OrderService) MarkPendingPayment(ctx context.Context, tx *sql.Tx, order
 }
 
 // ConfirmPayment transitions order to confirmed and enqueues order.confirmed, advancing its saga
-// from awaiting_payment through paid to completed. An order that already reached a terminal status
-// is left untouched, since a redelivered or late payment event is not an error.
-func (s *OrderService) ConfirmPayment(ctx context.Context, tx *sql.Tx, orderID uuid.UUID) error {
+// from awaiting_payment through paid to completed and recording paymentID on it, so a later
+// cancellation knows what to refund. An order that already reached a terminal status is left
+// untouched, since a redelivered or late payment event is not an error, except that a payment
+// landing after the customer cancelled is refunded rather than kept. So is one landing while the
+// saga is compensating, before the order is cancelled, or after recovery gave up on it as failed,
+// since the saga can no longer be paid either way.
+func (s *OrderService) ConfirmPayment(ctx context.Context, tx *sql.Tx, orderID, paymentID uuid.UUID) error {
 	order, err := s.repo.GetByID(ctx, orderID)
 	if err != nil {
 		return err
 	}
+	if order.Status == domain.StatusCancelled && paymentID != uuid.Nil {
+		if err := s.payments.Refund(ctx, paymentID, cancelledOrderRefundReason); err != nil {
+			return fmt.Errorf("refund payment %s for cancelled order %s: %w", paymentID, orderID, err)
+		}
+		return nil
+	}
 	if isTerminal(order.Status) {
 		return nil
 	}
 
+	state, err := s.saga.State(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	if state == saga.StateCompensating || state == saga.StateFailed {
+		return s.refundLatePayment(ctx, orderID, state, paymentID)
+	}
+
 	if err := s.saga.Transition(ctx, tx, orderID, saga.StatePaid); err != nil {
 		return err
 	}
+	if paymentID != uuid.Nil {
+		if err := s.saga.SetPaymentID(ctx, tx, orderID, paymentID); err != nil {
+			return err
+		}
+	}
 	if err := s.applyTransition(ctx, tx, order, (*domain.Order).Confirm, events.EventTypeOrderConfirmed); err != nil {
 		return err
 	}
@@ -188,6 +222,31 @@ func (s *OrderService) ConfirmPayment(ctx context.Context, tx *sql.Tx, orderID u
 	return nil
 }
 
+// refundLatePayment refunds paymentID, which landed on orderID while its saga was in state and
+// could no longer be paid. The payment is first recorded on the saga in its own transaction, so it
+// survives a failed refund rolling back the caller's transaction: a retried compensation refunds it
+// too, and an operator finishing a failed saga by hand sees it. Refunds are idempotent, so
+// refunding it twice is harmless.
+func (s *OrderService) refundLatePayment(ctx context.Context, orderID uuid.UUID, state saga.State, paymentID uuid.UUID) error {
+	if paymentID == uuid.Nil {
+		return nil
+	}
+	if s.sagaMetrics != nil {
+		s.sagaMetrics.RecordLatePayment(state)
+	}
+	if err := database.WithTx(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		return s.saga.SetPaymentID(ctx, tx, orderID, paymentID)
+	}, database.Separate()); err != nil {
+		return err
+	}
+	if err := s.payments.Refund(ctx, paymentID, cancelledOrderRefundReason); err != nil {
+		err = fmt.Errorf("refund payment %s for order %s: %w", paymentID, orderID, err)
+		_ = s.saga.SetLastError(ctx, orderID, err.Error())
+		return err
+	}
+	return nil
+}
+
 // FailPayment compensates an order whose payment failed: it transitions the saga to compensating,
 // releases the stock reservation, then transitions order to payment_failed and enqueues
 // order.cancelled and the saga to compensated. The event catalog has no dedicated payment-failed
@@ -208,7 +267,7 @@ func (s *OrderService) FailPayment(ctx context.Context, tx *sql.Tx, orderID uuid
 		return nil
 	}
 
-	if err := s.markCompensating(ctx, orderID); err != nil {
+	if err := s.markCompensating(ctx, orderID, paymentFailedReason, uuid.Nil); err != nil {
 		return err
 	}
 
@@ -217,7 +276,7 @@ func (s *OrderService) FailPayment(ctx context.Context, tx *sql.Tx, orderID uuid
 		return fmt.Errorf("release reservation for order %s: %w", orderID, err)
 	}
 
-	if err := s.applyTransition(ctx, tx, order, (*domain.Order).Fail, events.EventTypeOrderCancelled); err != nil {
+	if err := s.applyTransitionWithReason(ctx, tx, order, (*domain.Order).Fail, events.EventTypeOrderCancelled, paymentFailedReason); err != nil {
 		return err
 	}
 	if err := s.saga.Transition(ctx, tx, orderID, saga.StateCompensated); err != nil {
@@ -247,7 +306,7 @@ func (s *OrderService) FailAfterPayment(ctx context.Context, tx *sql.Tx, orderID
 		return nil
 	}
 
-	if err := s.markCompensating(ctx, orderID); err != nil {
+	if err := s.markCompensating(ctx, orderID, reason, paymentID); err != nil {
 		return err
 	}
 
@@ -260,7 +319,7 @@ func (s *OrderService) FailAfterPayment(ctx context.Context, tx *sql.Tx, orderID
 		return fmt.Errorf("release reservation for order %s: %w", orderID, err)
 	}
 
-	if err := s.applyTransition(ctx, tx, order, (*domain.Order).Cancel, events.EventTypeOrderCancelled); err != nil {
+	if err := s.applyTransitionWithReason(ctx, tx, order, (*domain.Order).Cancel, events.EventTypeOrderCancelled, reason); err != nil {
 		return err
 	}
 	if err := s.saga.Transition(ctx, tx, orderID, saga.StateCompensated); err != nil {
@@ -270,20 +329,271 @@ func (s *OrderService) FailAfterPayment(ctx context.Context, tx *sql.Tx, orderID
 	return nil
 }
 
-// markCompensating durably records that compensation for orderID has begun, in its own
-// transaction, so the saga survives a later compensating step failing and the caller's own
-// transaction rolling back.
-func (s *OrderService) markCompensating(ctx context.Context, orderID uuid.UUID) error {
-	tx, err := s.db.BeginTx(ctx, nil)
+// CancelByCustomer cancels orderID on behalf of customerID, who must own it, recording reason on
+// order.cancelled. An order awaiting payment has its stock reservation released; a confirmed one
+// has its payment refunded first and then its reservation released, undoing the saga's steps in
+// reverse. Any other status answers ORDER_ALREADY_PROCESSED, and a confirmed order whose saga
+// recorded no payment, such as one confirmed before payments were recorded, answers CONFLICT.
+//
+// As with FailAfterPayment, the saga is durably moved to compensating before either call, so a
+// failed refund or release leaves it there with last_error set and the customer can simply retry:
+// both calls are idempotent. The order, its event and the saga's move to compensated then commit
+// together. It returns the cancelled order.
+func (s *OrderService) CancelByCustomer(ctx context.Context, orderID, customerID uuid.UUID, reason string) (*domain.Order, error) {
+	order, err := s.repo.GetByID(ctx, orderID)
 	if err != nil {
-		return fmt.Errorf("begin transaction: %w", err)
+		return nil, err
+	}
+	if order.CustomerID != customerID {
+		return nil, apperrors.NewForbidden("order does not belong to the current user")
+	}
+	if order.Status != domain.StatusPendingPayment && order.Status != domain.StatusConfirmed {
+		return nil, apperrors.NewOrderAlreadyProcessed(orderID.String())
+	}
+	if reason == "" {
+		reason = customerCancellationReason
+	}
+
+	// Look the payment up before the saga moves, so a confirmed order with nothing to refund is
+	// turned away without leaving its saga in compensating.
+	var paymentID uuid.UUID
+	if order.Status == domain.StatusConfirmed {
+		if paymentID, err = s.saga.PaymentID(ctx, orderID); err != nil {
+			return nil, err
+		}
+		if paymentID == uuid.Nil {
+			return nil, apperrors.NewConflict(fmt.Sprintf("order %s is confirmed but has no recorded payment to refund", orderID))
+		}
 	}
-	defer func() { _ = tx.Rollback() }()
 
-	if err := s.saga.Transition(ctx, tx, orderID, saga.StateCompensating); err != nil {
+	if err := s.markCompensating(ctx, orderID, reason, uuid.Nil); err != nil {
+		return nil, err
+	}
+
+	if paymentID != uuid.Nil {
+		if err := s.payments.Refund(ctx, paymentID, reason); err != nil {
+			err = fmt.Errorf("refund payment %s for order %s: %w", paymentID, orderID, err)
+			_ = s.saga.SetLastError(ctx, orderID, err.Error())
+			return nil, err
+		}
+	}
+	if err := s.inventory.Release(ctx, orderID); err != nil {
+		_ = s.saga.SetLastError(ctx, orderID, err.Error())
+		return nil, fmt.Errorf("release reservation for order %s: %w", orderID, err)
+	}
+
+	if err := database.WithTx(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		// Each attempt starts again from the order as loaded, since a failed one already applied
+		// the transition to its copy.
+		attempt := *order
+		if err := s.applyTransitionWithReason(ctx, tx, &attempt, (*domain.Order).Cancel, events.EventTypeOrderCancelled, reason); err != nil {
+			return err
+		}
+		return s.saga.Transition(ctx, tx, orderID, saga.StateCompensated)
+	}); err != nil {
+		return nil, err
+	}
+	s.recordSagaCompensated(order.CreatedAt)
+
+	order.Status = domain.StatusCancelled
+	order.Version++
+	order.UpdatedAt = time.Now().UTC()
+	return order, nil
+}
+
+// CompensateTimedOut cancels orderID after the saga watchdog found its saga stuck past a deadline
+// and already moved it to compensating. Only stock has been committed before payment, so it
+// records saga_timeout as the compensation reason, releases the reservation and then cancels the
+// order with that reason, moving the saga to compensated alongside order.cancelled. A release that
+// fails is recorded as the saga's last_error and returned, leaving the saga in compensating for
+// RetryCompensation. A payment that lands meanwhile is refunded by ConfirmPayment.
+//
+// An order that was already cancelled or failed by the time the watchdog got to it only has its
+// saga finished. Any other status means the order moved on concurrently and cannot be compensated.
+func (s *OrderService) CompensateTimedOut(ctx context.Context, orderID uuid.UUID) error {
+	order, err := s.repo.GetByID(ctx, orderID)
+	if err != nil {
 		return err
 	}
-	return tx.Commit()
+
+	switch order.Status {
+	case domain.StatusPending, domain.StatusPendingPayment:
+	case domain.StatusCancelled, domain.StatusPaymentFailed:
+		return s.finishCompensation(ctx, order)
+	default:
+		err := apperrors.NewOrderAlreadyProcessed(orderID.String())
+		_ = s.saga.SetLastError(ctx, orderID, err.Error())
+		return err
+	}
+
+	if err := s.markCompensating(ctx, orderID, sagaTimeoutReason, uuid.Nil); err != nil {
+		return err
+	}
+	if err := s.inventory.Release(ctx, orderID); err != nil {
+		_ = s.saga.SetLastError(ctx, orderID, err.Error())
+		return fmt.Errorf("release reservation for order %s: %w", orderID, err)
+	}
+
+	if err := database.WithTx(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		attempt := *order
+		if err := s.applyTransitionWithReason(ctx, tx, &attempt, (*domain.Order).Cancel, events.EventTypeOrderCancelled, sagaTimeoutReason); err != nil {
+			return err
+		}
+		return s.saga.Transition(ctx, tx, orderID, saga.StateCompensated)
+	}); err != nil {
+		return err
+	}
+	s.recordSagaCompensated(order.CreatedAt)
+	return nil
+}
+
+// RetryCompensation runs the compensation of orderID's saga again after an earlier attempt left it
+// in compensating, for the compensation recovery worker. It refunds the payment recorded on the
+// saga, if any, releases the stock reservation and ends the order the way the first attempt would
+// have, from the reason it recorded, moving the saga to compensated alongside order.cancelled. The
+// refund and release are both idempotent, so repeating one that already landed is harmless. A step
+// that fails again is recorded as the saga's last_error and returned.
+//
+// An order already cancelled or failed only has its saga finished, since the steps before its
+// cancellation must have succeeded. An order past confirmation cannot be compensated and answers
+// ORDER_ALREADY_PROCESSED, for the worker to eventually escalate.
+func (s *OrderService) RetryCompensation(ctx context.Context, orderID uuid.UUID) error {
+	order, err := s.repo.GetByID(ctx, orderID)
+	if err != nil {
+		return err
+	}
+
+	switch order.Status {
+	case domain.StatusPending, domain.StatusPendingPayment, domain.StatusConfirmed:
+	case domain.StatusCancelled, domain.StatusPaymentFailed:
+		return s.finishCompensation(ctx, order)
+	default:
+		err := apperrors.NewOrderAlreadyProcessed(orderID.String())
+		_ = s.saga.SetLastError(ctx, orderID, err.Error())
+		return err
+	}
+
+	reason, err := s.saga.CompensationReason(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	if reason == "" {
+		reason = cancelledOrderRefundReason
+	}
+	end := (*domain.Order).Cancel
+	if reason == paymentFailedReason && order.Status == domain.StatusPendingPayment {
+		end = (*domain.Order).Fail
+	}
+
+	paymentID, err := s.saga.PaymentID(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	switch {
+	case paymentID != uuid.Nil:
+		if err := s.payments.Refund(ctx, paymentID, reason); err != nil {
+			_ = s.saga.SetLastError(ctx, orderID, err.Error())
+			return fmt.Errorf("refund payment %s for order %s: %w", paymentID, orderID, err)
+		}
+	case order.Status == domain.StatusConfirmed:
+		err := fmt.Errorf("order %s is confirmed but its saga recorded no payment to refund", orderID)
+		_ = s.saga.SetLastError(ctx, orderID, err.Error())
+		return err
+	}
+	if err := s.inventory.Release(ctx, orderID); err != nil {
+		_ = s.saga.SetLastError(ctx, orderID, err.Error())
+		return fmt.Errorf("release reservation for order %s: %w", orderID, err)
+	}
+
+	if err := database.WithTx(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		attempt := *order
+		if err := s.applyTransitionWithReason(ctx, tx, &attempt, end, events.EventTypeOrderCancelled, reason); err != nil {
+			return err
+		}
+		return s.saga.Transition(ctx, tx, orderID, saga.StateCompensated)
+	}); err != nil {
+		return err
+	}
+	s.recordSagaCompensated(order.CreatedAt)
+	return nil
+}
+
+// finishCompensation moves the saga of an order that is already cancelled or failed to
+// compensated, in its own transaction.
+func (s *OrderService) finishCompensation(ctx context.Context, order *domain.Order) error {
+	if err := database.WithTx(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		return s.saga.Transition(ctx, tx, order.ID, saga.StateCompensated)
+	}); err != nil {
+		return err
+	}
+	s.recordSagaCompensated(order.CreatedAt)
+	return nil
+}
+
+// StartProcessing moves a confirmed order into processing and enqueues order.processing, in its
+// own transaction. It returns the updated order.
+func (s *OrderService) StartProcessing(ctx context.Context, orderID uuid.UUID) (*domain.Order, error) {
+	return s.fulfil(ctx, orderID, (*domain.Order).StartProcessing, events.EventTypeOrderProcessing, nil)
+}
+
+// Ship moves a processing order into shipped, recording carrier and trackingNumber, and enqueues
+// order.shipped carrying both, in its own transaction. It returns the updated order.
+func (s *OrderService) Ship(ctx context.Context, orderID uuid.UUID, carrier, trackingNumber string) (*domain.Order, error) {
+	ship := func(o *domain.Order) error { return o.Ship(carrier, trackingNumber) }
+	return s.fulfil(ctx, orderID, ship, events.EventTypeOrderShipped, func(ctx context.Context, tx *sql.Tx, o *domain.Order) error {
+		return s.repo.UpdateShipment(ctx, tx, o.ID, o.Carrier, o.TrackingNumber)
+	})
+}
+
+// Deliver moves a shipped order into delivered, where its lifecycle ends, and enqueues
+// order.delivered, in its own transaction. It returns the updated order.
+func (s *OrderService) Deliver(ctx context.Context, orderID uuid.UUID) (*domain.Order, error) {
+	return s.fulfil(ctx, orderID, (*domain.Order).Deliver, events.EventTypeOrderDelivered, nil)
+}
+
+// fulfil applies a fulfilment transition to orderID and enqueues eventType in one transaction,
+// running persist, if given, in it as well to store what the transition recorded beyond the
+// status. Fulfilment happens after the saga completed, so it does not touch the saga.
+func (s *OrderService) fulfil(ctx context.Context, orderID uuid.UUID, apply func(*domain.Order) error, eventType string, persist func(ctx context.Context, tx *sql.Tx, o *domain.Order) error) (*domain.Order, error) {
+	var order *domain.Order
+	err := database.WithTx(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		if order, err = s.repo.GetByID(ctx, orderID); err != nil {
+			return err
+		}
+		if err := s.applyTransition(ctx, tx, order, apply, eventType); err != nil {
+			return err
+		}
+		if persist != nil {
+			return persist(ctx, tx, order)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	order.Version++
+	order.UpdatedAt = time.Now().UTC()
+	return order, nil
+}
+
+// markCompensating durably records that compensation for orderID has begun and why, along with
+// paymentID unless it is uuid.Nil, in its own transaction, so the saga survives a later
+// compensating step failing and the caller's own transaction rolling back, and RetryCompensation
+// knows what is left to undo. It never joins a transaction ctx carries.
+func (s *OrderService) markCompensating(ctx context.Context, orderID uuid.UUID, reason string, paymentID uuid.UUID) error {
+	return database.WithTx(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		if err := s.saga.Transition(ctx, tx, orderID, saga.StateCompensating); err != nil {
+			return err
+		}
+		if paymentID != uuid.Nil {
+			if err := s.saga.SetPaymentID(ctx, tx, orderID, paymentID); err != nil {
+				return err
+			}
+		}
+		return s.saga.SetCompensationReason(ctx, tx, orderID, reason)
+	}, database.Separate())
 }
 
 // recordSagaCompleted records a saga that reached the completed state, if metrics are configured.
@@ -302,10 +612,12 @@ func (s *OrderService) recordSagaCompensated(createdAt time.Time) {
 }
 
 // isTerminal reports whether status is an outcome a payment result would otherwise drive the
-// order to, so a redelivered or late event can be recognized and ignored.
+// order to, or one past it in fulfilment, so a redelivered or late event can be recognized and
+// ignored.
 func isTerminal(status domain.Status) bool {
 	switch status {
-	case domain.StatusConfirmed, domain.StatusPaymentFailed, domain.StatusCancelled:
+	case domain.StatusConfirmed, domain.StatusPaymentFailed, domain.StatusCancelled,
+		domain.StatusProcessing, domain.StatusShipped, domain.StatusDelivered:
 		return true
 	default:
 		return false
@@ -315,6 +627,12 @@ func isTerminal(status domain.Status) bool {
 // applyTransition runs apply on order, persists the resulting status and enqueues eventType, all
 // within tx.
 func (s *OrderService) applyTransition(ctx context.Context, tx *sql.Tx, order *domain.Order, apply func(*domain.Order) error, eventType string) error {
+	return s.applyTransitionWithReason(ctx, tx, order, apply, eventType, "")
+}
+
+// applyTransitionWithReason is applyTransition for a transition that records why it happened on
+// its event, such as a cancellation.
+func (s *OrderService) applyTransitionWithReason(ctx context.Context, tx *sql.Tx, order *domain.Order, apply func(*domain.Order) error, eventType, reason string) error {
 	expectedVersion := order.Version
 
 	if err := apply(order); err != nil {
@@ -325,7 +643,13 @@ func (s *OrderService) applyTransition(ctx context.Context, tx *sql.Tx, order *d
 		return err
 	}
 
-	payload, err := json.Marshal(newOrderStatusPayload(order))
+	payload := newOrderStatusPayload(order)
+	payload.Reason = reason
+	if eventType == events.EventTypeOrderConfirmed {
+		payload.ShippingAddress = order.ShippingAddress
+		payload.BillingAddress = order.BillingAddress
+	}
+	body, err := json.Marshal(payload)
 	if err != nil {
 		return fmt.Errorf("marshal %s payload: %w", eventType, err)
 	}
@@ -334,7 +658,7 @@ func (s *OrderService) applyTransition(ctx context.Context, tx *sql.Tx, order *d
 		Topic:       events.OrdersTopic,
 		EventType:   eventType,
 		AggregateID: order.ID.String(),
-		Payload:     payload,
+		Payload:     body,
 	})
 }
 
@@ -346,6 +670,15 @@ type orderStatusPayload struct {
 	Status           string `json:"status"`
 	TotalAmountCents int64  `json:"total_amount_cents"`
 	Currency         string `json:"currency"`
+	// Reason says why the order was cancelled; it is set on order.cancelled only.
+	Reason string `json:"reason,omitempty"`
+	// ShippingAddress and BillingAddress are set on order.confirmed, for fulfilment and
+	// notifications, when the customer gave them.
+	ShippingAddress *domain.Address `json:"shipping_address,omitempty"`
+	BillingAddress  *domain.Address `json:"billing_address,omitempty"`
+	// Carrier and TrackingNumber are set once the order has shipped.
+	Carrier        string `json:"carrier,omitempty"`
+	TrackingNumber string `json:"tracking_number,omitempty"`
 }
 
 func newOrderStatusPayload(o *domain.Order) orderStatusPayload {
@@ -355,5 +688,7 @@ func newOrderStatusPayload(o *domain.Order) orderStatusPayload {
 		Status:           string(o.Status),
 		TotalAmountCents: o.TotalAmountCents,
 		Currency:         o.Currency,
+		Carrier:          o.Carrier,
+		TrackingNumber:   o.TrackingNumber,
 	}
 }
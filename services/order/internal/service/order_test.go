@@ -3,16 +3,17 @@ package service
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"encoding/json"
 	"errors"
 	"reflect"
 	"regexp"
 	"testing"
-	"time"
 
 	sqlmock "github.com/DATA-DOG/go-sqlmock"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/order/internal/domain"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/order/internal/saga"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/cache"
 	apperrors "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/errors"
 	"github.com/google/uuid"
 	"github.com/prometheus/client_golang/prometheus"
@@ -40,6 +41,8 @@ type fakeRepository struct {
 
 	updateErr   error
 	updateCalls []updateCall
+
+	shipmentCalls []string
 }
 
 func (f *fakeRepository) Save(_ context.Context, order *domain.Order) error {
@@ -68,6 +71,11 @@ func (f *fakeRepository) UpdateStatus(_ context.Context, _ *sql.Tx, id uuid.UUID
 	return f.updateErr
 }
 
+func (f *fakeRepository) UpdateShipment(_ context.Context, _ *sql.Tx, _ uuid.UUID, carrier, trackingNumber string) error {
+	f.shipmentCalls = append(f.shipmentCalls, carrier+"/"+trackingNumber)
+	return nil
+}
+
 type sagaTransitionCall struct {
 	orderID uuid.UUID
 	to      saga.State
@@ -81,6 +89,14 @@ type fakeSagaRepository struct {
 	transitionErr   map[saga.State]error
 	transitionCalls []sagaTransitionCall
 
+	state saga.State
+
+	paymentID    uuid.UUID
+	paymentIDSet []uuid.UUID
+
+	compensationReason     string
+	compensationReasonsSet []string
+
 	lastErrorCalls []string
 }
 
@@ -99,6 +115,28 @@ func (f *fakeSagaRepository) Transition(_ context.Context, _ *sql.Tx, orderID uu
 	return nil
 }
 
+func (f *fakeSagaRepository) State(_ context.Context, _ uuid.UUID) (saga.State, error) {
+	return f.state, nil
+}
+
+func (f *fakeSagaRepository) SetPaymentID(_ context.Context, _ *sql.Tx, _, paymentID uuid.UUID) error {
+	f.paymentIDSet = append(f.paymentIDSet, paymentID)
+	return nil
+}
+
+func (f *fakeSagaRepository) PaymentID(_ context.Context, _ uuid.UUID) (uuid.UUID, error) {
+	return f.paymentID, nil
+}
+
+func (f *fakeSagaRepository) SetCompensationReason(_ context.Context, _ *sql.Tx, _ uuid.UUID, reason string) error {
+	f.compensationReasonsSet = append(f.compensationReasonsSet, reason)
+	return nil
+}
+
+func (f *fakeSagaRepository) CompensationReason(_ context.Context, _ uuid.UUID) (string, error) {
+	return f.compensationReason, nil
+}
+
 func (f *fakeSagaRepository) SetLastError(_ context.Context, _ uuid.UUID, message string) error {
 	f.lastErrorCalls = append(f.lastErrorCalls, message)
 	return nil
@@ -183,7 +221,7 @@ func TestOrderService_ConfirmPayment_RecordsSagaCompletedMetric(t *testing.T) {
 		t.Fatalf("db.Begin() error = %v", err)
 	}
 
-	if err := svc.ConfirmPayment(context.Background(), tx, order.ID); err != nil {
+	if err := svc.ConfirmPayment(context.Background(), tx, order.ID, uuid.New()); err != nil {
 		t.Fatalf("ConfirmPayment() error = %v", err)
 	}
 	if err := tx.Commit(); err != nil {
@@ -219,7 +257,7 @@ func TestOrderService_ConfirmPayment_TerminalOrderDoesNotRecordMetric(t *testing
 	if err != nil {
 		t.Fatalf("db.Begin() error = %v", err)
 	}
-	if err := svc.ConfirmPayment(context.Background(), tx, order.ID); err != nil {
+	if err := svc.ConfirmPayment(context.Background(), tx, order.ID, uuid.New()); err != nil {
 		t.Fatalf("ConfirmPayment() error = %v", err)
 	}
 	if err := tx.Commit(); err != nil {
@@ -328,7 +366,7 @@ func TestOrderService_ConfirmPayment_NoSagaMetricsConfiguredDoesNotPanic(t *test
 	if err != nil {
 		t.Fatalf("db.Begin() error = %v", err)
 	}
-	if err := svc.ConfirmPayment(context.Background(), tx, order.ID); err != nil {
+	if err := svc.ConfirmPayment(context.Background(), tx, order.ID, uuid.New()); err != nil {
 		t.Fatalf("ConfirmPayment() error = %v", err)
 	}
 	if err := tx.Commit(); err != nil {
@@ -601,13 +639,16 @@ func TestOrderService_ConfirmPayment(t *testing.T) {
 		defer func() { _ = db.Close() }()
 
 		order := newTestOrder(domain.StatusPendingPayment)
+		shipping := domain.Address{Name: "Ada", Line1: "1 Main St", City: "Springfield", PostalCode: "62704", Country: "US"}
+		order.ShippingAddress = &shipping
 		repo := &fakeRepository{order: order}
 		sagaRepo := &fakeSagaRepository{}
 		svc := NewOrderService(repo, db, sagaRepo, &fakeInventoryReleaser{}, &fakePaymentRefunder{})
 
+		var payload orderStatusPayload
 		mock.ExpectBegin()
 		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO outbox_messages")).
-			WithArgs(sqlmock.AnyArg(), "orders.events", "order.confirmed", order.ID.String(), sqlmock.AnyArg(), nil).
+			WithArgs(sqlmock.AnyArg(), "orders.events", "order.confirmed", order.ID.String(), jsonArg{&payload}, nil).
 			WillReturnResult(sqlmock.NewResult(0, 1))
 		mock.ExpectCommit()
 
@@ -616,7 +657,8 @@ func TestOrderService_ConfirmPayment(t *testing.T) {
 			t.Fatalf("db.Begin() error = %v", err)
 		}
 
-		if err := svc.ConfirmPayment(context.Background(), tx, order.ID); err != nil {
+		paymentID := uuid.New()
+		if err := svc.ConfirmPayment(context.Background(), tx, order.ID, paymentID); err != nil {
 			t.Fatalf("ConfirmPayment() error = %v", err)
 		}
 		if err := tx.Commit(); err != nil {
@@ -633,6 +675,160 @@ func TestOrderService_ConfirmPayment(t *testing.T) {
 		if !reflect.DeepEqual(sagaRepo.transitionCalls, wantTransitions) {
 			t.Errorf("saga transitions = %+v, want %+v", sagaRepo.transitionCalls, wantTransitions)
 		}
+		if !reflect.DeepEqual(sagaRepo.paymentIDSet, []uuid.UUID{paymentID}) {
+			t.Errorf("saga payment ids = %v, want [%v]", sagaRepo.paymentIDSet, paymentID)
+		}
+		if payload.ShippingAddress == nil || *payload.ShippingAddress != shipping {
+			t.Errorf("payload shipping address = %+v, want %+v", payload.ShippingAddress, shipping)
+		}
+		if payload.BillingAddress != nil {
+			t.Errorf("payload billing address = %+v, want none", payload.BillingAddress)
+		}
+	})
+
+	t.Run("refunds a payment that lands after the order was cancelled", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("sqlmock.New() error = %v", err)
+		}
+		defer func() { _ = db.Close() }()
+
+		order := newTestOrder(domain.StatusCancelled)
+		payments := &fakePaymentRefunder{}
+		svc := NewOrderService(&fakeRepository{order: order}, db, &fakeSagaRepository{}, &fakeInventoryReleaser{}, payments)
+
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatalf("db.Begin() error = %v", err)
+		}
+		paymentID := uuid.New()
+		if err := svc.ConfirmPayment(context.Background(), tx, order.ID, paymentID); err != nil {
+			t.Fatalf("ConfirmPayment() error = %v", err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatalf("tx.Commit() error = %v", err)
+		}
+
+		want := []paymentRefundCall{{paymentID, "order_cancelled"}}
+		if !reflect.DeepEqual(payments.refundCalls, want) {
+			t.Errorf("refund calls = %+v, want %+v", payments.refundCalls, want)
+		}
+	})
+
+	t.Run("refunds a payment that lands while the saga is compensating", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("sqlmock.New() error = %v", err)
+		}
+		defer func() { _ = db.Close() }()
+
+		// A customer cancellation moved the saga to compensating but has not cancelled the order
+		// yet.
+		order := newTestOrder(domain.StatusPendingPayment)
+		sagaRepo := &fakeSagaRepository{state: saga.StateCompensating}
+		payments := &fakePaymentRefunder{}
+		svc := NewOrderService(&fakeRepository{order: order}, db, sagaRepo, &fakeInventoryReleaser{}, payments)
+
+		mock.ExpectBegin()
+		// The payment is recorded on the saga in a transaction of its own.
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+		mock.ExpectCommit()
+
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatalf("db.Begin() error = %v", err)
+		}
+		paymentID := uuid.New()
+		if err := svc.ConfirmPayment(context.Background(), tx, order.ID, paymentID); err != nil {
+			t.Fatalf("ConfirmPayment() error = %v", err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatalf("tx.Commit() error = %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet expectations: %v", err)
+		}
+
+		if want := []paymentRefundCall{{paymentID, "order_cancelled"}}; !reflect.DeepEqual(payments.refundCalls, want) {
+			t.Errorf("refund calls = %+v, want %+v", payments.refundCalls, want)
+		}
+		if !reflect.DeepEqual(sagaRepo.paymentIDSet, []uuid.UUID{paymentID}) {
+			t.Errorf("saga payment ids = %v, want [%v]", sagaRepo.paymentIDSet, paymentID)
+		}
+		if len(sagaRepo.transitionCalls) != 0 {
+			t.Errorf("saga transitions = %+v, want none", sagaRepo.transitionCalls)
+		}
+	})
+
+	t.Run("refunds a payment that lands after recovery gave the saga up as failed", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("sqlmock.New() error = %v", err)
+		}
+		defer func() { _ = db.Close() }()
+
+		// The release kept failing, so the order was never cancelled.
+		order := newTestOrder(domain.StatusPendingPayment)
+		sagaRepo := &fakeSagaRepository{state: saga.StateFailed}
+		payments := &fakePaymentRefunder{}
+		registry := prometheus.NewRegistry()
+		svc := NewOrderService(&fakeRepository{order: order}, db, sagaRepo, &fakeInventoryReleaser{}, payments)
+		svc.SetSagaMetrics(saga.NewMetrics(registry))
+
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+
+		paymentID := uuid.New()
+		if err := svc.ConfirmPayment(context.Background(), nil, order.ID, paymentID); err != nil {
+			t.Fatalf("ConfirmPayment() error = %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet expectations: %v", err)
+		}
+
+		if want := []paymentRefundCall{{paymentID, "order_cancelled"}}; !reflect.DeepEqual(payments.refundCalls, want) {
+			t.Errorf("refund calls = %+v, want %+v", payments.refundCalls, want)
+		}
+		if !reflect.DeepEqual(sagaRepo.paymentIDSet, []uuid.UUID{paymentID}) {
+			t.Errorf("saga payment ids = %v, want [%v]", sagaRepo.paymentIDSet, paymentID)
+		}
+		if got := counterValue(t, registry, "order_saga_late_payments_total"); got != 1 {
+			t.Errorf("order_saga_late_payments_total = %v, want 1", got)
+		}
+	})
+
+	t.Run("keeps the payment on the saga when the late refund fails", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("sqlmock.New() error = %v", err)
+		}
+		defer func() { _ = db.Close() }()
+
+		order := newTestOrder(domain.StatusPendingPayment)
+		sagaRepo := &fakeSagaRepository{state: saga.StateCompensating}
+		payments := &fakePaymentRefunder{refundErr: errors.New("payment service unavailable")}
+		svc := NewOrderService(&fakeRepository{order: order}, db, sagaRepo, &fakeInventoryReleaser{}, payments)
+
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+
+		paymentID := uuid.New()
+		if err := svc.ConfirmPayment(context.Background(), nil, order.ID, paymentID); err == nil {
+			t.Fatal("ConfirmPayment() error = nil, want the refund failure")
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet expectations: %v", err)
+		}
+		if !reflect.DeepEqual(sagaRepo.paymentIDSet, []uuid.UUID{paymentID}) {
+			t.Errorf("saga payment ids = %v, want [%v]", sagaRepo.paymentIDSet, paymentID)
+		}
+		if len(sagaRepo.lastErrorCalls) != 1 {
+			t.Errorf("saga last errors = %v, want one", sagaRepo.lastErrorCalls)
+		}
 	})
 
 	t.Run("propagates an outbox insert failure", func(t *testing.T) {
@@ -655,7 +851,7 @@ func TestOrderService_ConfirmPayment(t *testing.T) {
 			t.Fatalf("db.Begin() error = %v", err)
 		}
 
-		if err := svc.ConfirmPayment(context.Background(), tx, order.ID); err == nil {
+		if err := svc.ConfirmPayment(context.Background(), tx, order.ID, uuid.New()); err == nil {
 			t.Fatal("expected error, got none")
 		}
 		if err := tx.Rollback(); err != nil {
@@ -684,7 +880,7 @@ func TestOrderService_ConfirmPayment(t *testing.T) {
 			t.Fatalf("db.Begin() error = %v", err)
 		}
 
-		if err := svc.ConfirmPayment(context.Background(), tx, uuid.New()); !errors.Is(err, errTestRepository) {
+		if err := svc.ConfirmPayment(context.Background(), tx, uuid.New(), uuid.New()); !errors.Is(err, errTestRepository) {
 			t.Errorf("error = %v, want %v", err, errTestRepository)
 		}
 		if err := tx.Rollback(); err != nil {
@@ -715,7 +911,7 @@ func TestOrderService_ConfirmPayment(t *testing.T) {
 			t.Fatalf("db.Begin() error = %v", err)
 		}
 
-		if err := svc.ConfirmPayment(context.Background(), tx, order.ID); !errors.Is(err, errTestRepository) {
+		if err := svc.ConfirmPayment(context.Background(), tx, order.ID, uuid.New()); !errors.Is(err, errTestRepository) {
 			t.Errorf("error = %v, want %v", err, errTestRepository)
 		}
 		if err := tx.Rollback(); err != nil {
@@ -751,7 +947,7 @@ func TestOrderService_ConfirmPayment(t *testing.T) {
 			t.Fatalf("db.Begin() error = %v", err)
 		}
 
-		if err := svc.ConfirmPayment(context.Background(), tx, order.ID); !errors.Is(err, errTestRepository) {
+		if err := svc.ConfirmPayment(context.Background(), tx, order.ID, uuid.New()); !errors.Is(err, errTestRepository) {
 			t.Errorf("error = %v, want %v", err, errTestRepository)
 		}
 		if err := tx.Rollback(); err != nil {
@@ -786,7 +982,7 @@ func TestOrderService_ConfirmPayment(t *testing.T) {
 					t.Fatalf("db.Begin() error = %v", err)
 				}
 
-				if err := svc.ConfirmPayment(context.Background(), tx, order.ID); err != nil {
+				if err := svc.ConfirmPayment(context.Background(), tx, order.ID, uuid.New()); err != nil {
 					t.Fatalf("ConfirmPayment() error = %v", err)
 				}
 				if err := tx.Commit(); err != nil {
@@ -1319,7 +1515,8 @@ func TestOrderService_FailAfterPayment(t *testing.T) {
 			t.Fatalf("db.Begin() error = %v", err)
 		}
 
-		err = svc.FailAfterPayment(context.Background(), tx, order.ID, uuid.New(), reason)
+		paymentID := uuid.New()
+		err = svc.FailAfterPayment(context.Background(), tx, order.ID, paymentID, reason)
 		if !errors.Is(err, refundErr) {
 			t.Errorf("error = %v, want to wrap %v", err, refundErr)
 		}
@@ -1340,6 +1537,10 @@ func TestOrderService_FailAfterPayment(t *testing.T) {
 		if len(sagaRepo.lastErrorCalls) != 1 {
 			t.Errorf("expected the failure to be recorded on the saga, lastErrorCalls = %v", sagaRepo.lastErrorCalls)
 		}
+		// A later retry refunds the same payment for the same reason.
+		if !reflect.DeepEqual(sagaRepo.paymentIDSet, []uuid.UUID{paymentID}) || !reflect.DeepEqual(sagaRepo.compensationReasonsSet, []string{reason}) {
+			t.Errorf("recorded payment ids = %v, reasons = %v, want [%v] and [%s]", sagaRepo.paymentIDSet, sagaRepo.compensationReasonsSet, paymentID, reason)
+		}
 	})
 
 	t.Run("a release failure after a successful refund leaves the saga compensating", func(t *testing.T) {
@@ -1427,38 +1628,31 @@ func TestOrderService_FailAfterPayment(t *testing.T) {
 	})
 }
 
-// fakeOrderCache is an in-memory OrderCache test double.
+// fakeOrderCache is an in-memory OrderCache test double that loads on a miss and keeps the
+// result.
 type fakeOrderCache struct {
-	store  map[string][]byte
-	getErr error
-	setErr error
+	store map[string][]byte
 }
 
 func newFakeOrderCache() *fakeOrderCache {
 	return &fakeOrderCache{store: make(map[string][]byte)}
 }
 
-func (f *fakeOrderCache) GetJSON(_ context.Context, key string, dest any) (bool, error) {
-	if f.getErr != nil {
-		return false, f.getErr
-	}
-	raw, ok := f.store[key]
-	if !ok {
-		return false, nil
+func (f *fakeOrderCache) GetOrLoad(ctx context.Context, key string, dest any, _ cache.LoadOptions, load cache.LoadFunc) (bool, error) {
+	if raw, ok := f.store[key]; ok {
+		return false, json.Unmarshal(raw, dest)
 	}
-	return true, json.Unmarshal(raw, dest)
-}
 
-func (f *fakeOrderCache) SetJSON(_ context.Context, key string, value any, _ time.Duration) error {
-	if f.setErr != nil {
-		return f.setErr
+	value, err := load(ctx)
+	if err != nil {
+		return false, err
 	}
 	data, err := json.Marshal(value)
 	if err != nil {
-		return err
+		return false, err
 	}
 	f.store[key] = data
-	return nil
+	return false, json.Unmarshal(data, dest)
 }
 
 func TestOrderService_GetByID_SecondReadDoesNotHitRepository(t *testing.T) {
@@ -1507,23 +1701,21 @@ func TestOrderService_GetByID_NoCacheAlwaysReadsRepository(t *testing.T) {
 	}
 }
 
-func TestOrderService_GetByID_CacheErrorFallsBackToRepository(t *testing.T) {
+func TestOrderService_GetByID_CacheDisabledForRequestReadsRepository(t *testing.T) {
 	order := newTestOrder(domain.StatusPendingPayment)
 	repo := &fakeRepository{order: order}
-	cache := newFakeOrderCache()
-	cache.getErr = errors.New("redis unavailable")
 	svc := NewOrderService(repo, nil, &fakeSagaRepository{}, &fakeInventoryReleaser{}, &fakePaymentRefunder{})
-	svc.SetCache(cache)
+	svc.SetCache(newFakeOrderCache())
+	svc.SetCacheEnabled(func(context.Context) bool { return false })
 
-	got, err := svc.GetByID(context.Background(), order.ID)
-	if err != nil {
-		t.Fatalf("GetByID() error = %v, want nil", err)
-	}
-	if got.ID != order.ID {
-		t.Errorf("GetByID() ID = %v, want %v", got.ID, order.ID)
+	for range 2 {
+		if _, err := svc.GetByID(context.Background(), order.ID); err != nil {
+			t.Fatalf("GetByID() error = %v", err)
+		}
 	}
-	if repo.getCalls != 1 {
-		t.Errorf("repository GetByID calls = %d, want 1", repo.getCalls)
+
+	if repo.getCalls != 2 {
+		t.Errorf("repository GetByID calls = %d, want 2 (cache disabled for the request)", repo.getCalls)
 	}
 }
 
@@ -1563,3 +1755,556 @@ func TestOrderService_ListByCustomer_DelegatesToRepository(t *testing.T) {
 		t.Fatalf("ListByCustomer() returned %d orders, want %d", len(got), len(want))
 	}
 }
+
+func TestOrderService_CancelByCustomer(t *testing.T) {
+	t.Run("releases the reservation of an order awaiting payment", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("sqlmock.New() error = %v", err)
+		}
+		defer func() { _ = db.Close() }()
+
+		order := newTestOrder(domain.StatusPendingPayment)
+		repo := &fakeRepository{order: order}
+		sagaRepo := &fakeSagaRepository{}
+		inventory := &fakeInventoryReleaser{}
+		payments := &fakePaymentRefunder{}
+		svc := NewOrderService(repo, db, sagaRepo, inventory, payments)
+
+		mock.ExpectBegin() // markCompensating
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO outbox_messages")).
+			WithArgs(sqlmock.AnyArg(), "orders.events", "order.cancelled", order.ID.String(), sqlmock.AnyArg(), nil).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		cancelled, err := svc.CancelByCustomer(context.Background(), order.ID, order.CustomerID, "changed_mind")
+		if err != nil {
+			t.Fatalf("CancelByCustomer() error = %v", err)
+		}
+		if cancelled.Status != domain.StatusCancelled || cancelled.Version != 2 {
+			t.Errorf("CancelByCustomer() = status %v version %d, want cancelled version 2", cancelled.Status, cancelled.Version)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet expectations: %v", err)
+		}
+		if len(payments.refundCalls) != 0 {
+			t.Errorf("refund calls = %+v, want none before payment", payments.refundCalls)
+		}
+		if !reflect.DeepEqual(inventory.released, []uuid.UUID{order.ID}) {
+			t.Errorf("released = %v, want [%v]", inventory.released, order.ID)
+		}
+		wantTransitions := []sagaTransitionCall{
+			{order.ID, saga.StateCompensating},
+			{order.ID, saga.StateCompensated},
+		}
+		if !reflect.DeepEqual(sagaRepo.transitionCalls, wantTransitions) {
+			t.Errorf("saga transitions = %+v, want %+v", sagaRepo.transitionCalls, wantTransitions)
+		}
+	})
+
+	t.Run("refunds then releases a confirmed order", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("sqlmock.New() error = %v", err)
+		}
+		defer func() { _ = db.Close() }()
+
+		order := newTestOrder(domain.StatusConfirmed)
+		paymentID := uuid.New()
+		inventory := &fakeInventoryReleaser{}
+		payments := &fakePaymentRefunder{}
+		svc := NewOrderService(&fakeRepository{order: order}, db, &fakeSagaRepository{paymentID: paymentID}, inventory, payments)
+
+		var payload map[string]any
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO outbox_messages")).
+			WithArgs(sqlmock.AnyArg(), "orders.events", "order.cancelled", order.ID.String(), jsonArg{&payload}, nil).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		if _, err := svc.CancelByCustomer(context.Background(), order.ID, order.CustomerID, ""); err != nil {
+			t.Fatalf("CancelByCustomer() error = %v", err)
+		}
+		want := []paymentRefundCall{{paymentID, "customer_request"}}
+		if !reflect.DeepEqual(payments.refundCalls, want) {
+			t.Errorf("refund calls = %+v, want %+v", payments.refundCalls, want)
+		}
+		if len(inventory.released) != 1 {
+			t.Errorf("released = %v, want one release", inventory.released)
+		}
+		if payload["reason"] != "customer_request" {
+			t.Errorf("order.cancelled reason = %v, want customer_request", payload["reason"])
+		}
+	})
+
+	t.Run("leaves the saga compensating when the refund fails", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("sqlmock.New() error = %v", err)
+		}
+		defer func() { _ = db.Close() }()
+
+		order := newTestOrder(domain.StatusConfirmed)
+		repo := &fakeRepository{order: order}
+		sagaRepo := &fakeSagaRepository{paymentID: uuid.New()}
+		inventory := &fakeInventoryReleaser{}
+		svc := NewOrderService(repo, db, sagaRepo, inventory, &fakePaymentRefunder{refundErr: errTestRepository})
+
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+
+		if _, err := svc.CancelByCustomer(context.Background(), order.ID, order.CustomerID, ""); !errors.Is(err, errTestRepository) {
+			t.Fatalf("CancelByCustomer() error = %v, want %v", err, errTestRepository)
+		}
+		if len(inventory.released) != 0 || len(repo.updateCalls) != 0 {
+			t.Errorf("released = %v, updates = %v, want neither after a failed refund", inventory.released, repo.updateCalls)
+		}
+		if len(sagaRepo.lastErrorCalls) != 1 {
+			t.Errorf("last error calls = %v, want one", sagaRepo.lastErrorCalls)
+		}
+	})
+
+	t.Run("rejects a confirmed order whose saga recorded no payment", func(t *testing.T) {
+		order := newTestOrder(domain.StatusConfirmed)
+		sagaRepo := &fakeSagaRepository{}
+		inventory := &fakeInventoryReleaser{}
+		payments := &fakePaymentRefunder{}
+		svc := NewOrderService(&fakeRepository{order: order}, nil, sagaRepo, inventory, payments)
+
+		_, err := svc.CancelByCustomer(context.Background(), order.ID, order.CustomerID, "")
+		var appErr *apperrors.AppError
+		if !errors.As(err, &appErr) || appErr.Code != "CONFLICT" {
+			t.Fatalf("error = %v, want CONFLICT", err)
+		}
+		if len(sagaRepo.transitionCalls) != 0 || len(sagaRepo.lastErrorCalls) != 0 {
+			t.Errorf("saga transitions = %+v, last errors = %v, want none", sagaRepo.transitionCalls, sagaRepo.lastErrorCalls)
+		}
+		if len(payments.refundCalls) != 0 || len(inventory.released) != 0 {
+			t.Errorf("refunds = %+v, releases = %v, want neither", payments.refundCalls, inventory.released)
+		}
+	})
+
+	t.Run("rejects another customer's order", func(t *testing.T) {
+		order := newTestOrder(domain.StatusPendingPayment)
+		svc := NewOrderService(&fakeRepository{order: order}, nil, &fakeSagaRepository{}, &fakeInventoryReleaser{}, &fakePaymentRefunder{})
+
+		_, err := svc.CancelByCustomer(context.Background(), order.ID, uuid.New(), "")
+		var appErr *apperrors.AppError
+		if !errors.As(err, &appErr) || appErr.Code != "FORBIDDEN" {
+			t.Errorf("error = %v, want FORBIDDEN", err)
+		}
+	})
+
+	t.Run("rejects an order past confirmation", func(t *testing.T) {
+		for _, status := range []domain.Status{domain.StatusPending, domain.StatusProcessing, domain.StatusCancelled, domain.StatusPaymentFailed} {
+			order := newTestOrder(status)
+			sagaRepo := &fakeSagaRepository{}
+			svc := NewOrderService(&fakeRepository{order: order}, nil, sagaRepo, &fakeInventoryReleaser{}, &fakePaymentRefunder{})
+
+			_, err := svc.CancelByCustomer(context.Background(), order.ID, order.CustomerID, "")
+			var appErr *apperrors.AppError
+			if !errors.As(err, &appErr) || appErr.Code != "ORDER_ALREADY_PROCESSED" {
+				t.Errorf("%s: error = %v, want ORDER_ALREADY_PROCESSED", status, err)
+			}
+			if len(sagaRepo.transitionCalls) != 0 {
+				t.Errorf("%s: saga transitions = %+v, want none", status, sagaRepo.transitionCalls)
+			}
+		}
+	})
+}
+
+func TestOrderService_CompensateTimedOut(t *testing.T) {
+	t.Run("releases the reservation and cancels the order", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("sqlmock.New() error = %v", err)
+		}
+		defer func() { _ = db.Close() }()
+
+		order := newTestOrder(domain.StatusPendingPayment)
+		repo := &fakeRepository{order: order}
+		sagaRepo := &fakeSagaRepository{}
+		inventory := &fakeInventoryReleaser{}
+		registry := prometheus.NewRegistry()
+		svc := NewOrderService(repo, db, sagaRepo, inventory, &fakePaymentRefunder{})
+		svc.SetSagaMetrics(saga.NewMetrics(registry))
+
+		var payload map[string]any
+		mock.ExpectBegin() // markCompensating
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO outbox_messages")).
+			WithArgs(sqlmock.AnyArg(), "orders.events", "order.cancelled", order.ID.String(), jsonArg{&payload}, nil).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		if err := svc.CompensateTimedOut(context.Background(), order.ID); err != nil {
+			t.Fatalf("CompensateTimedOut() error = %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet expectations: %v", err)
+		}
+		if !reflect.DeepEqual(inventory.released, []uuid.UUID{order.ID}) {
+			t.Errorf("released = %v, want [%v]", inventory.released, order.ID)
+		}
+		wantUpdates := []updateCall{{order.ID, domain.StatusCancelled, 1}}
+		if !reflect.DeepEqual(repo.updateCalls, wantUpdates) {
+			t.Errorf("updates = %+v, want %+v", repo.updateCalls, wantUpdates)
+		}
+		wantTransitions := []sagaTransitionCall{
+			{order.ID, saga.StateCompensating},
+			{order.ID, saga.StateCompensated},
+		}
+		if !reflect.DeepEqual(sagaRepo.transitionCalls, wantTransitions) {
+			t.Errorf("saga transitions = %+v, want %+v", sagaRepo.transitionCalls, wantTransitions)
+		}
+		if !reflect.DeepEqual(sagaRepo.compensationReasonsSet, []string{"saga_timeout"}) {
+			t.Errorf("compensation reasons = %v, want [saga_timeout]", sagaRepo.compensationReasonsSet)
+		}
+		if payload["reason"] != "saga_timeout" {
+			t.Errorf("order.cancelled reason = %v, want saga_timeout", payload["reason"])
+		}
+		if got := counterValue(t, registry, "order_saga_compensated_total"); got != 1 {
+			t.Errorf("order_saga_compensated_total = %v, want 1", got)
+		}
+	})
+
+	t.Run("leaves the saga compensating when the release fails", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("sqlmock.New() error = %v", err)
+		}
+		defer func() { _ = db.Close() }()
+
+		order := newTestOrder(domain.StatusPending)
+		repo := &fakeRepository{order: order}
+		sagaRepo := &fakeSagaRepository{}
+		svc := NewOrderService(repo, db, sagaRepo, &fakeInventoryReleaser{releaseErr: errTestRepository}, &fakePaymentRefunder{})
+
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+
+		if err := svc.CompensateTimedOut(context.Background(), order.ID); !errors.Is(err, errTestRepository) {
+			t.Fatalf("CompensateTimedOut() error = %v, want %v", err, errTestRepository)
+		}
+		wantTransitions := []sagaTransitionCall{{order.ID, saga.StateCompensating}}
+		if len(repo.updateCalls) != 0 || !reflect.DeepEqual(sagaRepo.transitionCalls, wantTransitions) {
+			t.Errorf("updates = %v, saga transitions = %v, want none past compensating after a failed release", repo.updateCalls, sagaRepo.transitionCalls)
+		}
+		if len(sagaRepo.lastErrorCalls) != 1 {
+			t.Errorf("last error calls = %v, want one", sagaRepo.lastErrorCalls)
+		}
+	})
+
+	t.Run("only finishes the saga of an order already cancelled", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("sqlmock.New() error = %v", err)
+		}
+		defer func() { _ = db.Close() }()
+
+		order := newTestOrder(domain.StatusPaymentFailed)
+		repo := &fakeRepository{order: order}
+		sagaRepo := &fakeSagaRepository{}
+		inventory := &fakeInventoryReleaser{}
+		svc := NewOrderService(repo, db, sagaRepo, inventory, &fakePaymentRefunder{})
+
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+
+		if err := svc.CompensateTimedOut(context.Background(), order.ID); err != nil {
+			t.Fatalf("CompensateTimedOut() error = %v", err)
+		}
+		if len(inventory.released) != 0 || len(repo.updateCalls) != 0 {
+			t.Errorf("released = %v, updates = %v, want neither", inventory.released, repo.updateCalls)
+		}
+		wantTransitions := []sagaTransitionCall{{order.ID, saga.StateCompensated}}
+		if !reflect.DeepEqual(sagaRepo.transitionCalls, wantTransitions) {
+			t.Errorf("saga transitions = %+v, want %+v", sagaRepo.transitionCalls, wantTransitions)
+		}
+	})
+
+	t.Run("refuses an order that moved past payment", func(t *testing.T) {
+		order := newTestOrder(domain.StatusConfirmed)
+		sagaRepo := &fakeSagaRepository{}
+		inventory := &fakeInventoryReleaser{}
+		svc := NewOrderService(&fakeRepository{order: order}, nil, sagaRepo, inventory, &fakePaymentRefunder{})
+
+		err := svc.CompensateTimedOut(context.Background(), order.ID)
+		var appErr *apperrors.AppError
+		if !errors.As(err, &appErr) || appErr.Code != "ORDER_ALREADY_PROCESSED" {
+			t.Errorf("error = %v, want ORDER_ALREADY_PROCESSED", err)
+		}
+		if len(inventory.released) != 0 || len(sagaRepo.lastErrorCalls) != 1 {
+			t.Errorf("released = %v, last error calls = %v, want no release and one last error", inventory.released, sagaRepo.lastErrorCalls)
+		}
+	})
+}
+
+func TestOrderService_RetryCompensation(t *testing.T) {
+	t.Run("refunds the recorded payment, releases and cancels for the recorded reason", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("sqlmock.New() error = %v", err)
+		}
+		defer func() { _ = db.Close() }()
+
+		order := newTestOrder(domain.StatusConfirmed)
+		paymentID := uuid.New()
+		repo := &fakeRepository{order: order}
+		sagaRepo := &fakeSagaRepository{paymentID: paymentID, compensationReason: "changed_mind"}
+		inventory := &fakeInventoryReleaser{}
+		payments := &fakePaymentRefunder{}
+		registry := prometheus.NewRegistry()
+		svc := NewOrderService(repo, db, sagaRepo, inventory, payments)
+		svc.SetSagaMetrics(saga.NewMetrics(registry))
+
+		var payload map[string]any
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO outbox_messages")).
+			WithArgs(sqlmock.AnyArg(), "orders.events", "order.cancelled", order.ID.String(), jsonArg{&payload}, nil).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		if err := svc.RetryCompensation(context.Background(), order.ID); err != nil {
+			t.Fatalf("RetryCompensation() error = %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet expectations: %v", err)
+		}
+		if want := []paymentRefundCall{{paymentID, "changed_mind"}}; !reflect.DeepEqual(payments.refundCalls, want) {
+			t.Errorf("refund calls = %+v, want %+v", payments.refundCalls, want)
+		}
+		if !reflect.DeepEqual(inventory.released, []uuid.UUID{order.ID}) {
+			t.Errorf("released = %v, want [%v]", inventory.released, order.ID)
+		}
+		if want := []updateCall{{order.ID, domain.StatusCancelled, 1}}; !reflect.DeepEqual(repo.updateCalls, want) {
+			t.Errorf("updates = %+v, want %+v", repo.updateCalls, want)
+		}
+		if want := []sagaTransitionCall{{order.ID, saga.StateCompensated}}; !reflect.DeepEqual(sagaRepo.transitionCalls, want) {
+			t.Errorf("saga transitions = %+v, want %+v", sagaRepo.transitionCalls, want)
+		}
+		if payload["reason"] != "changed_mind" {
+			t.Errorf("order.cancelled reason = %v, want changed_mind", payload["reason"])
+		}
+		if got := counterValue(t, registry, "order_saga_compensated_total"); got != 1 {
+			t.Errorf("order_saga_compensated_total = %v, want 1", got)
+		}
+	})
+
+	t.Run("fails an order whose payment failed without refunding", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("sqlmock.New() error = %v", err)
+		}
+		defer func() { _ = db.Close() }()
+
+		order := newTestOrder(domain.StatusPendingPayment)
+		repo := &fakeRepository{order: order}
+		payments := &fakePaymentRefunder{}
+		svc := NewOrderService(repo, db, &fakeSagaRepository{compensationReason: "payment_failed"}, &fakeInventoryReleaser{}, payments)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO outbox_messages")).
+			WithArgs(sqlmock.AnyArg(), "orders.events", "order.cancelled", order.ID.String(), sqlmock.AnyArg(), nil).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		if err := svc.RetryCompensation(context.Background(), order.ID); err != nil {
+			t.Fatalf("RetryCompensation() error = %v", err)
+		}
+		if len(payments.refundCalls) != 0 {
+			t.Errorf("refund calls = %+v, want none without a recorded payment", payments.refundCalls)
+		}
+		if want := []updateCall{{order.ID, domain.StatusPaymentFailed, 1}}; !reflect.DeepEqual(repo.updateCalls, want) {
+			t.Errorf("updates = %+v, want %+v", repo.updateCalls, want)
+		}
+	})
+
+	t.Run("a release that fails again leaves the saga compensating", func(t *testing.T) {
+		order := newTestOrder(domain.StatusPendingPayment)
+		repo := &fakeRepository{order: order}
+		sagaRepo := &fakeSagaRepository{compensationReason: "payment_failed"}
+		svc := NewOrderService(repo, nil, sagaRepo, &fakeInventoryReleaser{releaseErr: errTestRepository}, &fakePaymentRefunder{})
+
+		if err := svc.RetryCompensation(context.Background(), order.ID); !errors.Is(err, errTestRepository) {
+			t.Fatalf("RetryCompensation() error = %v, want %v", err, errTestRepository)
+		}
+		if len(repo.updateCalls) != 0 || len(sagaRepo.transitionCalls) != 0 {
+			t.Errorf("updates = %v, saga transitions = %v, want neither", repo.updateCalls, sagaRepo.transitionCalls)
+		}
+		if len(sagaRepo.lastErrorCalls) != 1 {
+			t.Errorf("last error calls = %v, want one", sagaRepo.lastErrorCalls)
+		}
+	})
+
+	t.Run("only finishes the saga of an order already cancelled", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("sqlmock.New() error = %v", err)
+		}
+		defer func() { _ = db.Close() }()
+
+		order := newTestOrder(domain.StatusCancelled)
+		sagaRepo := &fakeSagaRepository{paymentID: uuid.New()}
+		inventory := &fakeInventoryReleaser{}
+		payments := &fakePaymentRefunder{}
+		svc := NewOrderService(&fakeRepository{order: order}, db, sagaRepo, inventory, payments)
+
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+
+		if err := svc.RetryCompensation(context.Background(), order.ID); err != nil {
+			t.Fatalf("RetryCompensation() error = %v", err)
+		}
+		if len(payments.refundCalls) != 0 || len(inventory.released) != 0 {
+			t.Errorf("refunds = %v, released = %v, want neither", payments.refundCalls, inventory.released)
+		}
+		if want := []sagaTransitionCall{{order.ID, saga.StateCompensated}}; !reflect.DeepEqual(sagaRepo.transitionCalls, want) {
+			t.Errorf("saga transitions = %+v, want %+v", sagaRepo.transitionCalls, want)
+		}
+	})
+
+	t.Run("refuses an order in fulfilment", func(t *testing.T) {
+		order := newTestOrder(domain.StatusProcessing)
+		sagaRepo := &fakeSagaRepository{}
+		svc := NewOrderService(&fakeRepository{order: order}, nil, sagaRepo, &fakeInventoryReleaser{}, &fakePaymentRefunder{})
+
+		err := svc.RetryCompensation(context.Background(), order.ID)
+		var appErr *apperrors.AppError
+		if !errors.As(err, &appErr) || appErr.Code != "ORDER_ALREADY_PROCESSED" {
+			t.Errorf("error = %v, want ORDER_ALREADY_PROCESSED", err)
+		}
+		if len(sagaRepo.lastErrorCalls) != 1 {
+			t.Errorf("last error calls = %v, want one", sagaRepo.lastErrorCalls)
+		}
+	})
+}
+
+// jsonArg is a sqlmock.Argument that accepts a JSON argument and decodes it into dest.
+type jsonArg struct {
+	dest any
+}
+
+func (a jsonArg) Match(v driver.Value) bool {
+	raw, ok := v.([]byte)
+	if !ok {
+		return false
+	}
+	return json.Unmarshal(raw, a.dest) == nil
+}
+
+func TestOrderService_Fulfilment(t *testing.T) {
+	tests := []struct {
+		name      string
+		from      domain.Status
+		run       func(*OrderService, uuid.UUID) (*domain.Order, error)
+		wantTo    domain.Status
+		eventType string
+	}{
+		{
+			name: "StartProcessing",
+			from: domain.StatusConfirmed,
+			run: func(s *OrderService, id uuid.UUID) (*domain.Order, error) {
+				return s.StartProcessing(context.Background(), id)
+			},
+			wantTo:    domain.StatusProcessing,
+			eventType: "order.processing",
+		},
+		{
+			name: "Ship",
+			from: domain.StatusProcessing,
+			run: func(s *OrderService, id uuid.UUID) (*domain.Order, error) {
+				return s.Ship(context.Background(), id, "DHL", "JD0001")
+			},
+			wantTo:    domain.StatusShipped,
+			eventType: "order.shipped",
+		},
+		{
+			name:      "Deliver",
+			from:      domain.StatusShipped,
+			run:       func(s *OrderService, id uuid.UUID) (*domain.Order, error) { return s.Deliver(context.Background(), id) },
+			wantTo:    domain.StatusDelivered,
+			eventType: "order.delivered",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("sqlmock.New() error = %v", err)
+			}
+			defer func() { _ = db.Close() }()
+
+			order := newTestOrder(tt.from)
+			repo := &fakeRepository{order: order}
+			sagaRepo := &fakeSagaRepository{}
+			svc := NewOrderService(repo, db, sagaRepo, &fakeInventoryReleaser{}, &fakePaymentRefunder{})
+
+			var payload map[string]any
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta("INSERT INTO outbox_messages")).
+				WithArgs(sqlmock.AnyArg(), "orders.events", tt.eventType, order.ID.String(), jsonArg{&payload}, nil).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+
+			got, err := tt.run(svc, order.ID)
+			if err != nil {
+				t.Fatalf("%s() error = %v", tt.name, err)
+			}
+			if got.Status != tt.wantTo || got.Version != 2 {
+				t.Errorf("%s() = status %v version %d, want %v version 2", tt.name, got.Status, got.Version, tt.wantTo)
+			}
+			if payload["status"] != string(tt.wantTo) {
+				t.Errorf("%s payload status = %v, want %v", tt.eventType, payload["status"], tt.wantTo)
+			}
+			if len(sagaRepo.transitionCalls) != 0 {
+				t.Errorf("saga transitions = %+v, want none", sagaRepo.transitionCalls)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unmet expectations: %v", err)
+			}
+
+			if tt.name == "Ship" {
+				if !reflect.DeepEqual(repo.shipmentCalls, []string{"DHL/JD0001"}) {
+					t.Errorf("shipment calls = %v, want [DHL/JD0001]", repo.shipmentCalls)
+				}
+				if payload["carrier"] != "DHL" || payload["tracking_number"] != "JD0001" {
+					t.Errorf("order.shipped payload = %v, want the carrier and tracking number", payload)
+				}
+			}
+		})
+	}
+
+	t.Run("rejects a transition from the wrong status", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("sqlmock.New() error = %v", err)
+		}
+		defer func() { _ = db.Close() }()
+
+		order := newTestOrder(domain.StatusDelivered)
+		repo := &fakeRepository{order: order}
+		svc := NewOrderService(repo, db, &fakeSagaRepository{}, &fakeInventoryReleaser{}, &fakePaymentRefunder{})
+
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		_, err = svc.Ship(context.Background(), order.ID, "DHL", "JD0001")
+		var appErr *apperrors.AppError
+		if !errors.As(err, &appErr) || appErr.Code != "ORDER_ALREADY_PROCESSED" {
+			t.Errorf("Ship() error = %v, want ORDER_ALREADY_PROCESSED", err)
+		}
+		if len(repo.updateCalls) != 0 || len(repo.shipmentCalls) != 0 {
+			t.Errorf("updates = %v, shipments = %v, want none", repo.updateCalls, repo.shipmentCalls)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet expectations: %v", err)
+		}
+	})
+}
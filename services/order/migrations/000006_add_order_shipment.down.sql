@@ -0,0 +1,3 @@
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS tracking_number,
+    DROP COLUMN IF EXISTS carrier;
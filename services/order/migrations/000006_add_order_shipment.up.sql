@@ -0,0 +1,4 @@
+-- Shipment details recorded when an order moves to shipped.
+ALTER TABLE orders
+    ADD COLUMN carrier VARCHAR(100),
+    ADD COLUMN tracking_number VARCHAR(100);
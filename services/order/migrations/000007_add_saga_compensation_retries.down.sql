@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_order_sagas_next_compensation_at;
+ALTER TABLE order_sagas
+    DROP COLUMN IF EXISTS next_compensation_at,
+    DROP COLUMN IF EXISTS compensation_attempts,
+    DROP COLUMN IF EXISTS compensation_reason;
@@ -0,0 +1,10 @@
+-- Lets the compensation recovery worker retry a saga stuck compensating with exponential backoff
+-- and give up after a maximum number of attempts. compensation_reason records why compensation
+-- started, so a retry cancels the order the way the first attempt would have.
+ALTER TABLE order_sagas
+    ADD COLUMN compensation_reason VARCHAR(100),
+    ADD COLUMN compensation_attempts INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN next_compensation_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_order_sagas_next_compensation_at ON order_sagas(next_compensation_at)
+    WHERE state = 'compensating';
@@ -0,0 +1,10 @@
+// Package migrations embeds the order service's SQL migrations, so the binary can apply them
+// itself with its migrate subcommand or on startup.
+package migrations
+
+import "embed"
+
+// FS holds the NNNNNN_name.up.sql and .down.sql files in this directory.
+//
+//go:embed *.sql
+var FS embed.FS
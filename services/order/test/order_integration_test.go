@@ -217,7 +217,7 @@ type fakeOrderService struct {
 	confirmed []uuid.UUID
 }
 
-func (f *fakeOrderService) ConfirmPayment(_ context.Context, _ *sql.Tx, orderID uuid.UUID) error {
+func (f *fakeOrderService) ConfirmPayment(_ context.Context, _ *sql.Tx, orderID, _ uuid.UUID) error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 	f.confirmed = append(f.confirmed, orderID)
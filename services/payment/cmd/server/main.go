@@ -2,9 +2,7 @@ package main
 
 import (
 	"context"
-	stderrors "errors"
 	"log"
-	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -18,9 +16,12 @@ import (
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/payment/internal/service"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/database"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/events"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/httpserver"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/lifecycle"
 	sharedlogger "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/logger"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/metrics"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/outbox"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/tlsconfig"
 	sharedtracing "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/tracing"
 	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
@@ -30,6 +31,13 @@ import (
 const paymentGatewayMaxAmountCents = 1_000_000
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+		return
+	}
+
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
@@ -41,11 +49,22 @@ func main() {
 		Service:     cfg.Service.Name,
 		Version:     cfg.Service.Version,
 		OutputPaths: cfg.Logger.OutputPaths,
+		Sampling: &sharedlogger.SamplingConfig{
+			Initial:    cfg.Logger.Sampling.Initial,
+			Thereafter: cfg.Logger.Sampling.Thereafter,
+			Tick:       cfg.Logger.Sampling.Tick,
+		},
+		Redaction: &sharedlogger.RedactionConfig{
+			Mode:    cfg.Logger.Redaction.Mode,
+			Fields:  cfg.Logger.Redaction.Fields,
+			HashKey: cfg.Logger.Redaction.HashKey.Value(),
+		},
 	})
 	if err != nil {
 		log.Fatalf("Failed to initialize logger: %v", err)
 	}
 	defer func() { _ = appLogger.Sync() }()
+	prometheus.MustRegister(metrics.NewLoggerMetrics(appLogger, cfg.Service.Name))
 
 	appLogger.Info("Starting payment service",
 		zap.String("host", cfg.Server.Host),
@@ -60,16 +79,30 @@ func main() {
 	if err != nil {
 		appLogger.Fatal("Failed to initialize tracing", zap.Error(err))
 	}
-	defer func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		if err := shutdownTracing(ctx); err != nil {
-			appLogger.Error("Failed to shut down tracing", zap.Error(err))
-		}
-	}()
+
+	lc := lifecycle.New(appLogger.Logger)
+	lc.MustRegister(lifecycle.Component{Name: "tracing", Stop: shutdownTracing, Timeout: 5 * time.Second})
+
+	// The admin listener starts right after tracing and so stops just before it: pprof and the
+	// log level stay reachable while the rest of the service shuts down.
+	var admin *httpserver.AdminServer
+	if cfg.Server.AdminPort != "" {
+		admin = httpserver.NewAdmin(httpserver.AdminOptions{
+			Addr:     cfg.Server.Host + ":" + cfg.Server.AdminPort,
+			Logger:   appLogger.Logger,
+			LogLevel: appLogger,
+			Service:  cfg.Service.Name,
+			Version:  cfg.Service.Version,
+		})
+		lc.MustRegister(lifecycle.Component{
+			Name: "admin-http",
+			Run:  func(context.Context) error { return admin.Start() },
+			Stop: admin.Stop,
+		})
+	}
 
 	db, err := database.NewPostgresConnection(database.PostgresConfig{
-		URL:          cfg.DatabaseURL,
+		URL:          cfg.DatabaseURL.Value(),
 		MaxOpenConns: cfg.DatabasePool.MaxOpenConns,
 		MaxIdleConns: cfg.DatabasePool.MaxIdleConns,
 		MaxLifetime:  cfg.DatabasePool.MaxLifetime,
@@ -77,13 +110,53 @@ func main() {
 	if err != nil {
 		appLogger.Fatal("Failed to connect to database", zap.Error(err))
 	}
-	defer func() { _ = db.Close() }()
+	lc.MustRegister(lifecycle.Component{Name: "database", Stop: lifecycle.Close(db)})
+	if cfg.Migrations.OnStartup {
+		if err := migrateOnStartup(db, cfg.Migrations.Timeout, appLogger.Logger); err != nil {
+			appLogger.Fatal("Failed to apply database migrations", zap.Error(err))
+		}
+	}
 	prometheus.MustRegister(metrics.NewDatabaseMetrics(db.DB, cfg.Service.Name))
+	db.SetQueryMetrics(database.NewQueryMetrics(prometheus.DefaultRegisterer))
+	database.SetTxMetrics(database.NewTxMetrics(prometheus.DefaultRegisterer))
+	db.SetSlowQueryLog(appLogger.Logger, cfg.DatabasePool.SlowQueryThreshold)
+	db.SetRoutingMetrics(database.NewRoutingMetrics(prometheus.DefaultRegisterer))
+	prometheus.MustRegister(database.NewPoolMetrics(db))
+	if cfg.DatabaseReplica.Enabled() {
+		// A missing replica is not fatal: every read simply stays on the primary.
+		if err := db.OpenReplica(database.ReplicaConfig{
+			URL:                  cfg.DatabaseReplica.URL.Value(),
+			MaxOpenConns:         cfg.DatabasePool.MaxOpenConns,
+			MaxIdleConns:         cfg.DatabasePool.MaxIdleConns,
+			MaxLifetime:          cfg.DatabasePool.MaxLifetime,
+			MaxLag:               cfg.DatabaseReplica.MaxLag,
+			LagCheckInterval:     cfg.DatabaseReplica.LagCheckInterval,
+			ReadYourWritesWindow: cfg.DatabaseReplica.ReadYourWritesWindow,
+		}); err != nil {
+			appLogger.Warn("Failed to connect to the database replica, reading from the primary", zap.Error(err))
+		} else {
+			lc.MustRegister(lifecycle.Component{
+				Name:      "database-replica",
+				DependsOn: []string{"database"},
+				Run: func(ctx context.Context) error {
+					db.MonitorReplica(ctx, appLogger.Logger)
+					return nil
+				},
+			})
+		}
+	}
+
+	serverTLS, err := tlsconfig.Load(cfg.Server.TLS, appLogger.Logger)
+	if err != nil {
+		appLogger.Fatal("Failed to load server TLS files", zap.Error(err))
+	}
 
 	srv := server.New(server.Options{
-		Config: cfg,
-		Logger: appLogger.Logger,
-		DB:     db,
+		Config:  cfg,
+		Logger:  appLogger.Logger,
+		DB:      db,
+		Startup: lc.StartupCheck,
+		TLS:     serverTLS,
 	})
 
 	kafkaMetrics := events.NewKafkaMetrics(prometheus.DefaultRegisterer)
@@ -92,54 +165,55 @@ func main() {
 	publisher.SetMetrics(kafkaMetrics)
 	relay := outbox.NewRelay(db.DB, publisher, appLogger.Logger, cfg.Outbox.RelayInterval, cfg.Outbox.RelayBatchSize)
 	relay.SetMetrics(kafkaMetrics)
-	relay.Start(context.Background())
+	lc.MustRegister(lifecycle.Component{Name: "kafka-publisher", Stop: lifecycle.Close(publisher)})
+	lc.MustRegister(lifecycle.Component{
+		Name:      "outbox-relay",
+		DependsOn: []string{"database", "kafka-publisher"},
+		Start: func(context.Context) error {
+			relay.Start(context.Background())
+			return nil
+		},
+		// Payment events the orders consumer enqueued while draining go out before the publisher
+		// closes.
+		Stop: func(ctx context.Context) error {
+			relay.Stop()
+			return relay.Flush(ctx)
+		},
+	})
 
 	paymentGateway := gateway.NewStubClient(gateway.Config{MaxAmountCents: paymentGatewayMaxAmountCents})
-	paymentService := service.NewPaymentService(eventstore.NewRepository(db.DB), paymentGateway)
+	paymentRepo := eventstore.NewRepository(db.DB)
+	paymentRepo.SetReadRouter(db)
+	paymentService := service.NewPaymentService(paymentRepo, paymentGateway)
 	processedStore := events.NewProcessedStore(db.DB)
 	ordersSubscriber := events.NewSubscriber(events.KafkaConfig{
-		Brokers:  cfg.Kafka.Brokers,
-		GroupID:  cfg.Kafka.GroupID,
-		DLQTopic: events.DLQTopic(events.OrdersTopic),
+		Brokers:     cfg.Kafka.Brokers,
+		GroupID:     cfg.Kafka.GroupID,
+		DLQTopic:    events.DLQTopic(events.OrdersTopic),
+		LogPayloads: cfg.Kafka.LogPayloads,
 	}, events.OrdersTopic, appLogger.Logger)
 	ordersSubscriber.SetMetrics(kafkaMetrics)
 	ordersConsumer := consumer.NewOrdersConsumer(ordersSubscriber, db.DB, processedStore, paymentService, appLogger.Logger)
 
-	consumerCtx, stopConsumer := context.WithCancel(context.Background())
-	go func() {
-		if err := ordersConsumer.Start(consumerCtx); err != nil && !stderrors.Is(err, context.Canceled) {
-			appLogger.Error("orders consumer stopped", zap.Error(err))
-		}
-	}()
-
-	done := make(chan os.Signal, 1)
-	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	go func() {
-		if err := srv.Start(); err != nil && err != http.ErrServerClosed {
-			appLogger.Fatal("Server failed to start", zap.Error(err))
-		}
-	}()
-
-	<-done
-	appLogger.Info("Payment service is shutting down...")
-
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	if err := srv.Stop(ctx); err != nil {
-		appLogger.Error("Server forced to shutdown", zap.Error(err))
-	} else {
-		appLogger.Info("Payment service stopped gracefully")
-	}
+	lc.MustRegister(lifecycle.Component{Name: "orders-subscriber", Stop: lifecycle.Close(ordersSubscriber)})
+	lc.MustRegister(lifecycle.Component{
+		Name:      "orders-consumer",
+		DependsOn: []string{"database", "outbox-relay", "orders-subscriber"},
+		Run:       ordersConsumer.Start,
+	})
+	lc.MustRegister(lifecycle.Component{
+		Name:      "http",
+		DependsOn: []string{"database", "outbox-relay"},
+		Run:       func(context.Context) error { return srv.Start() },
+		Stop:      srv.Stop,
+		Timeout:   20 * time.Second,
+	})
 
-	stopConsumer()
-	if err := ordersSubscriber.Close(); err != nil {
-		appLogger.Error("Failed to close orders subscriber", zap.Error(err))
-	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	relay.Stop()
-	if err := publisher.Close(); err != nil {
-		appLogger.Error("Failed to close kafka publisher", zap.Error(err))
+	if err := lc.Run(ctx); err != nil {
+		appLogger.Fatal("Payment service stopped with errors", zap.Error(err))
 	}
+	appLogger.Info("Payment service stopped gracefully")
 }
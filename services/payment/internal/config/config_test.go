@@ -136,8 +136,8 @@ func TestLoadConfig(t *testing.T) {
 				if cfg.Logger.Level != "info" {
 					t.Errorf("LoadConfig() Logger.Level = %v, want info", cfg.Logger.Level)
 				}
-				if cfg.DatabaseURL != tt.envVars["PAYMENT_DATABASE_URL"] {
-					t.Errorf("LoadConfig() DatabaseURL = %v, want %v", cfg.DatabaseURL, tt.envVars["PAYMENT_DATABASE_URL"])
+				if cfg.DatabaseURL.Value() != tt.envVars["PAYMENT_DATABASE_URL"] {
+					t.Errorf("LoadConfig() DatabaseURL = %v, want %v", cfg.DatabaseURL.Value(), tt.envVars["PAYMENT_DATABASE_URL"])
 				}
 				if cfg.DatabasePool.MaxOpenConns != 25 {
 					t.Errorf("LoadConfig() DatabasePool.MaxOpenConns = %v, want 25", cfg.DatabasePool.MaxOpenConns)
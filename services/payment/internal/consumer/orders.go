@@ -8,6 +8,7 @@ import (
 	"fmt"
 
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/payment/internal/domain"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/database"
 	apperrors "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/errors"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/events"
 	"github.com/google/uuid"
@@ -81,20 +82,10 @@ func (c *OrdersConsumer) handle(ctx context.Context, event events.Event) error {
 		}
 	}
 
-	tx, err := c.db.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("begin transaction: %w", err)
-	}
-	defer func() { _ = tx.Rollback() }()
-
-	if _, err := c.processed.MarkProcessed(ctx, tx, event.ID, event.Type); err != nil {
+	return database.WithTx(ctx, c.db, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := c.processed.MarkProcessed(ctx, tx, event.ID, event.Type)
 		return err
-	}
-
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("commit transaction: %w", err)
-	}
-	return nil
+	})
 }
 
 // paymentRequest is the data an order.ready_for_payment event carries to charge a payment.
@@ -8,6 +8,7 @@ import (
 
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/payment/internal/domain"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/payment/internal/projection"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/database"
 	apperrors "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/errors"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/events"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/outbox"
@@ -17,6 +18,7 @@ import (
 // Repository rebuilds and persists payment aggregates through their event history and snapshots.
 type Repository struct {
 	db        *sql.DB
+	reads     database.ReadRouter
 	events    *Store
 	snapshots *SnapshotStore
 	status    *projection.PaymentStatus
@@ -34,6 +36,13 @@ func NewRepository(db *sql.DB) *Repository {
 	}
 }
 
+// SetReadRouter has Save note its writes with router once they commit, keeping reads of the
+// payment_status rows it touched on the primary for the read-your-writes window. Passing nil stops
+// noting them.
+func (r *Repository) SetReadRouter(router database.ReadRouter) {
+	r.reads = router
+}
+
 // FindByOrderID rebuilds the payment aggregate initiated for orderID, or returns nil if none exists.
 func (r *Repository) FindByOrderID(ctx context.Context, orderID uuid.UUID) (*domain.Payment, error) {
 	aggregateID, err := r.events.FindByOrderID(ctx, orderID)
@@ -84,45 +93,49 @@ func (r *Repository) Save(ctx context.Context, payment *domain.Payment) error {
 	}
 	expectedVersion := payment.Version - len(newEvents)
 
-	tx, err := r.db.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("begin transaction: %w", err)
-	}
-	defer func() { _ = tx.Rollback() }()
-
-	if err := r.events.Append(ctx, tx, payment.ID, expectedVersion, newEvents); err != nil {
-		return err
-	}
-
-	for _, event := range newEvents {
-		if err := r.status.Apply(ctx, tx, payment, event); err != nil {
+	err := database.WithTx(ctx, r.db, func(ctx context.Context, tx *sql.Tx) error {
+		if err := r.events.Append(ctx, tx, payment.ID, expectedVersion, newEvents); err != nil {
 			return err
 		}
-	}
 
-	for _, event := range newEvents {
-		payload, err := newOutboxPayload(payment, event)
-		if err != nil {
-			return err
+		for _, event := range newEvents {
+			if err := r.status.Apply(ctx, tx, payment, event); err != nil {
+				return err
+			}
 		}
-		if err := r.outbox.Enqueue(ctx, tx, outbox.Message{
-			Topic:       events.PaymentsTopic,
-			EventType:   event.EventType(),
-			AggregateID: payment.ID.String(),
-			Payload:     payload,
-		}); err != nil {
-			return err
+
+		for _, event := range newEvents {
+			payload, err := newOutboxPayload(payment, event)
+			if err != nil {
+				return err
+			}
+			if err := r.outbox.Enqueue(ctx, tx, outbox.Message{
+				Topic:       events.PaymentsTopic,
+				EventType:   event.EventType(),
+				AggregateID: payment.ID.String(),
+				Payload:     payload,
+			}); err != nil {
+				return err
+			}
 		}
-	}
 
-	if payment.Version%SnapshotThreshold == 0 {
-		if err := r.snapshots.SaveSnapshot(ctx, tx, payment); err != nil {
-			return err
+		if payment.Version%SnapshotThreshold == 0 {
+			if err := r.snapshots.SaveSnapshot(ctx, tx, payment); err != nil {
+				return err
+			}
 		}
-	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("commit transaction: %w", err)
+		if r.reads != nil {
+			paymentKey, orderKey := projection.PaymentReadKey(payment.ID), projection.OrderReadKey(payment.OrderID)
+			database.AfterCommit(ctx, func() {
+				r.reads.NoteWrite(paymentKey)
+				r.reads.NoteWrite(orderKey)
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 	payment.ClearPendingEvents()
 	return nil
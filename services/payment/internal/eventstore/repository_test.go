@@ -5,15 +5,32 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"reflect"
 	"testing"
 
 	sqlmock "github.com/DATA-DOG/go-sqlmock"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/payment/internal/domain"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/payment/internal/projection"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/database"
 	apperrors "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/errors"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/events"
 	"github.com/google/uuid"
 )
 
+// fakeReadRouter sends every read to reader and records the keys noted as written.
+type fakeReadRouter struct {
+	reader  *sql.DB
+	written []string
+}
+
+func (r *fakeReadRouter) Reader(_ context.Context, _ string) database.Queryer {
+	return r.reader
+}
+
+func (r *fakeReadRouter) NoteWrite(key string) {
+	r.written = append(r.written, key)
+}
+
 // buildHistory returns a payment's aggregate id and the three events that take it from
 // initiated through completed to refunded.
 func buildHistory(t *testing.T) (uuid.UUID, []domain.Event) {
@@ -222,6 +239,49 @@ func TestRepository_Save(t *testing.T) {
 		}
 	})
 
+	t.Run("notes its writes once the caller's transaction commits", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("sqlmock.New: %v", err)
+		}
+		defer func() { _ = db.Close() }()
+
+		payment, err := domain.Initiate(uuid.New(), uuid.New(), 100, "USD")
+		if err != nil {
+			t.Fatalf("Initiate: %v", err)
+		}
+
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO payment_events").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT INTO payment_status").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT INTO outbox_messages").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		router := &fakeReadRouter{}
+		repo := NewRepository(db)
+		repo.SetReadRouter(router)
+		// Save joins the transaction the caller already opened, as the orders consumer does.
+		err = database.WithTx(context.Background(), db, func(ctx context.Context, _ *sql.Tx) error {
+			if err := repo.Save(ctx, payment); err != nil {
+				return err
+			}
+			if len(router.written) != 0 {
+				t.Errorf("Save() noted writes %v before the commit, want none", router.written)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("WithTx() error = %v", err)
+		}
+		want := []string{projection.PaymentReadKey(payment.ID), projection.OrderReadKey(payment.OrderID)}
+		if !reflect.DeepEqual(router.written, want) {
+			t.Errorf("Save() noted writes %v, want %v", router.written, want)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet expectations: %v", err)
+		}
+	})
+
 	t.Run("writes one outbox row per pending event", func(t *testing.T) {
 		db, mock, err := sqlmock.New()
 		if err != nil {
@@ -5,13 +5,13 @@ import (
 	"context"
 	"encoding/json"
 	stderrors "errors"
-	"io"
 	"net/http"
 	"time"
 
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/payment/internal/domain"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/payment/internal/repository"
 	apperrors "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/errors"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/validation"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
@@ -49,9 +49,9 @@ func NewPaymentsHandler(service PaymentProcessor, statusReader PaymentStatusRead
 }
 
 type processPaymentRequest struct {
-	OrderID     string `json:"order_id"`
-	AmountCents int64  `json:"amount_cents"`
-	Currency    string `json:"currency"`
+	OrderID     string `json:"order_id" validate:"required,uuid"`
+	AmountCents int64  `json:"amount_cents" validate:"gt=0"`
+	Currency    string `json:"currency" validate:"len=3"`
 }
 
 type paymentResponse struct {
@@ -80,28 +80,24 @@ func newPaymentResponse(p *domain.Payment) paymentResponse {
 func (h *PaymentsHandler) Process(w http.ResponseWriter, r *http.Request) {
 	customerID, err := customerIDFromHeader(r)
 	if err != nil {
-		h.writeError(w, err)
+		h.writeError(w, r, err)
 		return
 	}
 
 	var req processPaymentRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeError(w, apperrors.NewBadRequest("invalid request body"))
+	if err := validation.DecodeJSON(w, r, &req); err != nil {
+		h.writeError(w, r, err)
 		return
 	}
 
-	orderID, err := uuid.Parse(req.OrderID)
-	if err != nil {
-		h.writeError(w, apperrors.NewValidationError("order_id", "must be a valid uuid"))
-		return
-	}
+	orderID := uuid.MustParse(req.OrderID)
 	if req.Currency == "" {
 		req.Currency = "USD"
 	}
 
 	payment, err := h.service.ProcessPayment(r.Context(), orderID, customerID, req.AmountCents, req.Currency)
 	if err != nil {
-		h.writeError(w, err)
+		h.writeError(w, r, err)
 		return
 	}
 
@@ -116,13 +112,13 @@ type refundPaymentRequest struct {
 func (h *PaymentsHandler) Refund(w http.ResponseWriter, r *http.Request) {
 	id, err := uuid.Parse(r.PathValue("id"))
 	if err != nil {
-		h.writeError(w, apperrors.NewBadRequest("invalid payment id"))
+		h.writeError(w, r, apperrors.NewBadRequest("invalid payment id"))
 		return
 	}
 
 	var req refundPaymentRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !stderrors.Is(err, io.EOF) {
-		h.writeError(w, apperrors.NewBadRequest("invalid request body"))
+	if err := validation.Decode(w, r, &req, validation.DecodeOptions{AllowEmpty: true}); err != nil {
+		h.writeError(w, r, err)
 		return
 	}
 	if req.Reason == "" {
@@ -131,7 +127,7 @@ func (h *PaymentsHandler) Refund(w http.ResponseWriter, r *http.Request) {
 
 	payment, err := h.service.RefundPayment(r.Context(), id, req.Reason)
 	if err != nil {
-		h.writeError(w, err)
+		h.writeError(w, r, err)
 		return
 	}
 
@@ -172,13 +168,13 @@ func newPaymentStatusResponse(s *repository.PaymentStatus) paymentStatusResponse
 func (h *PaymentsHandler) Get(w http.ResponseWriter, r *http.Request) {
 	id, err := uuid.Parse(r.PathValue("id"))
 	if err != nil {
-		h.writeError(w, apperrors.NewBadRequest("invalid payment id"))
+		h.writeError(w, r, apperrors.NewBadRequest("invalid payment id"))
 		return
 	}
 
 	status, err := h.statusReader.GetByID(r.Context(), id)
 	if err != nil {
-		h.writeError(w, err)
+		h.writeError(w, r, err)
 		return
 	}
 
@@ -193,19 +189,19 @@ type listPaymentsResponse struct {
 func (h *PaymentsHandler) List(w http.ResponseWriter, r *http.Request) {
 	raw := r.URL.Query().Get("order_id")
 	if raw == "" {
-		h.writeError(w, apperrors.NewValidationError("order_id", "is required"))
+		h.writeError(w, r, apperrors.NewValidationError("order_id", "is required"))
 		return
 	}
 
 	orderID, err := uuid.Parse(raw)
 	if err != nil {
-		h.writeError(w, apperrors.NewValidationError("order_id", "must be a valid uuid"))
+		h.writeError(w, r, apperrors.NewValidationError("order_id", "must be a valid uuid"))
 		return
 	}
 
 	statuses, err := h.statusReader.ListByOrderID(r.Context(), orderID)
 	if err != nil {
-		h.writeError(w, err)
+		h.writeError(w, r, err)
 		return
 	}
 
@@ -230,17 +226,17 @@ type listPaymentEventsResponse struct {
 func (h *PaymentsHandler) Events(w http.ResponseWriter, r *http.Request) {
 	id, err := uuid.Parse(r.PathValue("id"))
 	if err != nil {
-		h.writeError(w, apperrors.NewBadRequest("invalid payment id"))
+		h.writeError(w, r, apperrors.NewBadRequest("invalid payment id"))
 		return
 	}
 
 	events, err := h.events.Load(r.Context(), id, 0)
 	if err != nil {
-		h.writeError(w, err)
+		h.writeError(w, r, err)
 		return
 	}
 	if len(events) == 0 {
-		h.writeError(w, apperrors.NewNotFound("payment"))
+		h.writeError(w, r, apperrors.NewNotFound("payment"))
 		return
 	}
 
@@ -248,7 +244,7 @@ func (h *PaymentsHandler) Events(w http.ResponseWriter, r *http.Request) {
 	for i, event := range events {
 		data, err := domain.MarshalEvent(event)
 		if err != nil {
-			h.writeError(w, err)
+			h.writeError(w, r, err)
 			return
 		}
 		responses[i] = paymentEventResponse{Type: event.EventType(), Data: data}
@@ -278,11 +274,13 @@ func (h *PaymentsHandler) writeJSON(w http.ResponseWriter, status int, body any)
 	}
 }
 
-func (h *PaymentsHandler) writeError(w http.ResponseWriter, err error) {
+func (h *PaymentsHandler) writeError(w http.ResponseWriter, r *http.Request, err error) {
 	var appErr *apperrors.AppError
 	if !stderrors.As(err, &appErr) {
 		h.logger.Error("unexpected error", zap.Error(err))
 		appErr = apperrors.NewInternalServerError("internal server error")
 	}
-	h.writeJSON(w, appErr.HTTPCode, appErr)
+	if err := apperrors.WriteProblem(w, r, appErr); err != nil {
+		h.logger.Error("failed to encode error response", zap.Error(err))
+	}
 }
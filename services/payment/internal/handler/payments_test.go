@@ -106,13 +106,18 @@ func newTestMuxWithReaders(t *testing.T, processor PaymentProcessor, statusReade
 	return mux
 }
 
-func decodeAppError(t *testing.T, body []byte) apperrors.AppError {
+// decodeProblem decodes the RFC 7807 problem in w, failing the test unless it was served as
+// application/problem+json.
+func decodeProblem(t *testing.T, w *httptest.ResponseRecorder) apperrors.Problem {
 	t.Helper()
-	var appErr apperrors.AppError
-	if err := json.Unmarshal(body, &appErr); err != nil {
+	if got := w.Header().Get("Content-Type"); got != apperrors.ProblemContentType {
+		t.Errorf("Content-Type = %q, want %q", got, apperrors.ProblemContentType)
+	}
+	var problem apperrors.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
 		t.Fatalf("failed to decode error body: %v", err)
 	}
-	return appErr
+	return problem
 }
 
 func TestPaymentsHandler_Process(t *testing.T) {
@@ -175,6 +180,14 @@ func TestPaymentsHandler_Process(t *testing.T) {
 			wantStatus: http.StatusBadRequest,
 			wantCode:   "VALIDATION_ERROR",
 		},
+		{
+			name:       "non-positive amount",
+			userID:     customerID.String(),
+			body:       `{"order_id":"` + uuid.New().String() + `","amount_cents":0,"currency":"USD"}`,
+			processor:  &fakePaymentProcessor{},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "VALIDATION_ERROR",
+		},
 		{
 			name:       "declined by the gateway",
 			userID:     customerID.String(),
@@ -226,9 +239,9 @@ func TestPaymentsHandler_Process(t *testing.T) {
 				return
 			}
 
-			appErr := decodeAppError(t, w.Body.Bytes())
-			if appErr.Code != tt.wantCode {
-				t.Errorf("Code = %v, want %v", appErr.Code, tt.wantCode)
+			problem := decodeProblem(t, w)
+			if problem.Code != tt.wantCode {
+				t.Errorf("Code = %v, want %v", problem.Code, tt.wantCode)
 			}
 		})
 	}
@@ -336,9 +349,9 @@ func TestPaymentsHandler_Refund(t *testing.T) {
 				return
 			}
 
-			appErr := decodeAppError(t, w.Body.Bytes())
-			if appErr.Code != tt.wantCode {
-				t.Errorf("Code = %v, want %v", appErr.Code, tt.wantCode)
+			problem := decodeProblem(t, w)
+			if problem.Code != tt.wantCode {
+				t.Errorf("Code = %v, want %v", problem.Code, tt.wantCode)
 			}
 		})
 	}
@@ -405,9 +418,9 @@ func TestPaymentsHandler_Get(t *testing.T) {
 				return
 			}
 
-			appErr := decodeAppError(t, w.Body.Bytes())
-			if appErr.Code != tt.wantCode {
-				t.Errorf("Code = %v, want %v", appErr.Code, tt.wantCode)
+			problem := decodeProblem(t, w)
+			if problem.Code != tt.wantCode {
+				t.Errorf("Code = %v, want %v", problem.Code, tt.wantCode)
 			}
 		})
 	}
@@ -479,9 +492,9 @@ func TestPaymentsHandler_List(t *testing.T) {
 				return
 			}
 
-			appErr := decodeAppError(t, w.Body.Bytes())
-			if appErr.Code != tt.wantCode {
-				t.Errorf("Code = %v, want %v", appErr.Code, tt.wantCode)
+			problem := decodeProblem(t, w)
+			if problem.Code != tt.wantCode {
+				t.Errorf("Code = %v, want %v", problem.Code, tt.wantCode)
 			}
 		})
 	}
@@ -559,9 +572,9 @@ func TestPaymentsHandler_Events(t *testing.T) {
 				return
 			}
 
-			appErr := decodeAppError(t, w.Body.Bytes())
-			if appErr.Code != tt.wantCode {
-				t.Errorf("Code = %v, want %v", appErr.Code, tt.wantCode)
+			problem := decodeProblem(t, w)
+			if problem.Code != tt.wantCode {
+				t.Errorf("Code = %v, want %v", problem.Code, tt.wantCode)
 			}
 		})
 	}
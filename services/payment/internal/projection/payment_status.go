@@ -8,8 +8,21 @@ import (
 	"time"
 
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/payment/internal/domain"
+	"github.com/google/uuid"
 )
 
+// PaymentReadKey is the read-your-writes key for the payment_status row of payment id. The event
+// store notes it, along with OrderReadKey, whenever it writes the row, so a payment read right
+// after it is charged or refunded is not served from a replica that has yet to see it.
+func PaymentReadKey(id uuid.UUID) string {
+	return "payment:" + id.String()
+}
+
+// OrderReadKey is the read-your-writes key for the payments of orderID.
+func OrderReadKey(orderID uuid.UUID) string {
+	return "order:" + orderID.String()
+}
+
 // PaymentStatus upserts payment_status rows from payment domain events. It is meant to run inside the
 // same transaction as the event store write, so the read model never lags behind the event stream.
 type PaymentStatus struct{}
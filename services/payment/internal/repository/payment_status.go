@@ -8,6 +8,8 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/services/payment/internal/projection"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/database"
 	apperrors "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/errors"
 	"github.com/google/uuid"
 )
@@ -32,7 +34,8 @@ type PaymentStatus struct {
 
 // PaymentStatusRepository reads payment_status rows.
 type PaymentStatusRepository struct {
-	db *sql.DB
+	db    *sql.DB
+	reads database.ReadRouter
 }
 
 // NewPaymentStatusRepository builds a repository backed by the given database handle.
@@ -40,9 +43,23 @@ func NewPaymentStatusRepository(db *sql.DB) *PaymentStatusRepository {
 	return &PaymentStatusRepository{db: db}
 }
 
+// SetReadRouter lets every read go to a replica through router, except for a payment or order
+// written within the read-your-writes window. Passing nil reads from the primary.
+func (r *PaymentStatusRepository) SetReadRouter(router database.ReadRouter) {
+	r.reads = router
+}
+
+// reader returns where to read on behalf of key.
+func (r *PaymentStatusRepository) reader(ctx context.Context, key string) database.Queryer {
+	if r.reads == nil {
+		return r.db
+	}
+	return r.reads.Reader(ctx, key)
+}
+
 // GetByID returns the payment_status row for id.
 func (r *PaymentStatusRepository) GetByID(ctx context.Context, id uuid.UUID) (*PaymentStatus, error) {
-	status, err := r.scan(r.db.QueryRowContext(ctx, `
+	status, err := r.scan(r.reader(ctx, projection.PaymentReadKey(id)).QueryRowContext(ctx, `
 		SELECT `+paymentStatusColumns+` FROM payment_status WHERE id = $1
 	`, id))
 	if stderrors.Is(err, sql.ErrNoRows) {
@@ -56,7 +73,7 @@ func (r *PaymentStatusRepository) GetByID(ctx context.Context, id uuid.UUID) (*P
 
 // ListByOrderID returns every payment recorded for orderID, most recent first.
 func (r *PaymentStatusRepository) ListByOrderID(ctx context.Context, orderID uuid.UUID) ([]*PaymentStatus, error) {
-	rows, err := r.db.QueryContext(ctx, `
+	rows, err := r.reader(ctx, projection.OrderReadKey(orderID)).QueryContext(ctx, `
 		SELECT `+paymentStatusColumns+` FROM payment_status WHERE order_id = $1
 		ORDER BY created_at DESC
 	`, orderID)
@@ -17,6 +17,7 @@ import (
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/httpserver"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/metrics"
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/middleware"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/tlsconfig"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
@@ -37,6 +38,11 @@ type Options struct {
 	Logger  *zap.Logger
 	Metrics prometheus.Registerer
 	DB      *database.DB
+	// Startup is optional: when set, /health/startup reports it, typically the lifecycle
+	// manager's StartupCheck.
+	Startup httpserver.Check
+	// TLS is optional: when set, the server serves HTTPS with it (see httpserver.Options.TLS).
+	TLS *tlsconfig.Reloader
 }
 
 // New builds the payment HTTP server: health checks, metrics and the shared middleware chain.
@@ -56,13 +62,17 @@ func New(opts Options) *Server {
 	}
 
 	mux := http.NewServeMux()
-	httpserver.NewHealthHandlers(opts.Config.Service.Name, checks).Register(mux)
+	health := httpserver.NewHealthHandlers(opts.Config.Service.Name, checks)
+	health.SetStartupCheck(opts.Startup)
+	health.Register(mux)
 
 	if opts.DB != nil {
 		repo := eventstore.NewRepository(opts.DB.DB)
+		repo.SetReadRouter(opts.DB)
 		gatewayClient := gateway.NewStubClient(gateway.Config{MaxAmountCents: paymentGatewayMaxAmountCents})
 		paymentService := service.NewPaymentService(repo, gatewayClient)
 		statusReader := repository.NewPaymentStatusRepository(opts.DB.DB)
+		statusReader.SetReadRouter(opts.DB)
 		eventReader := eventstore.NewStore(opts.DB.DB)
 
 		paymentsHandler := handler.NewPaymentsHandler(paymentService, statusReader, eventReader, opts.Logger)
@@ -97,6 +107,7 @@ func New(opts Options) *Server {
 		Addr:    opts.Config.Server.Host + ":" + opts.Config.Server.Port,
 		Handler: outer,
 		Logger:  opts.Logger,
+		TLS:     opts.TLS,
 	})
 
 	return &Server{runtime: runtime}
@@ -76,13 +76,17 @@ func (s *PaymentService) ProcessPayment(ctx context.Context, orderID, customerID
 	return payment, nil
 }
 
-// RefundPayment reverses a completed payment. It fails with a conflict when the payment is not
-// currently completed.
+// RefundPayment reverses a completed payment. A payment that was already refunded is returned as
+// it is, so a compensation retried after an earlier refund landed succeeds without refunding
+// twice. It fails with a conflict when the payment is in any other status.
 func (s *PaymentService) RefundPayment(ctx context.Context, id uuid.UUID, reason string) (*domain.Payment, error) {
 	payment, err := s.repo.Load(ctx, id)
 	if err != nil {
 		return nil, err
 	}
+	if payment.Status == domain.StatusRefunded {
+		return payment, nil
+	}
 
 	if err := payment.Refund(reason); err != nil {
 		return nil, err
@@ -243,6 +243,33 @@ func TestPaymentService_RefundPayment(t *testing.T) {
 		}
 	})
 
+	t.Run("returns an already refunded payment without refunding it again", func(t *testing.T) {
+		repo := newFakeRepository()
+		payment, err := domain.Initiate(uuid.New(), uuid.New(), 4999, "USD")
+		if err != nil {
+			t.Fatalf("Initiate: %v", err)
+		}
+		if err := payment.Process("txn_1"); err != nil {
+			t.Fatalf("Process: %v", err)
+		}
+		if err := payment.Refund("order_cancelled"); err != nil {
+			t.Fatalf("Refund: %v", err)
+		}
+		if err := repo.Save(context.Background(), payment); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+		repo.saveErr = errTestRepository
+
+		svc := NewPaymentService(repo, &fakeGateway{})
+		got, err := svc.RefundPayment(context.Background(), payment.ID, "order_cancelled")
+		if err != nil {
+			t.Fatalf("RefundPayment() error = %v", err)
+		}
+		if got.Status != domain.StatusRefunded || got.Version != payment.Version {
+			t.Errorf("RefundPayment() = status %v version %d, want refunded version %d", got.Status, got.Version, payment.Version)
+		}
+	})
+
 	t.Run("returns a conflict when the payment is not completed", func(t *testing.T) {
 		repo := newFakeRepository()
 		payment, err := domain.Initiate(uuid.New(), uuid.New(), 4999, "USD")
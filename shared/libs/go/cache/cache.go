@@ -1,4 +1,5 @@
-// Package cache implements a cache-aside helper over Redis, encoding values as JSON.
+// Package cache implements a cache-aside helper over Redis, encoding values as JSON, with an
+// optional in-process tier in front of it.
 package cache
 
 import (
@@ -6,6 +7,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math/rand/v2"
 	"time"
 
 	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/database"
@@ -26,6 +28,11 @@ type Cache struct {
 
 	metrics *Metrics
 	name    string
+
+	local   *localTier
+	flights flightGroup
+	now     func() time.Time
+	random  func() float64
 }
 
 // New builds a Cache backed by client. defaultTTL is used by SetJSON whenever it is called with
@@ -34,19 +41,33 @@ func New(client *database.RedisClient, defaultTTL time.Duration) *Cache {
 	if defaultTTL <= 0 {
 		defaultTTL = DefaultTTL
 	}
-	return &Cache{client: client, defaultTTL: defaultTTL}
+	return &Cache{
+		client:     client,
+		defaultTTL: defaultTTL,
+		now:        time.Now,
+		random:     rand.Float64,
+	}
 }
 
-// SetMetrics attaches m so GetJSON reads are recorded as hits or misses under name. Passing nil
+// SetMetrics attaches m so GetJSON and GetOrLoad reads are recorded under name. Passing nil
 // disables metrics.
 func (c *Cache) SetMetrics(m *Metrics, name string) {
 	c.metrics = m
 	c.name = name
 }
 
-// GetJSON reads key and unmarshals it into dest. A missing key is not an error: it reports a
-// miss (false, nil) and leaves dest untouched.
+// GetJSON reads key and unmarshals it into dest, trying the local tier before Redis when it is
+// enabled. A missing key is not an error: it reports a miss (false, nil) and leaves dest
+// untouched.
 func (c *Cache) GetJSON(ctx context.Context, key string, dest any) (bool, error) {
+	if raw, ok := c.getLocal(key); ok {
+		if err := json.Unmarshal(raw, dest); err != nil {
+			return false, fmt.Errorf("unmarshal cache key %s: %w", key, err)
+		}
+		c.observeHit()
+		return true, nil
+	}
+
 	raw, err := c.client.Get(ctx, key).Bytes()
 	if errors.Is(err, redis.Nil) {
 		c.observeMiss()
@@ -59,6 +80,7 @@ func (c *Cache) GetJSON(ctx context.Context, key string, dest any) (bool, error)
 	if err := json.Unmarshal(raw, dest); err != nil {
 		return false, fmt.Errorf("unmarshal cache key %s: %w", key, err)
 	}
+	c.setLocal(key, raw, 0)
 	c.observeHit()
 	return true, nil
 }
@@ -75,9 +97,35 @@ func (c *Cache) observeMiss() {
 	}
 }
 
+func (c *Cache) observeLocalHit() {
+	if c.metrics != nil {
+		c.metrics.ObserveLocalHit(c.name)
+	}
+}
+
+func (c *Cache) observeError() {
+	if c.metrics != nil {
+		c.metrics.ObserveError(c.name)
+	}
+}
+
+func (c *Cache) observeStale() {
+	if c.metrics != nil {
+		c.metrics.ObserveStale(c.name)
+	}
+}
+
+func (c *Cache) observeCoalesced() {
+	if c.metrics != nil {
+		c.metrics.ObserveCoalesced(c.name)
+	}
+}
+
 // SetJSON marshals value as JSON and stores it under key with ttl, or the cache's default TTL
-// when ttl <= 0.
-func (c *Cache) SetJSON(ctx context.Context, key string, value any, ttl time.Duration) error {
+// when ttl <= 0. The key is recorded under each of tags so InvalidateTag can delete it later. With
+// the local tier enabled, the new value is kept locally and the other replicas are told to evict
+// their copy.
+func (c *Cache) SetJSON(ctx context.Context, key string, value any, ttl time.Duration, tags ...string) error {
 	if ttl <= 0 {
 		ttl = c.defaultTTL
 	}
@@ -87,44 +135,88 @@ func (c *Cache) SetJSON(ctx context.Context, key string, value any, ttl time.Dur
 		return fmt.Errorf("marshal cache key %s: %w", key, err)
 	}
 
-	if err := c.client.Set(ctx, key, data, ttl).Err(); err != nil {
+	if err := c.store(ctx, key, data, ttl, tags); err != nil {
+		c.deleteLocal(key)
 		return fmt.Errorf("set cache key %s: %w", key, err)
 	}
-	return nil
+	c.setLocal(key, data, ttl)
+	return c.broadcast(ctx, invalidation{Keys: []string{key}})
 }
 
-// Delete removes keys. Deleting keys that do not exist is not an error.
+// store writes data under key and records it under tags in one transaction, so a tagged key is
+// never left untracked by its tags. In Cluster mode the key and each tag set usually hash to
+// different slots, and the transaction is split into one per slot.
+func (c *Cache) store(ctx context.Context, key string, data []byte, ttl time.Duration, tags []string) error {
+	if len(tags) == 0 {
+		return c.client.Set(ctx, key, data, ttl).Err()
+	}
+
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, key, data, ttl)
+		tagQueue(ctx, pipe, key, ttl, tags)
+		return nil
+	})
+	return err
+}
+
+// Delete removes keys from Redis and from the local tier of every replica. Deleting keys that do
+// not exist is not an error.
 func (c *Cache) Delete(ctx context.Context, keys ...string) error {
 	if len(keys) == 0 {
 		return nil
 	}
 
-	if err := c.client.Del(ctx, keys...).Err(); err != nil {
+	err := deleteKeys(ctx, c.client, keys)
+	c.deleteLocal(keys...)
+	if err != nil {
 		return fmt.Errorf("delete cache keys %v: %w", keys, err)
 	}
-	return nil
+	return c.broadcast(ctx, invalidation{Keys: keys})
 }
 
-// DeleteByPrefix removes every key starting with prefix, scanning the keyspace in batches
-// instead of blocking Redis with KEYS.
-func (c *Cache) DeleteByPrefix(ctx context.Context, prefix string) error {
-	pattern := prefix + "*"
-
-	var cursor uint64
-	for {
-		keys, next, err := c.client.Scan(ctx, cursor, pattern, scanBatchSize).Result()
-		if err != nil {
-			return fmt.Errorf("scan cache keys %s: %w", pattern, err)
-		}
-
-		if err := c.Delete(ctx, keys...); err != nil {
-			return err
+// deleteKeys deletes keys with one DEL each, pipelined, rather than a single DEL for all of them,
+// which Redis Cluster rejects once the keys hash to different slots.
+func deleteKeys(ctx context.Context, client redis.Cmdable, keys []string) error {
+	_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Del(ctx, key)
 		}
+		return nil
+	})
+	return err
+}
 
-		cursor = next
-		if cursor == 0 {
-			break
+// DeleteByPrefix removes every key starting with prefix from Redis, scanning the keyspace in
+// batches instead of blocking Redis with KEYS, and from the local tier of every replica. In
+// Cluster mode every master is scanned, since each only holds the keys of its own slots.
+func (c *Cache) DeleteByPrefix(ctx context.Context, prefix string) error {
+	pattern := prefix + "*"
+	// Evict locally once Redis is done, so a concurrent read cannot refill the local tier from a
+	// key that is about to be deleted.
+	defer c.deleteLocalPrefix(prefix)
+
+	err := c.client.ForEachNode(ctx, func(ctx context.Context, node *redis.Client) error {
+		var cursor uint64
+		for {
+			keys, next, err := node.Scan(ctx, cursor, pattern, scanBatchSize).Result()
+			if err != nil {
+				return fmt.Errorf("scan cache keys %s on %s: %w", pattern, node.Options().Addr, err)
+			}
+
+			if len(keys) > 0 {
+				if err := deleteKeys(ctx, node, keys); err != nil {
+					return fmt.Errorf("delete cache keys %v: %w", keys, err)
+				}
+			}
+
+			cursor = next
+			if cursor == 0 {
+				return nil
+			}
 		}
+	})
+	if err != nil {
+		return err
 	}
-	return nil
+	return c.broadcast(ctx, invalidation{Prefix: prefix})
 }
@@ -24,7 +24,7 @@ func newTestCache(t *testing.T) *Cache {
 	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
 	t.Cleanup(func() { _ = client.Close() })
 
-	return New(&database.RedisClient{Client: client}, time.Minute)
+	return New(&database.RedisClient{UniversalClient: client}, time.Minute)
 }
 
 func TestCache_New_UsesDefaultTTLWhenNonPositive(t *testing.T) {
@@ -32,7 +32,7 @@ func TestCache_New_UsesDefaultTTLWhenNonPositive(t *testing.T) {
 	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
 	t.Cleanup(func() { _ = client.Close() })
 
-	c := New(&database.RedisClient{Client: client}, 0)
+	c := New(&database.RedisClient{UniversalClient: client}, 0)
 	ctx := context.Background()
 
 	if err := c.SetJSON(ctx, "key", sampleValue{Name: "widget"}, 0); err != nil {
@@ -87,7 +87,7 @@ func TestCache_GetJSON_ReturnsErrorOnConnectionFailure(t *testing.T) {
 	mr := miniredis.RunT(t)
 	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
 	t.Cleanup(func() { _ = client.Close() })
-	c := New(&database.RedisClient{Client: client}, time.Minute)
+	c := New(&database.RedisClient{UniversalClient: client}, time.Minute)
 	mr.Close()
 
 	var dest sampleValue
@@ -139,7 +139,7 @@ func TestCache_SetJSON_ReturnsErrorOnConnectionFailure(t *testing.T) {
 	mr := miniredis.RunT(t)
 	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
 	t.Cleanup(func() { _ = client.Close() })
-	c := New(&database.RedisClient{Client: client}, time.Minute)
+	c := New(&database.RedisClient{UniversalClient: client}, time.Minute)
 	mr.Close()
 
 	if err := c.SetJSON(context.Background(), "key", sampleValue{Name: "widget"}, time.Minute); err == nil {
@@ -180,7 +180,7 @@ func TestCache_Delete_ReturnsErrorOnConnectionFailure(t *testing.T) {
 	mr := miniredis.RunT(t)
 	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
 	t.Cleanup(func() { _ = client.Close() })
-	c := New(&database.RedisClient{Client: client}, time.Minute)
+	c := New(&database.RedisClient{UniversalClient: client}, time.Minute)
 	mr.Close()
 
 	if err := c.Delete(context.Background(), "key"); err == nil {
@@ -254,7 +254,7 @@ func TestCache_DeleteByPrefix_ReturnsErrorOnScanFailure(t *testing.T) {
 	mr := miniredis.RunT(t)
 	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
 	t.Cleanup(func() { _ = client.Close() })
-	c := New(&database.RedisClient{Client: client}, time.Minute)
+	c := New(&database.RedisClient{UniversalClient: client}, time.Minute)
 	mr.Close()
 
 	if err := c.DeleteByPrefix(context.Background(), "product:"); err == nil {
@@ -266,7 +266,7 @@ func TestCache_DeleteByPrefix_ReturnsErrorWhenDeleteFails(t *testing.T) {
 	mr := miniredis.RunT(t)
 	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
 	t.Cleanup(func() { _ = client.Close() })
-	c := New(&database.RedisClient{Client: client}, time.Minute)
+	c := New(&database.RedisClient{UniversalClient: client}, time.Minute)
 	ctx := context.Background()
 
 	if err := c.SetJSON(ctx, "product:1", sampleValue{Name: "widget"}, time.Minute); err != nil {
@@ -0,0 +1,241 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultEarlyRefreshBeta is the early refresh factor GetOrLoad uses when LoadOptions.Beta is zero.
+const DefaultEarlyRefreshBeta = 1.0
+
+// backgroundRefreshTimeout bounds an early refresh, which runs detached from the read that
+// triggered it and so cannot rely on that read's deadline.
+const backgroundRefreshTimeout = 10 * time.Second
+
+// LoadFunc loads the current value for a key that GetOrLoad could not answer from the cache.
+type LoadFunc func(ctx context.Context) (any, error)
+
+// LoadOptions controls how long GetOrLoad keeps a loaded value and when it refreshes it.
+type LoadOptions struct {
+	// TTL is how long a loaded value is served as fresh. A non-positive TTL uses the cache's
+	// default TTL.
+	TTL time.Duration
+
+	// StaleTTL is how long past TTL a value is kept to answer reads whose load fails
+	// (stale-if-error). Zero disables stale reads.
+	StaleTTL time.Duration
+
+	// Beta scales probabilistic early refresh: the larger it is, the earlier before expiry a hit
+	// may trigger a background reload. Zero uses DefaultEarlyRefreshBeta; a negative value
+	// disables early refresh.
+	Beta float64
+
+	// Tags are recorded for the key whenever it is loaded, so InvalidateTag can delete it.
+	Tags []string
+}
+
+// entry is the envelope GetOrLoad stores in Redis. Besides the value it records when the value
+// stops being fresh and how long it took to load, which early refresh needs to decide how far
+// ahead of expiry to reload.
+type entry struct {
+	Value     json.RawMessage `json:"value"`
+	ExpiresAt time.Time       `json:"expires_at"`
+	LoadTime  time.Duration   `json:"load_time"`
+}
+
+// GetOrLoad reads key into dest, calling load on a miss and caching what it returns for
+// opts.TTL. Concurrent misses for the same key on this instance share a single load. A fresh hit
+// may reload the value in the background shortly before it expires, so hot keys do not all expire
+// at once. When the value has expired but is still within opts.StaleTTL and load fails, the
+// expired value is written into dest and stale is true; otherwise the error from load is returned
+// unchanged.
+//
+// Redis failures never fail the read: they are recorded as errors and the value is loaded
+// directly. Keys written by GetOrLoad hold an envelope rather than the bare value and must only be
+// read back through GetOrLoad.
+func (c *Cache) GetOrLoad(ctx context.Context, key string, dest any, opts LoadOptions, load LoadFunc) (stale bool, err error) {
+	cached := c.readEntry(ctx, key)
+
+	now := c.now()
+	if cached != nil && now.Before(cached.ExpiresAt) {
+		c.observeHit()
+		if c.shouldRefreshEarly(cached, opts, now) {
+			c.refreshInBackground(ctx, key, opts, load)
+		}
+		return false, unmarshalValue(key, cached.Value, dest)
+	}
+	c.observeMiss()
+
+	raw, joined, err := c.flights.do(ctx, key, func() ([]byte, error) {
+		return c.loadAndStore(ctx, key, opts, load)
+	})
+	if joined {
+		c.observeCoalesced()
+	}
+	if err != nil {
+		if cached == nil {
+			return false, err
+		}
+		c.observeStale()
+		return true, unmarshalValue(key, cached.Value, dest)
+	}
+	return false, unmarshalValue(key, raw, dest)
+}
+
+// readEntry returns the envelope stored under key in the local tier or Redis, or nil when there
+// is none or it cannot be read. A malformed envelope is treated like a miss so the next load
+// overwrites it.
+func (c *Cache) readEntry(ctx context.Context, key string) *entry {
+	raw, ok := c.getLocal(key)
+	if !ok {
+		var err error
+		raw, err = c.client.Get(ctx, key).Bytes()
+		if errors.Is(err, redis.Nil) {
+			return nil
+		}
+		if err != nil {
+			c.observeError()
+			return nil
+		}
+		c.setLocal(key, raw, 0)
+	}
+
+	var e entry
+	if err := json.Unmarshal(raw, &e); err != nil || e.Value == nil {
+		c.observeError()
+		return nil
+	}
+	return &e
+}
+
+// loadAndStore calls load and caches its result. Failing to write the result to Redis is
+// recorded but does not fail the load.
+func (c *Cache) loadAndStore(ctx context.Context, key string, opts LoadOptions, load LoadFunc) ([]byte, error) {
+	started := c.now()
+	value, err := load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	loaded := c.now()
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("marshal cache key %s: %w", key, err)
+	}
+
+	ttl := opts.TTL
+	if ttl <= 0 {
+		ttl = c.defaultTTL
+	}
+	data, err := json.Marshal(entry{Value: raw, ExpiresAt: loaded.Add(ttl), LoadTime: loaded.Sub(started)})
+	if err != nil {
+		return nil, fmt.Errorf("marshal cache key %s: %w", key, err)
+	}
+
+	if err := c.store(ctx, key, data, ttl+max(opts.StaleTTL, 0), opts.Tags); err != nil {
+		c.observeError()
+		return raw, nil
+	}
+	c.setLocal(key, data, ttl+max(opts.StaleTTL, 0))
+	return raw, nil
+}
+
+// shouldRefreshEarly implements probabilistic early expiration (XFetch): a hit refreshes once
+// now + LoadTime*beta*-ln(rand) reaches the expiry, so refreshes become likelier the closer the
+// value is to expiring and the slower it is to load.
+func (c *Cache) shouldRefreshEarly(e *entry, opts LoadOptions, now time.Time) bool {
+	beta := opts.Beta
+	if beta < 0 || e.LoadTime <= 0 {
+		return false
+	}
+	if beta == 0 {
+		beta = DefaultEarlyRefreshBeta
+	}
+
+	// 1-rand keeps the argument in (0, 1] so the logarithm stays finite.
+	gap := time.Duration(float64(e.LoadTime) * beta * -math.Log(1-c.random()))
+	return !now.Add(gap).Before(e.ExpiresAt)
+}
+
+// refreshInBackground reloads key without blocking the caller, unless a load for key is already
+// in flight.
+func (c *Cache) refreshInBackground(ctx context.Context, key string, opts LoadOptions, load LoadFunc) {
+	if c.flights.inFlight(key) {
+		return
+	}
+
+	go func() {
+		refreshCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), backgroundRefreshTimeout)
+		defer cancel()
+
+		_, _, _ = c.flights.do(refreshCtx, key, func() ([]byte, error) {
+			return c.loadAndStore(refreshCtx, key, opts, load)
+		})
+	}()
+}
+
+func unmarshalValue(key string, raw []byte, dest any) error {
+	if err := json.Unmarshal(raw, dest); err != nil {
+		return fmt.Errorf("unmarshal cache key %s: %w", key, err)
+	}
+	return nil
+}
+
+// flightGroup runs at most one load per key at a time, handing its result to every caller that
+// asked for the same key meanwhile. It mirrors golang.org/x/sync/singleflight but reports whether
+// a caller joined someone else's load, which the coalesced metric counts, and lets a joined
+// caller stop waiting when its own context ends.
+type flightGroup struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+type flight struct {
+	done chan struct{}
+	val  []byte
+	err  error
+}
+
+func (g *flightGroup) do(ctx context.Context, key string, fn func() ([]byte, error)) (val []byte, joined bool, err error) {
+	g.mu.Lock()
+	if f, ok := g.flights[key]; ok {
+		g.mu.Unlock()
+		select {
+		case <-f.done:
+			return f.val, true, f.err
+		case <-ctx.Done():
+			return nil, true, ctx.Err()
+		}
+	}
+
+	if g.flights == nil {
+		g.flights = make(map[string]*flight)
+	}
+	f := &flight{done: make(chan struct{})}
+	g.flights[key] = f
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.flights, key)
+		g.mu.Unlock()
+		close(f.done)
+	}()
+
+	f.val, f.err = fn()
+	return f.val, false, f.err
+}
+
+func (g *flightGroup) inFlight(key string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	_, ok := g.flights[key]
+	return ok
+}
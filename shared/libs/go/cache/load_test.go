@@ -0,0 +1,298 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/database"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+)
+
+// newLoadTestCache returns a cache with metrics attached and a clock the test controls, along
+// with the miniredis instance backing it.
+func newLoadTestCache(t *testing.T) (*Cache, *Metrics, *miniredis.Miniredis, *time.Time) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	c := New(&database.RedisClient{UniversalClient: client}, time.Minute)
+	m := NewMetrics(prometheus.NewRegistry())
+	c.SetMetrics(m, "sample")
+
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+	return c, m, mr, &now
+}
+
+func loadValue(calls *atomic.Int32, name string) LoadFunc {
+	return func(context.Context) (any, error) {
+		calls.Add(1)
+		return sampleValue{Name: name}, nil
+	}
+}
+
+func TestCache_GetOrLoad_MissLoadsThenHits(t *testing.T) {
+	c, m, _, _ := newLoadTestCache(t)
+	ctx := context.Background()
+	var calls atomic.Int32
+
+	for range 2 {
+		var got sampleValue
+		stale, err := c.GetOrLoad(ctx, "key", &got, LoadOptions{TTL: time.Minute, Beta: -1}, loadValue(&calls, "widget"))
+		if err != nil {
+			t.Fatalf("GetOrLoad() error = %v", err)
+		}
+		if stale {
+			t.Error("GetOrLoad() stale = true, want false")
+		}
+		if got.Name != "widget" {
+			t.Errorf("GetOrLoad() name = %q, want %q", got.Name, "widget")
+		}
+	}
+
+	if calls.Load() != 1 {
+		t.Errorf("load calls = %d, want 1", calls.Load())
+	}
+	if got := testutil.ToFloat64(m.misses.WithLabelValues("sample")); got != 1 {
+		t.Errorf("misses = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.hits.WithLabelValues("sample")); got != 1 {
+		t.Errorf("hits = %v, want 1", got)
+	}
+}
+
+func TestCache_GetOrLoad_StoresWithTTLPlusStaleTTL(t *testing.T) {
+	c, _, mr, _ := newLoadTestCache(t)
+	var calls atomic.Int32
+
+	var got sampleValue
+	opts := LoadOptions{TTL: time.Minute, StaleTTL: time.Hour}
+	if _, err := c.GetOrLoad(context.Background(), "key", &got, opts, loadValue(&calls, "widget")); err != nil {
+		t.Fatalf("GetOrLoad() error = %v", err)
+	}
+
+	if ttl := mr.TTL("key"); ttl != time.Minute+time.Hour {
+		t.Errorf("TTL = %v, want %v", ttl, time.Minute+time.Hour)
+	}
+}
+
+func TestCache_GetOrLoad_LoadErrorWithoutCachedValueIsReturned(t *testing.T) {
+	c, _, _, _ := newLoadTestCache(t)
+	loadErr := errors.New("database down")
+
+	var got sampleValue
+	stale, err := c.GetOrLoad(context.Background(), "key", &got, LoadOptions{StaleTTL: time.Hour}, func(context.Context) (any, error) {
+		return nil, loadErr
+	})
+	if !errors.Is(err, loadErr) {
+		t.Fatalf("GetOrLoad() error = %v, want %v", err, loadErr)
+	}
+	if stale {
+		t.Error("GetOrLoad() stale = true, want false")
+	}
+}
+
+func TestCache_GetOrLoad_ServesStaleValueWhenLoadFails(t *testing.T) {
+	c, m, _, now := newLoadTestCache(t)
+	ctx := context.Background()
+	var calls atomic.Int32
+	opts := LoadOptions{TTL: time.Minute, StaleTTL: time.Hour, Beta: -1}
+
+	var first sampleValue
+	if _, err := c.GetOrLoad(ctx, "key", &first, opts, loadValue(&calls, "widget")); err != nil {
+		t.Fatalf("GetOrLoad() error = %v", err)
+	}
+
+	*now = now.Add(2 * time.Minute)
+	var got sampleValue
+	stale, err := c.GetOrLoad(ctx, "key", &got, opts, func(context.Context) (any, error) {
+		return nil, errors.New("database down")
+	})
+	if err != nil {
+		t.Fatalf("GetOrLoad() error = %v", err)
+	}
+	if !stale {
+		t.Error("GetOrLoad() stale = false, want true")
+	}
+	if got.Name != "widget" {
+		t.Errorf("GetOrLoad() name = %q, want %q", got.Name, "widget")
+	}
+	if got := testutil.ToFloat64(m.stale.WithLabelValues("sample")); got != 1 {
+		t.Errorf("stale = %v, want 1", got)
+	}
+}
+
+func TestCache_GetOrLoad_ExpiredValueIsReloaded(t *testing.T) {
+	c, _, _, now := newLoadTestCache(t)
+	ctx := context.Background()
+	var calls atomic.Int32
+	opts := LoadOptions{TTL: time.Minute, StaleTTL: time.Hour, Beta: -1}
+
+	var got sampleValue
+	if _, err := c.GetOrLoad(ctx, "key", &got, opts, loadValue(&calls, "old")); err != nil {
+		t.Fatalf("GetOrLoad() error = %v", err)
+	}
+
+	*now = now.Add(2 * time.Minute)
+	stale, err := c.GetOrLoad(ctx, "key", &got, opts, loadValue(&calls, "new"))
+	if err != nil {
+		t.Fatalf("GetOrLoad() error = %v", err)
+	}
+	if stale {
+		t.Error("GetOrLoad() stale = true, want false")
+	}
+	if got.Name != "new" {
+		t.Errorf("GetOrLoad() name = %q, want %q", got.Name, "new")
+	}
+}
+
+func TestCache_GetOrLoad_CoalescesConcurrentMisses(t *testing.T) {
+	c, m, _, _ := newLoadTestCache(t)
+	ctx := context.Background()
+
+	const callers = 5
+	release := make(chan struct{})
+	var calls atomic.Int32
+	load := func(context.Context) (any, error) {
+		calls.Add(1)
+		<-release
+		return sampleValue{Name: "widget"}, nil
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var got sampleValue
+			if _, err := c.GetOrLoad(ctx, "key", &got, LoadOptions{}, load); err != nil {
+				errs <- err
+			}
+		}()
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for testutil.ToFloat64(m.misses.WithLabelValues("sample")) < callers && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("GetOrLoad() error = %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("load calls = %d, want 1", calls.Load())
+	}
+	if got := testutil.ToFloat64(m.coalesced.WithLabelValues("sample")); got != callers-1 {
+		t.Errorf("coalesced = %v, want %d", got, callers-1)
+	}
+}
+
+func TestCache_GetOrLoad_RefreshesEarlyNearExpiry(t *testing.T) {
+	c, _, _, now := newLoadTestCache(t)
+	ctx := context.Background()
+
+	clock := *now
+	var mu sync.Mutex
+	c.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return clock
+	}
+	advance := func(d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		clock = clock.Add(d)
+	}
+
+	slowLoad := func(context.Context) (any, error) {
+		advance(time.Second)
+		return sampleValue{Name: "old"}, nil
+	}
+	var got sampleValue
+	if _, err := c.GetOrLoad(ctx, "key", &got, LoadOptions{TTL: time.Minute}, slowLoad); err != nil {
+		t.Fatalf("GetOrLoad() error = %v", err)
+	}
+
+	// rand close to 1 makes -ln(1-rand) large, so a hit shortly before expiry must refresh.
+	c.random = func() float64 { return 0.999 }
+	advance(55 * time.Second)
+
+	refreshed := make(chan struct{})
+	stale, err := c.GetOrLoad(ctx, "key", &got, LoadOptions{TTL: time.Minute}, func(context.Context) (any, error) {
+		defer close(refreshed)
+		return sampleValue{Name: "new"}, nil
+	})
+	if err != nil {
+		t.Fatalf("GetOrLoad() error = %v", err)
+	}
+	if stale || got.Name != "old" {
+		t.Errorf("GetOrLoad() = (%v, %q), want cached (false, %q)", stale, got.Name, "old")
+	}
+
+	select {
+	case <-refreshed:
+	case <-time.After(time.Second):
+		t.Fatal("expected a background refresh before expiry")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		c.random = func() float64 { return 0 }
+		var after sampleValue
+		if _, err := c.GetOrLoad(ctx, "key", &after, LoadOptions{TTL: time.Minute}, slowLoad); err != nil {
+			t.Fatalf("GetOrLoad() error = %v", err)
+		}
+		if after.Name == "new" {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Error("refreshed value was not written to the cache")
+}
+
+func TestCache_GetOrLoad_FallsBackToLoadWhenRedisUnavailable(t *testing.T) {
+	c, m, mr, _ := newLoadTestCache(t)
+	mr.Close()
+	var calls atomic.Int32
+
+	var got sampleValue
+	stale, err := c.GetOrLoad(context.Background(), "key", &got, LoadOptions{}, loadValue(&calls, "widget"))
+	if err != nil {
+		t.Fatalf("GetOrLoad() error = %v", err)
+	}
+	if stale || got.Name != "widget" {
+		t.Errorf("GetOrLoad() = (%v, %q), want (false, %q)", stale, got.Name, "widget")
+	}
+	if got := testutil.ToFloat64(m.errors.WithLabelValues("sample")); got != 2 {
+		t.Errorf("errors = %v, want 2 (read and write)", got)
+	}
+}
+
+func TestCache_GetOrLoad_MalformedEntryIsReloaded(t *testing.T) {
+	c, _, mr, _ := newLoadTestCache(t)
+	if err := mr.Set("key", "not-json"); err != nil {
+		t.Fatalf("miniredis Set() error = %v", err)
+	}
+	var calls atomic.Int32
+
+	var got sampleValue
+	if _, err := c.GetOrLoad(context.Background(), "key", &got, LoadOptions{}, loadValue(&calls, "widget")); err != nil {
+		t.Fatalf("GetOrLoad() error = %v", err)
+	}
+	if calls.Load() != 1 || got.Name != "widget" {
+		t.Errorf("GetOrLoad() = %q after %d loads, want %q after 1", got.Name, calls.Load(), "widget")
+	}
+}
@@ -0,0 +1,296 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultInvalidationChannel is the Redis pub/sub channel invalidations are broadcast on when
+// LocalOptions.Channel is empty.
+const DefaultInvalidationChannel = "cache:invalidations"
+
+// listenRetryDelay is how long ListenInvalidations waits before reading again after the
+// subscription connection failed, so an unreachable Redis does not turn the loop into a busy spin.
+const listenRetryDelay = time.Second
+
+// LocalOptions sizes the in-process tier EnableLocal puts in front of Redis.
+type LocalOptions struct {
+	// MaxEntries caps how many entries are held in memory; the least recently used entry is
+	// evicted beyond it. A non-positive value disables the local tier.
+	MaxEntries int
+
+	// TTL caps how long an entry is served from memory. It bounds how stale a replica can get if
+	// it misses an invalidation, for instance while its subscription is reconnecting.
+	TTL time.Duration
+
+	// Channel is the pub/sub channel invalidations are broadcast and received on. Empty uses
+	// DefaultInvalidationChannel.
+	Channel string
+}
+
+// localTier is the in-process cache in front of Redis together with what it needs to take part
+// in invalidation broadcasts.
+type localTier struct {
+	entries *lruCache
+	channel string
+	// origin identifies this Cache in the invalidations it publishes, so it can skip its own.
+	origin string
+}
+
+// invalidation is the message broadcast on the invalidation channel: the keys, or the key prefix,
+// every other replica must evict from its local tier.
+type invalidation struct {
+	Origin string   `json:"origin"`
+	Keys   []string `json:"keys,omitempty"`
+	Prefix string   `json:"prefix,omitempty"`
+}
+
+// EnableLocal puts a size- and TTL-bounded in-process LRU in front of Redis. Reads are answered
+// from memory when possible and fill it otherwise. SetJSON, Delete and DeleteByPrefix broadcast
+// the keys they change over Redis pub/sub, so every replica running ListenInvalidations evicts
+// them from its own local tier as well. Values filled by GetOrLoad are not broadcast: they are
+// reloads of unchanged data. EnableLocal must be called before the cache is used; a non-positive
+// MaxEntries or TTL leaves the local tier disabled.
+func (c *Cache) EnableLocal(opts LocalOptions) {
+	if opts.MaxEntries <= 0 || opts.TTL <= 0 {
+		return
+	}
+	channel := opts.Channel
+	if channel == "" {
+		channel = DefaultInvalidationChannel
+	}
+	c.local = &localTier{
+		entries: newLRUCache(opts.MaxEntries, opts.TTL),
+		channel: channel,
+		origin:  uuid.NewString(),
+	}
+}
+
+// ListenInvalidations subscribes to the invalidation channel and evicts the keys other replicas
+// broadcast from the local tier, until ctx is cancelled. The whole local tier is dropped each
+// time the subscription is re-established after a connection failure, since anything broadcast
+// while it was down was missed. Connection failures are recorded as cache errors and retried. It
+// returns immediately when the local tier is disabled.
+func (c *Cache) ListenInvalidations(ctx context.Context) {
+	if c.local == nil {
+		return
+	}
+
+	pubsub := c.client.Subscribe(ctx, c.local.channel)
+	stop := context.AfterFunc(ctx, func() { _ = pubsub.Close() })
+	defer func() {
+		if stop() {
+			_ = pubsub.Close()
+		}
+	}()
+
+	subscribed := false
+	for {
+		msg, err := pubsub.Receive(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			c.observeError()
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(listenRetryDelay):
+			}
+			continue
+		}
+
+		switch msg := msg.(type) {
+		case *redis.Subscription:
+			if subscribed {
+				c.local.entries.purge()
+			}
+			subscribed = true
+		case *redis.Message:
+			c.applyInvalidation(msg.Payload)
+		}
+	}
+}
+
+func (c *Cache) applyInvalidation(payload string) {
+	var inv invalidation
+	if err := json.Unmarshal([]byte(payload), &inv); err != nil {
+		c.observeError()
+		return
+	}
+	if inv.Origin == c.local.origin {
+		return
+	}
+
+	c.deleteLocal(inv.Keys...)
+	if inv.Prefix != "" {
+		c.deleteLocalPrefix(inv.Prefix)
+	}
+}
+
+// broadcast publishes inv to the other replicas. It is a no-op when the local tier is disabled.
+func (c *Cache) broadcast(ctx context.Context, inv invalidation) error {
+	if c.local == nil {
+		return nil
+	}
+	inv.Origin = c.local.origin
+
+	payload, err := json.Marshal(inv)
+	if err != nil {
+		return fmt.Errorf("marshal cache invalidation: %w", err)
+	}
+	if err := c.client.Publish(ctx, c.local.channel, payload).Err(); err != nil {
+		c.observeError()
+		return fmt.Errorf("publish cache invalidation: %w", err)
+	}
+	return nil
+}
+
+func (c *Cache) getLocal(key string) ([]byte, bool) {
+	if c.local == nil {
+		return nil, false
+	}
+	raw, ok := c.local.entries.get(key, c.now())
+	if ok {
+		c.observeLocalHit()
+	}
+	return raw, ok
+}
+
+// setLocal keeps raw in memory for the local TTL, or for ttl when that is shorter, so a local
+// entry never outlives the Redis entry it mirrors.
+func (c *Cache) setLocal(key string, raw []byte, ttl time.Duration) {
+	if c.local == nil {
+		return
+	}
+	c.local.entries.set(key, raw, ttl, c.now())
+}
+
+func (c *Cache) deleteLocal(keys ...string) {
+	if c.local != nil {
+		c.local.entries.delete(keys...)
+	}
+}
+
+func (c *Cache) deleteLocalPrefix(prefix string) {
+	if c.local != nil {
+		c.local.entries.deletePrefix(prefix)
+	}
+}
+
+// lruCache is a fixed-size, TTL-bounded least-recently-used map from key to raw value. It is safe
+// for concurrent use.
+type lruCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	ttl        time.Duration
+	// order holds *lruItem values, most recently used first.
+	order *list.List
+	items map[string]*list.Element
+}
+
+type lruItem struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+func newLRUCache(maxEntries int, ttl time.Duration) *lruCache {
+	return &lruCache{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		order:      list.New(),
+		items:      make(map[string]*list.Element),
+	}
+}
+
+func (l *lruCache) get(key string, now time.Time) ([]byte, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	elem, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+	item := elem.Value.(*lruItem)
+	if !now.Before(item.expiresAt) {
+		l.removeElement(elem)
+		return nil, false
+	}
+	l.order.MoveToFront(elem)
+	return item.value, true
+}
+
+// set stores value under key until now plus the cache's TTL, or plus ttl when that is positive
+// and shorter.
+func (l *lruCache) set(key string, value []byte, ttl time.Duration, now time.Time) {
+	if ttl <= 0 || ttl > l.ttl {
+		ttl = l.ttl
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if elem, ok := l.items[key]; ok {
+		item := elem.Value.(*lruItem)
+		item.value = value
+		item.expiresAt = now.Add(ttl)
+		l.order.MoveToFront(elem)
+		return
+	}
+
+	l.items[key] = l.order.PushFront(&lruItem{key: key, value: value, expiresAt: now.Add(ttl)})
+	for l.order.Len() > l.maxEntries {
+		l.removeElement(l.order.Back())
+	}
+}
+
+func (l *lruCache) delete(keys ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, key := range keys {
+		if elem, ok := l.items[key]; ok {
+			l.removeElement(elem)
+		}
+	}
+}
+
+func (l *lruCache) deletePrefix(prefix string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for key, elem := range l.items {
+		if strings.HasPrefix(key, prefix) {
+			l.removeElement(elem)
+		}
+	}
+}
+
+func (l *lruCache) purge() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.order.Init()
+	clear(l.items)
+}
+
+func (l *lruCache) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.order.Len()
+}
+
+// removeElement drops elem from both the list and the index. The caller must hold l.mu.
+func (l *lruCache) removeElement(elem *list.Element) {
+	l.order.Remove(elem)
+	delete(l.items, elem.Value.(*lruItem).key)
+}
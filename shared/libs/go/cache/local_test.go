@@ -0,0 +1,309 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/database"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+)
+
+// newReplica returns a cache with the local tier enabled on mr, as one service replica would
+// build it.
+func newReplica(t *testing.T, mr *miniredis.Miniredis) *Cache {
+	t.Helper()
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	c := New(&database.RedisClient{UniversalClient: client}, time.Minute)
+	c.EnableLocal(LocalOptions{MaxEntries: 100, TTL: time.Minute})
+	return c
+}
+
+// listen runs c.ListenInvalidations until the test ends and waits for the subscription to be in
+// place, so broadcasts published afterwards are guaranteed to reach it.
+func listen(t *testing.T, mr *miniredis.Miniredis, c *Cache) {
+	t.Helper()
+
+	before := mr.PubSubNumSub(DefaultInvalidationChannel)[DefaultInvalidationChannel]
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.ListenInvalidations(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	deadline := time.Now().Add(time.Second)
+	for mr.PubSubNumSub(DefaultInvalidationChannel)[DefaultInvalidationChannel] <= before {
+		if time.Now().After(deadline) {
+			t.Fatal("ListenInvalidations() did not subscribe in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// eventually polls cond until it holds or a second has passed.
+func eventually(t *testing.T, cond func() bool, msg string) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal(msg)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCache_Local_ServesReadsWithoutRedis(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newReplica(t, mr)
+	m := NewMetrics(prometheus.NewRegistry())
+	c.SetMetrics(m, "sample")
+	ctx := context.Background()
+
+	if err := c.SetJSON(ctx, "key", sampleValue{Name: "widget"}, time.Minute); err != nil {
+		t.Fatalf("SetJSON() error = %v", err)
+	}
+	// Changing the value behind the cache's back shows the next read never reaches Redis.
+	if err := mr.Set("key", `{"name":"changed"}`); err != nil {
+		t.Fatalf("miniredis Set() error = %v", err)
+	}
+
+	var got sampleValue
+	hit, err := c.GetJSON(ctx, "key", &got)
+	if err != nil || !hit {
+		t.Fatalf("GetJSON() = (%v, %v), want (true, nil)", hit, err)
+	}
+	if got.Name != "widget" {
+		t.Errorf("GetJSON() name = %q, want %q (from the local tier)", got.Name, "widget")
+	}
+	if got := testutil.ToFloat64(m.localHits.WithLabelValues("sample")); got != 1 {
+		t.Errorf("local hits = %v, want 1", got)
+	}
+}
+
+func TestCache_Local_EntriesExpireAfterLocalTTL(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newReplica(t, mr)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if err := c.SetJSON(ctx, "key", sampleValue{Name: "widget"}, time.Hour); err != nil {
+		t.Fatalf("SetJSON() error = %v", err)
+	}
+	if err := mr.Set("key", `{"name":"changed"}`); err != nil {
+		t.Fatalf("miniredis Set() error = %v", err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	var got sampleValue
+	if _, err := c.GetJSON(ctx, "key", &got); err != nil {
+		t.Fatalf("GetJSON() error = %v", err)
+	}
+	if got.Name != "changed" {
+		t.Errorf("GetJSON() name = %q, want %q (re-read from Redis)", got.Name, "changed")
+	}
+}
+
+func TestCache_Local_DeleteEvictsOnOtherReplicas(t *testing.T) {
+	mr := miniredis.RunT(t)
+	writer := newReplica(t, mr)
+	reader := newReplica(t, mr)
+	listen(t, mr, reader)
+	ctx := context.Background()
+
+	if err := writer.SetJSON(ctx, "product:1", sampleValue{Name: "widget"}, time.Minute); err != nil {
+		t.Fatalf("SetJSON() error = %v", err)
+	}
+	var got sampleValue
+	if hit, err := reader.GetJSON(ctx, "product:1", &got); err != nil || !hit {
+		t.Fatalf("GetJSON() = (%v, %v), want (true, nil)", hit, err)
+	}
+	if reader.local.entries.len() != 1 {
+		t.Fatalf("reader local entries = %d, want 1", reader.local.entries.len())
+	}
+
+	if err := writer.Delete(ctx, "product:1"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	eventually(t, func() bool { return reader.local.entries.len() == 0 }, "reader still holds product:1 locally after Delete on another replica")
+	if hit, err := reader.GetJSON(ctx, "product:1", &got); err != nil || hit {
+		t.Errorf("GetJSON() after Delete = (%v, %v), want (false, nil)", hit, err)
+	}
+}
+
+func TestCache_Local_DeleteByPrefixEvictsOnOtherReplicas(t *testing.T) {
+	mr := miniredis.RunT(t)
+	writer := newReplica(t, mr)
+	reader := newReplica(t, mr)
+	listen(t, mr, reader)
+	ctx := context.Background()
+
+	for _, key := range []string{"products:list:a", "products:list:b", "product:1"} {
+		if err := mr.Set(key, `{"name":"widget"}`); err != nil {
+			t.Fatalf("miniredis Set() error = %v", err)
+		}
+		var got sampleValue
+		if _, err := reader.GetJSON(ctx, key, &got); err != nil {
+			t.Fatalf("GetJSON(%s) error = %v", key, err)
+		}
+	}
+
+	if err := writer.DeleteByPrefix(ctx, "products:list:"); err != nil {
+		t.Fatalf("DeleteByPrefix() error = %v", err)
+	}
+
+	eventually(t, func() bool { return reader.local.entries.len() == 1 }, "reader still holds list pages locally after DeleteByPrefix on another replica")
+	if _, ok := reader.local.entries.get("product:1", reader.now()); !ok {
+		t.Error("product:1 was evicted, want only keys under the prefix evicted")
+	}
+}
+
+func TestCache_Local_SetJSONEvictsStaleCopiesOnOtherReplicas(t *testing.T) {
+	mr := miniredis.RunT(t)
+	writer := newReplica(t, mr)
+	reader := newReplica(t, mr)
+	listen(t, mr, reader)
+	ctx := context.Background()
+
+	if err := writer.SetJSON(ctx, "key", sampleValue{Name: "old"}, time.Minute); err != nil {
+		t.Fatalf("SetJSON() error = %v", err)
+	}
+	var got sampleValue
+	if _, err := reader.GetJSON(ctx, "key", &got); err != nil {
+		t.Fatalf("GetJSON() error = %v", err)
+	}
+	if err := writer.SetJSON(ctx, "key", sampleValue{Name: "new"}, time.Minute); err != nil {
+		t.Fatalf("SetJSON() error = %v", err)
+	}
+
+	eventually(t, func() bool {
+		var got sampleValue
+		_, err := reader.GetJSON(ctx, "key", &got)
+		return err == nil && got.Name == "new"
+	}, "reader kept serving the old value after SetJSON on another replica")
+}
+
+func TestCache_Local_IgnoresItsOwnBroadcasts(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newReplica(t, mr)
+	listen(t, mr, c)
+	ctx := context.Background()
+
+	c.setLocal("marker", []byte("{}"), 0)
+	if err := c.SetJSON(ctx, "key", sampleValue{Name: "widget"}, time.Minute); err != nil {
+		t.Fatalf("SetJSON() error = %v", err)
+	}
+	// Broadcasts are delivered in order, so once another replica's later broadcast has evicted the
+	// marker, our own SetJSON broadcast has been handled too.
+	other := newReplica(t, mr)
+	if err := other.Delete(ctx, "marker"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	eventually(t, func() bool {
+		_, ok := c.local.entries.get("marker", c.now())
+		return !ok
+	}, "marker was not evicted by the other replica's broadcast")
+
+	if _, ok := c.local.entries.get("key", c.now()); !ok {
+		t.Error("local entry was evicted by the replica's own broadcast")
+	}
+}
+
+func TestCache_Local_DisabledByNonPositiveOptions(t *testing.T) {
+	c := newTestCache(t)
+	c.EnableLocal(LocalOptions{MaxEntries: 0, TTL: time.Minute})
+
+	if c.local != nil {
+		t.Error("EnableLocal() with MaxEntries 0 enabled the local tier")
+	}
+	// With the local tier disabled there is nothing to listen for, so this must return at once.
+	c.ListenInvalidations(context.Background())
+}
+
+func TestCache_Local_GetOrLoadUsesLocalTier(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newReplica(t, mr)
+	ctx := context.Background()
+
+	calls := 0
+	load := func(context.Context) (any, error) {
+		calls++
+		return sampleValue{Name: "widget"}, nil
+	}
+
+	var got sampleValue
+	if _, err := c.GetOrLoad(ctx, "key", &got, LoadOptions{Beta: -1}, load); err != nil {
+		t.Fatalf("GetOrLoad() error = %v", err)
+	}
+	mr.Del("key")
+	if _, err := c.GetOrLoad(ctx, "key", &got, LoadOptions{Beta: -1}, load); err != nil {
+		t.Fatalf("GetOrLoad() error = %v", err)
+	}
+
+	if calls != 1 {
+		t.Errorf("load calls = %d, want 1 (second read served from the local tier)", calls)
+	}
+}
+
+func TestLRUCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	l := newLRUCache(2, time.Minute)
+	now := time.Now()
+
+	l.set("a", []byte("1"), 0, now)
+	l.set("b", []byte("2"), 0, now)
+	l.get("a", now)
+	l.set("c", []byte("3"), 0, now)
+
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok := l.get(key, now); ok != want {
+			t.Errorf("get(%s) present = %v, want %v", key, ok, want)
+		}
+	}
+}
+
+func TestLRUCache_ShorterEntryTTLWins(t *testing.T) {
+	l := newLRUCache(10, time.Minute)
+	now := time.Now()
+
+	l.set("short", []byte("1"), time.Second, now)
+	l.set("long", []byte("2"), time.Hour, now)
+
+	later := now.Add(30 * time.Second)
+	if _, ok := l.get("short", later); ok {
+		t.Error("get(short) present after its own TTL, want expired")
+	}
+	if _, ok := l.get("long", later); !ok {
+		t.Error("get(long) expired before the local TTL, want present")
+	}
+	if _, ok := l.get("long", now.Add(2*time.Minute)); ok {
+		t.Error("get(long) present after the local TTL, want expired")
+	}
+}
+
+func TestLRUCache_Purge(t *testing.T) {
+	l := newLRUCache(10, time.Minute)
+	for i := range 3 {
+		l.set(fmt.Sprintf("k%d", i), nil, 0, time.Now())
+	}
+
+	l.purge()
+
+	if l.len() != 0 {
+		t.Errorf("len() after purge = %d, want 0", l.len())
+	}
+}
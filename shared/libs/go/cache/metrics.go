@@ -2,14 +2,18 @@ package cache
 
 import "github.com/prometheus/client_golang/prometheus"
 
-// Metrics records cache hits, misses and errors, labeled by cache name.
+// Metrics records cache hits, misses, errors, stale reads and coalesced loads, labeled by cache
+// name.
 type Metrics struct {
-	hits   *prometheus.CounterVec
-	misses *prometheus.CounterVec
-	errors *prometheus.CounterVec
+	hits      *prometheus.CounterVec
+	localHits *prometheus.CounterVec
+	misses    *prometheus.CounterVec
+	errors    *prometheus.CounterVec
+	stale     *prometheus.CounterVec
+	coalesced *prometheus.CounterVec
 }
 
-// NewMetrics registers cache hit, miss and error counters on registerer.
+// NewMetrics registers cache hit, miss, error, stale and coalesced counters on registerer.
 func NewMetrics(registerer prometheus.Registerer) *Metrics {
 	labels := []string{"cache"}
 
@@ -18,6 +22,10 @@ func NewMetrics(registerer prometheus.Registerer) *Metrics {
 			Name: "cache_hits_total",
 			Help: "Total number of cache reads that found a value.",
 		}, labels),
+		localHits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "cache_local_hits_total",
+			Help: "Total number of cache reads answered from the in-process tier without asking Redis.",
+		}, labels),
 		misses: prometheus.NewCounterVec(prometheus.CounterOpts{
 			Name: "cache_misses_total",
 			Help: "Total number of cache reads that found no value.",
@@ -26,9 +34,17 @@ func NewMetrics(registerer prometheus.Registerer) *Metrics {
 			Name: "cache_errors_total",
 			Help: "Total number of cache operations that failed.",
 		}, labels),
+		stale: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "cache_stale_total",
+			Help: "Total number of reads answered with an expired value because the load failed.",
+		}, labels),
+		coalesced: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "cache_coalesced_total",
+			Help: "Total number of misses that waited on a load already in flight for the same key.",
+		}, labels),
 	}
 
-	registerer.MustRegister(m.hits, m.misses, m.errors)
+	registerer.MustRegister(m.hits, m.localHits, m.misses, m.errors, m.stale, m.coalesced)
 	return m
 }
 
@@ -37,6 +53,12 @@ func (m *Metrics) ObserveHit(cacheName string) {
 	m.hits.WithLabelValues(cacheName).Inc()
 }
 
+// ObserveLocalHit increments the local tier hit counter for cacheName. Local hits are also
+// counted as hits.
+func (m *Metrics) ObserveLocalHit(cacheName string) {
+	m.localHits.WithLabelValues(cacheName).Inc()
+}
+
 // ObserveMiss increments the miss counter for cacheName.
 func (m *Metrics) ObserveMiss(cacheName string) {
 	m.misses.WithLabelValues(cacheName).Inc()
@@ -46,3 +68,13 @@ func (m *Metrics) ObserveMiss(cacheName string) {
 func (m *Metrics) ObserveError(cacheName string) {
 	m.errors.WithLabelValues(cacheName).Inc()
 }
+
+// ObserveStale increments the stale read counter for cacheName.
+func (m *Metrics) ObserveStale(cacheName string) {
+	m.stale.WithLabelValues(cacheName).Inc()
+}
+
+// ObserveCoalesced increments the coalesced load counter for cacheName.
+func (m *Metrics) ObserveCoalesced(cacheName string) {
+	m.coalesced.WithLabelValues(cacheName).Inc()
+}
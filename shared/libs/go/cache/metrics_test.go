@@ -44,6 +44,31 @@ func TestMetrics_ObserveError(t *testing.T) {
 	}
 }
 
+func TestMetrics_ObserveStale(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	m := NewMetrics(registry)
+
+	m.ObserveStale("product")
+
+	got := testutil.ToFloat64(m.stale.WithLabelValues("product"))
+	if got != 1 {
+		t.Errorf("stale total = %v, want 1", got)
+	}
+}
+
+func TestMetrics_ObserveCoalesced(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	m := NewMetrics(registry)
+
+	m.ObserveCoalesced("order")
+	m.ObserveCoalesced("order")
+
+	got := testutil.ToFloat64(m.coalesced.WithLabelValues("order"))
+	if got != 2 {
+		t.Errorf("coalesced total = %v, want 2", got)
+	}
+}
+
 func TestMetrics_LabelsAreIndependentPerCacheName(t *testing.T) {
 	registry := prometheus.NewRegistry()
 	m := NewMetrics(registry)
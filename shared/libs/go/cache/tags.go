@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tagKeyPrefix namespaces the Redis sets that record which keys carry a tag.
+const tagKeyPrefix = "cache-tag:"
+
+// tagKey returns the Redis set holding the keys tagged with tag.
+func tagKey(tag string) string {
+	return tagKeyPrefix + tag
+}
+
+// tagQueue adds key to the set of every tag in tags. Each set's TTL is only ever extended, to at
+// least ttl, so a set never expires before the longest-lived key it references.
+func tagQueue(ctx context.Context, pipe redis.Pipeliner, key string, ttl time.Duration, tags []string) {
+	for _, tag := range tags {
+		set := tagKey(tag)
+		pipe.SAdd(ctx, set, key)
+		pipe.ExpireNX(ctx, set, ttl)
+		pipe.ExpireGT(ctx, set, ttl)
+	}
+}
+
+// InvalidateTag deletes every key stored with any of tags, from Redis and from the local tier of
+// every replica, along with the tag sets themselves. Keys written under a tag while it is being
+// invalidated may be deleted too, which only costs a cache miss. Keys that already expired are
+// skipped silently.
+func (c *Cache) InvalidateTag(ctx context.Context, tags ...string) error {
+	for _, tag := range tags {
+		if err := c.invalidateTag(ctx, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// invalidateTag pops the members of tag's set in batches and deletes them, until the set is
+// empty. Popping rather than reading the set and deleting it afterwards means a key tagged
+// concurrently is either popped and deleted here, or left in the set for the next invalidation;
+// it is never dropped from the set while its entry survives.
+func (c *Cache) invalidateTag(ctx context.Context, tag string) error {
+	set := tagKey(tag)
+	for {
+		keys, err := c.client.SPopN(ctx, set, scanBatchSize).Result()
+		if err != nil {
+			return fmt.Errorf("pop cache tag %s: %w", tag, err)
+		}
+		if len(keys) == 0 {
+			return nil
+		}
+
+		err = deleteKeys(ctx, c.client, keys)
+		c.deleteLocal(keys...)
+		if err != nil {
+			// Put the keys back so a retry still finds them; if that fails too they simply live
+			// out their TTL.
+			members := make([]any, len(keys))
+			for i, key := range keys {
+				members[i] = key
+			}
+			_ = c.client.SAdd(ctx, set, members...).Err()
+			return fmt.Errorf("delete cache keys for tag %s: %w", tag, err)
+		}
+		if err := c.broadcast(ctx, invalidation{Keys: keys}); err != nil {
+			return err
+		}
+	}
+}
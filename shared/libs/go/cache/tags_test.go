@@ -0,0 +1,183 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/database"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTaggedTestCache(t *testing.T) (*Cache, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	return New(&database.RedisClient{UniversalClient: client}, time.Minute), mr
+}
+
+func TestCache_SetJSON_RecordsTags(t *testing.T) {
+	c, mr := newTaggedTestCache(t)
+
+	if err := c.SetJSON(context.Background(), "page:1", sampleValue{Name: "widget"}, time.Minute, "pages", "widgets"); err != nil {
+		t.Fatalf("SetJSON() error = %v", err)
+	}
+
+	for _, tag := range []string{"pages", "widgets"} {
+		members, err := mr.SMembers(tagKey(tag))
+		if err != nil {
+			t.Fatalf("SMembers(%s) error = %v", tag, err)
+		}
+		if len(members) != 1 || members[0] != "page:1" {
+			t.Errorf("tag %s members = %v, want [page:1]", tag, members)
+		}
+		if ttl := mr.TTL(tagKey(tag)); ttl != time.Minute {
+			t.Errorf("tag %s TTL = %v, want %v", tag, ttl, time.Minute)
+		}
+	}
+}
+
+func TestCache_SetJSON_TagTTLOnlyGrows(t *testing.T) {
+	c, mr := newTaggedTestCache(t)
+	ctx := context.Background()
+
+	if err := c.SetJSON(ctx, "long", sampleValue{}, time.Hour, "pages"); err != nil {
+		t.Fatalf("SetJSON() error = %v", err)
+	}
+	if err := c.SetJSON(ctx, "short", sampleValue{}, time.Minute, "pages"); err != nil {
+		t.Fatalf("SetJSON() error = %v", err)
+	}
+
+	if ttl := mr.TTL(tagKey("pages")); ttl != time.Hour {
+		t.Errorf("tag TTL = %v, want %v (the longest-lived member's)", ttl, time.Hour)
+	}
+}
+
+func TestCache_InvalidateTag_DeletesTaggedKeysOnly(t *testing.T) {
+	c, mr := newTaggedTestCache(t)
+	ctx := context.Background()
+
+	for _, key := range []string{"page:1", "page:2"} {
+		if err := c.SetJSON(ctx, key, sampleValue{}, time.Minute, "pages"); err != nil {
+			t.Fatalf("SetJSON(%s) error = %v", key, err)
+		}
+	}
+	if err := c.SetJSON(ctx, "other", sampleValue{}, time.Minute, "others"); err != nil {
+		t.Fatalf("SetJSON() error = %v", err)
+	}
+
+	if err := c.InvalidateTag(ctx, "pages"); err != nil {
+		t.Fatalf("InvalidateTag() error = %v", err)
+	}
+
+	for _, key := range []string{"page:1", "page:2", tagKey("pages")} {
+		if mr.Exists(key) {
+			t.Errorf("%s still exists after InvalidateTag", key)
+		}
+	}
+	if !mr.Exists("other") {
+		t.Error("key under a different tag was deleted")
+	}
+}
+
+func TestCache_InvalidateTag_ManyKeys(t *testing.T) {
+	c, mr := newTaggedTestCache(t)
+	ctx := context.Background()
+
+	const keys = scanBatchSize*2 + 5
+	for i := range keys {
+		if err := c.SetJSON(ctx, fmt.Sprintf("page:%d", i), sampleValue{}, time.Minute, "pages"); err != nil {
+			t.Fatalf("SetJSON() error = %v", err)
+		}
+	}
+
+	if err := c.InvalidateTag(ctx, "pages"); err != nil {
+		t.Fatalf("InvalidateTag() error = %v", err)
+	}
+	if got := len(mr.Keys()); got != 0 {
+		t.Errorf("keys left after InvalidateTag = %d, want 0", got)
+	}
+}
+
+func TestCache_InvalidateTag_UnknownTagIsNotAnError(t *testing.T) {
+	c, _ := newTaggedTestCache(t)
+
+	if err := c.InvalidateTag(context.Background(), "missing"); err != nil {
+		t.Errorf("InvalidateTag() error = %v, want nil", err)
+	}
+}
+
+func TestCache_InvalidateTag_EvictsLocalTier(t *testing.T) {
+	c, _ := newTaggedTestCache(t)
+	c.EnableLocal(LocalOptions{MaxEntries: 10, TTL: time.Minute})
+	ctx := context.Background()
+
+	if err := c.SetJSON(ctx, "page:1", sampleValue{Name: "widget"}, time.Minute, "pages"); err != nil {
+		t.Fatalf("SetJSON() error = %v", err)
+	}
+	if err := c.InvalidateTag(ctx, "pages"); err != nil {
+		t.Fatalf("InvalidateTag() error = %v", err)
+	}
+
+	var got sampleValue
+	if hit, err := c.GetJSON(ctx, "page:1", &got); err != nil || hit {
+		t.Errorf("GetJSON() after InvalidateTag = (%v, %v), want (false, nil)", hit, err)
+	}
+}
+
+func TestCache_InvalidateTag_RestoresTagWhenDeleteFails(t *testing.T) {
+	c, mr := newTaggedTestCache(t)
+	ctx := context.Background()
+
+	if err := c.SetJSON(ctx, "page:1", sampleValue{}, time.Minute, "pages"); err != nil {
+		t.Fatalf("SetJSON() error = %v", err)
+	}
+	mr.Server().SetPreHook(func(peer *server.Peer, cmd string, _ ...string) bool {
+		if cmd == "DEL" {
+			peer.WriteError("ERR injected failure")
+			return true
+		}
+		return false
+	})
+
+	if err := c.InvalidateTag(ctx, "pages"); err == nil {
+		t.Fatal("InvalidateTag() error = nil, want an error")
+	}
+	members, err := mr.SMembers(tagKey("pages"))
+	if err != nil || len(members) != 1 {
+		t.Errorf("tag members after failed delete = (%v, %v), want [page:1]", members, err)
+	}
+}
+
+func TestCache_InvalidateTag_ReturnsErrorOnConnectionFailure(t *testing.T) {
+	c, mr := newTaggedTestCache(t)
+	mr.Close()
+
+	if err := c.InvalidateTag(context.Background(), "pages"); err == nil {
+		t.Error("InvalidateTag() error = nil, want an error")
+	}
+}
+
+func TestCache_GetOrLoad_RecordsTags(t *testing.T) {
+	c, mr := newTaggedTestCache(t)
+	ctx := context.Background()
+
+	var got []sampleValue
+	load := func(context.Context) (any, error) { return []sampleValue{{Name: "widget"}}, nil }
+	if _, err := c.GetOrLoad(ctx, "page:1", &got, LoadOptions{Tags: []string{"pages"}}, load); err != nil {
+		t.Fatalf("GetOrLoad() error = %v", err)
+	}
+	if err := c.InvalidateTag(ctx, "pages"); err != nil {
+		t.Fatalf("InvalidateTag() error = %v", err)
+	}
+
+	if mr.Exists("page:1") {
+		t.Error("page:1 still exists after invalidating its tag")
+	}
+}
@@ -1,19 +1,28 @@
 package config
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
+	"sync"
 
 	"github.com/spf13/viper"
 )
 
 // CfgLoader provides a flexible way to load configurations
 type CfgLoader struct {
-	v *viper.Viper
+	mu         sync.Mutex
+	v          *viper.Viper
+	configDirs []string
+	providers  map[string]SecretProvider
 }
 
-// New creates a new CfgLoader instance
+// New creates a new CfgLoader instance. If <SERVICE>_CONFIG_DIR is set, that directory is added
+// with AddConfigDir.
 func New(serviceName string) *CfgLoader {
 	v := viper.New()
 
@@ -22,41 +31,142 @@ func New(serviceName string) *CfgLoader {
 	v.AddConfigPath(".")
 	v.AddConfigPath("./configs")
 
+	prefix := strings.ToUpper(serviceName)
 	v.AutomaticEnv()
-	v.SetEnvPrefix(strings.ToUpper(serviceName))
+	v.SetEnvPrefix(prefix)
 	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 
-	return &CfgLoader{v: v}
+	cl := &CfgLoader{
+		v:         v,
+		providers: map[string]SecretProvider{"file": FileProvider{}},
+	}
+	if dir := os.Getenv(prefix + "_CONFIG_DIR"); dir != "" {
+		cl.AddConfigDir(dir)
+	}
+	return cl
+}
+
+// AddConfigDir adds a directory holding one file per config key, the layout of a mounted
+// Kubernetes ConfigMap: a file named "rate_limit.requests_per_minute" containing "200" sets that
+// key. Its values override the config file but, like it, are overridden by environment
+// variables. Hidden files, such as the "..data" symlink Kubernetes swaps on update, are ignored,
+// and a directory that does not exist yet is treated as empty.
+func (cl *CfgLoader) AddConfigDir(dir string) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	cl.configDirs = append(cl.configDirs, dir)
 }
 
-// Load loads configuration into the provided struct
+// Load loads configuration into the provided struct and resolves the references held in its
+// Secret fields. The config file, config directories and secrets are re-read on every call, so
+// calling it again picks up changes made since.
 func (cl *CfgLoader) Load(cfg interface{}) error {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
 	if err := cl.v.ReadInConfig(); err != nil {
 		var configFileNotFoundError viper.ConfigFileNotFoundError
 		if !errors.As(err, &configFileNotFoundError) {
 			return fmt.Errorf("error reading config file: %w", err)
 		}
+		// Without a config file nothing resets the values merged from config directories on the
+		// previous call, so a key removed from a directory would linger.
+		if err := cl.v.ReadConfig(strings.NewReader("")); err != nil {
+			return fmt.Errorf("error resetting config: %w", err)
+		}
+	}
+
+	for _, dir := range cl.configDirs {
+		values, err := readConfigDir(dir)
+		if err != nil {
+			return err
+		}
+		if err := cl.v.MergeConfigMap(values); err != nil {
+			return fmt.Errorf("error merging config directory %s: %w", dir, err)
+		}
 	}
 
 	if err := cl.v.Unmarshal(cfg); err != nil {
 		return fmt.Errorf("error unmarshaling config: %w", err)
 	}
 
+	ctx, cancel := context.WithTimeout(context.Background(), secretResolveTimeout)
+	defer cancel()
+	if err := cl.resolveSecrets(ctx, reflect.ValueOf(cfg), ""); err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// readConfigDir returns the key-per-file values in dir as the nested map viper merges.
+func readConfigDir(dir string) (map[string]any, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading config directory %s: %w", dir, err)
+	}
+
+	values := make(map[string]any)
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		// Stat rather than entry.Type(): ConfigMap keys are symlinks into the current data dir.
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading config key %s: %w", name, err)
+		}
+		setNested(values, strings.Split(name, "."), strings.TrimSpace(string(raw)))
+	}
+	return values, nil
+}
+
+func setNested(m map[string]any, keys []string, value string) {
+	for _, key := range keys[:len(keys)-1] {
+		child, ok := m[key].(map[string]any)
+		if !ok {
+			child = make(map[string]any)
+			m[key] = child
+		}
+		m = child
+	}
+	m[keys[len(keys)-1]] = value
+}
+
+// watchedPaths returns the config file in use, if one was found, and the config directories.
+func (cl *CfgLoader) watchedPaths() (file string, dirs []string) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	return cl.v.ConfigFileUsed(), append([]string(nil), cl.configDirs...)
+}
+
 // SetDefault sets a default value for a config key
 func (cl *CfgLoader) SetDefault(key string, value interface{}) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
 	cl.v.SetDefault(key, value)
 }
 
 // BindEnv binds one or more environment variables to a config key.
 // The first variable with a non-empty value wins.
 func (cl *CfgLoader) BindEnv(key string, envVars ...string) error {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
 	return cl.v.BindEnv(append([]string{key}, envVars...)...)
 }
 
 // GetString returns the value associated with the key as a string.
 func (cl *CfgLoader) GetString(key string) string {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
 	return cl.v.GetString(key)
 }
@@ -126,3 +126,79 @@ func TestEnv_OverridesDefault(t *testing.T) {
 		t.Errorf("GetString() = %q, want %q (env must override default)", got, "9999")
 	}
 }
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+}
+
+type dirConfig struct {
+	RateLimit struct {
+		RequestsPerMinute int `mapstructure:"requests_per_minute"`
+	} `mapstructure:"rate_limit"`
+	Logger struct {
+		Level string `mapstructure:"level"`
+	} `mapstructure:"logger"`
+}
+
+func TestLoad_ConfigDirOverridesDefaultsAndIsReReadOnEveryLoad(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "rate_limit.requests_per_minute"), "200\n")
+	writeFile(t, filepath.Join(dir, "..data"), "ignored")
+
+	loader := New("config_dir_service")
+	loader.SetDefault("rate_limit.requests_per_minute", 100)
+	loader.SetDefault("logger.level", "info")
+	loader.AddConfigDir(dir)
+
+	var cfg dirConfig
+	if err := loader.Load(&cfg); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.RateLimit.RequestsPerMinute != 200 || cfg.Logger.Level != "info" {
+		t.Errorf("Load() = %+v, want 200 requests per minute from the dir and the default level", cfg)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "rate_limit.requests_per_minute")); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	writeFile(t, filepath.Join(dir, "logger.level"), "debug")
+
+	cfg = dirConfig{}
+	if err := loader.Load(&cfg); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.RateLimit.RequestsPerMinute != 100 || cfg.Logger.Level != "debug" {
+		t.Errorf("Load() after change = %+v, want the default rate restored and level debug", cfg)
+	}
+}
+
+func TestLoad_EnvOverridesConfigDir(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "logger.level"), "debug")
+	t.Setenv("ENV_OVER_DIR_SERVICE_LOGGER_LEVEL", "warn")
+	t.Setenv("ENV_OVER_DIR_SERVICE_CONFIG_DIR", dir)
+
+	loader := New("env_over_dir_service")
+	loader.SetDefault("logger.level", "info")
+
+	var cfg dirConfig
+	if err := loader.Load(&cfg); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Logger.Level != "warn" {
+		t.Errorf("cfg.Logger.Level = %q, want %q (env must override the config dir)", cfg.Logger.Level, "warn")
+	}
+}
+
+func TestLoad_MissingConfigDirIsNotError(t *testing.T) {
+	loader := New("missing_dir_service")
+	loader.AddConfigDir(filepath.Join(t.TempDir(), "absent"))
+
+	var cfg dirConfig
+	if err := loader.Load(&cfg); err != nil {
+		t.Fatalf("Load() error = %v, want nil when the config dir is absent", err)
+	}
+}
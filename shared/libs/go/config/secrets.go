@@ -0,0 +1,178 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// redacted is what a Secret prints as.
+const redacted = "[REDACTED]"
+
+// secretResolveTimeout bounds resolving every secret reference in one Load.
+const secretResolveTimeout = 10 * time.Second
+
+// Secret is a configuration value that must not be logged. It prints, formats and marshals to
+// JSON as "[REDACTED]", so a config struct can be dumped or passed to a logger as a whole; only
+// Value returns the real content.
+//
+// A Secret field may hold a reference instead of the value itself, such as
+// "file:///run/secrets/jwt". CfgLoader.Load replaces the reference with what the provider
+// registered for its scheme returns.
+type Secret string
+
+// Value returns the secret in clear.
+func (s Secret) Value() string {
+	return string(s)
+}
+
+// String implements fmt.Stringer.
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+// GoString implements fmt.GoStringer, covering the %#v verb.
+func (s Secret) GoString() string {
+	return `config.Secret("` + s.String() + `")`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + s.String() + `"`), nil
+}
+
+// SecretProvider resolves a secret reference to its value. ref is the parsed reference, whose
+// scheme is the one the provider was registered for.
+type SecretProvider interface {
+	Resolve(ctx context.Context, ref *url.URL) (string, error)
+}
+
+// SecretProviderFunc adapts a function to SecretProvider.
+type SecretProviderFunc func(ctx context.Context, ref *url.URL) (string, error)
+
+// Resolve calls f.
+func (f SecretProviderFunc) Resolve(ctx context.Context, ref *url.URL) (string, error) {
+	return f(ctx, ref)
+}
+
+// FileProvider resolves "file://" references by reading the file, the way Docker and Kubernetes
+// mount secrets. A trailing newline is dropped. If Root is set, reference paths are taken
+// relative to it, which lets tests point references at a temporary directory.
+type FileProvider struct {
+	Root string
+}
+
+// Resolve reads the file ref names.
+func (p FileProvider) Resolve(_ context.Context, ref *url.URL) (string, error) {
+	path := ref.Path
+	if ref.Host != "" {
+		// file://run/secrets/jwt parses "run" as the host; treat it as a relative path.
+		path = ref.Host + path
+	}
+	if path == "" {
+		return "", fmt.Errorf("file secret reference has no path")
+	}
+	if p.Root != "" {
+		path = filepath.Join(p.Root, path)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read secret file: %w", err)
+	}
+	return strings.TrimRight(string(raw), "\r\n"), nil
+}
+
+// RegisterSecretProvider makes Load resolve references with the given URL scheme through p,
+// replacing any provider already registered for it. The "file" scheme is registered by New.
+func (cl *CfgLoader) RegisterSecretProvider(scheme string, p SecretProvider) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	cl.providers[strings.ToLower(scheme)] = p
+}
+
+// Secret returns the value of key, resolving it first if it is a secret reference. It is for
+// secrets read outside the unmarshalled struct, such as a database URL parsed by hand.
+func (cl *CfgLoader) Secret(key string) (Secret, error) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), secretResolveTimeout)
+	defer cancel()
+
+	value, err := cl.resolve(ctx, cl.v.GetString(key))
+	if err != nil {
+		return "", fmt.Errorf("resolve secret %s: %w", key, err)
+	}
+	return Secret(value), nil
+}
+
+// resolve returns the value a reference points to, or value unchanged if it is not a reference
+// to a registered scheme. A database URL such as "postgres://..." is therefore left alone.
+func (cl *CfgLoader) resolve(ctx context.Context, value string) (string, error) {
+	scheme, _, ok := strings.Cut(value, "://")
+	if !ok {
+		return value, nil
+	}
+	provider, ok := cl.providers[strings.ToLower(scheme)]
+	if !ok {
+		return value, nil
+	}
+
+	ref, err := url.Parse(value)
+	if err != nil {
+		return "", fmt.Errorf("parse secret reference: %w", err)
+	}
+	return provider.Resolve(ctx, ref)
+}
+
+var secretType = reflect.TypeOf(Secret(""))
+
+// resolveSecrets replaces every reference held in a Secret field of the struct v points to,
+// descending into nested structs and pointers to structs.
+func (cl *CfgLoader) resolveSecrets(ctx context.Context, v reflect.Value, path string) error {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	switch {
+	case !v.IsValid():
+		return nil
+	case v.Type() == secretType:
+		value, err := cl.resolve(ctx, v.String())
+		if err != nil {
+			return fmt.Errorf("resolve secret %s: %w", path, err)
+		}
+		v.SetString(value)
+	case v.Kind() == reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := field.Tag.Get("mapstructure")
+			if name == "" || name == "-" || name == ",squash" {
+				name = field.Name
+			}
+			if path != "" {
+				name = path + "." + name
+			}
+			if err := cl.resolveSecrets(ctx, v.Field(i), name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type secretConfig struct {
+	Database DatabaseConfig `mapstructure:"database"`
+	Token    Secret         `mapstructure:"token"`
+	Name     string         `mapstructure:"name"`
+}
+
+func TestSecret_IsRedacted(t *testing.T) {
+	cfg := secretConfig{Token: "hunter2", Name: "svc"}
+	cfg.Database.Password = "pg-pass"
+
+	for _, verb := range []string{"%v", "%+v", "%#v", "%s"} {
+		if out := fmt.Sprintf(verb, cfg); strings.Contains(out, "hunter2") || strings.Contains(out, "pg-pass") {
+			t.Errorf("Sprintf(%q) = %s, want secrets redacted", verb, out)
+		}
+	}
+
+	out, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if strings.Contains(string(out), "hunter2") || !strings.Contains(string(out), redacted) {
+		t.Errorf("json.Marshal() = %s, want the token redacted", out)
+	}
+
+	if got := cfg.Token.Value(); got != "hunter2" {
+		t.Errorf("Value() = %q, want %q", got, "hunter2")
+	}
+	if got := Secret("").String(); got != "" {
+		t.Errorf("empty Secret String() = %q, want empty so a missing secret is visible", got)
+	}
+}
+
+func TestLoad_ResolvesFileSecretReferences(t *testing.T) {
+	dir := t.TempDir()
+	tokenPath := filepath.Join(dir, "token")
+	writeFile(t, tokenPath, "s3cret\n")
+
+	loader := New("secret_ref_service")
+	loader.SetDefault("token", "file://"+tokenPath)
+	loader.SetDefault("database.password", "plain-password")
+	loader.SetDefault("name", "file://"+tokenPath)
+
+	var cfg secretConfig
+	if err := loader.Load(&cfg); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Token.Value() != "s3cret" {
+		t.Errorf("Token = %q, want the file content without its newline", cfg.Token.Value())
+	}
+	if cfg.Database.Password.Value() != "plain-password" {
+		t.Errorf("Database.Password = %q, want a plain value left as is", cfg.Database.Password.Value())
+	}
+	if cfg.Name != "file://"+tokenPath {
+		t.Errorf("Name = %q, want a non-Secret field left unresolved", cfg.Name)
+	}
+
+	// A rotated secret is picked up by the next Load.
+	writeFile(t, tokenPath, "rotated")
+	if err := loader.Load(&cfg); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Token.Value() != "rotated" {
+		t.Errorf("Token after rotation = %q, want %q", cfg.Token.Value(), "rotated")
+	}
+}
+
+func TestLoad_UnreadableSecretNamesTheField(t *testing.T) {
+	loader := New("missing_secret_service")
+	loader.SetDefault("database.password", "file://"+filepath.Join(t.TempDir(), "absent"))
+
+	var cfg secretConfig
+	err := loader.Load(&cfg)
+	if err == nil {
+		t.Fatal("Load() error = nil, want an error for an unreadable secret file")
+	}
+	if !strings.Contains(err.Error(), "database.password") {
+		t.Errorf("Load() error = %v, want it to name database.password", err)
+	}
+}
+
+func TestRegisterSecretProvider(t *testing.T) {
+	loader := New("vault_service")
+	loader.RegisterSecretProvider("vault", SecretProviderFunc(func(_ context.Context, ref *url.URL) (string, error) {
+		return "from-vault:" + ref.Host + ref.Path + "#" + ref.Query().Get("field"), nil
+	}))
+	loader.SetDefault("token", "vault://kv/gateway?field=jwt")
+
+	var cfg secretConfig
+	if err := loader.Load(&cfg); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if want := "from-vault:kv/gateway#jwt"; cfg.Token.Value() != want {
+		t.Errorf("Token = %q, want %q", cfg.Token.Value(), want)
+	}
+}
+
+func TestCfgLoaderSecret(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "db_url"), "postgres://u:p@db:5432/app")
+	t.Setenv("SECRET_KEY_SERVICE_DATABASE_URL", "file://"+filepath.Join(dir, "db_url"))
+
+	loader := New("secret_key_service")
+	got, err := loader.Secret("database.url")
+	if err != nil {
+		t.Fatalf("Secret() error = %v", err)
+	}
+	if got.Value() != "postgres://u:p@db:5432/app" {
+		t.Errorf("Secret() = %q, want the referenced URL", got.Value())
+	}
+
+	t.Setenv("SECRET_KEY_SERVICE_DATABASE_URL", "postgres://plain@db:5432/app")
+	if got, _ := loader.Secret("database.url"); got.Value() != "postgres://plain@db:5432/app" {
+		t.Errorf("Secret() = %q, want a URL with an unregistered scheme returned unchanged", got.Value())
+	}
+}
+
+func TestFileProvider_Root(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "run", "secrets"), 0o700); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	writeFile(t, filepath.Join(root, "run", "secrets", "jwt"), "local-jwt\r\n")
+
+	ref, _ := url.Parse("file:///run/secrets/jwt")
+	got, err := FileProvider{Root: root}.Resolve(context.Background(), ref)
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if got != "local-jwt" {
+		t.Errorf("Resolve() = %q, want %q", got, "local-jwt")
+	}
+}
@@ -1,33 +1,198 @@
 package config
 
+import (
+	"fmt"
+	"time"
+)
+
 // Common configuration types that can be composed by services
 
 type ServerConfig struct {
 	Host string `mapstructure:"host"`
 	Port string `mapstructure:"port"`
+	// AdminPort is where the admin listener (pprof, log level, build info) serves; empty turns
+	// it off.
+	AdminPort string `mapstructure:"admin_port"`
+	// TLS turns on HTTPS for the public listener when it names a certificate.
+	TLS TLSConfig `mapstructure:"tls"`
+}
+
+// Validate checks that the admin listener, if enabled, does not share the public port, and that
+// the TLS settings are complete.
+func (c ServerConfig) Validate() error {
+	if c.AdminPort != "" && c.AdminPort == c.Port {
+		return fmt.Errorf("admin port %s must differ from the server port", c.AdminPort)
+	}
+	if c.TLS.CAFile != "" && c.TLS.CertFile == "" {
+		return fmt.Errorf("server TLS with a client CA also needs a certificate")
+	}
+	if err := c.TLS.Validate(); err != nil {
+		return fmt.Errorf("server TLS: %w", err)
+	}
+	return nil
+}
+
+// TLSConfig names the PEM files used for TLS between services. On a server, CertFile and KeyFile
+// are what it serves, and CAFile, when set, makes it require client certificates signed by that
+// CA (mutual TLS). On a client, CAFile verifies the server instead of the system roots, CertFile
+// and KeyFile are the certificate it presents, and ServerName overrides the name it expects.
+// The files are re-read every ReloadInterval, so rotated certificates take effect without a
+// restart; zero turns reloading off.
+type TLSConfig struct {
+	CertFile       string        `mapstructure:"cert_file"`
+	KeyFile        string        `mapstructure:"key_file"`
+	CAFile         string        `mapstructure:"ca_file"`
+	ServerName     string        `mapstructure:"server_name"`
+	ReloadInterval time.Duration `mapstructure:"reload_interval"`
+}
+
+// Enabled reports whether any TLS file is configured.
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != "" || c.CAFile != ""
+}
+
+func (c TLSConfig) Validate() error {
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return fmt.Errorf("TLS certificate and key files must be set together")
+	}
+	if c.ReloadInterval < 0 {
+		return fmt.Errorf("TLS reload interval must not be negative, got %s", c.ReloadInterval)
+	}
+	return nil
 }
 
 type DatabaseConfig struct {
 	Host     string `mapstructure:"host"`
 	Port     string `mapstructure:"port"`
 	User     string `mapstructure:"user"`
-	Password string `mapstructure:"password"`
+	Password Secret `mapstructure:"password"`
 	DBName   string `mapstructure:"dbname"`
 	SSLMode  string `mapstructure:"sslmode"`
 }
 
+// DatabaseReplicaConfig points reads at a streaming replica of the service's database. An empty
+// URL keeps every read on the primary. Reads fall back to the primary while the replica is more
+// than MaxLag behind, and stay there for ReadYourWritesWindow after a write they depend on.
+type DatabaseReplicaConfig struct {
+	URL                  Secret        `mapstructure:"url"`
+	MaxLag               time.Duration `mapstructure:"max_lag"`
+	LagCheckInterval     time.Duration `mapstructure:"lag_check_interval"`
+	ReadYourWritesWindow time.Duration `mapstructure:"read_your_writes_window"`
+}
+
+// Enabled reports whether a replica is configured.
+func (c DatabaseReplicaConfig) Enabled() bool {
+	return c.URL != ""
+}
+
+// Validate checks the lag settings of a configured replica.
+func (c DatabaseReplicaConfig) Validate() error {
+	if !c.Enabled() {
+		return nil
+	}
+	if c.MaxLag <= 0 {
+		return fmt.Errorf("replica max lag must be positive, got %s", c.MaxLag)
+	}
+	if c.LagCheckInterval <= 0 {
+		return fmt.Errorf("replica lag check interval must be positive, got %s", c.LagCheckInterval)
+	}
+	if c.ReadYourWritesWindow < 0 {
+		return fmt.Errorf("read-your-writes window must not be negative, got %s", c.ReadYourWritesWindow)
+	}
+	return nil
+}
+
+// RedisConfig locates Redis. Mode is "standalone" (the default), which connects to URL;
+// "sentinel", which asks the sentinels in Addrs for the master named MasterName; or "cluster",
+// which discovers the cluster from the seed nodes in Addrs. Password authenticates to the data
+// nodes in the last two modes, SentinelPassword to the sentinels.
 type RedisConfig struct {
-	Host     string `mapstructure:"host"`
-	Port     string `mapstructure:"port"`
-	Password string `mapstructure:"password"`
-	DB       int    `mapstructure:"db"`
-	URL      string `mapstructure:"url"`
+	Host             string   `mapstructure:"host"`
+	Port             string   `mapstructure:"port"`
+	Password         Secret   `mapstructure:"password"`
+	DB               int      `mapstructure:"db"`
+	URL              string   `mapstructure:"url"`
+	Mode             string   `mapstructure:"mode"`
+	Addrs            []string `mapstructure:"addrs"`
+	MasterName       string   `mapstructure:"master_name"`
+	SentinelPassword Secret   `mapstructure:"sentinel_password"`
+}
+
+// Validate checks that the settings Mode needs are present.
+func (c RedisConfig) Validate() error {
+	switch c.Mode {
+	case "", "standalone":
+		if c.URL == "" {
+			return fmt.Errorf("REDIS_URL environment variable is not set")
+		}
+	case "sentinel":
+		if len(c.Addrs) == 0 || c.MasterName == "" {
+			return fmt.Errorf("redis sentinel mode needs REDIS_ADDRS and REDIS_MASTER_NAME")
+		}
+	case "cluster":
+		if len(c.Addrs) == 0 {
+			return fmt.Errorf("redis cluster mode needs REDIS_ADDRS")
+		}
+	default:
+		return fmt.Errorf("unknown redis mode %q", c.Mode)
+	}
+	return nil
+}
+
+// LocalCacheConfig sizes the in-process cache tier kept in front of Redis. A MaxEntries of 0
+// disables the tier.
+type LocalCacheConfig struct {
+	MaxEntries int           `mapstructure:"max_entries"`
+	TTL        time.Duration `mapstructure:"ttl"`
+}
+
+// FeatureFlagsConfig selects where feature flags are read from: Backend is "file" (Path names a
+// JSON file), "postgres" (the service's feature_flags table) or empty to run on the built-in
+// defaults. Flags are re-read every RefreshInterval.
+type FeatureFlagsConfig struct {
+	Backend         string        `mapstructure:"backend"`
+	Path            string        `mapstructure:"path"`
+	RefreshInterval time.Duration `mapstructure:"refresh_interval"`
+}
+
+func (c FeatureFlagsConfig) Validate() error {
+	switch c.Backend {
+	case "", "postgres":
+	case "file":
+		if c.Path == "" {
+			return fmt.Errorf("feature flag file backend needs a path")
+		}
+	default:
+		return fmt.Errorf("unknown feature flag backend %q", c.Backend)
+	}
+	if c.Backend != "" && c.RefreshInterval <= 0 {
+		return fmt.Errorf("feature flag refresh interval must be positive, got %s", c.RefreshInterval)
+	}
+	return nil
 }
 
 type KafkaConfig struct {
 	Brokers  []string `mapstructure:"brokers"`
 	GroupID  string   `mapstructure:"group_id"`
 	DLQTopic string   `mapstructure:"dlq_topic"`
+	// LogPayloads logs the raw value of Kafka messages that fail to parse. Off by default, as
+	// payloads carry customer data.
+	LogPayloads bool `mapstructure:"log_payloads"`
+}
+
+// MigrationsConfig controls applying a service's embedded migrations as it starts. Timeout covers
+// waiting for another replica's migration lock as well as migrating.
+type MigrationsConfig struct {
+	OnStartup bool          `mapstructure:"on_startup"`
+	Timeout   time.Duration `mapstructure:"timeout"`
+}
+
+// Validate checks that a startup migration has time to run.
+func (c MigrationsConfig) Validate() error {
+	if c.OnStartup && c.Timeout <= 0 {
+		return fmt.Errorf("migration timeout must be positive when migrating on startup, got %s", c.Timeout)
+	}
+	return nil
 }
 
 type JaegerConfig struct {
@@ -40,7 +205,26 @@ type ServiceConfig struct {
 }
 
 type LoggerConfig struct {
-	Level       string   `mapstructure:"level"`
-	Environment string   `mapstructure:"environment"`
-	OutputPaths []string `mapstructure:"output_paths"`
+	Level       string                `mapstructure:"level"`
+	Environment string                `mapstructure:"environment"`
+	OutputPaths []string              `mapstructure:"output_paths"`
+	Sampling    LoggerSamplingConfig  `mapstructure:"sampling"`
+	Redaction   LoggerRedactionConfig `mapstructure:"redaction"`
+}
+
+// LoggerRedactionConfig controls how personal data is kept out of logs. Mode is mask, hash or
+// off; Fields are field names redacted whatever they hold, on top of the logger's defaults.
+type LoggerRedactionConfig struct {
+	Mode    string   `mapstructure:"mode"`
+	Fields  []string `mapstructure:"fields"`
+	HashKey Secret   `mapstructure:"hash_key"`
+}
+
+// LoggerSamplingConfig caps repeated log lines: within each Tick, the first Initial lines with
+// the same level and message are written, then every Thereafter-th. An Initial of 0 turns
+// sampling off.
+type LoggerSamplingConfig struct {
+	Initial    int           `mapstructure:"initial"`
+	Thereafter int           `mapstructure:"thereafter"`
+	Tick       time.Duration `mapstructure:"tick"`
 }
@@ -1,6 +1,9 @@
 package config
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestRedisConfig_URLParsedThroughViper(t *testing.T) {
 	loader := New("redis_types_service")
@@ -35,3 +38,114 @@ func TestKafkaConfig_GroupIDAndDLQTopicParsedThroughViper(t *testing.T) {
 		t.Errorf("cfg.Kafka.DLQTopic = %q, want %q", cfg.Kafka.DLQTopic, "orders.events.dlq")
 	}
 }
+
+func TestFeatureFlagsConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     FeatureFlagsConfig
+		wantErr bool
+	}{
+		{name: "defaults only", cfg: FeatureFlagsConfig{}},
+		{name: "file", cfg: FeatureFlagsConfig{Backend: "file", Path: "/etc/flags.json", RefreshInterval: time.Second}},
+		{name: "file without path", cfg: FeatureFlagsConfig{Backend: "file", RefreshInterval: time.Second}, wantErr: true},
+		{name: "postgres", cfg: FeatureFlagsConfig{Backend: "postgres", RefreshInterval: time.Second}},
+		{name: "no refresh interval", cfg: FeatureFlagsConfig{Backend: "postgres"}, wantErr: true},
+		{name: "unknown backend", cfg: FeatureFlagsConfig{Backend: "consul", RefreshInterval: time.Second}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestServerConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     ServerConfig
+		wantErr bool
+	}{
+		{name: "admin disabled", cfg: ServerConfig{Port: "8080"}},
+		{name: "separate admin port", cfg: ServerConfig{Port: "8080", AdminPort: "9090"}},
+		{name: "admin on the public port", cfg: ServerConfig{Port: "8080", AdminPort: "8080"}, wantErr: true},
+		{name: "tls", cfg: ServerConfig{Port: "8443", TLS: TLSConfig{CertFile: "tls.crt", KeyFile: "tls.key", CAFile: "ca.crt"}}},
+		{name: "tls certificate without a key", cfg: ServerConfig{Port: "8443", TLS: TLSConfig{CertFile: "tls.crt"}}, wantErr: true},
+		{name: "client CA without a certificate", cfg: ServerConfig{Port: "8443", TLS: TLSConfig{CAFile: "ca.crt"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMigrationsConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     MigrationsConfig
+		wantErr bool
+	}{
+		{name: "off", cfg: MigrationsConfig{}},
+		{name: "on startup", cfg: MigrationsConfig{OnStartup: true, Timeout: time.Minute}},
+		{name: "on startup without a timeout", cfg: MigrationsConfig{OnStartup: true}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDatabaseReplicaConfigValidate(t *testing.T) {
+	replica := DatabaseReplicaConfig{URL: "postgres://replica/orders", MaxLag: 5 * time.Second, LagCheckInterval: time.Second}
+	tests := []struct {
+		name    string
+		cfg     DatabaseReplicaConfig
+		wantErr bool
+	}{
+		{name: "no replica", cfg: DatabaseReplicaConfig{}},
+		{name: "replica", cfg: replica},
+		{name: "replica without a max lag", cfg: DatabaseReplicaConfig{URL: replica.URL, LagCheckInterval: time.Second}, wantErr: true},
+		{name: "replica without a check interval", cfg: DatabaseReplicaConfig{URL: replica.URL, MaxLag: time.Second}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRedisConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     RedisConfig
+		wantErr bool
+	}{
+		{name: "standalone", cfg: RedisConfig{URL: "redis://redis:6379/0"}},
+		{name: "standalone without a URL", cfg: RedisConfig{Mode: "standalone"}, wantErr: true},
+		{name: "sentinel", cfg: RedisConfig{Mode: "sentinel", Addrs: []string{"sentinel:26379"}, MasterName: "eventflow"}},
+		{name: "sentinel without a master name", cfg: RedisConfig{Mode: "sentinel", Addrs: []string{"sentinel:26379"}}, wantErr: true},
+		{name: "cluster", cfg: RedisConfig{Mode: "cluster", Addrs: []string{"redis-0:6379"}}},
+		{name: "cluster without nodes", cfg: RedisConfig{Mode: "cluster"}, wantErr: true},
+		{name: "unknown mode", cfg: RedisConfig{Mode: "ring", URL: "redis://redis:6379/0"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
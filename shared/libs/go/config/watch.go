@@ -0,0 +1,203 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+)
+
+// reloadDebounce coalesces the burst of events a single change produces, such as an editor's
+// write-then-rename or the symlink swap Kubernetes does when it updates a ConfigMap, into one
+// reload.
+const reloadDebounce = 100 * time.Millisecond
+
+// BuildFunc loads a complete configuration from loader, applying any post-processing and
+// validation the service needs. A Watcher calls it on every change, so it must be safe to call
+// repeatedly.
+type BuildFunc[T any] func(loader *CfgLoader) (*T, error)
+
+// Watcher reloads a configuration whenever its config file or config directories change. A
+// reloaded configuration is only applied if build accepts it; an invalid one is logged and the
+// previous configuration stays in effect. Subscribers registered with OnChange are told about the
+// sections that changed.
+type Watcher[T any] struct {
+	loader *CfgLoader
+	build  BuildFunc[T]
+	logger *zap.Logger
+
+	mu              sync.Mutex
+	current         *T
+	subscribers     []func(previous, next *T)
+	refreshInterval time.Duration
+}
+
+// NewWatcher returns a Watcher that starts from initial, the configuration the service was
+// started with, and rebuilds it with build on every change.
+func NewWatcher[T any](loader *CfgLoader, initial *T, build BuildFunc[T], logger *zap.Logger) *Watcher[T] {
+	return &Watcher[T]{loader: loader, build: build, logger: logger, current: initial}
+}
+
+// OnChange calls apply with the section of the configuration that section picks out, each time a
+// reload changes it. Sections are compared with reflect.DeepEqual, so apply only runs for changes
+// that concern it. Subscribers run on the watcher's goroutine, in registration order, and must
+// not block.
+func OnChange[T, S any](w *Watcher[T], section func(*T) S, apply func(S)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.subscribers = append(w.subscribers, func(previous, next *T) {
+		if s := section(next); !reflect.DeepEqual(section(previous), s) {
+			apply(s)
+		}
+	})
+}
+
+// SetRefreshInterval makes Run also reload every interval, whether or not a watched file changed,
+// so that secrets rotated behind a provider reference are picked up. Zero, the default, reloads
+// on file changes only. It must be called before Run.
+func (w *Watcher[T]) SetRefreshInterval(interval time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.refreshInterval = interval
+}
+
+// Current returns the configuration most recently applied.
+func (w *Watcher[T]) Current() *T {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.current
+}
+
+// Reload rebuilds the configuration and, if it is valid, makes it current and notifies
+// subscribers. An invalid configuration is returned as an error and changes nothing.
+func (w *Watcher[T]) Reload() error {
+	next, err := w.build(w.loader)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	previous := w.current
+	w.current = next
+	for _, notify := range w.subscribers {
+		notify(previous, next)
+	}
+	return nil
+}
+
+// Run watches the config file and config directories, reloading after each change and on every
+// refresh interval, until ctx is cancelled. It watches the directories holding them rather than
+// the files, since editors and Kubernetes replace files instead of writing to them in place. It
+// returns at once if there is nothing to watch and no refresh interval, and an error only if
+// watching cannot be set up.
+func (w *Watcher[T]) Run(ctx context.Context) error {
+	file, dirs := w.loader.watchedPaths()
+	if file != "" {
+		if abs, err := filepath.Abs(file); err == nil {
+			file = abs
+		}
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("create config watcher: %w", err)
+	}
+	defer func() { _ = watcher.Close() }()
+
+	watched := 0
+	var fileDir string
+	if file != "" {
+		fileDir = filepath.Dir(file)
+		if err := watcher.Add(fileDir); err != nil {
+			return fmt.Errorf("watch config file %s: %w", file, err)
+		}
+		watched++
+	}
+	for _, dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				w.logger.Warn("Config directory does not exist, not watching it", zap.String("dir", dir))
+				continue
+			}
+			return fmt.Errorf("watch config directory %s: %w", dir, err)
+		}
+		watched++
+	}
+	w.mu.Lock()
+	refreshInterval := w.refreshInterval
+	w.mu.Unlock()
+	if watched == 0 && refreshInterval <= 0 {
+		return nil
+	}
+
+	debounce := time.NewTimer(reloadDebounce)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	var refresh <-chan time.Time
+	if refreshInterval > 0 {
+		ticker := time.NewTicker(refreshInterval)
+		defer ticker.Stop()
+		refresh = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if relevant(event, file, fileDir, dirs) {
+				debounce.Reset(reloadDebounce)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			w.logger.Warn("Config watcher error", zap.Error(err))
+		case <-debounce.C:
+			if err := w.Reload(); err != nil {
+				w.logger.Error("Rejected configuration change, keeping the previous configuration", zap.Error(err))
+				continue
+			}
+			w.logger.Info("Configuration reloaded")
+		case <-refresh:
+			if err := w.Reload(); err != nil {
+				w.logger.Error("Rejected refreshed configuration, keeping the previous configuration", zap.Error(err))
+			}
+		}
+	}
+}
+
+// relevant reports whether event may have changed the configuration. Every event in a config
+// directory counts; in the config file's directory, only events for the file itself or for the
+// hidden entries Kubernetes swaps when the file is mounted from a ConfigMap.
+func relevant(event fsnotify.Event, file, fileDir string, dirs []string) bool {
+	if event.Op == fsnotify.Chmod {
+		return false
+	}
+	dir := filepath.Dir(event.Name)
+	for _, d := range dirs {
+		if filepath.Clean(d) == dir {
+			return true
+		}
+	}
+	if dir != fileDir {
+		return false
+	}
+	name := filepath.Base(event.Name)
+	return name == filepath.Base(file) || strings.HasPrefix(name, "..")
+}
@@ -0,0 +1,162 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func buildDirConfig(loader *CfgLoader) (*dirConfig, error) {
+	var cfg dirConfig
+	if err := loader.Load(&cfg); err != nil {
+		return nil, err
+	}
+	if cfg.RateLimit.RequestsPerMinute <= 0 {
+		return nil, errors.New("requests_per_minute must be positive")
+	}
+	return &cfg, nil
+}
+
+func newDirWatcher(t *testing.T, dir string) *Watcher[dirConfig] {
+	t.Helper()
+	loader := New("watch_service")
+	loader.SetDefault("rate_limit.requests_per_minute", 100)
+	loader.SetDefault("logger.level", "info")
+	loader.AddConfigDir(dir)
+
+	initial, err := buildDirConfig(loader)
+	if err != nil {
+		t.Fatalf("build initial config: %v", err)
+	}
+	return NewWatcher(loader, initial, buildDirConfig, zap.NewNop())
+}
+
+func TestWatcher_ReloadNotifiesOnlyChangedSections(t *testing.T) {
+	dir := t.TempDir()
+	w := newDirWatcher(t, dir)
+
+	var rates []int
+	var levels []string
+	OnChange(w, func(c *dirConfig) int { return c.RateLimit.RequestsPerMinute }, func(rpm int) { rates = append(rates, rpm) })
+	OnChange(w, func(c *dirConfig) string { return c.Logger.Level }, func(level string) { levels = append(levels, level) })
+
+	writeFile(t, filepath.Join(dir, "logger.level"), "debug")
+	if err := w.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+
+	if len(rates) != 0 {
+		t.Errorf("rate subscriber called with %v, want no calls for an unchanged section", rates)
+	}
+	if len(levels) != 1 || levels[0] != "debug" {
+		t.Errorf("level subscriber called with %v, want [debug]", levels)
+	}
+	if got := w.Current().Logger.Level; got != "debug" {
+		t.Errorf("Current().Logger.Level = %q, want %q", got, "debug")
+	}
+}
+
+func TestWatcher_ReloadRejectsInvalidConfig(t *testing.T) {
+	dir := t.TempDir()
+	w := newDirWatcher(t, dir)
+
+	called := false
+	OnChange(w, func(c *dirConfig) int { return c.RateLimit.RequestsPerMinute }, func(int) { called = true })
+
+	writeFile(t, filepath.Join(dir, "rate_limit.requests_per_minute"), "0")
+	if err := w.Reload(); err == nil {
+		t.Fatal("Reload() error = nil, want the validation error")
+	}
+
+	if called {
+		t.Error("subscriber called for a rejected configuration")
+	}
+	if got := w.Current().RateLimit.RequestsPerMinute; got != 100 {
+		t.Errorf("Current().RateLimit.RequestsPerMinute = %d, want the previous 100", got)
+	}
+}
+
+func TestWatcher_RunReloadsOnFileChange(t *testing.T) {
+	dir := t.TempDir()
+	w := newDirWatcher(t, dir)
+
+	changed := make(chan int, 1)
+	OnChange(w, func(c *dirConfig) int { return c.RateLimit.RequestsPerMinute }, func(rpm int) { changed <- rpm })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- w.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Run() error = %v", err)
+		}
+	})
+
+	// The watch is registered asynchronously, so keep rewriting until a reload lands.
+	deadline := time.After(5 * time.Second)
+	for {
+		writeFile(t, filepath.Join(dir, "rate_limit.requests_per_minute"), "250")
+		select {
+		case rpm := <-changed:
+			if rpm != 250 {
+				t.Errorf("subscriber got %d, want 250", rpm)
+			}
+			return
+		case <-time.After(200 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("configuration was not reloaded after the file changed")
+		}
+	}
+}
+
+func TestWatcher_RunWithNothingToWatchReturns(t *testing.T) {
+	loader := New("nothing_to_watch_service")
+	w := NewWatcher(loader, &dirConfig{}, buildDirConfig, zap.NewNop())
+
+	if err := w.Run(context.Background()); err != nil {
+		t.Errorf("Run() error = %v, want nil", err)
+	}
+}
+
+func TestWatcher_RunRefreshesSecretsPeriodically(t *testing.T) {
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	writeFile(t, tokenPath, "first")
+
+	loader := New("refresh_service")
+	loader.SetDefault("token", "file://"+tokenPath)
+	build := func(l *CfgLoader) (*secretConfig, error) {
+		var cfg secretConfig
+		if err := l.Load(&cfg); err != nil {
+			return nil, err
+		}
+		return &cfg, nil
+	}
+	initial, err := build(loader)
+	if err != nil {
+		t.Fatalf("build initial config: %v", err)
+	}
+
+	w := NewWatcher(loader, initial, build, zap.NewNop())
+	w.SetRefreshInterval(20 * time.Millisecond)
+	rotated := make(chan string, 1)
+	OnChange(w, func(c *secretConfig) Secret { return c.Token }, func(s Secret) { rotated <- s.Value() })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = w.Run(ctx) }()
+
+	writeFile(t, tokenPath, "second")
+	select {
+	case got := <-rotated:
+		if got != "second" {
+			t.Errorf("rotated secret = %q, want %q", got, "second")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("rotated secret was not picked up by the periodic refresh")
+	}
+}
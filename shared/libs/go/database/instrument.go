@@ -0,0 +1,375 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// tracerName identifies the tracer query and transaction spans are recorded against.
+const tracerName = "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/database"
+
+var tracer = otel.Tracer(tracerName)
+
+// instrumentation is shared by every connection a DB opens. Metrics and the slow query log are
+// attached after the pool is open, so they are swapped in atomically.
+type instrumentation struct {
+	metrics       atomic.Pointer[QueryMetrics]
+	slowLogger    atomic.Pointer[zap.Logger]
+	slowThreshold atomic.Int64
+}
+
+// query is one statement being timed, from the call that runs it until its rows are closed.
+type query struct {
+	inst  *instrumentation
+	ctx   context.Context
+	span  trace.Span
+	name  string
+	text  string
+	start time.Time
+}
+
+func (i *instrumentation) start(ctx context.Context, statement string) *query {
+	name := QueryNameFromContext(ctx)
+	operation, table := summarize(statement)
+	if name == "" {
+		name = queryName(statement, operation, table)
+	}
+	text := sanitize(statement)
+
+	attrs := []attribute.KeyValue{
+		semconv.DBSystemNamePostgreSQL,
+		semconv.DBQueryTextKey.String(text),
+		semconv.DBQuerySummaryKey.String(name),
+	}
+	if operation != "" {
+		attrs = append(attrs, semconv.DBOperationNameKey.String(operation))
+	}
+	if table != "" {
+		attrs = append(attrs, semconv.DBCollectionNameKey.String(table))
+	}
+	ctx, span := tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	return &query{inst: i, ctx: ctx, span: span, name: name, text: text, start: time.Now()}
+}
+
+// end records the outcome of q. driver.ErrSkip is not a failure: database/sql retries the
+// statement another way, which is timed on its own.
+func (q *query) end(err error) {
+	duration := time.Since(q.start)
+	if err != nil && !errors.Is(err, driver.ErrSkip) {
+		q.span.RecordError(err)
+		q.span.SetStatus(codes.Error, err.Error())
+	}
+	q.span.End()
+	if errors.Is(err, driver.ErrSkip) {
+		return
+	}
+
+	if m := q.inst.metrics.Load(); m != nil {
+		m.observe(q.name, err, duration)
+	}
+	threshold := time.Duration(q.inst.slowThreshold.Load())
+	if log := q.inst.slowLogger.Load(); log != nil && threshold > 0 && duration >= threshold {
+		fields := []zap.Field{
+			zap.String("query", q.name),
+			zap.Duration("duration", duration),
+			zap.String("statement", q.text),
+		}
+		if sc := q.span.SpanContext(); sc.IsValid() {
+			fields = append(fields, zap.String("trace_id", sc.TraceID().String()))
+		}
+		if err != nil {
+			fields = append(fields, zap.Error(err))
+		}
+		log.Warn("Slow database query", fields...)
+	}
+}
+
+func (q *query) endExec(result driver.Result, err error) {
+	if err == nil {
+		if affected, rerr := result.RowsAffected(); rerr == nil {
+			q.span.SetAttributes(attribute.Int64("db.response.rows_affected", affected))
+		}
+	}
+	q.end(err)
+}
+
+// connector wraps the postgres connector so every connection it opens is instrumented.
+type connector struct {
+	base driver.Connector
+	inst *instrumentation
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	cn, err := c.base.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: cn, inst: c.inst}, nil
+}
+
+func (c *connector) Driver() driver.Driver {
+	return c.base.Driver()
+}
+
+// conn instruments statements run directly on a connection and the transactions it begins. Every
+// optional driver interface is implemented and passed through, so wrapping never changes how
+// database/sql talks to the driver underneath.
+type conn struct {
+	driver.Conn
+	inst *instrumentation
+}
+
+func (c *conn) Prepare(statement string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), statement)
+}
+
+func (c *conn) PrepareContext(ctx context.Context, statement string) (driver.Stmt, error) {
+	var (
+		st  driver.Stmt
+		err error
+	)
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		st, err = p.PrepareContext(ctx, statement)
+	} else {
+		st, err = c.Conn.Prepare(statement)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &stmt{Stmt: st, conn: c, statement: statement}, nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) { // required by driver.Conn
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	_, span := tracer.Start(ctx, "transaction", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemNamePostgreSQL, attribute.Bool("db.transaction.read_only", opts.ReadOnly)))
+
+	var (
+		tx  driver.Tx
+		err error
+	)
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		tx, err = b.BeginTx(ctx, opts)
+	} else {
+		tx, err = c.Conn.Begin() // fallback for drivers without BeginTx
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.End()
+		return nil, err
+	}
+	return &transaction{Tx: tx, span: span}, nil
+}
+
+func (c *conn) ExecContext(ctx context.Context, statement string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	q := c.inst.start(ctx, statement)
+	result, err := execer.ExecContext(q.ctx, statement, args)
+	q.endExec(result, err)
+	return result, err
+}
+
+func (c *conn) QueryContext(ctx context.Context, statement string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	q := c.inst.start(ctx, statement)
+	r, err := queryer.QueryContext(q.ctx, statement, args)
+	if err != nil {
+		q.end(err)
+		return nil, err
+	}
+	return &rows{Rows: r, query: q}, nil
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *conn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *conn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *conn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// stmt instruments a prepared statement each time it runs.
+type stmt struct {
+	driver.Stmt
+	conn      *conn
+	statement string
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) { // required by driver.Stmt
+	return s.ExecContext(context.Background(), namedValues(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) { // required by driver.Stmt
+	return s.QueryContext(context.Background(), namedValues(args))
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	q := s.conn.inst.start(ctx, s.statement)
+	var (
+		result driver.Result
+		err    error
+	)
+	if e, ok := s.Stmt.(driver.StmtExecContext); ok {
+		result, err = e.ExecContext(q.ctx, args)
+	} else {
+		result, err = s.Stmt.Exec(values(args)) // fallback for drivers without ExecContext
+	}
+	q.endExec(result, err)
+	return result, err
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	q := s.conn.inst.start(ctx, s.statement)
+	var (
+		r   driver.Rows
+		err error
+	)
+	if e, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		r, err = e.QueryContext(q.ctx, args)
+	} else {
+		r, err = s.Stmt.Query(values(args)) // fallback for drivers without QueryContext
+	}
+	if err != nil {
+		q.end(err)
+		return nil, err
+	}
+	return &rows{Rows: r, query: q}, nil
+}
+
+// CheckNamedValue defers to the statement, then to its connection, which is the order
+// database/sql itself checks them in.
+func (s *stmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return s.conn.CheckNamedValue(nv)
+}
+
+// rows keeps a query's span open until its rows are read, so the span covers fetching them.
+type rows struct {
+	driver.Rows
+	query    *query
+	returned int64
+	err      error
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	err := r.Rows.Next(dest)
+	switch {
+	case err == nil:
+		r.returned++
+	case !errors.Is(err, io.EOF):
+		r.err = err
+	}
+	return err
+}
+
+func (r *rows) Close() error {
+	err := r.Rows.Close()
+	if r.query != nil {
+		r.query.span.SetAttributes(semconv.DBResponseReturnedRowsKey.Int64(r.returned))
+		r.query.end(r.err)
+		r.query = nil
+	}
+	return err
+}
+
+func (r *rows) ColumnTypeDatabaseTypeName(index int) string {
+	if t, ok := r.Rows.(driver.RowsColumnTypeDatabaseTypeName); ok {
+		return t.ColumnTypeDatabaseTypeName(index)
+	}
+	return ""
+}
+
+func (r *rows) ColumnTypeScanType(index int) reflect.Type {
+	if t, ok := r.Rows.(driver.RowsColumnTypeScanType); ok {
+		return t.ColumnTypeScanType(index)
+	}
+	return reflect.TypeFor[any]()
+}
+
+// transaction spans a transaction from BEGIN to COMMIT or ROLLBACK. Statements run inside it
+// get spans of their own under the caller's context, alongside this one.
+type transaction struct {
+	driver.Tx
+	span trace.Span
+}
+
+func (t *transaction) Commit() error {
+	err := t.Tx.Commit()
+	t.finish("commit", err)
+	return err
+}
+
+func (t *transaction) Rollback() error {
+	err := t.Tx.Rollback()
+	t.finish("rollback", err)
+	return err
+}
+
+func (t *transaction) finish(outcome string, err error) {
+	t.span.SetAttributes(attribute.String("db.transaction.outcome", outcome))
+	if err != nil {
+		t.span.RecordError(err)
+		t.span.SetStatus(codes.Error, err.Error())
+	}
+	t.span.End()
+}
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return named
+}
+
+func values(args []driver.NamedValue) []driver.Value {
+	vals := make([]driver.Value, len(args))
+	for i, a := range args {
+		vals[i] = a.Value
+	}
+	return vals
+}
@@ -0,0 +1,194 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+var spans = tracetest.NewSpanRecorder()
+
+func init() {
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)))
+}
+
+// dsnConnector opens sqlmock connections through the driver.Connector NewPostgresConnection wraps.
+type dsnConnector struct {
+	dsn string
+	drv driver.Driver
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) { return c.drv.Open(c.dsn) }
+func (c dsnConnector) Driver() driver.Driver                        { return c.drv }
+
+// newInstrumentedMock returns a DB instrumented the way NewPostgresConnection instruments one,
+// over a sqlmock connection.
+func newInstrumentedMock(t *testing.T) (*DB, sqlmock.Sqlmock) {
+	t.Helper()
+	dsn := t.Name()
+	mockDB, mock, err := sqlmock.NewWithDSN(dsn)
+	if err != nil {
+		t.Fatalf("sqlmock.NewWithDSN() error = %v", err)
+	}
+	t.Cleanup(func() { _ = mockDB.Close() })
+
+	inst := &instrumentation{}
+	db := sql.OpenDB(&connector{base: dsnConnector{dsn: dsn, drv: mockDB.Driver()}, inst: inst})
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+	return &DB{DB: db, inst: inst}, mock
+}
+
+// lastSpan returns the most recent span named name.
+func lastSpan(t *testing.T, name string) sdktrace.ReadOnlySpan {
+	t.Helper()
+	ended := spans.Ended()
+	for i := len(ended) - 1; i >= 0; i-- {
+		if ended[i].Name() == name {
+			return ended[i]
+		}
+	}
+	t.Fatalf("no span named %q", name)
+	return nil
+}
+
+func spanAttr(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestInstrumentedDB_ExecRecordsSpanAndMetrics(t *testing.T) {
+	db, mock := newInstrumentedMock(t)
+	reg := prometheus.NewRegistry()
+	db.SetQueryMetrics(NewQueryMetrics(reg))
+	mock.ExpectExec("UPDATE orders").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if _, err := db.ExecContext(context.Background(),
+		"UPDATE orders SET status = 'cancelled', updated_at = NOW() WHERE id = $1", "order-1"); err != nil {
+		t.Fatalf("ExecContext() error = %v", err)
+	}
+
+	span := lastSpan(t, "UPDATE orders")
+	if got := spanAttr(span, "db.query.text").AsString(); got != "UPDATE orders SET status = ?, updated_at = NOW() WHERE id = $1" {
+		t.Errorf("db.query.text = %q, want the literal stripped", got)
+	}
+	if got := spanAttr(span, "db.response.rows_affected").AsInt64(); got != 1 {
+		t.Errorf("db.response.rows_affected = %d, want 1", got)
+	}
+	if got := testutil.CollectAndCount(reg, "db_query_duration_seconds"); got != 1 {
+		t.Errorf("db_query_duration_seconds series = %d, want 1", got)
+	}
+}
+
+func TestInstrumentedDB_QuerySpanCoversRows(t *testing.T) {
+	db, mock := newInstrumentedMock(t)
+	mock.ExpectQuery("SELECT id FROM order_sagas").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("saga-1").AddRow("saga-2"))
+
+	ctx := WithQueryName(context.Background(), "saga.lock")
+	rows, err := db.QueryContext(ctx, "SELECT id FROM order_sagas WHERE state = $1 FOR UPDATE", "awaiting_payment")
+	if err != nil {
+		t.Fatalf("QueryContext() error = %v", err)
+	}
+	for rows.Next() {
+	}
+	_ = rows.Close()
+
+	span := lastSpan(t, "saga.lock")
+	if got := spanAttr(span, "db.response.returned_rows").AsInt64(); got != 2 {
+		t.Errorf("db.response.returned_rows = %d, want 2", got)
+	}
+	if got := spanAttr(span, "db.collection.name").AsString(); got != "order_sagas" {
+		t.Errorf("db.collection.name = %q, want order_sagas", got)
+	}
+}
+
+func TestInstrumentedDB_TransactionSpan(t *testing.T) {
+	db, mock := newInstrumentedMock(t)
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("BeginTx() error = %v", err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+
+	if got := spanAttr(lastSpan(t, "transaction"), "db.transaction.outcome").AsString(); got != "rollback" {
+		t.Errorf("db.transaction.outcome = %q, want rollback", got)
+	}
+}
+
+func TestInstrumentedDB_SlowQueryLog(t *testing.T) {
+	db, mock := newInstrumentedMock(t)
+	core, logs := observer.New(zapcore.WarnLevel)
+	db.SetSlowQueryLog(zap.New(core), 10*time.Millisecond)
+	mock.ExpectExec("DELETE FROM processed_events").WillDelayFor(20 * time.Millisecond).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM processed_events").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	for range 2 {
+		if _, err := db.ExecContext(context.Background(), "DELETE FROM processed_events WHERE processed_at < $1", time.Now()); err != nil {
+			t.Fatalf("ExecContext() error = %v", err)
+		}
+	}
+
+	if logs.Len() != 1 {
+		t.Fatalf("slow query log lines = %d, want 1", logs.Len())
+	}
+	if got := logs.All()[0].ContextMap()["query"]; got != "DELETE processed_events" {
+		t.Errorf("logged query = %v, want DELETE processed_events", got)
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"SELECT * FROM orders WHERE id = $1", "SELECT * FROM orders WHERE id = $1"},
+		{"SELECT *\n\t FROM orders  WHERE email = 'a@b.com' LIMIT 10", "SELECT * FROM orders WHERE email = ? LIMIT ?"},
+		{"INSERT INTO t2 (a) VALUES ('it''s', 1.5)", "INSERT INTO t2 (a) VALUES (?, ?)"},
+	}
+	for _, tt := range tests {
+		if got := sanitize(tt.in); got != tt.want {
+			t.Errorf("sanitize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestQueryName(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"SELECT id FROM orders WHERE id = $1", "SELECT orders"},
+		{"select id from order_sagas where id = $1 for update", "SELECT order_sagas FOR UPDATE"},
+		{"INSERT INTO outbox_messages(id) VALUES ($1)", "INSERT outbox_messages"},
+		{"UPDATE stock SET reserved = reserved + $1", "UPDATE stock"},
+		{"WITH x AS (SELECT 1) SELECT * FROM x", "WITH"},
+		{"", "unknown"},
+	}
+	for _, tt := range tests {
+		operation, table := summarize(tt.in)
+		if got := queryName(tt.in, operation, table); got != tt.want {
+			t.Errorf("queryName(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
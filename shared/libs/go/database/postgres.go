@@ -3,10 +3,12 @@ package database
 import (
 	"database/sql"
 	"fmt"
+	"sync/atomic"
 	"time"
 
-	_ "github.com/lib/pq" // registers the postgres driver for database/sql
+	"github.com/lib/pq"
 	"github.com/spf13/viper"
+	"go.uber.org/zap"
 )
 
 type PostgresConfig struct {
@@ -18,13 +20,24 @@ type PostgresConfig struct {
 
 type DB struct {
 	*sql.DB
+	inst *instrumentation
+
+	// replica is set by OpenReplica; nil keeps every read on the primary.
+	replica *replica
+	routing atomic.Pointer[RoutingMetrics]
 }
 
+// NewPostgresConnection opens a pool whose connections are traced: every statement gets a span
+// carrying its text with literal values stripped, and every transaction one from BEGIN to its
+// end. Query metrics and the slow query log are attached afterwards with SetQueryMetrics and
+// SetSlowQueryLog.
 func NewPostgresConnection(config PostgresConfig) (*DB, error) {
-	db, err := sql.Open("postgres", config.URL)
+	base, err := pq.NewConnector(config.URL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database connection: %w", err)
 	}
+	inst := &instrumentation{}
+	db := sql.OpenDB(&connector{base: base, inst: inst})
 
 	db.SetMaxOpenConns(config.MaxOpenConns)
 	db.SetMaxIdleConns(config.MaxIdleConns)
@@ -35,10 +48,30 @@ func NewPostgresConnection(config PostgresConfig) (*DB, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	return &DB{DB: db}, nil
+	return &DB{DB: db, inst: inst}, nil
+}
+
+// SetQueryMetrics attaches m so every query's duration is recorded. Passing nil disables metrics.
+func (db *DB) SetQueryMetrics(m *QueryMetrics) {
+	if db.inst != nil {
+		db.inst.metrics.Store(m)
+	}
+}
+
+// SetSlowQueryLog logs a warning for every query that takes threshold or longer, reading its
+// rows included. A nil logger or a threshold of zero turns it off.
+func (db *DB) SetSlowQueryLog(logger *zap.Logger, threshold time.Duration) {
+	if db.inst == nil {
+		return
+	}
+	db.inst.slowLogger.Store(logger)
+	db.inst.slowThreshold.Store(int64(threshold))
 }
 
 func (db *DB) Close() error {
+	if db.replica != nil {
+		_ = db.replica.db.Close()
+	}
 	return db.DB.Close()
 }
 
@@ -0,0 +1,166 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// maxStatementLength caps the statement text put on spans and in the slow query log.
+const maxStatementLength = 2048
+
+type queryNameKey struct{}
+
+// WithQueryName names the statements run with ctx, for their spans, the query latency histogram
+// and the slow query log. Without a name, one is made up from the statement, such as
+// "SELECT orders" or "UPDATE order_sagas".
+func WithQueryName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, queryNameKey{}, name)
+}
+
+// QueryNameFromContext returns the name set with WithQueryName, or "" if there is none.
+func QueryNameFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(queryNameKey{}).(string)
+	return name
+}
+
+// QueryMetrics records how long each named query takes.
+type QueryMetrics struct {
+	duration *prometheus.HistogramVec
+}
+
+// NewQueryMetrics registers db_query_duration_seconds on registerer.
+func NewQueryMetrics(registerer prometheus.Registerer) *QueryMetrics {
+	m := &QueryMetrics{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "db_query_duration_seconds",
+			Help:    "Duration of database queries in seconds, including reading their rows.",
+			Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		}, []string{"query", "status"}),
+	}
+	registerer.MustRegister(m.duration)
+	return m
+}
+
+func (m *QueryMetrics) observe(name string, err error, duration time.Duration) {
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	m.duration.WithLabelValues(name, status).Observe(duration.Seconds())
+}
+
+// summarize returns the operation a statement performs and the table it performs it on, either
+// of which may be empty.
+func summarize(statement string) (operation, table string) {
+	words := strings.Fields(statement)
+	if len(words) == 0 {
+		return "", ""
+	}
+	operation = strings.ToUpper(words[0])
+
+	var after string
+	switch operation {
+	case "SELECT", "DELETE":
+		after = "FROM"
+	case "INSERT":
+		after = "INTO"
+	case "UPDATE":
+		if len(words) > 1 {
+			return operation, tableName(words[1])
+		}
+		return operation, ""
+	default:
+		return operation, ""
+	}
+	for i := 1; i < len(words)-1; i++ {
+		if strings.EqualFold(words[i], after) {
+			return operation, tableName(words[i+1])
+		}
+	}
+	return operation, ""
+}
+
+func tableName(word string) string {
+	word = strings.TrimRight(word, ",;")
+	if i := strings.IndexByte(word, '('); i >= 0 {
+		word = word[:i]
+	}
+	return strings.Trim(word, `"`)
+}
+
+// queryName makes up a name for a statement that was not given one. Locking reads are told apart
+// from plain ones, since they are the queries that wait on other transactions.
+func queryName(statement, operation, table string) string {
+	if operation == "" {
+		return "unknown"
+	}
+	name := operation
+	if table != "" {
+		name += " " + table
+	}
+	if operation == "SELECT" && strings.Contains(strings.ToUpper(statement), "FOR UPDATE") {
+		name += " FOR UPDATE"
+	}
+	return name
+}
+
+// sanitize strips literal values from a statement, leaving its shape and placeholders, and
+// collapses its whitespace. Values normally arrive as $n arguments, which never reach a span;
+// this catches any that were written into the SQL itself.
+func sanitize(statement string) string {
+	var b strings.Builder
+	b.Grow(len(statement))
+	space := false
+	for i := 0; i < len(statement); i++ {
+		c := statement[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = b.Len() > 0
+			continue
+		case c == '\'':
+			// Skip to the closing quote; a doubled quote is an escaped one.
+			for i++; i < len(statement); i++ {
+				if statement[i] == '\'' {
+					if i+1 < len(statement) && statement[i+1] == '\'' {
+						i++
+						continue
+					}
+					break
+				}
+			}
+			c = '?'
+		case c >= '0' && c <= '9' && !partOfIdentifier(statement, i):
+			for i+1 < len(statement) && (isDigit(statement[i+1]) || statement[i+1] == '.') {
+				i++
+			}
+			c = '?'
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteByte(c)
+		if b.Len() >= maxStatementLength {
+			b.WriteString("...")
+			break
+		}
+	}
+	return b.String()
+}
+
+// partOfIdentifier reports whether the digit at i belongs to a name, such as order_items2, or a
+// placeholder, such as $2, rather than being a number.
+func partOfIdentifier(s string, i int) bool {
+	if i == 0 {
+		return false
+	}
+	p := s[i-1]
+	return p == '$' || p == '_' || isDigit(p) || (p >= 'a' && p <= 'z') || (p >= 'A' && p <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
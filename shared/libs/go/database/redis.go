@@ -3,49 +3,207 @@ package database
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 	"github.com/spf13/viper"
 )
 
+// Redis deployment modes RedisConfig.Mode selects.
+const (
+	RedisStandalone = "standalone"
+	RedisSentinel   = "sentinel"
+	RedisCluster    = "cluster"
+)
+
 type RedisConfig struct {
-	URL      string `mapstructure:"REDIS_URL"`
-	PoolSize int    `mapstructure:"REDIS_POOL_SIZE"`
+	// Mode is RedisStandalone (the default when empty), RedisSentinel or RedisCluster.
+	Mode string `mapstructure:"REDIS_MODE"`
+	// URL locates a standalone server, credentials and database included.
+	URL string `mapstructure:"REDIS_URL"`
+	// Addrs are the sentinels in Sentinel mode and the seed nodes in Cluster mode.
+	Addrs []string `mapstructure:"REDIS_ADDRS"`
+	// MasterName is the name the sentinels monitor the master under.
+	MasterName       string `mapstructure:"REDIS_MASTER_NAME"`
+	Password         string `mapstructure:"REDIS_PASSWORD"`
+	SentinelPassword string `mapstructure:"REDIS_SENTINEL_PASSWORD"`
+	// DB selects the database in Sentinel mode; Cluster mode only has database 0.
+	DB       int `mapstructure:"REDIS_DB"`
+	PoolSize int `mapstructure:"REDIS_POOL_SIZE"`
 }
 
+// RedisClient is a connection to Redis in any of the supported modes. Commands on a single key
+// work the same in every mode; code that walks the keyspace or pings servers goes through
+// ForEachNode, since in Cluster mode each node only holds part of it.
 type RedisClient struct {
-	*redis.Client
+	redis.UniversalClient
+
+	// sentinels are the sentinel addresses in Sentinel mode, pinged by NodeStatus.
+	sentinels        []string
+	sentinelPassword string
 }
 
+// NewRedisConnection connects to Redis in the mode config selects and pings it.
 func NewRedisConnection(config RedisConfig) (*RedisClient, error) {
-	opts, err := redis.ParseURL(config.URL)
+	rdb, err := newUniversalClient(config)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse redis URL: %w", err)
+		return nil, err
 	}
-	opts.PoolSize = config.PoolSize
 
-	rdb := redis.NewClient(opts)
+	client := &RedisClient{UniversalClient: rdb}
+	if config.Mode == RedisSentinel {
+		client.sentinels = config.Addrs
+		client.sentinelPassword = config.SentinelPassword
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	if err := rdb.Ping(ctx).Err(); err != nil {
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
 		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
-	return &RedisClient{Client: rdb}, nil
+	return client, nil
+}
+
+// newUniversalClient builds the client for config's mode: a plain client for a standalone
+// server, a failover client that follows the master the sentinels name, or a cluster client.
+func newUniversalClient(config RedisConfig) (redis.UniversalClient, error) {
+	switch config.Mode {
+	case "", RedisStandalone:
+		opts, err := redis.ParseURL(config.URL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse redis URL: %w", err)
+		}
+		opts.PoolSize = config.PoolSize
+		return redis.NewClient(opts), nil
+	case RedisSentinel:
+		if len(config.Addrs) == 0 || config.MasterName == "" {
+			return nil, fmt.Errorf("redis sentinel mode needs sentinel addresses and a master name")
+		}
+		return redis.NewUniversalClient(&redis.UniversalOptions{
+			Addrs:            config.Addrs,
+			MasterName:       config.MasterName,
+			Password:         config.Password,
+			SentinelPassword: config.SentinelPassword,
+			DB:               config.DB,
+			PoolSize:         config.PoolSize,
+		}), nil
+	case RedisCluster:
+		if len(config.Addrs) == 0 {
+			return nil, fmt.Errorf("redis cluster mode needs at least one node address")
+		}
+		return redis.NewUniversalClient(&redis.UniversalOptions{
+			Addrs:         config.Addrs,
+			Password:      config.Password,
+			PoolSize:      config.PoolSize,
+			IsClusterMode: true,
+		}), nil
+	default:
+		return nil, fmt.Errorf("unknown redis mode %q", config.Mode)
+	}
+}
+
+// ForEachNode calls fn with a client for every node holding data: each cluster master in Cluster
+// mode, concurrently, or the one server otherwise. Keys a node's client is given must live on
+// that node.
+func (r *RedisClient) ForEachNode(ctx context.Context, fn func(ctx context.Context, node *redis.Client) error) error {
+	switch c := r.UniversalClient.(type) {
+	case *redis.ClusterClient:
+		return c.ForEachMaster(ctx, fn)
+	case *redis.Client:
+		return fn(ctx, c)
+	default:
+		return fmt.Errorf("unsupported redis client %T", r.UniversalClient)
+	}
+}
+
+// NodeStatus pings every node of the deployment and returns each one's error, nil for a node that
+// answered, keyed by address: every master and replica in Cluster mode, the current master and
+// every sentinel in Sentinel mode, the one server otherwise.
+func (r *RedisClient) NodeStatus(ctx context.Context) map[string]error {
+	var (
+		mu     sync.Mutex
+		status = make(map[string]error)
+	)
+	record := func(addr string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		status[addr] = err
+	}
+
+	switch c := r.UniversalClient.(type) {
+	case *redis.ClusterClient:
+		// ForEachShard gives up on the first error fn returns, so each ping's error is recorded
+		// rather than returned and one unreachable node does not hide the others.
+		if err := c.ForEachShard(ctx, func(ctx context.Context, node *redis.Client) error {
+			record(node.Options().Addr, node.Ping(ctx).Err())
+			return nil
+		}); err != nil {
+			record("cluster", err)
+		}
+	case *redis.Client:
+		addr := c.Options().Addr
+		if len(r.sentinels) > 0 {
+			// A failover client dials whichever master the sentinels name, so it has no fixed
+			// address of its own.
+			addr = "master"
+		}
+		record(addr, c.Ping(ctx).Err())
+	}
+
+	var wg sync.WaitGroup
+	for _, addr := range r.sentinels {
+		wg.Go(func() {
+			sentinel := redis.NewSentinelClient(&redis.Options{Addr: addr, Password: r.sentinelPassword})
+			defer func() { _ = sentinel.Close() }()
+			record("sentinel "+addr, sentinel.Ping(ctx).Err())
+		})
+	}
+	wg.Wait()
+	return status
+}
+
+// Healthy reports whether every node NodeStatus pings answers, naming each node that does not.
+func (r *RedisClient) Healthy(ctx context.Context) error {
+	status := r.NodeStatus(ctx)
+	addrs := make([]string, 0, len(status))
+	for addr := range status {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+
+	var failed []string
+	for _, addr := range addrs {
+		if err := status[addr]; err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", addr, err))
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d of %d redis nodes unhealthy: %s", len(failed), len(status), strings.Join(failed, "; "))
 }
 
 func (r *RedisClient) Close() error {
-	return r.Client.Close()
+	return r.UniversalClient.Close()
 }
 
 func LoadRedisConfig() (RedisConfig, error) {
 	v := viper.New()
 	v.AutomaticEnv()
 
+	v.SetDefault("REDIS_MODE", RedisStandalone)
 	v.SetDefault("REDIS_URL", "redis://localhost:6379/0")
+	v.SetDefault("REDIS_ADDRS", []string{})
+	v.SetDefault("REDIS_MASTER_NAME", "")
+	v.SetDefault("REDIS_PASSWORD", "")
+	v.SetDefault("REDIS_SENTINEL_PASSWORD", "")
+	v.SetDefault("REDIS_DB", 0)
 	v.SetDefault("REDIS_POOL_SIZE", 10)
 
 	var config RedisConfig
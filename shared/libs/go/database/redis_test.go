@@ -1,9 +1,13 @@
 package database
 
 import (
+	"context"
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
 )
 
 func TestLoadRedisConfig_Defaults(t *testing.T) {
@@ -71,10 +75,66 @@ func TestNewRedisConnection_Success(t *testing.T) {
 		t.Fatalf("NewRedisConnection() error = %v", err)
 	}
 
-	if got := rc.Options().PoolSize; got != 7 {
+	client, ok := rc.UniversalClient.(*redis.Client)
+	if !ok {
+		t.Fatalf("UniversalClient = %T, want *redis.Client for a standalone server", rc.UniversalClient)
+	}
+	if got := client.Options().PoolSize; got != 7 {
 		t.Errorf("PoolSize = %d, want 7", got)
 	}
 	if err := rc.Close(); err != nil {
 		t.Fatalf("Close() error = %v", err)
 	}
 }
+
+func TestNewUniversalClient_Modes(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     RedisConfig
+		want    string
+		wantErr bool
+	}{
+		{name: "standalone by default", cfg: RedisConfig{URL: "redis://cache:6379/0"}, want: "*redis.Client"},
+		{name: "sentinel", cfg: RedisConfig{Mode: RedisSentinel, Addrs: []string{"sentinel-0:26379"}, MasterName: "eventflow"}, want: "*redis.Client"},
+		{name: "cluster", cfg: RedisConfig{Mode: RedisCluster, Addrs: []string{"redis-0:6379", "redis-1:6379"}}, want: "*redis.ClusterClient"},
+		{name: "sentinel without a master name", cfg: RedisConfig{Mode: RedisSentinel, Addrs: []string{"sentinel-0:26379"}}, wantErr: true},
+		{name: "cluster without nodes", cfg: RedisConfig{Mode: RedisCluster}, wantErr: true},
+		{name: "unknown mode", cfg: RedisConfig{Mode: "ring"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := newUniversalClient(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newUniversalClient() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			defer func() { _ = client.Close() }()
+			if got := fmt.Sprintf("%T", client); got != tt.want {
+				t.Errorf("newUniversalClient() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedisClient_Healthy(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rc, err := NewRedisConnection(RedisConfig{URL: "redis://" + mr.Addr(), PoolSize: 1})
+	if err != nil {
+		t.Fatalf("NewRedisConnection() error = %v", err)
+	}
+	defer func() { _ = rc.Close() }()
+
+	if err := rc.Healthy(context.Background()); err != nil {
+		t.Fatalf("Healthy() error = %v, want nil", err)
+	}
+
+	addr := mr.Addr()
+	mr.Close()
+	err = rc.Healthy(context.Background())
+	if err == nil || !strings.Contains(err.Error(), addr) {
+		t.Errorf("Healthy() error = %v, want one naming %s", err, addr)
+	}
+}
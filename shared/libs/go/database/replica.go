@@ -0,0 +1,313 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultReplicaMaxLag           = 5 * time.Second
+	defaultReplicaLagCheckInterval = time.Second
+	defaultReadYourWritesWindow    = 5 * time.Second
+)
+
+// replicaLagQuery reports how far the replica's replay is behind the primary, in seconds. A
+// replica that has replayed everything it received is not behind, however long ago the last
+// transaction was; a server that is not a replica at all reports 0.
+const replicaLagQuery = `
+	SELECT COALESCE(CASE
+		WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+		ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())
+	END, 0)`
+
+// Pool names, as they appear in metric labels.
+const (
+	PoolPrimary = "primary"
+	PoolReplica = "replica"
+)
+
+// Why a read went to the pool it did, as it appears in the reason label of db_reads_total.
+const (
+	routeReplica        = "replica"
+	routeTransaction    = "transaction"
+	routeReadYourWrites = "read_your_writes"
+	routeReplicaLag     = "replica_lag"
+	routeNoReplica      = "no_replica"
+)
+
+// ReplicaConfig opens the streaming replica reads are routed to.
+type ReplicaConfig struct {
+	URL          string
+	MaxOpenConns int
+	MaxIdleConns int
+	MaxLifetime  time.Duration
+	// MaxLag is how far behind the primary the replica may fall before reads go back to the
+	// primary. Defaults to 5s.
+	MaxLag time.Duration
+	// LagCheckInterval is how often MonitorReplica measures the lag. Defaults to 1s.
+	LagCheckInterval time.Duration
+	// ReadYourWritesWindow is how long reads for a key stay on the primary after NoteWrite for
+	// it. Defaults to 5s; it should cover the replica's usual lag.
+	ReadYourWritesWindow time.Duration
+}
+
+// ReadRouter picks where a read runs. *DB implements it; repositories take one through
+// SetReadRouter and read from the primary when none is set.
+type ReadRouter interface {
+	// Reader returns the connection to read on behalf of key: the transaction ctx carries, the
+	// primary, or the replica. key names what is being read, such as "customer:<id>"; an empty
+	// key opts out of read-your-writes.
+	Reader(ctx context.Context, key string) Queryer
+	// NoteWrite keeps reads for key on the primary for the read-your-writes window.
+	NoteWrite(key string)
+}
+
+type replica struct {
+	db     *sql.DB
+	maxLag time.Duration
+	check  time.Duration
+	window time.Duration
+
+	// lag is the last measured lag in nanoseconds, or -1 when the last check failed.
+	lag    atomic.Int64
+	writes sync.Map // key -> time.Time the read-your-writes window for it ends
+}
+
+// OpenReplica opens the replica pool and measures its lag once, so reads route to it as soon
+// as it returns. Its statements are traced, timed and slow-logged like the primary's. Call
+// MonitorReplica to keep the lag current.
+func (db *DB) OpenReplica(config ReplicaConfig) error {
+	base, err := pq.NewConnector(config.URL)
+	if err != nil {
+		return fmt.Errorf("failed to open replica connection: %w", err)
+	}
+	pool := sql.OpenDB(&connector{base: base, inst: db.inst})
+	pool.SetMaxOpenConns(config.MaxOpenConns)
+	pool.SetMaxIdleConns(config.MaxIdleConns)
+	pool.SetConnMaxLifetime(config.MaxLifetime)
+
+	if err := pool.Ping(); err != nil {
+		_ = pool.Close()
+		return fmt.Errorf("failed to ping replica: %w", err)
+	}
+
+	r := newReplica(pool, config)
+	// A failed first check leaves reads on the primary until MonitorReplica's next one succeeds.
+	_, _ = r.measure(context.Background())
+	db.replica = r
+	return nil
+}
+
+func newReplica(pool *sql.DB, config ReplicaConfig) *replica {
+	r := &replica{
+		db:     pool,
+		maxLag: config.MaxLag,
+		check:  config.LagCheckInterval,
+		window: config.ReadYourWritesWindow,
+	}
+	if r.maxLag <= 0 {
+		r.maxLag = defaultReplicaMaxLag
+	}
+	if r.check <= 0 {
+		r.check = defaultReplicaLagCheckInterval
+	}
+	if r.window <= 0 {
+		r.window = defaultReadYourWritesWindow
+	}
+	r.lag.Store(-1)
+	return r
+}
+
+// measure records the replica's current lag, or -1 if it cannot be read.
+func (r *replica) measure(ctx context.Context) (time.Duration, error) {
+	var seconds float64
+	if err := r.db.QueryRowContext(WithQueryName(ctx, "replica_lag"), replicaLagQuery).Scan(&seconds); err != nil {
+		r.lag.Store(-1)
+		return 0, err
+	}
+	lag := time.Duration(seconds * float64(time.Second))
+	r.lag.Store(int64(lag))
+	return lag, nil
+}
+
+// healthy reports whether the last lag check succeeded and found the replica within maxLag.
+func (r *replica) healthy() bool {
+	lag := r.lag.Load()
+	return lag >= 0 && time.Duration(lag) <= r.maxLag
+}
+
+// MonitorReplica measures the replica's lag every LagCheckInterval until ctx is done, and
+// forgets read-your-writes windows that have ended. While the lag is over MaxLag, or cannot be
+// measured, every read goes to the primary. It returns at once when no replica is open.
+func (db *DB) MonitorReplica(ctx context.Context, logger *zap.Logger) {
+	r := db.replica
+	if r == nil {
+		return
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	ticker := time.NewTicker(r.check)
+	defer ticker.Stop()
+	wasHealthy := r.healthy()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		lag, err := r.measure(ctx)
+		if m := db.routing.Load(); m != nil && err == nil {
+			m.lag.Set(lag.Seconds())
+		}
+		healthy := r.healthy()
+		switch {
+		case wasHealthy && !healthy:
+			logger.Warn("Replica unavailable or lagging, reading from the primary",
+				zap.Duration("lag", lag), zap.Duration("max_lag", r.maxLag), zap.Error(err))
+		case !wasHealthy && healthy:
+			logger.Info("Replica caught up, routing reads to it again", zap.Duration("lag", lag))
+		}
+		wasHealthy = healthy
+		r.forgetEndedWrites(time.Now())
+	}
+}
+
+func (r *replica) forgetEndedWrites(now time.Time) {
+	r.writes.Range(func(key, until any) bool {
+		if now.After(until.(time.Time)) {
+			r.writes.Delete(key)
+		}
+		return true
+	})
+}
+
+// Reader routes a read. A read inside a transaction stays in it; otherwise it goes to the
+// replica unless there is none, the replica is lagging, or key was written within the
+// read-your-writes window. The window is tracked per process, so a read served by another
+// replica of the service may still miss the write.
+func (db *DB) Reader(ctx context.Context, key string) Queryer {
+	if tx, ok := TxFromContext(ctx); ok {
+		db.countRead(PoolPrimary, routeTransaction)
+		return tx
+	}
+	r := db.replica
+	if r == nil {
+		db.countRead(PoolPrimary, routeNoReplica)
+		return db.DB
+	}
+	if key != "" {
+		if until, ok := r.writes.Load(key); ok && time.Now().Before(until.(time.Time)) {
+			db.countRead(PoolPrimary, routeReadYourWrites)
+			return db.DB
+		}
+	}
+	if !r.healthy() {
+		db.countRead(PoolPrimary, routeReplicaLag)
+		return db.DB
+	}
+	db.countRead(PoolReplica, routeReplica)
+	return r.db
+}
+
+// NoteWrite starts the read-your-writes window for key. It is a no-op without a replica.
+func (db *DB) NoteWrite(key string) {
+	if db.replica == nil || key == "" {
+		return
+	}
+	db.replica.writes.Store(key, time.Now().Add(db.replica.window))
+}
+
+func (db *DB) countRead(pool, reason string) {
+	if m := db.routing.Load(); m != nil {
+		m.reads.WithLabelValues(pool, reason).Inc()
+	}
+}
+
+// RoutingMetrics counts where reads are routed and tracks the replica's lag.
+type RoutingMetrics struct {
+	reads *prometheus.CounterVec
+	lag   prometheus.Gauge
+}
+
+// NewRoutingMetrics registers db_reads_total and db_replica_lag_seconds on registerer.
+func NewRoutingMetrics(registerer prometheus.Registerer) *RoutingMetrics {
+	m := &RoutingMetrics{
+		reads: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "db_reads_total",
+			Help: "Total number of reads routed, by the pool that served them and why.",
+		}, []string{"pool", "reason"}),
+		lag: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "db_replica_lag_seconds",
+			Help: "Replication lag of the read replica at the last check.",
+		}),
+	}
+	registerer.MustRegister(m.reads, m.lag)
+	return m
+}
+
+// SetRoutingMetrics attaches m so read routing and replica lag are recorded. Passing nil
+// disables them.
+func (db *DB) SetRoutingMetrics(m *RoutingMetrics) {
+	db.routing.Store(m)
+}
+
+// PoolMetrics reports connection pool stats for the primary and, once one is open, the
+// replica, labelled by pool. It is read at scrape time.
+type PoolMetrics struct {
+	db *DB
+
+	open    *prometheus.Desc
+	inUse   *prometheus.Desc
+	idle    *prometheus.Desc
+	waits   *prometheus.Desc
+	waitDur *prometheus.Desc
+}
+
+// NewPoolMetrics builds a PoolMetrics collector for db. Register it to have it scraped.
+func NewPoolMetrics(db *DB) *PoolMetrics {
+	labels := []string{"pool"}
+	return &PoolMetrics{
+		db:      db,
+		open:    prometheus.NewDesc("db_pool_connections_open", "Established connections in the pool, in use and idle.", labels, nil),
+		inUse:   prometheus.NewDesc("db_pool_connections_in_use", "Connections of the pool currently in use.", labels, nil),
+		idle:    prometheus.NewDesc("db_pool_connections_idle", "Idle connections in the pool.", labels, nil),
+		waits:   prometheus.NewDesc("db_pool_wait_total", "Total number of times a caller waited for a connection from the pool.", labels, nil),
+		waitDur: prometheus.NewDesc("db_pool_wait_seconds_total", "Total time callers spent waiting for a connection from the pool.", labels, nil),
+	}
+}
+
+// Describe sends every metric descriptor this collector can report.
+func (m *PoolMetrics) Describe(ch chan<- *prometheus.Desc) {
+	ch <- m.open
+	ch <- m.inUse
+	ch <- m.idle
+	ch <- m.waits
+	ch <- m.waitDur
+}
+
+// Collect reports the current stats of each pool.
+func (m *PoolMetrics) Collect(ch chan<- prometheus.Metric) {
+	m.collect(ch, PoolPrimary, m.db.DB.Stats())
+	if r := m.db.replica; r != nil {
+		m.collect(ch, PoolReplica, r.db.Stats())
+	}
+}
+
+func (m *PoolMetrics) collect(ch chan<- prometheus.Metric, pool string, stats sql.DBStats) {
+	ch <- prometheus.MustNewConstMetric(m.open, prometheus.GaugeValue, float64(stats.OpenConnections), pool)
+	ch <- prometheus.MustNewConstMetric(m.inUse, prometheus.GaugeValue, float64(stats.InUse), pool)
+	ch <- prometheus.MustNewConstMetric(m.idle, prometheus.GaugeValue, float64(stats.Idle), pool)
+	ch <- prometheus.MustNewConstMetric(m.waits, prometheus.CounterValue, float64(stats.WaitCount), pool)
+	ch <- prometheus.MustNewConstMetric(m.waitDur, prometheus.CounterValue, stats.WaitDuration.Seconds(), pool)
+}
@@ -0,0 +1,136 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// newRoutedDB returns a DB with sqlmock pools for the primary and the replica.
+func newRoutedDB(t *testing.T, config ReplicaConfig) (*DB, *sql.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	primary, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New() error = %v", err)
+	}
+	replicaPool, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New() error = %v", err)
+	}
+	t.Cleanup(func() {
+		_ = primary.Close()
+		_ = replicaPool.Close()
+	})
+	return &DB{DB: primary, replica: newReplica(replicaPool, config)}, replicaPool, mock
+}
+
+func expectLag(mock sqlmock.Sqlmock, seconds float64) {
+	mock.ExpectQuery("pg_last_wal_replay_lsn").WillReturnRows(sqlmock.NewRows([]string{"lag"}).AddRow(seconds))
+}
+
+func TestReader_WithoutReplicaReadsPrimary(t *testing.T) {
+	primary, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New() error = %v", err)
+	}
+	defer primary.Close()
+	db := &DB{DB: primary}
+
+	db.NoteWrite("customer:1")
+	if got := db.Reader(context.Background(), "customer:1"); got != primary {
+		t.Errorf("Reader() = %v, want the primary", got)
+	}
+}
+
+func TestReader_RoutesOnLagAndRecentWrites(t *testing.T) {
+	db, replicaPool, mock := newRoutedDB(t, ReplicaConfig{MaxLag: time.Second, ReadYourWritesWindow: time.Minute})
+	m := NewRoutingMetrics(prometheus.NewRegistry())
+	db.SetRoutingMetrics(m)
+	ctx := context.Background()
+
+	if got := db.Reader(ctx, ""); got != db.DB {
+		t.Errorf("Reader() before any lag check = %v, want the primary", got)
+	}
+
+	expectLag(mock, 0.2)
+	if _, err := db.replica.measure(ctx); err != nil {
+		t.Fatalf("measure() error = %v", err)
+	}
+	if got := db.Reader(ctx, "customer:1"); got != replicaPool {
+		t.Errorf("Reader() with the replica caught up = %v, want the replica", got)
+	}
+
+	db.NoteWrite("customer:1")
+	if got := db.Reader(ctx, "customer:1"); got != db.DB {
+		t.Errorf("Reader() right after a write = %v, want the primary", got)
+	}
+	if got := db.Reader(ctx, "customer:2"); got != replicaPool {
+		t.Errorf("Reader() for another key = %v, want the replica", got)
+	}
+
+	expectLag(mock, 3)
+	if _, err := db.replica.measure(ctx); err != nil {
+		t.Fatalf("measure() error = %v", err)
+	}
+	if got := db.Reader(ctx, "customer:2"); got != db.DB {
+		t.Errorf("Reader() with the replica 3s behind = %v, want the primary", got)
+	}
+
+	for _, tc := range []struct {
+		pool, reason string
+		want         float64
+	}{
+		{PoolReplica, routeReplica, 2},
+		{PoolPrimary, routeReadYourWrites, 1},
+		{PoolPrimary, routeReplicaLag, 2},
+	} {
+		if got := testutil.ToFloat64(m.reads.WithLabelValues(tc.pool, tc.reason)); got != tc.want {
+			t.Errorf("db_reads_total{pool=%s,reason=%s} = %v, want %v", tc.pool, tc.reason, got, tc.want)
+		}
+	}
+}
+
+func TestReader_StaysInTransaction(t *testing.T) {
+	primary, primaryMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New() error = %v", err)
+	}
+	defer primary.Close()
+	replicaPool, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New() error = %v", err)
+	}
+	defer replicaPool.Close()
+	db := &DB{DB: primary, replica: newReplica(replicaPool, ReplicaConfig{})}
+	expectLag(mock, 0)
+	if _, err := db.replica.measure(context.Background()); err != nil {
+		t.Fatalf("measure() error = %v", err)
+	}
+
+	primaryMock.ExpectBegin()
+	primaryMock.ExpectCommit()
+	err = WithTx(context.Background(), primary, func(ctx context.Context, tx *sql.Tx) error {
+		if got := db.Reader(ctx, ""); got != tx {
+			t.Errorf("Reader() inside WithTx = %v, want the transaction", got)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithTx() error = %v", err)
+	}
+}
+
+func TestReplica_ForgetEndedWrites(t *testing.T) {
+	db, _, _ := newRoutedDB(t, ReplicaConfig{ReadYourWritesWindow: time.Second})
+	db.NoteWrite("customer:1")
+
+	db.replica.forgetEndedWrites(time.Now().Add(2 * time.Second))
+	if _, ok := db.replica.writes.Load("customer:1"); ok {
+		t.Error("forgetEndedWrites() kept a window that had ended")
+	}
+}
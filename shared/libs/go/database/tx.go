@@ -0,0 +1,202 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	defaultTxAttempts  = 3
+	defaultTxBaseDelay = 20 * time.Millisecond
+)
+
+// Postgres error codes a transaction can simply be run again after: another transaction won a
+// serialization conflict, or the two deadlocked and this one was chosen to abort.
+const (
+	sqlStateSerializationFailure = "40001"
+	sqlStateDeadlockDetected     = "40P01"
+)
+
+type txKey struct{}
+
+type afterCommitKey struct{}
+
+// afterCommit collects the functions AfterCommit registered during one attempt of a transaction.
+type afterCommit struct {
+	fns []func()
+}
+
+// Queryer is what *sql.DB and *sql.Tx have in common.
+type Queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// TxFromContext returns the transaction WithTx put in ctx, if there is one.
+func TxFromContext(ctx context.Context) (*sql.Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(*sql.Tx)
+	return tx, ok
+}
+
+// Conn returns the transaction ctx carries, or db if it carries none, so a repository method
+// called from inside WithTx reads and writes in that transaction without taking it as an
+// argument.
+func Conn(ctx context.Context, db *sql.DB) Queryer {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx
+	}
+	return db
+}
+
+// AfterCommit runs fn once the transaction WithTx put in ctx has committed, or at once when ctx
+// carries none. fn is dropped if the transaction rolls back, and a transaction WithTx runs again
+// only keeps what its last attempt registered. It suits effects that must not be seen before the
+// write is, such as starting a read-your-writes window.
+func AfterCommit(ctx context.Context, fn func()) {
+	if hooks, ok := ctx.Value(afterCommitKey{}).(*afterCommit); ok {
+		hooks.fns = append(hooks.fns, fn)
+		return
+	}
+	fn()
+}
+
+type txConfig struct {
+	opts      sql.TxOptions
+	attempts  int
+	baseDelay time.Duration
+	separate  bool
+}
+
+// TxOption configures WithTx.
+type TxOption func(*txConfig)
+
+// Isolation runs the transaction at level instead of the database default, READ COMMITTED.
+func Isolation(level sql.IsolationLevel) TxOption {
+	return func(c *txConfig) { c.opts.Isolation = level }
+}
+
+// ReadOnly runs the transaction read-only.
+func ReadOnly() TxOption {
+	return func(c *txConfig) { c.opts.ReadOnly = true }
+}
+
+// MaxAttempts runs the transaction at most n times when it keeps failing with a serialization
+// failure or deadlock. The default is 3; 1 turns retrying off.
+func MaxAttempts(n int) TxOption {
+	return func(c *txConfig) { c.attempts = n }
+}
+
+// Separate begins a transaction of its own even when ctx already carries one, for work that has
+// to commit whatever becomes of the caller's transaction.
+func Separate() TxOption {
+	return func(c *txConfig) { c.separate = true }
+}
+
+// TxMetrics counts transactions WithTx runs again.
+type TxMetrics struct {
+	retries *prometheus.CounterVec
+}
+
+// NewTxMetrics registers db_transaction_retries_total on registerer.
+func NewTxMetrics(registerer prometheus.Registerer) *TxMetrics {
+	m := &TxMetrics{
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "db_transaction_retries_total",
+			Help: "Total number of transactions retried after a serialization failure or deadlock.",
+		}, []string{"sqlstate"}),
+	}
+	registerer.MustRegister(m.retries)
+	return m
+}
+
+var txMetrics atomic.Pointer[TxMetrics]
+
+// SetTxMetrics attaches m so every retry WithTx makes, anywhere in the process, is counted.
+// Passing nil disables metrics.
+func SetTxMetrics(m *TxMetrics) {
+	txMetrics.Store(m)
+}
+
+// WithTx runs fn in a transaction on db and commits it if fn returns nil, rolling it back
+// otherwise. The context fn receives carries the transaction, so a nested WithTx, or a repository
+// reading through Conn, joins it instead of beginning another; the outermost WithTx alone commits.
+//
+// When fn or the commit fails with a serialization failure or deadlock, the whole transaction is
+// run again after a short backoff, up to MaxAttempts. fn may therefore run more than once and must
+// not have effects outside the transaction that cannot be repeated. A nested call never retries on
+// its own, since only the outermost transaction can be run again; the error reaches it instead.
+//
+// fn's error is returned as it is, so callers can still inspect it with errors.As.
+func WithTx(ctx context.Context, db *sql.DB, fn func(ctx context.Context, tx *sql.Tx) error, opts ...TxOption) error {
+	cfg := txConfig{attempts: defaultTxAttempts, baseDelay: defaultTxBaseDelay}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if tx, ok := TxFromContext(ctx); ok && !cfg.separate {
+		return fn(ctx, tx)
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := runTx(ctx, db, &cfg.opts, fn)
+		state, retryable := retryableState(err)
+		if !retryable || attempt >= cfg.attempts {
+			return err
+		}
+
+		if m := txMetrics.Load(); m != nil {
+			m.retries.WithLabelValues(state).Inc()
+		}
+		// Full jitter, so transactions that collided do not collide again in step.
+		delay := time.Duration(rand.Int64N(int64(cfg.baseDelay) << (attempt - 1)))
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+func runTx(ctx context.Context, db *sql.DB, opts *sql.TxOptions, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	hooks := &afterCommit{}
+	txCtx := context.WithValue(context.WithValue(ctx, txKey{}, tx), afterCommitKey{}, hooks)
+	if err := fn(txCtx, tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	for _, hook := range hooks.fns {
+		hook()
+	}
+	return nil
+}
+
+// retryableState returns the SQLSTATE of err if it is one a transaction can be retried after.
+func retryableState(err error) (string, bool) {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return "", false
+	}
+	switch code := string(pqErr.Code); code {
+	case sqlStateSerializationFailure, sqlStateDeadlockDetected:
+		return code, true
+	default:
+		return "", false
+	}
+}
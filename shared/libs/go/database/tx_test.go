@@ -0,0 +1,178 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestWithTx_CommitsAndCarriesTx(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New() error = %v", err)
+	}
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE orders").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = WithTx(context.Background(), db, func(ctx context.Context, tx *sql.Tx) error {
+		if carried, ok := TxFromContext(ctx); !ok || carried != tx {
+			t.Error("TxFromContext() inside WithTx did not return the transaction")
+		}
+		// A nested call joins rather than beginning a second transaction.
+		return WithTx(ctx, db, func(ctx context.Context, _ *sql.Tx) error {
+			_, err := Conn(ctx, db).ExecContext(ctx, "UPDATE orders SET status = $1", "confirmed")
+			return err
+		})
+	})
+	if err != nil {
+		t.Fatalf("WithTx() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestAfterCommit(t *testing.T) {
+	t.Run("runs after the outermost commit", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("sqlmock.New() error = %v", err)
+		}
+		defer db.Close()
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+
+		ran := false
+		err = WithTx(context.Background(), db, func(ctx context.Context, _ *sql.Tx) error {
+			return WithTx(ctx, db, func(ctx context.Context, _ *sql.Tx) error {
+				AfterCommit(ctx, func() { ran = true })
+				if ran {
+					t.Error("AfterCommit() ran fn before the transaction committed")
+				}
+				return nil
+			})
+		})
+		if err != nil {
+			t.Fatalf("WithTx() error = %v", err)
+		}
+		if !ran {
+			t.Error("AfterCommit() did not run fn after the commit")
+		}
+	})
+
+	t.Run("drops fn on rollback", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("sqlmock.New() error = %v", err)
+		}
+		defer db.Close()
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		ran := false
+		err = WithTx(context.Background(), db, func(ctx context.Context, _ *sql.Tx) error {
+			AfterCommit(ctx, func() { ran = true })
+			return errors.New("boom")
+		})
+		if err == nil {
+			t.Fatal("WithTx() error = nil, want the error from fn")
+		}
+		if ran {
+			t.Error("AfterCommit() ran fn for a transaction that rolled back")
+		}
+	})
+
+	t.Run("runs at once without a transaction", func(t *testing.T) {
+		ran := false
+		AfterCommit(context.Background(), func() { ran = true })
+		if !ran {
+			t.Error("AfterCommit() without a transaction did not run fn")
+		}
+	})
+}
+
+func TestWithTx_RetriesSerializationFailure(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New() error = %v", err)
+	}
+	defer db.Close()
+	reg := prometheus.NewRegistry()
+	m := NewTxMetrics(reg)
+	SetTxMetrics(m)
+	defer SetTxMetrics(nil)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE inventory").WillReturnError(&pq.Error{Code: "40001"})
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE inventory").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	calls := 0
+	err = WithTx(context.Background(), db, func(ctx context.Context, tx *sql.Tx) error {
+		calls++
+		_, err := tx.ExecContext(ctx, "UPDATE inventory SET quantity_reserved = quantity_reserved + 1")
+		return err
+	}, Isolation(sql.LevelSerializable))
+	if err != nil {
+		t.Fatalf("WithTx() error = %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("fn ran %d times, want 2", calls)
+	}
+	if got := testutil.ToFloat64(m.retries.WithLabelValues("40001")); got != 1 {
+		t.Errorf("db_transaction_retries_total{sqlstate=40001} = %v, want 1", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestWithTx_GivesUpAfterMaxAttempts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New() error = %v", err)
+	}
+	defer db.Close()
+	for range 2 {
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+	}
+
+	deadlock := &pq.Error{Code: "40P01"}
+	err = WithTx(context.Background(), db, func(context.Context, *sql.Tx) error { return deadlock }, MaxAttempts(2))
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != "40P01" {
+		t.Fatalf("WithTx() error = %v, want the deadlock", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestWithTx_DoesNotRetryOtherErrors(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New() error = %v", err)
+	}
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	boom := errors.New("insufficient stock")
+	if err := WithTx(context.Background(), db, func(context.Context, *sql.Tx) error { return boom }); !errors.Is(err, boom) {
+		t.Fatalf("WithTx() error = %v, want %v", err, boom)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
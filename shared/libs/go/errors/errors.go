@@ -10,6 +10,15 @@ type AppError struct {
 	Message  string `json:"message"`
 	Details  string `json:"details,omitempty"`
 	HTTPCode int    `json:"-"`
+	// Fields lists every invalid input field when the error reports more than one at once.
+	Fields []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes one invalid input field. Field is the JSON path of the value, such as
+// "items[2].quantity".
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
 }
 
 func (e *AppError) Error() string {
@@ -76,6 +85,30 @@ func NewConflict(message string) *AppError {
 	}
 }
 
+func NewPayloadTooLarge(message string) *AppError {
+	return &AppError{
+		Code:     "PAYLOAD_TOO_LARGE",
+		Message:  message,
+		HTTPCode: http.StatusRequestEntityTooLarge,
+	}
+}
+
+func NewTooManyRequests(message string) *AppError {
+	return &AppError{
+		Code:     "RATE_LIMIT_EXCEEDED",
+		Message:  message,
+		HTTPCode: http.StatusTooManyRequests,
+	}
+}
+
+func NewServiceUnavailable(message string) *AppError {
+	return &AppError{
+		Code:     "SERVICE_UNAVAILABLE",
+		Message:  message,
+		HTTPCode: http.StatusServiceUnavailable,
+	}
+}
+
 func NewInternalServerError(message string) *AppError {
 	return &AppError{
 		Code:     "INTERNAL_SERVER_ERROR",
@@ -89,6 +122,21 @@ func NewValidationError(field, message string) *AppError {
 		Code:     "VALIDATION_ERROR",
 		Message:  fmt.Sprintf("Validation failed for field '%s': %s", field, message),
 		HTTPCode: http.StatusBadRequest,
+		Fields:   []FieldError{{Field: field, Message: message}},
+	}
+}
+
+// NewValidationErrors reports several invalid fields in a single error, so a client can fix all of
+// them in one round trip.
+func NewValidationErrors(fields ...FieldError) *AppError {
+	if len(fields) == 1 {
+		return NewValidationError(fields[0].Field, fields[0].Message)
+	}
+	return &AppError{
+		Code:     "VALIDATION_ERROR",
+		Message:  fmt.Sprintf("Validation failed for %d fields", len(fields)),
+		HTTPCode: http.StatusBadRequest,
+		Fields:   fields,
 	}
 }
 
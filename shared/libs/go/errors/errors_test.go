@@ -64,6 +64,27 @@ func TestConstructors(t *testing.T) {
 			wantHTTPCode: http.StatusConflict,
 			wantMessage:  "already exists",
 		},
+		{
+			name:         "NewPayloadTooLarge",
+			err:          NewPayloadTooLarge("too big"),
+			wantCode:     "PAYLOAD_TOO_LARGE",
+			wantHTTPCode: http.StatusRequestEntityTooLarge,
+			wantMessage:  "too big",
+		},
+		{
+			name:         "NewTooManyRequests",
+			err:          NewTooManyRequests("slow down"),
+			wantCode:     "RATE_LIMIT_EXCEEDED",
+			wantHTTPCode: http.StatusTooManyRequests,
+			wantMessage:  "slow down",
+		},
+		{
+			name:         "NewServiceUnavailable",
+			err:          NewServiceUnavailable("backend down"),
+			wantCode:     "SERVICE_UNAVAILABLE",
+			wantHTTPCode: http.StatusServiceUnavailable,
+			wantMessage:  "backend down",
+		},
 		{
 			name:         "NewInternalServerError",
 			err:          NewInternalServerError("boom"),
@@ -0,0 +1,120 @@
+package errors
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ProblemContentType is the media type of an RFC 7807 problem details document.
+const ProblemContentType = "application/problem+json"
+
+// ProblemTypeBase prefixes the type URI of every problem. The AppError code, lower-cased with
+// underscores turned into hyphens, completes it: ORDER_NOT_FOUND becomes
+// https://eventflow-commerce.dev/problems/order-not-found.
+const ProblemTypeBase = "https://eventflow-commerce.dev/problems/"
+
+// requestIDHeader is the header the request ID middleware sets on both the request and the
+// response.
+const requestIDHeader = "X-Request-ID"
+
+// Problem is an RFC 7807 problem details document. Besides the standard members it carries the
+// AppError code, so clients can keep switching on it, and the trace and request IDs of the
+// failed request, so a report can be matched to its logs and trace.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	TraceID   string       `json:"trace_id,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// ProblemType returns the type URI of the problem reported under code.
+func ProblemType(code string) string {
+	return ProblemTypeBase + strings.ToLower(strings.ReplaceAll(code, "_", "-"))
+}
+
+// ProblemTitle returns the title of the problem reported under code: the code in sentence case,
+// so ORDER_NOT_FOUND becomes "Order not found". The title is the same for every occurrence of a
+// problem; what went wrong this time goes in the detail.
+func ProblemTitle(code string) string {
+	title := strings.ToLower(strings.ReplaceAll(code, "_", " "))
+	if title == "" {
+		return title
+	}
+	return strings.ToUpper(title[:1]) + title[1:]
+}
+
+// NewProblem builds the problem document reporting appErr as the response to r. The title is
+// derived from the code and the detail carries the message, followed by the details when there
+// are any. The instance is the request path; the trace ID is taken from the span in r's context,
+// and the request ID from the X-Request-ID header. r may be nil, in which case those members are
+// left empty.
+func NewProblem(r *http.Request, appErr *AppError) Problem {
+	status := appErr.HTTPCode
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+
+	detail := appErr.Message
+	if appErr.Details != "" {
+		detail += ": " + appErr.Details
+	}
+
+	p := Problem{
+		Type:   ProblemType(appErr.Code),
+		Title:  ProblemTitle(appErr.Code),
+		Status: status,
+		Detail: detail,
+		Code:   appErr.Code,
+		Errors: appErr.Fields,
+	}
+	if r == nil {
+		return p
+	}
+
+	p.Instance = r.URL.Path
+	p.RequestID = r.Header.Get(requestIDHeader)
+	if sc := trace.SpanContextFromContext(r.Context()); sc.HasTraceID() {
+		p.TraceID = sc.TraceID().String()
+	}
+	return p
+}
+
+// WriteProblem writes appErr to w as the application/problem+json response to r, using
+// appErr.HTTPCode as the status. The request ID already set on the response, if any, takes
+// precedence over the one on the request. It returns the error from encoding the body, after the
+// status has been sent.
+func WriteProblem(w http.ResponseWriter, r *http.Request, appErr *AppError) error {
+	p := NewProblem(r, appErr)
+	if id := w.Header().Get(requestIDHeader); id != "" {
+		p.RequestID = id
+	}
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(p.Status)
+	return json.NewEncoder(w).Encode(p)
+}
+
+// FromProblem turns a problem document received from another service back into an AppError,
+// keeping the status it was returned with. The detail becomes the message, since NewProblem
+// already joined the original message and details into it; a problem without a detail falls back
+// to its title.
+func FromProblem(p Problem) *AppError {
+	message := p.Detail
+	if message == "" {
+		message = p.Title
+	}
+	return &AppError{
+		Code:     p.Code,
+		Message:  message,
+		HTTPCode: p.Status,
+		Fields:   p.Errors,
+	}
+}
@@ -0,0 +1,159 @@
+package errors
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestProblemType(t *testing.T) {
+	got := ProblemType("ORDER_NOT_FOUND")
+	want := "https://eventflow-commerce.dev/problems/order-not-found"
+	if got != want {
+		t.Errorf("ProblemType() = %q, want %q", got, want)
+	}
+}
+
+func TestProblemTitle(t *testing.T) {
+	tests := map[string]string{
+		"ORDER_NOT_FOUND":     "Order not found",
+		"RATE_LIMIT_EXCEEDED": "Rate limit exceeded",
+		"CONFLICT":            "Conflict",
+		"":                    "",
+	}
+	for code, want := range tests {
+		if got := ProblemTitle(code); got != want {
+			t.Errorf("ProblemTitle(%q) = %q, want %q", code, got, want)
+		}
+	}
+}
+
+func TestWriteProblem(t *testing.T) {
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/orders/42?verbose=1", nil).WithContext(ctx)
+	req.Header.Set("X-Request-ID", "req-1")
+	rec := httptest.NewRecorder()
+
+	if err := WriteProblem(rec, req, NewOrderNotFound("42")); err != nil {
+		t.Fatalf("WriteProblem() error = %v", err)
+	}
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if got := rec.Header().Get("Content-Type"); got != ProblemContentType {
+		t.Errorf("Content-Type = %q, want %q", got, ProblemContentType)
+	}
+
+	var got Problem
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("decode problem: %v", err)
+	}
+	want := Problem{
+		Type:      "https://eventflow-commerce.dev/problems/order-not-found",
+		Title:     "Order not found",
+		Status:    http.StatusNotFound,
+		Detail:    "Order not found: Order with ID 42 does not exist",
+		Instance:  "/api/v1/orders/42",
+		Code:      "ORDER_NOT_FOUND",
+		TraceID:   "4bf92f3577b34da6a3ce929d0e0e4736",
+		RequestID: "req-1",
+	}
+	if got.Type != want.Type || got.Title != want.Title || got.Status != want.Status ||
+		got.Detail != want.Detail || got.Instance != want.Instance || got.Code != want.Code ||
+		got.TraceID != want.TraceID || got.RequestID != want.RequestID {
+		t.Errorf("problem = %+v, want %+v", got, want)
+	}
+}
+
+func TestWriteProblem_PrefersResponseRequestID(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	rec.Header().Set("X-Request-ID", "generated")
+
+	if err := WriteProblem(rec, req, NewBadRequest("bad")); err != nil {
+		t.Fatalf("WriteProblem() error = %v", err)
+	}
+
+	var got Problem
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("decode problem: %v", err)
+	}
+	if got.RequestID != "generated" {
+		t.Errorf("request_id = %q, want %q", got.RequestID, "generated")
+	}
+	if got.TraceID != "" {
+		t.Errorf("trace_id = %q, want empty without a span", got.TraceID)
+	}
+}
+
+func TestWriteProblem_FieldErrors(t *testing.T) {
+	rec := httptest.NewRecorder()
+	appErr := NewValidationErrors(
+		FieldError{Field: "items[2].quantity", Message: "must be greater than zero"},
+		FieldError{Field: "currency", Message: "is required"},
+	)
+
+	if err := WriteProblem(rec, httptest.NewRequest(http.MethodPost, "/api/v1/orders", nil), appErr); err != nil {
+		t.Fatalf("WriteProblem() error = %v", err)
+	}
+
+	var got Problem
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("decode problem: %v", err)
+	}
+	if rec.Code != http.StatusBadRequest || got.Code != "VALIDATION_ERROR" {
+		t.Errorf("status, code = %d, %q, want %d, %q", rec.Code, got.Code, http.StatusBadRequest, "VALIDATION_ERROR")
+	}
+	if len(got.Errors) != 2 || got.Errors[0].Field != "items[2].quantity" || got.Errors[1].Field != "currency" {
+		t.Errorf("errors = %+v, want both field errors in order", got.Errors)
+	}
+}
+
+func TestNewProblem_NilRequestAndMissingStatus(t *testing.T) {
+	got := NewProblem(nil, &AppError{Code: "SOME_CODE", Message: "boom"})
+
+	if got.Status != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", got.Status, http.StatusInternalServerError)
+	}
+	if got.Instance != "" || got.RequestID != "" || got.TraceID != "" {
+		t.Errorf("request members = %+v, want empty without a request", got)
+	}
+}
+
+func TestFromProblem(t *testing.T) {
+	original := NewInsufficientInventory("p-1", 5, 2)
+
+	got := FromProblem(NewProblem(nil, original))
+
+	if got.Code != original.Code || got.Error() != original.Error() || got.HTTPCode != original.HTTPCode {
+		t.Errorf("FromProblem() = %+v, want %+v", got, original)
+	}
+}
+
+func TestFromProblem_WithoutDetailUsesTitle(t *testing.T) {
+	got := FromProblem(Problem{Title: "Conflict", Status: http.StatusConflict, Code: "CONFLICT"})
+
+	if got.Message != "Conflict" {
+		t.Errorf("FromProblem() message = %q, want %q", got.Message, "Conflict")
+	}
+}
+
+func TestNewValidationErrors_SingleFieldMatchesNewValidationError(t *testing.T) {
+	got := NewValidationErrors(FieldError{Field: "order_id", Message: "is required"})
+	want := NewValidationError("order_id", "is required")
+
+	if got.Message != want.Message || len(got.Fields) != 1 {
+		t.Errorf("NewValidationErrors() = %+v, want %+v", got, want)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/logger"
 	"github.com/google/uuid"
 	"github.com/segmentio/kafka-go"
 	"github.com/spf13/viper"
@@ -25,6 +26,10 @@ type KafkaConfig struct {
 	DLQTopic       string        `mapstructure:"KAFKA_DLQ_TOPIC"`
 	MaxRetries     int           `mapstructure:"KAFKA_MAX_RETRIES"`
 	RetryBaseDelay time.Duration `mapstructure:"KAFKA_RETRY_BASE_DELAY"`
+	// LogPayloads logs the raw value of messages that fail to unmarshal, at debug level. Payloads
+	// carry customer IDs and amounts, so it is meant for debugging a malformed producer, not for
+	// leaving on.
+	LogPayloads bool `mapstructure:"KAFKA_LOG_PAYLOADS"`
 }
 
 type Event struct {
@@ -66,6 +71,7 @@ type Subscriber struct {
 	maxRetries     int
 	retryBaseDelay time.Duration
 	metrics        *KafkaMetrics
+	logPayloads    bool
 }
 
 // SetMetrics attaches m so Publish observations are recorded. Passing nil disables metrics.
@@ -127,6 +133,7 @@ func NewSubscriber(config KafkaConfig, topic string, logger *zap.Logger) *Subscr
 		dlqWriter:      dlqWriter,
 		maxRetries:     maxRetries,
 		retryBaseDelay: retryBaseDelay,
+		logPayloads:    config.LogPayloads,
 	}
 }
 
@@ -213,15 +220,26 @@ func (s *Subscriber) processMessage(ctx context.Context, msg kafka.Message, hand
 
 	var event Event
 	if err := json.Unmarshal(msg.Value, &event); err != nil {
-		s.logger.Error("Failed to unmarshal Kafka message", zap.Error(err), zap.ByteString("message", msg.Value))
+		s.logger.Error("Failed to unmarshal Kafka message", zap.Error(err),
+			zap.String("topic", s.topic), zap.Int64("offset", msg.Offset), zap.Int("size", len(msg.Value)))
+		if s.logPayloads {
+			s.logger.Debug("Unparseable Kafka message payload", zap.Int64("offset", msg.Offset), zap.ByteString("message", msg.Value))
+		}
 		span.RecordError(err)
 		s.handleFailure(ctx, msg, "unmarshal_error", "unknown")
 		return
 	}
 
+	// Tagging the event's correlation ID lets a scoped log level turn on debug for one saga.
+	log := s.logger.With(zap.String("event_id", event.ID), zap.String("event_type", event.Type))
+	if event.CorrelationID != "" {
+		log = log.With(zap.String(logger.FieldCorrelationID, event.CorrelationID))
+	}
+	log.Debug("Handling Kafka event", zap.String("topic", s.topic), zap.Int64("offset", msg.Offset))
+
 	start := time.Now()
 	if err := s.handleWithRetry(msgCtx, event, handler); err != nil {
-		s.logger.Error("Failed to handle event after retries", zap.Error(err), zap.String("event_id", event.ID))
+		log.Error("Failed to handle event after retries", zap.Error(err))
 		span.RecordError(err)
 		s.handleFailure(ctx, msg, "handler_error", event.Type)
 		return
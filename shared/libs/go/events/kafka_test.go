@@ -12,6 +12,8 @@ import (
 	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/segmentio/kafka-go"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 // fakeReader is a substitute kafkaReader that serves a single preset message and records
@@ -681,3 +683,24 @@ func TestLoadKafkaConfig_ReturnsErrorForUnparsableEnvValue(t *testing.T) {
 		t.Fatal("LoadKafkaConfig() error = nil, want error for a non-numeric KAFKA_MAX_RETRIES")
 	}
 }
+
+func TestSubscriber_ProcessMessage_LogsPayloadOnlyWhenEnabled(t *testing.T) {
+	msg := kafka.Message{Value: []byte(`{"customer_id": "cust-1", "amount": 12`)}
+
+	for _, logPayloads := range []bool{false, true} {
+		core, logs := observer.New(zapcore.DebugLevel)
+		sub := &Subscriber{reader: &fakeReader{message: msg}, logger: zap.New(core), logPayloads: logPayloads}
+
+		sub.processMessage(context.Background(), msg, func(context.Context, Event) error { return nil })
+
+		logged := false
+		for _, entry := range logs.All() {
+			if _, ok := entry.ContextMap()["message"]; ok {
+				logged = true
+			}
+		}
+		if logged != logPayloads {
+			t.Errorf("logPayloads = %v: payload logged = %v, want %v", logPayloads, logged, logPayloads)
+		}
+	}
+}
@@ -13,6 +13,9 @@ const (
 	EventTypeOrderReadyForPayment = "order.ready_for_payment"
 	EventTypeOrderConfirmed       = "order.confirmed"
 	EventTypeOrderCancelled       = "order.cancelled"
+	EventTypeOrderProcessing      = "order.processing"
+	EventTypeOrderShipped         = "order.shipped"
+	EventTypeOrderDelivered       = "order.delivered"
 
 	EventTypePaymentInitiated = "payment.initiated"
 	EventTypePaymentProcessed = "payment.processed"
@@ -29,6 +29,9 @@ func TestEventTypeConstants(t *testing.T) {
 		{"EventTypeOrderReadyForPayment", EventTypeOrderReadyForPayment, "order.ready_for_payment"},
 		{"EventTypeOrderConfirmed", EventTypeOrderConfirmed, "order.confirmed"},
 		{"EventTypeOrderCancelled", EventTypeOrderCancelled, "order.cancelled"},
+		{"EventTypeOrderProcessing", EventTypeOrderProcessing, "order.processing"},
+		{"EventTypeOrderShipped", EventTypeOrderShipped, "order.shipped"},
+		{"EventTypeOrderDelivered", EventTypeOrderDelivered, "order.delivered"},
 		{"EventTypePaymentInitiated", EventTypePaymentInitiated, "payment.initiated"},
 		{"EventTypePaymentProcessed", EventTypePaymentProcessed, "payment.processed"},
 		{"EventTypePaymentFailed", EventTypePaymentFailed, "payment.failed"},
@@ -0,0 +1,33 @@
+package featureflags
+
+import (
+	"context"
+	"net/http"
+)
+
+// UserIDHeader is the header api-gateway sets to the authenticated user's ID.
+const UserIDHeader = "X-User-ID"
+
+type contextKey struct{}
+
+// WithUserID returns a copy of ctx carrying userID as the subject of per-user rollouts.
+func WithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, contextKey{}, userID)
+}
+
+// UserID returns the user ID ctx carries, or "" if it has none.
+func UserID(ctx context.Context) string {
+	userID, _ := ctx.Value(contextKey{}).(string)
+	return userID
+}
+
+// Middleware puts the X-User-ID header on the request context, so Flags.EnabledForUser can key
+// rollouts by user anywhere below the handler.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if userID := r.Header.Get(UserIDHeader); userID != "" {
+			r = r.WithContext(WithUserID(r.Context(), userID))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
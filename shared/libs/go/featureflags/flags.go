@@ -0,0 +1,210 @@
+// Package featureflags turns behaviour on and off at runtime, for everyone or for a stable
+// percentage of users or orders, without a deploy. Flags are read from a Source (a JSON file or a
+// Postgres table) and refreshed periodically, so a change made there or through the admin
+// endpoint reaches every replica within one refresh interval.
+package featureflags
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Flag is the state of one feature flag.
+type Flag struct {
+	Key string `json:"key"`
+	// Enabled switches the flag on; a disabled flag is off for everyone whatever its Percentage.
+	Enabled bool `json:"enabled"`
+	// Percentage is the share of subjects, 0 to 100, an enabled flag is on for. 100 turns it on
+	// for everyone.
+	Percentage  int    `json:"percentage"`
+	Description string `json:"description,omitempty"`
+}
+
+// On reports whether the flag is on for everyone.
+func (f Flag) On() bool {
+	return f.Enabled && f.Percentage >= 100
+}
+
+// OnFor reports whether the flag is on for subject, such as a user or order ID. Each subject
+// falls in a fixed bucket per flag, so raising Percentage only ever adds subjects, and a subject
+// keeps its answer between calls and across replicas.
+func (f Flag) OnFor(subject string) bool {
+	if !f.Enabled || f.Percentage <= 0 {
+		return false
+	}
+	if f.Percentage >= 100 {
+		return true
+	}
+	return bucket(f.Key, subject) < f.Percentage
+}
+
+// bucket maps subject to 0..99. The key is mixed in so that different flags rolled out to the
+// same percentage do not all land on the same subjects.
+func bucket(key, subject string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(subject))
+	return int(h.Sum32() % 100)
+}
+
+// Validate reports whether f can be stored.
+func (f Flag) Validate() error {
+	if f.Key == "" {
+		return fmt.Errorf("flag key is required")
+	}
+	if f.Percentage < 0 || f.Percentage > 100 {
+		return fmt.Errorf("flag %s: percentage must be between 0 and 100, got %d", f.Key, f.Percentage)
+	}
+	return nil
+}
+
+// Flags evaluates feature flags against the most recent snapshot loaded from its Source. A flag
+// the source does not define falls back to the default it was declared with, and an undeclared
+// one is off, so a missing or unreachable backend leaves the service on its defaults.
+type Flags struct {
+	source   Source
+	logger   *zap.Logger
+	defaults map[string]Flag
+
+	mu     sync.RWMutex
+	loaded map[string]Flag
+}
+
+// New returns Flags backed by source, with defaults used for flags the source does not define.
+// It does not read the source; call Refresh before relying on its values.
+func New(source Source, logger *zap.Logger, defaults ...Flag) *Flags {
+	f := &Flags{source: source, logger: logger, defaults: make(map[string]Flag, len(defaults))}
+	for _, d := range defaults {
+		f.defaults[d.Key] = d
+	}
+	return f
+}
+
+// Default returns a flag that is on for everyone or off for everyone, for use as a default.
+func Default(key string, enabled bool, description string) Flag {
+	return Flag{Key: key, Enabled: enabled, Percentage: 100, Description: description}
+}
+
+// Get returns the current state of key, and whether it is known at all.
+func (f *Flags) Get(key string) (Flag, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if flag, ok := f.loaded[key]; ok {
+		return flag, true
+	}
+	flag, ok := f.defaults[key]
+	if !ok {
+		flag.Key = key
+	}
+	return flag, ok
+}
+
+// Enabled reports whether key is on for everyone.
+func (f *Flags) Enabled(key string) bool {
+	flag, _ := f.Get(key)
+	return flag.On()
+}
+
+// EnabledFor reports whether key is on for subject, for a rollout keyed by something other than
+// the calling user, such as an order ID.
+func (f *Flags) EnabledFor(key, subject string) bool {
+	flag, _ := f.Get(key)
+	return flag.OnFor(subject)
+}
+
+// EnabledForUser reports whether key is on for the user Middleware found on the request ctx
+// belongs to. Without a user, only a flag on for everyone is on.
+func (f *Flags) EnabledForUser(ctx context.Context, key string) bool {
+	flag, _ := f.Get(key)
+	if userID := UserID(ctx); userID != "" {
+		return flag.OnFor(userID)
+	}
+	return flag.On()
+}
+
+// All returns every known flag, loaded or default, sorted by key.
+func (f *Flags) All() []Flag {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	all := make([]Flag, 0, len(f.defaults)+len(f.loaded))
+	for key, flag := range f.defaults {
+		if _, ok := f.loaded[key]; !ok {
+			all = append(all, flag)
+		}
+	}
+	for _, flag := range f.loaded {
+		all = append(all, flag)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Key < all[j].Key })
+	return all
+}
+
+// Refresh reloads every flag from the source. On error the previous snapshot stays in use.
+func (f *Flags) Refresh(ctx context.Context) error {
+	loaded, err := f.source.Load(ctx)
+	if err != nil {
+		return fmt.Errorf("load feature flags: %w", err)
+	}
+
+	byKey := make(map[string]Flag, len(loaded))
+	for _, flag := range loaded {
+		byKey[flag.Key] = flag
+	}
+
+	f.mu.Lock()
+	f.loaded = byKey
+	f.mu.Unlock()
+	return nil
+}
+
+// Set stores flag in the source and applies it at once, for sources that can be written to.
+// It returns ErrReadOnly for a source that cannot.
+func (f *Flags) Set(ctx context.Context, flag Flag) error {
+	if err := flag.Validate(); err != nil {
+		return err
+	}
+	writer, ok := f.source.(Writer)
+	if !ok {
+		return ErrReadOnly
+	}
+	if err := writer.Save(ctx, flag); err != nil {
+		return fmt.Errorf("save feature flag %s: %w", flag.Key, err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	loaded := make(map[string]Flag, len(f.loaded)+1)
+	for key, existing := range f.loaded {
+		loaded[key] = existing
+	}
+	loaded[flag.Key] = flag
+	f.loaded = loaded
+	return nil
+}
+
+// Run refreshes the flags every interval until ctx is cancelled. A failed refresh is logged and
+// the previous values kept.
+func (f *Flags) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := f.Refresh(ctx); err != nil {
+				f.logger.Warn("Failed to refresh feature flags, keeping the previous values", zap.Error(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package featureflags
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestFlag_OnFor(t *testing.T) {
+	tests := []struct {
+		name string
+		flag Flag
+		want int // subjects out of 1000 the flag is on for
+	}{
+		{name: "disabled", flag: Flag{Key: "f", Enabled: false, Percentage: 100}, want: 0},
+		{name: "zero percent", flag: Flag{Key: "f", Enabled: true, Percentage: 0}, want: 0},
+		{name: "everyone", flag: Flag{Key: "f", Enabled: true, Percentage: 100}, want: 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			on := 0
+			for i := 0; i < 1000; i++ {
+				if tt.flag.OnFor(fmt.Sprintf("user-%d", i)) {
+					on++
+				}
+			}
+			if on != tt.want {
+				t.Errorf("OnFor() on for %d of 1000 subjects, want %d", on, tt.want)
+			}
+		})
+	}
+}
+
+func TestFlag_OnForPercentageIsStableAndMonotonic(t *testing.T) {
+	ten := Flag{Key: "pricing.v2", Enabled: true, Percentage: 10}
+	fifty := Flag{Key: "pricing.v2", Enabled: true, Percentage: 50}
+
+	onAtTen := 0
+	for i := 0; i < 10000; i++ {
+		subject := fmt.Sprintf("order-%d", i)
+		if ten.OnFor(subject) {
+			onAtTen++
+			if !fifty.OnFor(subject) {
+				t.Fatalf("subject %s is on at 10%% but off at 50%%", subject)
+			}
+		}
+		if ten.OnFor(subject) != ten.OnFor(subject) {
+			t.Fatalf("OnFor(%s) changed between calls", subject)
+		}
+	}
+	if onAtTen < 800 || onAtTen > 1200 {
+		t.Errorf("OnFor() at 10%% on for %d of 10000 subjects, want about 1000", onAtTen)
+	}
+}
+
+func TestFlags_DefaultsApplyUntilTheSourceDefinesAFlag(t *testing.T) {
+	source := StaticSource{{Key: "cache.enabled", Enabled: false, Percentage: 100}}
+	flags := New(source, zap.NewNop(),
+		Default("cache.enabled", true, "Use the Redis cache"),
+		Default("cache.invalidation_consumer", true, ""),
+	)
+
+	if !flags.Enabled("cache.enabled") {
+		t.Error("Enabled(cache.enabled) = false before Refresh, want the default true")
+	}
+	if err := flags.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if flags.Enabled("cache.enabled") {
+		t.Error("Enabled(cache.enabled) = true, want the source's false")
+	}
+	if !flags.Enabled("cache.invalidation_consumer") {
+		t.Error("Enabled(cache.invalidation_consumer) = false, want the default for a flag the source omits")
+	}
+	if flags.Enabled("unknown") {
+		t.Error("Enabled(unknown) = true, want an undeclared flag off")
+	}
+
+	if got := len(flags.All()); got != 2 {
+		t.Errorf("All() returned %d flags, want 2", got)
+	}
+}
+
+type failingSource struct{}
+
+func (failingSource) Load(context.Context) ([]Flag, error) { return nil, errors.New("db down") }
+
+func TestFlags_RefreshErrorKeepsPreviousValues(t *testing.T) {
+	flags := New(failingSource{}, zap.NewNop(), Default("cache.enabled", true, ""))
+
+	if err := flags.Refresh(context.Background()); err == nil {
+		t.Fatal("Refresh() error = nil, want the source error")
+	}
+	if !flags.Enabled("cache.enabled") {
+		t.Error("Enabled() = false after a failed refresh, want the default kept")
+	}
+}
+
+func TestFlags_EnabledForUser(t *testing.T) {
+	flags := New(StaticSource{{Key: "f", Enabled: true, Percentage: 50}}, zap.NewNop())
+	if err := flags.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	if flags.EnabledForUser(context.Background(), "f") {
+		t.Error("EnabledForUser() without a user = true, want a partial rollout off")
+	}
+
+	var on, off string
+	for i := 0; on == "" || off == ""; i++ {
+		id := fmt.Sprintf("user-%d", i)
+		if flags.EnabledFor("f", id) {
+			on = id
+		} else {
+			off = id
+		}
+	}
+	if !flags.EnabledForUser(WithUserID(context.Background(), on), "f") {
+		t.Errorf("EnabledForUser(%s) = false, want it to match EnabledFor", on)
+	}
+	if flags.EnabledForUser(WithUserID(context.Background(), off), "f") {
+		t.Errorf("EnabledForUser(%s) = true, want it to match EnabledFor", off)
+	}
+}
+
+type memorySource struct {
+	flags []Flag
+}
+
+func (m *memorySource) Load(context.Context) ([]Flag, error) { return m.flags, nil }
+
+func (m *memorySource) Save(_ context.Context, flag Flag) error {
+	m.flags = append(m.flags, flag)
+	return nil
+}
+
+func TestFlags_Set(t *testing.T) {
+	source := &memorySource{}
+	flags := New(source, zap.NewNop())
+
+	if err := flags.Set(context.Background(), Flag{Key: "f", Enabled: true, Percentage: 100}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if !flags.Enabled("f") || len(source.flags) != 1 {
+		t.Errorf("Set() did not apply and store the flag: enabled=%v stored=%v", flags.Enabled("f"), source.flags)
+	}
+
+	if err := flags.Set(context.Background(), Flag{Key: "f", Percentage: 101}); err == nil {
+		t.Error("Set() error = nil, want a percentage over 100 rejected")
+	}
+
+	readOnly := New(StaticSource(nil), zap.NewNop())
+	if err := readOnly.Set(context.Background(), Flag{Key: "f", Percentage: 100}); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Set() on a read-only source error = %v, want ErrReadOnly", err)
+	}
+}
@@ -0,0 +1,115 @@
+package featureflags
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	apperrors "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/errors"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/validation"
+	"go.uber.org/zap"
+)
+
+// Handler serves the feature flag admin endpoint:
+//
+//	GET /admin/flags                 every flag with its current state
+//	GET /admin/flags/{key}           one flag; ?subject=<id> adds whether it is on for that subject
+//	PUT /admin/flags/{key}           store a flag (Postgres backend only)
+//
+// It is mounted on each service's own port, which api-gateway does not route to.
+type Handler struct {
+	flags  *Flags
+	logger *zap.Logger
+}
+
+// NewHandler returns the admin endpoint for flags.
+func NewHandler(flags *Flags, logger *zap.Logger) *Handler {
+	return &Handler{flags: flags, logger: logger}
+}
+
+// Register mounts the endpoint on mux.
+func (h *Handler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /admin/flags", h.list)
+	mux.HandleFunc("GET /admin/flags/{key}", h.get)
+	mux.HandleFunc("PUT /admin/flags/{key}", h.put)
+}
+
+type flagResponse struct {
+	Flag
+	Subject      string `json:"subject,omitempty"`
+	OnForSubject *bool  `json:"on_for_subject,omitempty"`
+}
+
+type putFlagRequest struct {
+	Enabled     bool   `json:"enabled"`
+	Percentage  *int   `json:"percentage" validate:"min=0,max=100"`
+	Description string `json:"description"`
+}
+
+func (h *Handler) list(w http.ResponseWriter, r *http.Request) {
+	h.writeJSON(w, r, http.StatusOK, map[string][]Flag{"flags": h.flags.All()})
+}
+
+func (h *Handler) get(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	flag, ok := h.flags.Get(key)
+	if !ok {
+		h.writeError(w, r, apperrors.NewNotFound("Feature flag"))
+		return
+	}
+
+	resp := flagResponse{Flag: flag}
+	if subject := r.URL.Query().Get("subject"); subject != "" {
+		on := flag.OnFor(subject)
+		resp.Subject = subject
+		resp.OnForSubject = &on
+	}
+	h.writeJSON(w, r, http.StatusOK, resp)
+}
+
+func (h *Handler) put(w http.ResponseWriter, r *http.Request) {
+	var req putFlagRequest
+	if err := validation.DecodeJSON(w, r, &req); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	flag := Flag{Key: r.PathValue("key"), Enabled: req.Enabled, Percentage: 100, Description: req.Description}
+	if req.Percentage != nil {
+		flag.Percentage = *req.Percentage
+	}
+
+	if err := h.flags.Set(r.Context(), flag); err != nil {
+		if errors.Is(err, ErrReadOnly) {
+			h.writeError(w, r, apperrors.NewConflict("Feature flags are read-only with the configured backend"))
+			return
+		}
+		h.writeError(w, r, err)
+		return
+	}
+
+	h.logger.Info("Feature flag changed",
+		zap.String("key", flag.Key),
+		zap.Bool("enabled", flag.Enabled),
+		zap.Int("percentage", flag.Percentage))
+	h.writeJSON(w, r, http.StatusOK, flag)
+}
+
+func (h *Handler) writeJSON(w http.ResponseWriter, r *http.Request, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		h.logger.Error("Failed to encode response", zap.String("path", r.URL.Path), zap.Error(err))
+	}
+}
+
+func (h *Handler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var appErr *apperrors.AppError
+	if !errors.As(err, &appErr) {
+		h.logger.Error("unexpected error", zap.Error(err))
+		appErr = apperrors.NewInternalServerError("internal server error")
+	}
+	if err := apperrors.WriteProblem(w, r, appErr); err != nil {
+		h.logger.Error("failed to encode error response", zap.Error(err))
+	}
+}
@@ -0,0 +1,120 @@
+package featureflags
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	apperrors "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/errors"
+	"go.uber.org/zap"
+)
+
+func newTestMux(flags *Flags) *http.ServeMux {
+	mux := http.NewServeMux()
+	NewHandler(flags, zap.NewNop()).Register(mux)
+	return mux
+}
+
+func TestHandler_List(t *testing.T) {
+	flags := New(StaticSource(nil), zap.NewNop(), Default("b", true, ""), Default("a", false, ""))
+	w := httptest.NewRecorder()
+
+	newTestMux(flags).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/flags", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	var body struct {
+		Flags []Flag `json:"flags"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(body.Flags) != 2 || body.Flags[0].Key != "a" || body.Flags[1].Key != "b" {
+		t.Errorf("flags = %+v, want a and b in key order", body.Flags)
+	}
+}
+
+func TestHandler_GetEvaluatesSubject(t *testing.T) {
+	flags := New(StaticSource(nil), zap.NewNop(), Default("a", true, ""))
+	mux := newTestMux(flags)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/flags/a?subject=user-1", nil))
+	var got flagResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if got.Key != "a" || got.OnForSubject == nil || !*got.OnForSubject {
+		t.Errorf("response = %+v, want flag a on for user-1", got)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/flags/missing", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status for an unknown flag = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestHandler_Put(t *testing.T) {
+	source := &memorySource{}
+	flags := New(source, zap.NewNop())
+	mux := newTestMux(flags)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/admin/flags/pricing.v2",
+		strings.NewReader(`{"enabled": true, "percentage": 20}`)))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	if flag, _ := flags.Get("pricing.v2"); !flag.Enabled || flag.Percentage != 20 {
+		t.Errorf("Get() = %+v, want enabled at 20%%", flag)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/admin/flags/pricing.v2",
+		strings.NewReader(`{"enabled": true, "percentage": 120}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status for percentage 120 = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestHandler_PutReadOnlySource(t *testing.T) {
+	mux := newTestMux(New(StaticSource(nil), zap.NewNop()))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/admin/flags/a", strings.NewReader(`{"enabled": true}`)))
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusConflict)
+	}
+	var problem apperrors.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("decode problem: %v", err)
+	}
+	if problem.Code != "CONFLICT" {
+		t.Errorf("Code = %q, want CONFLICT", problem.Code)
+	}
+}
+
+func TestMiddleware_PutsUserIDOnContext(t *testing.T) {
+	var got string
+	handler := Middleware(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got = UserID(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(UserIDHeader, "user-42")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if got != "user-42" {
+		t.Errorf("UserID() = %q, want %q", got, "user-42")
+	}
+	if UserID(context.Background()) != "" {
+		t.Error("UserID() on an empty context is not empty")
+	}
+}
@@ -0,0 +1,159 @@
+package featureflags
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// ErrReadOnly is returned by Flags.Set when the source cannot be written to, as with a file.
+var ErrReadOnly = errors.New("feature flag source is read-only")
+
+// Source loads the complete set of flags.
+type Source interface {
+	Load(ctx context.Context) ([]Flag, error)
+}
+
+// Writer is implemented by sources that can store a flag, which the admin endpoint needs to
+// change one.
+type Writer interface {
+	Save(ctx context.Context, flag Flag) error
+}
+
+// Backends accepted by NewSource.
+const (
+	BackendNone     = ""
+	BackendFile     = "file"
+	BackendPostgres = "postgres"
+)
+
+// NewSource returns the source for backend: a FileSource reading path, a PostgresSource on db, or,
+// for BackendNone, a source with no flags so that only defaults apply.
+func NewSource(backend, path string, db *sql.DB) (Source, error) {
+	switch backend {
+	case BackendNone:
+		return StaticSource(nil), nil
+	case BackendFile:
+		if path == "" {
+			return nil, fmt.Errorf("feature flag file backend needs a path")
+		}
+		return NewFileSource(path), nil
+	case BackendPostgres:
+		if db == nil {
+			return nil, fmt.Errorf("feature flag postgres backend needs a database")
+		}
+		return NewPostgresSource(db), nil
+	default:
+		return nil, fmt.Errorf("unknown feature flag backend %q", backend)
+	}
+}
+
+// StaticSource is a fixed set of flags, for tests and for running on defaults alone.
+type StaticSource []Flag
+
+// Load returns the flags.
+func (s StaticSource) Load(context.Context) ([]Flag, error) {
+	return s, nil
+}
+
+// FileSource reads flags from a JSON file mapping each key to its state:
+//
+//	{
+//	  "order.new_saga_step": {"enabled": true, "percentage": 10, "description": "..."},
+//	  "cache.enabled": {"enabled": false}
+//	}
+//
+// A flag that omits percentage is on for everyone when enabled. The file is re-read on every
+// Load, so edits, including a ConfigMap update, apply on the next refresh.
+type FileSource struct {
+	path string
+}
+
+// NewFileSource returns a FileSource reading path.
+func NewFileSource(path string) *FileSource {
+	return &FileSource{path: path}
+}
+
+type fileFlag struct {
+	Enabled     bool   `json:"enabled"`
+	Percentage  *int   `json:"percentage"`
+	Description string `json:"description"`
+}
+
+// Load reads and validates the file.
+func (s *FileSource) Load(context.Context) ([]Flag, error) {
+	raw, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("read feature flag file: %w", err)
+	}
+
+	var entries map[string]fileFlag
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return nil, fmt.Errorf("parse feature flag file %s: %w", s.path, err)
+	}
+
+	flags := make([]Flag, 0, len(entries))
+	for key, entry := range entries {
+		flag := Flag{Key: key, Enabled: entry.Enabled, Percentage: 100, Description: entry.Description}
+		if entry.Percentage != nil {
+			flag.Percentage = *entry.Percentage
+		}
+		if err := flag.Validate(); err != nil {
+			return nil, err
+		}
+		flags = append(flags, flag)
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Key < flags[j].Key })
+	return flags, nil
+}
+
+// PostgresSource reads and writes flags in the service's feature_flags table.
+type PostgresSource struct {
+	db *sql.DB
+}
+
+// NewPostgresSource returns a PostgresSource on db.
+func NewPostgresSource(db *sql.DB) *PostgresSource {
+	return &PostgresSource{db: db}
+}
+
+// Load reads every row of feature_flags.
+func (s *PostgresSource) Load(ctx context.Context) ([]Flag, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT key, enabled, percentage, description FROM feature_flags ORDER BY key`)
+	if err != nil {
+		return nil, fmt.Errorf("query feature flags: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var flags []Flag
+	for rows.Next() {
+		var flag Flag
+		if err := rows.Scan(&flag.Key, &flag.Enabled, &flag.Percentage, &flag.Description); err != nil {
+			return nil, fmt.Errorf("scan feature flag: %w", err)
+		}
+		flags = append(flags, flag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate feature flags: %w", err)
+	}
+	return flags, nil
+}
+
+// Save inserts or replaces flag.
+func (s *PostgresSource) Save(ctx context.Context, flag Flag) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO feature_flags (key, enabled, percentage, description)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (key) DO UPDATE
+		SET enabled = EXCLUDED.enabled, percentage = EXCLUDED.percentage, description = EXCLUDED.description`,
+		flag.Key, flag.Enabled, flag.Percentage, flag.Description)
+	if err != nil {
+		return fmt.Errorf("upsert feature flag: %w", err)
+	}
+	return nil
+}
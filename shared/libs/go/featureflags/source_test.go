@@ -0,0 +1,111 @@
+package featureflags
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestFileSource_Load(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flags.json")
+	content := `{
+		"cache.enabled": {"enabled": false},
+		"pricing.v2": {"enabled": true, "percentage": 25, "description": "New pricing rules"}
+	}`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	got, err := NewFileSource(path).Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	want := []Flag{
+		{Key: "cache.enabled", Enabled: false, Percentage: 100},
+		{Key: "pricing.v2", Enabled: true, Percentage: 25, Description: "New pricing rules"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Load() = %+v, want %+v", got, want)
+	}
+}
+
+func TestFileSource_LoadRejectsInvalidFiles(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"malformed.json":  `{"f": `,
+		"percentage.json": `{"f": {"enabled": true, "percentage": 150}}`,
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+		if _, err := NewFileSource(path).Load(context.Background()); err == nil {
+			t.Errorf("Load(%s) error = nil, want an error", name)
+		}
+	}
+
+	if _, err := NewFileSource(filepath.Join(dir, "absent.json")).Load(context.Background()); err == nil {
+		t.Error("Load() of a missing file error = nil, want an error")
+	}
+}
+
+func TestPostgresSource(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New() error = %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	source := NewPostgresSource(db)
+
+	mock.ExpectQuery(`SELECT key, enabled, percentage, description FROM feature_flags`).
+		WillReturnRows(sqlmock.NewRows([]string{"key", "enabled", "percentage", "description"}).
+			AddRow("pricing.v2", true, 10, "New pricing rules"))
+	mock.ExpectExec(`INSERT INTO feature_flags`).
+		WithArgs("pricing.v2", true, 50, "New pricing rules").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	got, err := source.Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(got) != 1 || got[0] != (Flag{Key: "pricing.v2", Enabled: true, Percentage: 10, Description: "New pricing rules"}) {
+		t.Errorf("Load() = %+v, want the pricing.v2 row", got)
+	}
+
+	if err := source.Save(context.Background(), Flag{Key: "pricing.v2", Enabled: true, Percentage: 50, Description: "New pricing rules"}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestNewSource(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New() error = %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	tests := []struct {
+		backend, path string
+		wantErr       bool
+	}{
+		{backend: BackendNone},
+		{backend: BackendFile, path: "flags.json"},
+		{backend: BackendFile, wantErr: true},
+		{backend: BackendPostgres},
+		{backend: "consul", wantErr: true},
+	}
+	for _, tt := range tests {
+		_, err := NewSource(tt.backend, tt.path, db)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NewSource(%q, %q) error = %v, wantErr %v", tt.backend, tt.path, err, tt.wantErr)
+		}
+	}
+}
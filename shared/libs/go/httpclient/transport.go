@@ -0,0 +1,25 @@
+// Package httpclient builds the HTTP transport services use to call each other, so that every
+// internal client pools connections, speaks HTTP/2 where it can and uses TLS the same way.
+package httpclient
+
+import (
+	"net/http"
+
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/tlsconfig"
+)
+
+// NewTransport returns a transport for calls to other services. With tls set, https:// URLs are
+// verified against its CA and present its client certificate for mutual TLS; with nil, the
+// system roots are used and no client certificate is sent. HTTP/2 is negotiated with servers
+// that offer it over TLS.
+//
+// One transport is meant to be shared by all of a service's clients, so they share its
+// connection pool.
+func NewTransport(tls *tlsconfig.Reloader) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ForceAttemptHTTP2 = true
+	if tls != nil {
+		transport.TLSClientConfig = tls.ClientConfig()
+	}
+	return transport
+}
@@ -0,0 +1,273 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	apperrors "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/errors"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/logger"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/validation"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// defaultScopedLevelTTL is how long a scoped log level lasts when the request sets no ttl.
+const defaultScopedLevelTTL = 15 * time.Minute
+
+// adminWriteTimeout leaves room for a CPU profile or execution trace, which pprof collects for
+// the number of seconds the caller asks for (30 by default) before writing anything.
+const adminWriteTimeout = 90 * time.Second
+
+// LevelController reads and changes a logger's minimum level; *logger.Logger implements it.
+type LevelController interface {
+	Level() zapcore.Level
+	SetLevel(level string) error
+}
+
+// ScopedLevelController changes the level for loggers carrying one correlation or user ID;
+// *logger.Logger implements it. When AdminOptions.LogLevel implements it too, /admin/log-level
+// accepts scoped changes.
+type ScopedLevelController interface {
+	SetScopedLevel(field, value, level string, ttl time.Duration) error
+	ClearScopedLevel(field, value string)
+	ScopedLevels() []logger.ScopedLevel
+}
+
+// AdminOptions configures an AdminServer.
+type AdminOptions struct {
+	Addr   string
+	Logger *zap.Logger
+	// LogLevel is optional: when set, /admin/log-level reads and changes it.
+	LogLevel LevelController
+	// Service and Version are reported by /admin/build-info alongside what the Go toolchain
+	// recorded in the binary.
+	Service string
+	Version string
+}
+
+// AdminServer is a second listener, on its own port, for operational endpoints that must not be
+// reachable through the public one:
+//
+//	GET /debug/pprof/...    runtime profiles
+//	GET /metrics            Prometheus metrics
+//	GET /admin/log-level    the current log level
+//	PUT /admin/log-level    change it: {"level": "debug"}, or for one correlation or user ID
+//	                        only: {"level": "debug", "user_id": "u-1", "ttl": "15m"}
+//	DELETE /admin/log-level/{field}/{value}
+//	                        drop a scoped level before it expires
+//	GET /admin/build-info   service, version, Go version and VCS revision
+//
+// Services add their own endpoints through Handle, HandleFunc or Mux.
+type AdminServer struct {
+	*Server
+	mux      *http.ServeMux
+	logger   *zap.Logger
+	logLevel LevelController
+	info     BuildInfo
+}
+
+// BuildInfo is the body of /admin/build-info.
+type BuildInfo struct {
+	Service     string `json:"service"`
+	Version     string `json:"version"`
+	GoVersion   string `json:"go_version"`
+	Module      string `json:"module,omitempty"`
+	VCSRevision string `json:"vcs_revision,omitempty"`
+	VCSTime     string `json:"vcs_time,omitempty"`
+	VCSModified bool   `json:"vcs_modified,omitempty"`
+}
+
+// NewAdmin builds the admin server. Like New, it does not listen until Start is called.
+func NewAdmin(opts AdminOptions) *AdminServer {
+	logger := opts.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	a := &AdminServer{
+		mux:      http.NewServeMux(),
+		logger:   logger,
+		logLevel: opts.LogLevel,
+		info:     readBuildInfo(opts.Service, opts.Version),
+	}
+
+	a.mux.HandleFunc("/debug/pprof/", pprof.Index)
+	a.mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	a.mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	a.mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	a.mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	a.mux.Handle("GET /metrics", promhttp.Handler())
+	a.mux.HandleFunc("GET /admin/build-info", a.buildInfo)
+	if a.logLevel != nil {
+		a.mux.HandleFunc("GET /admin/log-level", a.getLogLevel)
+		a.mux.HandleFunc("PUT /admin/log-level", a.putLogLevel)
+		if _, ok := a.logLevel.(ScopedLevelController); ok {
+			a.mux.HandleFunc("DELETE /admin/log-level/{field}/{value}", a.deleteScopedLevel)
+		}
+	}
+
+	a.Server = New(Options{
+		Addr:         opts.Addr,
+		Handler:      a.mux,
+		Logger:       logger,
+		WriteTimeout: adminWriteTimeout,
+	})
+	return a
+}
+
+// Handle registers handler for pattern on the admin listener.
+func (a *AdminServer) Handle(pattern string, handler http.Handler) {
+	a.mux.Handle(pattern, handler)
+}
+
+// HandleFunc registers handler for pattern on the admin listener.
+func (a *AdminServer) HandleFunc(pattern string, handler http.HandlerFunc) {
+	a.mux.HandleFunc(pattern, handler)
+}
+
+// Mux returns the admin listener's mux, for handlers that register themselves on one.
+func (a *AdminServer) Mux() *http.ServeMux {
+	return a.mux
+}
+
+func readBuildInfo(service, version string) BuildInfo {
+	info := BuildInfo{Service: service, Version: version, GoVersion: runtime.Version()}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	info.Module = bi.Main.Path
+	for _, setting := range bi.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			info.VCSRevision = setting.Value
+		case "vcs.time":
+			info.VCSTime = setting.Value
+		case "vcs.modified":
+			info.VCSModified = setting.Value == "true"
+		}
+	}
+	return info
+}
+
+func (a *AdminServer) buildInfo(w http.ResponseWriter, r *http.Request) {
+	a.writeJSON(w, r, a.info)
+}
+
+type logLevelRequest struct {
+	Level         string `json:"level" validate:"required"`
+	CorrelationID string `json:"correlation_id"`
+	UserID        string `json:"user_id"`
+	// TTL bounds a scoped change, as a Go duration; empty means defaultScopedLevelTTL.
+	TTL string `json:"ttl"`
+}
+
+type logLevelResponse struct {
+	Level  string               `json:"level"`
+	Scoped []logger.ScopedLevel `json:"scoped,omitempty"`
+}
+
+func (a *AdminServer) getLogLevel(w http.ResponseWriter, r *http.Request) {
+	a.writeJSON(w, r, a.logLevelState())
+}
+
+func (a *AdminServer) logLevelState() logLevelResponse {
+	resp := logLevelResponse{Level: a.logLevel.Level().String()}
+	if scoped, ok := a.logLevel.(ScopedLevelController); ok {
+		resp.Scoped = scoped.ScopedLevels()
+	}
+	return resp
+}
+
+func (a *AdminServer) putLogLevel(w http.ResponseWriter, r *http.Request) {
+	var req logLevelRequest
+	if err := validation.DecodeJSON(w, r, &req); err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+
+	if req.CorrelationID != "" || req.UserID != "" {
+		a.putScopedLevel(w, r, req)
+		return
+	}
+
+	previous := a.logLevel.Level()
+	if err := a.logLevel.SetLevel(req.Level); err != nil {
+		a.writeError(w, r, apperrors.NewValidationError("level", "must be one of debug, info, warn, error, dpanic, panic, fatal"))
+		return
+	}
+
+	a.logger.Info("Log level changed",
+		zap.Stringer("from", previous),
+		zap.Stringer("to", a.logLevel.Level()))
+	a.writeJSON(w, r, a.logLevelState())
+}
+
+func (a *AdminServer) putScopedLevel(w http.ResponseWriter, r *http.Request, req logLevelRequest) {
+	scoped, ok := a.logLevel.(ScopedLevelController)
+	if !ok {
+		a.writeError(w, r, apperrors.NewValidationError("level", "this service cannot scope the log level"))
+		return
+	}
+	if req.CorrelationID != "" && req.UserID != "" {
+		a.writeError(w, r, apperrors.NewValidationError("correlation_id", "set either correlation_id or user_id, not both"))
+		return
+	}
+	field, value := logger.FieldCorrelationID, req.CorrelationID
+	if req.UserID != "" {
+		field, value = logger.FieldUserID, req.UserID
+	}
+
+	ttl := defaultScopedLevelTTL
+	if req.TTL != "" {
+		parsed, err := time.ParseDuration(req.TTL)
+		if err != nil || parsed <= 0 {
+			a.writeError(w, r, apperrors.NewValidationError("ttl", "must be a positive duration such as 15m"))
+			return
+		}
+		ttl = parsed
+	}
+
+	if err := scoped.SetScopedLevel(field, value, req.Level, ttl); err != nil {
+		a.writeError(w, r, apperrors.NewValidationError("level", "must be one of debug, info, warn, error, dpanic, panic, fatal"))
+		return
+	}
+
+	a.logger.Info("Scoped log level set",
+		zap.String("field", field),
+		zap.String("value", value),
+		zap.String("level", req.Level),
+		zap.Duration("ttl", ttl))
+	a.writeJSON(w, r, a.logLevelState())
+}
+
+func (a *AdminServer) deleteScopedLevel(w http.ResponseWriter, r *http.Request) {
+	field, value := r.PathValue("field"), r.PathValue("value")
+	a.logLevel.(ScopedLevelController).ClearScopedLevel(field, value)
+	a.logger.Info("Scoped log level cleared", zap.String("field", field), zap.String("value", value))
+	a.writeJSON(w, r, a.logLevelState())
+}
+
+func (a *AdminServer) writeJSON(w http.ResponseWriter, r *http.Request, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		a.logger.Error("Failed to encode response", zap.String("path", r.URL.Path), zap.Error(err))
+	}
+}
+
+func (a *AdminServer) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var appErr *apperrors.AppError
+	if !errors.As(err, &appErr) {
+		a.logger.Error("unexpected error", zap.Error(err))
+		appErr = apperrors.NewInternalServerError("internal server error")
+	}
+	if err := apperrors.WriteProblem(w, r, appErr); err != nil {
+		a.logger.Error("failed to encode error response", zap.Error(err))
+	}
+}
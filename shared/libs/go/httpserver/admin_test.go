@@ -0,0 +1,157 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	_ LevelController       = (*logger.Logger)(nil)
+	_ ScopedLevelController = (*logger.Logger)(nil)
+)
+
+func serveAdmin(a *AdminServer, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	a.Handler().ServeHTTP(w, req)
+	return w
+}
+
+func TestAdminServer_BuildInfo(t *testing.T) {
+	a := NewAdmin(AdminOptions{Addr: "127.0.0.1:0", Service: "order", Version: "1.2.3"})
+
+	w := serveAdmin(a, http.MethodGet, "/admin/build-info", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var info BuildInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &info); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if info.Service != "order" || info.Version != "1.2.3" {
+		t.Errorf("build info = %+v, want service order version 1.2.3", info)
+	}
+	if info.GoVersion != runtime.Version() {
+		t.Errorf("go_version = %q, want %q", info.GoVersion, runtime.Version())
+	}
+}
+
+func TestAdminServer_LogLevel(t *testing.T) {
+	level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	a := NewAdmin(AdminOptions{Addr: "127.0.0.1:0", LogLevel: atomicLevel{level}})
+
+	w := serveAdmin(a, http.MethodGet, "/admin/log-level", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"level":"info"`) {
+		t.Fatalf("GET /admin/log-level = %d %s, want 200 with level info", w.Code, w.Body.String())
+	}
+
+	w = serveAdmin(a, http.MethodPut, "/admin/log-level", `{"level":"debug"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT /admin/log-level = %d %s, want 200", w.Code, w.Body.String())
+	}
+	if level.Level() != zapcore.DebugLevel {
+		t.Errorf("level = %v, want debug", level.Level())
+	}
+
+	w = serveAdmin(a, http.MethodPut, "/admin/log-level", `{"level":"loud"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("PUT with an unknown level = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if level.Level() != zapcore.DebugLevel {
+		t.Errorf("level = %v after a rejected change, want debug", level.Level())
+	}
+}
+
+func TestAdminServer_ScopedLogLevel(t *testing.T) {
+	l, err := logger.New(logger.Config{Level: "info", Environment: "production"})
+	if err != nil {
+		t.Fatalf("logger.New() error = %v", err)
+	}
+	a := NewAdmin(AdminOptions{Addr: "127.0.0.1:0", LogLevel: l})
+	user := l.WithUserID("u-1")
+
+	w := serveAdmin(a, http.MethodPut, "/admin/log-level", `{"level":"debug","user_id":"u-1","ttl":"5m"}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"value":"u-1"`) {
+		t.Fatalf("PUT scoped level = %d %s, want 200 listing the u-1 override", w.Code, w.Body.String())
+	}
+	if !user.Core().Enabled(zapcore.DebugLevel) || l.Core().Enabled(zapcore.DebugLevel) {
+		t.Error("debug should be enabled for u-1 only")
+	}
+
+	w = serveAdmin(a, http.MethodPut, "/admin/log-level", `{"level":"debug","user_id":"u-1","correlation_id":"c-1"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("PUT with both scopes = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	w = serveAdmin(a, http.MethodDelete, "/admin/log-level/user_id/u-1", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("DELETE scoped level = %d, want 200", w.Code)
+	}
+	if user.Core().Enabled(zapcore.DebugLevel) {
+		t.Error("debug should be off for u-1 after the override is deleted")
+	}
+}
+
+func TestAdminServer_ScopedLogLevelNeedsAScopedController(t *testing.T) {
+	a := NewAdmin(AdminOptions{Addr: "127.0.0.1:0", LogLevel: atomicLevel{zap.NewAtomicLevel()}})
+
+	w := serveAdmin(a, http.MethodPut, "/admin/log-level", `{"level":"debug","user_id":"u-1"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("PUT scoped level = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestAdminServer_LogLevelNotMountedWithoutController(t *testing.T) {
+	a := NewAdmin(AdminOptions{Addr: "127.0.0.1:0"})
+
+	if w := serveAdmin(a, http.MethodGet, "/admin/log-level", ""); w.Code != http.StatusNotFound {
+		t.Errorf("GET /admin/log-level = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestAdminServer_PprofAndMetrics(t *testing.T) {
+	a := NewAdmin(AdminOptions{Addr: "127.0.0.1:0"})
+
+	for _, path := range []string{"/debug/pprof/", "/debug/pprof/goroutine?debug=1", "/metrics"} {
+		if w := serveAdmin(a, http.MethodGet, path, ""); w.Code != http.StatusOK {
+			t.Errorf("GET %s = %d, want %d", path, w.Code, http.StatusOK)
+		}
+	}
+}
+
+func TestAdminServer_HandleRegistersServiceEndpoints(t *testing.T) {
+	a := NewAdmin(AdminOptions{Addr: "127.0.0.1:0"})
+	a.HandleFunc("GET /admin/outbox", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	if w := serveAdmin(a, http.MethodGet, "/admin/outbox", ""); w.Code != http.StatusTeapot {
+		t.Errorf("GET /admin/outbox = %d, want %d", w.Code, http.StatusTeapot)
+	}
+}
+
+// atomicLevel adapts zap.AtomicLevel to LevelController.
+type atomicLevel struct {
+	zap.AtomicLevel
+}
+
+func (l atomicLevel) SetLevel(level string) error {
+	parsed, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	l.AtomicLevel.SetLevel(parsed)
+	return nil
+}
@@ -3,70 +3,251 @@ package httpserver
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"sync"
 	"time"
 )
 
+const (
+	// DefaultCheckTimeout bounds a check registered without a timeout of its own. It stays well
+	// under the 5 second readiness probe timeout the manifests use, so one hung dependency is
+	// reported as such instead of failing the whole probe.
+	DefaultCheckTimeout = 2 * time.Second
+	// DefaultHealthCacheTTL is how long readiness results are reused before the checks run again.
+	DefaultHealthCacheTTL = 2 * time.Second
+)
+
+// Health statuses reported by the endpoints and by each check.
+const (
+	StatusHealthy   = "healthy"
+	StatusDegraded  = "degraded"
+	StatusUnhealthy = "unhealthy"
+)
+
 // Check reports whether a dependency the service relies on is healthy.
 type Check func(ctx context.Context) error
 
+// Tier says how much a failing dependency matters to readiness.
+type Tier int
+
+const (
+	// Critical dependencies are needed to serve requests: if one fails, the service is unhealthy
+	// and readiness answers 503.
+	Critical Tier = iota
+	// NonCritical dependencies, such as a cache, only make the service degraded: readiness still
+	// answers 200 so the pod keeps receiving traffic.
+	NonCritical
+)
+
+// Dependency is a check together with its tier and timeout.
+type Dependency struct {
+	Check Check
+	Tier  Tier
+	// Timeout bounds one run of Check; zero means DefaultCheckTimeout.
+	Timeout time.Duration
+}
+
+// CheckResult is the outcome of one check in a readiness response.
+type CheckResult struct {
+	Status    string  `json:"status"`
+	Critical  bool    `json:"critical"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
 // HealthStatus is the JSON body returned by the health and readiness endpoints.
 type HealthStatus struct {
-	Status    string            `json:"status"`
-	Service   string            `json:"service"`
-	Timestamp time.Time         `json:"timestamp"`
-	Details   map[string]string `json:"details,omitempty"`
+	Status    string    `json:"status"`
+	Service   string    `json:"service"`
+	Timestamp time.Time `json:"timestamp"`
+	// Details maps each failing check to its error.
+	Details map[string]string `json:"details,omitempty"`
+	// Checks holds the result and latency of every check readiness ran.
+	Checks map[string]CheckResult `json:"checks,omitempty"`
 }
 
 // HealthHandlers serves the health, liveness and readiness endpoints for a service.
 type HealthHandlers struct {
-	service string
-	checks  map[string]Check
+	service      string
+	dependencies map[string]Dependency
+	cacheTTL     time.Duration
+	startup      Check
+
+	// mu serialises readiness runs, so concurrent probes share one run instead of each dialling
+	// every dependency.
+	mu       sync.Mutex
+	cached   HealthStatus
+	cachedAt time.Time
 }
 
-// NewHealthHandlers builds handlers that report as the given service name and run checks on readiness.
+// NewHealthHandlers builds handlers that report as the given service name and run checks on
+// readiness, treating every check as critical.
 func NewHealthHandlers(service string, checks map[string]Check) *HealthHandlers {
-	return &HealthHandlers{service: service, checks: checks}
+	dependencies := make(map[string]Dependency, len(checks))
+	for name, check := range checks {
+		dependencies[name] = Dependency{Check: check, Tier: Critical}
+	}
+	return NewTieredHealthHandlers(service, dependencies)
+}
+
+// NewTieredHealthHandlers builds handlers that run dependencies on readiness, each in its tier.
+func NewTieredHealthHandlers(service string, dependencies map[string]Dependency) *HealthHandlers {
+	return &HealthHandlers{service: service, dependencies: dependencies, cacheTTL: DefaultHealthCacheTTL}
+}
+
+// SetCacheTTL changes how long readiness results are reused. Zero or less runs the checks on
+// every request.
+func (h *HealthHandlers) SetCacheTTL(ttl time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.cacheTTL = ttl
+}
+
+// SetStartupCheck makes the startup endpoint report check, typically whether every component of
+// the service has started. Without one, the endpoint reports healthy as soon as it is served.
+func (h *HealthHandlers) SetStartupCheck(check Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.startup = check
 }
 
-// Register attaches the health, liveness and readiness routes to mux.
+// Register attaches the health, liveness, readiness and startup routes to mux.
 func (h *HealthHandlers) Register(mux *http.ServeMux) {
 	mux.HandleFunc("GET /health", h.Health)
 	mux.HandleFunc("GET /health/live", h.Live)
 	mux.HandleFunc("GET /health/ready", h.Ready)
+	mux.HandleFunc("GET /health/startup", h.Startup)
 }
 
 // Health always reports healthy; it signals the process is up.
 func (h *HealthHandlers) Health(w http.ResponseWriter, _ *http.Request) {
-	writeHealthStatus(w, http.StatusOK, HealthStatus{Status: "healthy", Service: h.service, Timestamp: time.Now()})
+	writeHealthStatus(w, http.StatusOK, HealthStatus{Status: StatusHealthy, Service: h.service, Timestamp: time.Now()})
 }
 
 // Live always reports healthy; it backs the liveness probe.
 func (h *HealthHandlers) Live(w http.ResponseWriter, _ *http.Request) {
-	writeHealthStatus(w, http.StatusOK, HealthStatus{Status: "healthy", Service: h.service, Timestamp: time.Now()})
+	writeHealthStatus(w, http.StatusOK, HealthStatus{Status: StatusHealthy, Service: h.service, Timestamp: time.Now()})
 }
 
-// Ready runs the registered checks and reports 503 with the failing ones if any fail.
-func (h *HealthHandlers) Ready(w http.ResponseWriter, r *http.Request) {
-	details := make(map[string]string)
+// Startup reports 503 until the startup check passes; it backs the startup probe, which holds
+// off the liveness probe while the service is still starting.
+func (h *HealthHandlers) Startup(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	check := h.startup
+	h.mu.Unlock()
 
-	for name, check := range h.checks {
+	if check != nil {
 		if err := check(r.Context()); err != nil {
-			details[name] = err.Error()
+			writeHealthStatus(w, http.StatusServiceUnavailable, HealthStatus{
+				Status:    StatusUnhealthy,
+				Service:   h.service,
+				Timestamp: time.Now(),
+				Details:   map[string]string{"startup": err.Error()},
+			})
+			return
+		}
+	}
+	writeHealthStatus(w, http.StatusOK, HealthStatus{Status: StatusHealthy, Service: h.service, Timestamp: time.Now()})
+}
+
+// Ready runs the registered checks concurrently and reports their results. It answers 503 if a
+// critical check fails, and 200 with status "degraded" if only non-critical ones do.
+func (h *HealthHandlers) Ready(w http.ResponseWriter, r *http.Request) {
+	status := h.readiness(r.Context())
+
+	code := http.StatusOK
+	if status.Status == StatusUnhealthy {
+		code = http.StatusServiceUnavailable
+	}
+	writeHealthStatus(w, code, status)
+}
+
+// readiness returns the cached result if it is recent enough, and runs the checks otherwise.
+func (h *HealthHandlers) readiness(ctx context.Context) HealthStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.cacheTTL > 0 && !h.cachedAt.IsZero() && time.Since(h.cachedAt) < h.cacheTTL {
+		return h.cached
+	}
+
+	// The result is shared with other callers through the cache, so a probe that gives up early
+	// must not cut the checks short for them; each check is bounded by its own timeout instead.
+	status := h.runChecks(context.WithoutCancel(ctx))
+	h.cached = status
+	h.cachedAt = time.Now()
+	return status
+}
+
+func (h *HealthHandlers) runChecks(ctx context.Context) HealthStatus {
+	type outcome struct {
+		name   string
+		result CheckResult
+	}
+
+	outcomes := make(chan outcome, len(h.dependencies))
+	for name, dep := range h.dependencies {
+		go func() {
+			outcomes <- outcome{name: name, result: runCheck(ctx, dep)}
+		}()
+	}
+
+	status := HealthStatus{Status: StatusHealthy, Service: h.service, Timestamp: time.Now()}
+	if len(h.dependencies) > 0 {
+		status.Checks = make(map[string]CheckResult, len(h.dependencies))
+	}
+	for range h.dependencies {
+		o := <-outcomes
+		status.Checks[o.name] = o.result
+		if o.result.Status == StatusHealthy {
+			continue
+		}
+
+		if status.Details == nil {
+			status.Details = make(map[string]string)
+		}
+		status.Details[o.name] = o.result.Error
+		if o.result.Critical {
+			status.Status = StatusUnhealthy
+		} else if status.Status == StatusHealthy {
+			status.Status = StatusDegraded
 		}
 	}
+	return status
+}
+
+// runCheck runs dep.Check under its timeout. A check that ignores its context is abandoned when
+// the timeout passes and reported as failed; its goroutine finishes in the background.
+func runCheck(ctx context.Context, dep Dependency) CheckResult {
+	timeout := dep.Timeout
+	if timeout <= 0 {
+		timeout = DefaultCheckTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
 
-	if len(details) == 0 {
-		writeHealthStatus(w, http.StatusOK, HealthStatus{Status: "healthy", Service: h.service, Timestamp: time.Now()})
-		return
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- dep.Check(ctx) }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("check timed out after %s", timeout)
 	}
 
-	writeHealthStatus(w, http.StatusServiceUnavailable, HealthStatus{
-		Status:    "unhealthy",
-		Service:   h.service,
-		Timestamp: time.Now(),
-		Details:   details,
-	})
+	result := CheckResult{
+		Status:    StatusHealthy,
+		Critical:  dep.Tier == Critical,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusUnhealthy
+		result.Error = err.Error()
+	}
+	return result
 }
 
 func writeHealthStatus(w http.ResponseWriter, code int, status HealthStatus) {
@@ -6,9 +6,25 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
+func readyStatus(t *testing.T, h *HealthHandlers) (int, HealthStatus) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/health/ready", nil)
+	w := httptest.NewRecorder()
+
+	h.Ready(w, req)
+
+	var status HealthStatus
+	if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return w.Code, status
+}
+
 func TestHealthHandlers_HealthAndLive(t *testing.T) {
 	h := NewHealthHandlers("order", nil)
 
@@ -99,7 +115,7 @@ func TestHealthHandlers_Register(t *testing.T) {
 	mux := http.NewServeMux()
 	h.Register(mux)
 
-	for _, path := range []string{"/health", "/health/live", "/health/ready"} {
+	for _, path := range []string{"/health", "/health/live", "/health/ready", "/health/startup"} {
 		req := httptest.NewRequest(http.MethodGet, path, nil)
 		w := httptest.NewRecorder()
 
@@ -110,3 +126,137 @@ func TestHealthHandlers_Register(t *testing.T) {
 		}
 	}
 }
+
+func TestHealthHandlers_Ready_NonCriticalFailureIsDegraded(t *testing.T) {
+	h := NewTieredHealthHandlers("inventory", map[string]Dependency{
+		"database": {Check: func(context.Context) error { return nil }},
+		"redis":    {Check: func(context.Context) error { return errors.New("connection refused") }, Tier: NonCritical},
+	})
+
+	code, status := readyStatus(t, h)
+
+	if code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, code)
+	}
+	if status.Status != StatusDegraded {
+		t.Errorf("expected status %q, got %q", StatusDegraded, status.Status)
+	}
+	if got := status.Checks["redis"]; got.Status != StatusUnhealthy || got.Critical || got.Error != "connection refused" {
+		t.Errorf("redis check = %+v, want a failed non-critical check", got)
+	}
+	if got := status.Checks["database"]; got.Status != StatusHealthy || !got.Critical {
+		t.Errorf("database check = %+v, want a healthy critical check", got)
+	}
+}
+
+func TestHealthHandlers_Ready_CriticalFailureWinsOverDegraded(t *testing.T) {
+	h := NewTieredHealthHandlers("inventory", map[string]Dependency{
+		"database": {Check: func(context.Context) error { return errors.New("connection refused") }},
+		"redis":    {Check: func(context.Context) error { return errors.New("connection refused") }, Tier: NonCritical},
+	})
+
+	code, status := readyStatus(t, h)
+
+	if code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status %d, got %d", http.StatusServiceUnavailable, code)
+	}
+	if status.Status != StatusUnhealthy {
+		t.Errorf("expected status %q, got %q", StatusUnhealthy, status.Status)
+	}
+}
+
+func TestHealthHandlers_Ready_ChecksRunConcurrentlyWithTimeouts(t *testing.T) {
+	hang := func(context.Context) error {
+		// Ignores its context, like a dial without a deadline.
+		time.Sleep(time.Second)
+		return nil
+	}
+	h := NewTieredHealthHandlers("order", map[string]Dependency{
+		"kafka":    {Check: hang, Timeout: 50 * time.Millisecond},
+		"database": {Check: hang, Timeout: 50 * time.Millisecond},
+	})
+
+	start := time.Now()
+	code, status := readyStatus(t, h)
+	elapsed := time.Since(start)
+
+	if elapsed > 500*time.Millisecond {
+		t.Errorf("Ready took %v, want the checks bounded by their timeouts and run in parallel", elapsed)
+	}
+	if code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status %d, got %d", http.StatusServiceUnavailable, code)
+	}
+	for _, name := range []string{"kafka", "database"} {
+		got := status.Checks[name]
+		if got.Error != "check timed out after 50ms" {
+			t.Errorf("%s error = %q, want a timeout", name, got.Error)
+		}
+		if got.LatencyMS < 50 {
+			t.Errorf("%s latency = %vms, want at least the timeout", name, got.LatencyMS)
+		}
+	}
+}
+
+func TestHealthHandlers_Ready_CachesResults(t *testing.T) {
+	var calls atomic.Int32
+	h := NewHealthHandlers("order", map[string]Check{
+		"database": func(context.Context) error {
+			calls.Add(1)
+			return nil
+		},
+	})
+
+	readyStatus(t, h)
+	readyStatus(t, h)
+	if got := calls.Load(); got != 1 {
+		t.Errorf("check ran %d times within the cache TTL, want 1", got)
+	}
+
+	h.SetCacheTTL(0)
+	readyStatus(t, h)
+	readyStatus(t, h)
+	if got := calls.Load(); got != 3 {
+		t.Errorf("check ran %d times with caching off, want 3", got)
+	}
+}
+
+func TestHealthHandlers_Ready_CancelledProbeDoesNotFailChecks(t *testing.T) {
+	h := NewHealthHandlers("order", map[string]Check{
+		"database": func(ctx context.Context) error { return ctx.Err() },
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodGet, "/health/ready", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+	h.Ready(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d (body=%s)", http.StatusOK, w.Code, w.Body.String())
+	}
+}
+
+func TestHealthHandlers_Startup(t *testing.T) {
+	var started atomic.Bool
+	h := NewHealthHandlers("order", nil)
+	h.SetStartupCheck(func(context.Context) error {
+		if !started.Load() {
+			return errors.New("service is still starting")
+		}
+		return nil
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/health/startup", nil)
+	w := httptest.NewRecorder()
+	h.Startup(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("before startup: expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+
+	started.Store(true)
+	w = httptest.NewRecorder()
+	h.Startup(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("after startup: expected status %d, got %d", http.StatusOK, w.Code)
+	}
+}
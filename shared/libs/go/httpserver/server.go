@@ -9,6 +9,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/tlsconfig"
 	"go.uber.org/zap"
 )
 
@@ -26,12 +27,17 @@ type Options struct {
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
 	IdleTimeout  time.Duration
+	// TLS is optional: when set, the server serves HTTPS, negotiating HTTP/2 with clients that
+	// support it, requires client certificates if TLS has a CA, and keeps TLS reloading its
+	// files while it runs. Nil serves plaintext HTTP/1.1.
+	TLS *tlsconfig.Reloader
 }
 
 // Server wraps http.Server with graceful shutdown and default timeouts.
 type Server struct {
 	httpServer *http.Server
 	logger     *zap.Logger
+	tls        *tlsconfig.Reloader
 
 	mu       sync.Mutex
 	listener net.Listener
@@ -57,15 +63,21 @@ func New(opts Options) *Server {
 		logger = zap.NewNop()
 	}
 
+	httpServer := &http.Server{
+		Addr:         opts.Addr,
+		Handler:      opts.Handler,
+		ReadTimeout:  readTimeout,
+		WriteTimeout: writeTimeout,
+		IdleTimeout:  idleTimeout,
+	}
+	if opts.TLS != nil {
+		httpServer.TLSConfig = opts.TLS.ServerConfig()
+	}
+
 	return &Server{
-		httpServer: &http.Server{
-			Addr:         opts.Addr,
-			Handler:      opts.Handler,
-			ReadTimeout:  readTimeout,
-			WriteTimeout: writeTimeout,
-			IdleTimeout:  idleTimeout,
-		},
-		logger: logger,
+		httpServer: httpServer,
+		logger:     logger,
+		tls:        opts.TLS,
 	}
 }
 
@@ -80,8 +92,19 @@ func (s *Server) Start() error {
 	s.listener = ln
 	s.mu.Unlock()
 
-	s.logger.Info("starting http server", zap.String("address", ln.Addr().String()))
-	return s.httpServer.Serve(ln)
+	if s.tls == nil {
+		s.logger.Info("starting http server", zap.String("address", ln.Addr().String()))
+		return s.httpServer.Serve(ln)
+	}
+
+	// Serve returns as soon as Stop begins, which ends the reloading with it.
+	reloadCtx, stopReloading := context.WithCancel(context.Background())
+	defer stopReloading()
+	go s.tls.Run(reloadCtx)
+
+	s.logger.Info("starting https server", zap.String("address", ln.Addr().String()))
+	// The certificate comes from TLSConfig, which ServeTLS extends to offer HTTP/2.
+	return s.httpServer.ServeTLS(ln, "", "")
 }
 
 // Stop gracefully shuts down the server, waiting for in-flight requests to finish.
@@ -0,0 +1,372 @@
+// Package lifecycle starts a service's components in dependency order and stops them in the
+// reverse order, so that nothing is shut down while something still running relies on it: the
+// HTTP server stops accepting requests before the consumers drain, the consumers drain before
+// the outbox is flushed, and the database closes last.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultStepTimeout bounds a component's Start or Stop when it sets no Timeout.
+	DefaultStepTimeout = 10 * time.Second
+	// DefaultShutdownTimeout bounds the whole of Stop when Run shuts down.
+	DefaultShutdownTimeout = 30 * time.Second
+)
+
+// Component is one part of a service with a start and stop step. Every field but Name is
+// optional: a connection opened before the manager exists only needs Stop to close it.
+type Component struct {
+	Name string
+	// DependsOn names components that must be started before this one and stopped after it.
+	DependsOn []string
+	// Start prepares the component and must return once it is running.
+	Start func(ctx context.Context) error
+	// Run is the component's long-running loop, such as a consumer's Subscribe or an HTTP
+	// server's Serve. It is started after Start in its own goroutine with a context that is
+	// cancelled when the component stops. A Run that returns early with an error makes the
+	// manager shut the service down.
+	Run func(ctx context.Context) error
+	// Stop releases the component. It is called before Run's context is cancelled, so an HTTP
+	// server can shut down gracefully, and the manager then waits for Run to return.
+	Stop func(ctx context.Context) error
+	// Timeout bounds Start and the stop step separately; zero means DefaultStepTimeout.
+	Timeout time.Duration
+}
+
+// Manager starts and stops registered components.
+type Manager struct {
+	logger *zap.Logger
+
+	mu              sync.Mutex
+	components      []*entry
+	byName          map[string]*entry
+	started         []*entry
+	starting        bool
+	ready           bool
+	failed          chan struct{}
+	failOnce        sync.Once
+	stopped         bool
+	shutdownTimeout time.Duration
+}
+
+type entry struct {
+	Component
+	cancelRun context.CancelFunc
+	runDone   chan struct{}
+	stopping  bool
+}
+
+// New returns an empty Manager.
+func New(logger *zap.Logger) *Manager {
+	return &Manager{
+		logger:          logger,
+		byName:          make(map[string]*entry),
+		failed:          make(chan struct{}),
+		shutdownTimeout: DefaultShutdownTimeout,
+	}
+}
+
+// SetShutdownTimeout changes how long Run gives Stop. Zero or less keeps the default.
+func (m *Manager) SetShutdownTimeout(timeout time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if timeout > 0 {
+		m.shutdownTimeout = timeout
+	}
+}
+
+// Register adds c. Components may be registered in any order; dependencies are resolved when
+// Start runs. Among components with no dependency between them, the one registered first starts
+// first and stops last.
+func (m *Manager) Register(c Component) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if c.Name == "" {
+		return errors.New("component name is required")
+	}
+	if _, ok := m.byName[c.Name]; ok {
+		return fmt.Errorf("component %s is already registered", c.Name)
+	}
+	if m.starting {
+		return fmt.Errorf("register component %s: manager already started", c.Name)
+	}
+	e := &entry{Component: c}
+	m.components = append(m.components, e)
+	m.byName[c.Name] = e
+	return nil
+}
+
+// MustRegister is Register for wiring in main, where a bad registration is a programming error.
+func (m *Manager) MustRegister(c Component) {
+	if err := m.Register(c); err != nil {
+		panic(err)
+	}
+}
+
+// Started reports whether every component has started.
+func (m *Manager) Started() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.ready
+}
+
+// StartupCheck fails until every component has started. It backs the startup probe.
+func (m *Manager) StartupCheck(context.Context) error {
+	if !m.Started() {
+		return errors.New("service is still starting")
+	}
+	return nil
+}
+
+// Failed is closed when a component's Run returns an error before the component was stopped.
+func (m *Manager) Failed() <-chan struct{} {
+	return m.failed
+}
+
+// Start starts every component in dependency order. If one fails to start, the ones already
+// started are stopped again and the error is returned.
+func (m *Manager) Start(ctx context.Context) error {
+	m.mu.Lock()
+	if m.starting {
+		m.mu.Unlock()
+		return errors.New("manager already started")
+	}
+	m.starting = true
+	m.mu.Unlock()
+
+	order, err := m.order()
+	if err != nil {
+		return err
+	}
+
+	for _, e := range order {
+		if err := m.startOne(ctx, e); err != nil {
+			if stopErr := m.Stop(context.WithoutCancel(ctx)); stopErr != nil {
+				m.logger.Error("Failed to stop components after a failed start", zap.Error(stopErr))
+			}
+			return err
+		}
+	}
+
+	m.mu.Lock()
+	m.ready = true
+	m.mu.Unlock()
+	m.logger.Info("All components started", zap.Int("components", len(order)))
+	return nil
+}
+
+func (m *Manager) startOne(ctx context.Context, e *entry) error {
+	if e.Start != nil {
+		start := time.Now()
+		m.logger.Info("Starting component", zap.String("component", e.Name))
+		if err := runStep(ctx, timeoutOf(e.Component), e.Start); err != nil {
+			m.logger.Error("Failed to start component", zap.String("component", e.Name), zap.Error(err))
+			return fmt.Errorf("start %s: %w", e.Name, err)
+		}
+		m.logger.Info("Started component", zap.String("component", e.Name), zap.Duration("duration", time.Since(start)))
+	}
+
+	if e.Run != nil {
+		runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		e.cancelRun = cancel
+		e.runDone = make(chan struct{})
+		go m.run(runCtx, e)
+	}
+
+	m.mu.Lock()
+	m.started = append(m.started, e)
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *Manager) run(ctx context.Context, e *entry) {
+	defer close(e.runDone)
+
+	err := e.Run(ctx)
+
+	m.mu.Lock()
+	stopping := e.stopping
+	m.mu.Unlock()
+
+	if !stopping {
+		if err != nil {
+			m.fail(e, err)
+		} else {
+			m.logger.Info("Component finished", zap.String("component", e.Name))
+		}
+		return
+	}
+	if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, http.ErrServerClosed) {
+		m.logger.Warn("Component exited with an error while stopping", zap.String("component", e.Name), zap.Error(err))
+	}
+}
+
+func (m *Manager) fail(e *entry, err error) {
+	m.logger.Error("Component stopped unexpectedly", zap.String("component", e.Name), zap.Error(err))
+	m.failOnce.Do(func() { close(m.failed) })
+}
+
+// Stop stops every started component in the reverse of the order they started in. Each step is
+// bounded by the component's timeout and by ctx; a step that fails or times out is logged and
+// the rest still run. It returns the errors of the steps that failed.
+func (m *Manager) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	if m.stopped {
+		m.mu.Unlock()
+		return nil
+	}
+	m.stopped = true
+	m.ready = false
+	started := m.started
+	m.mu.Unlock()
+
+	var errs []error
+	for i := len(started) - 1; i >= 0; i-- {
+		if err := m.stopOne(ctx, started[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *Manager) stopOne(ctx context.Context, e *entry) error {
+	m.mu.Lock()
+	e.stopping = true
+	m.mu.Unlock()
+
+	start := time.Now()
+	m.logger.Info("Stopping component", zap.String("component", e.Name))
+
+	err := runStep(ctx, timeoutOf(e.Component), func(ctx context.Context) error {
+		var stopErr error
+		if e.Stop != nil {
+			stopErr = e.Stop(ctx)
+		}
+		if e.cancelRun != nil {
+			e.cancelRun()
+			select {
+			case <-e.runDone:
+			case <-ctx.Done():
+				return errors.Join(stopErr, fmt.Errorf("waiting for %s to exit: %w", e.Name, ctx.Err()))
+			}
+		}
+		return stopErr
+	})
+	if err != nil {
+		m.logger.Error("Failed to stop component", zap.String("component", e.Name),
+			zap.Duration("duration", time.Since(start)), zap.Error(err))
+		return fmt.Errorf("stop %s: %w", e.Name, err)
+	}
+	m.logger.Info("Stopped component", zap.String("component", e.Name), zap.Duration("duration", time.Since(start)))
+	return nil
+}
+
+// Run starts every component, waits until ctx is cancelled (typically by a shutdown signal) or a
+// component fails, and then stops everything within the shutdown timeout.
+func (m *Manager) Run(ctx context.Context) error {
+	if err := m.Start(ctx); err != nil {
+		return err
+	}
+
+	var cause error
+	select {
+	case <-ctx.Done():
+		m.logger.Info("Shutting down")
+	case <-m.failed:
+		cause = errors.New("a component stopped unexpectedly")
+		m.logger.Error("Shutting down after a component failure")
+	}
+
+	m.mu.Lock()
+	timeout := m.shutdownTimeout
+	m.mu.Unlock()
+	stopCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+
+	return errors.Join(cause, m.Stop(stopCtx))
+}
+
+// order sorts the components so that each comes after everything it depends on, keeping
+// registration order where dependencies allow.
+func (m *Manager) order() ([]*entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(m.components))
+	order := make([]*entry, 0, len(m.components))
+
+	var visit func(e *entry, path []string) error
+	visit = func(e *entry, path []string) error {
+		switch state[e.Name] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle: %v", append(path, e.Name))
+		}
+		state[e.Name] = visiting
+		for _, dep := range e.DependsOn {
+			d, ok := m.byName[dep]
+			if !ok {
+				return fmt.Errorf("component %s depends on unknown component %s", e.Name, dep)
+			}
+			if err := visit(d, append(path, e.Name)); err != nil {
+				return err
+			}
+		}
+		state[e.Name] = done
+		order = append(order, e)
+		return nil
+	}
+
+	for _, e := range m.components {
+		if err := visit(e, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// Close adapts a Close method, which takes no context, for use as Component.Stop.
+func Close(closer interface{ Close() error }) func(context.Context) error {
+	return func(context.Context) error { return closer.Close() }
+}
+
+func timeoutOf(c Component) time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	return DefaultStepTimeout
+}
+
+// runStep calls fn with a context bounded by timeout and returns when fn does or the context
+// ends, whichever comes first. A step that ignores its context is abandoned rather than allowed
+// to hold up the rest of the shutdown.
+func runStep(ctx context.Context, timeout time.Duration, fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- fn(ctx) }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("timed out after %s: %w", timeout, ctx.Err())
+	}
+}
@@ -0,0 +1,278 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zaptest"
+)
+
+// recorder collects the steps components take, in order.
+type recorder struct {
+	mu    sync.Mutex
+	steps []string
+}
+
+func (r *recorder) add(step string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.steps = append(r.steps, step)
+}
+
+func (r *recorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.steps...)
+}
+
+func (r *recorder) component(name string, dependsOn ...string) Component {
+	return Component{
+		Name:      name,
+		DependsOn: dependsOn,
+		Start: func(context.Context) error {
+			r.add("start " + name)
+			return nil
+		},
+		Stop: func(context.Context) error {
+			r.add("stop " + name)
+			return nil
+		},
+	}
+}
+
+func TestManager_StartsInDependencyOrderAndStopsInReverse(t *testing.T) {
+	rec := &recorder{}
+	m := New(zaptest.NewLogger(t))
+	// Registered out of order on purpose: dependencies, not registration, decide the order.
+	m.MustRegister(rec.component("http", "database", "outbox"))
+	m.MustRegister(rec.component("consumer", "database", "outbox"))
+	m.MustRegister(rec.component("outbox", "database"))
+	m.MustRegister(rec.component("database"))
+
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if err := m.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	want := []string{
+		"start database", "start outbox", "start http", "start consumer",
+		"stop consumer", "stop http", "stop outbox", "stop database",
+	}
+	if got := rec.get(); !reflect.DeepEqual(got, want) {
+		t.Errorf("steps = %v, want %v", got, want)
+	}
+}
+
+func TestManager_Register_RejectsDuplicatesAndMissingNames(t *testing.T) {
+	m := New(zaptest.NewLogger(t))
+	if err := m.Register(Component{}); err == nil {
+		t.Error("Register() without a name, want an error")
+	}
+	if err := m.Register(Component{Name: "database"}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if err := m.Register(Component{Name: "database"}); err == nil {
+		t.Error("Register() of a duplicate name, want an error")
+	}
+}
+
+func TestManager_Start_RejectsUnknownDependencyAndCycles(t *testing.T) {
+	m := New(zaptest.NewLogger(t))
+	m.MustRegister(Component{Name: "http", DependsOn: []string{"database"}})
+	if err := m.Start(context.Background()); err == nil || !strings.Contains(err.Error(), "unknown component database") {
+		t.Errorf("Start() error = %v, want an unknown dependency error", err)
+	}
+
+	m = New(zaptest.NewLogger(t))
+	m.MustRegister(Component{Name: "a", DependsOn: []string{"b"}})
+	m.MustRegister(Component{Name: "b", DependsOn: []string{"a"}})
+	if err := m.Start(context.Background()); err == nil || !strings.Contains(err.Error(), "dependency cycle") {
+		t.Errorf("Start() error = %v, want a dependency cycle error", err)
+	}
+}
+
+func TestManager_Start_FailureStopsWhatAlreadyStarted(t *testing.T) {
+	rec := &recorder{}
+	m := New(zaptest.NewLogger(t))
+	m.MustRegister(rec.component("database"))
+	m.MustRegister(Component{
+		Name:      "outbox",
+		DependsOn: []string{"database"},
+		Start:     func(context.Context) error { return errors.New("broker unreachable") },
+		Stop: func(context.Context) error {
+			rec.add("stop outbox")
+			return nil
+		},
+	})
+
+	err := m.Start(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "start outbox: broker unreachable") {
+		t.Fatalf("Start() error = %v, want the outbox failure", err)
+	}
+	if m.Started() {
+		t.Error("Started() = true after a failed start")
+	}
+	want := []string{"start database", "stop database"}
+	if got := rec.get(); !reflect.DeepEqual(got, want) {
+		t.Errorf("steps = %v, want %v", got, want)
+	}
+}
+
+func TestManager_StartupCheck(t *testing.T) {
+	m := New(zaptest.NewLogger(t))
+	m.MustRegister(Component{Name: "database"})
+
+	if err := m.StartupCheck(context.Background()); err == nil {
+		t.Error("StartupCheck() before Start = nil, want an error")
+	}
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if err := m.StartupCheck(context.Background()); err != nil {
+		t.Errorf("StartupCheck() after Start = %v, want nil", err)
+	}
+	_ = m.Stop(context.Background())
+	if err := m.StartupCheck(context.Background()); err == nil {
+		t.Error("StartupCheck() after Stop = nil, want an error")
+	}
+}
+
+func TestManager_Stop_DrainsRunBeforeStoppingDependencies(t *testing.T) {
+	rec := &recorder{}
+	m := New(zaptest.NewLogger(t))
+	m.MustRegister(rec.component("outbox"))
+	m.MustRegister(Component{
+		Name:      "consumer",
+		DependsOn: []string{"outbox"},
+		Run: func(ctx context.Context) error {
+			<-ctx.Done()
+			time.Sleep(10 * time.Millisecond) // finish the message in hand
+			rec.add("consumer drained")
+			return ctx.Err()
+		},
+		Stop: func(context.Context) error {
+			rec.add("stop consumer")
+			return nil
+		},
+	})
+
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if err := m.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	want := []string{"start outbox", "stop consumer", "consumer drained", "stop outbox"}
+	if got := rec.get(); !reflect.DeepEqual(got, want) {
+		t.Errorf("steps = %v, want %v", got, want)
+	}
+}
+
+func TestManager_Stop_TimesOutAHungStepAndCarriesOn(t *testing.T) {
+	rec := &recorder{}
+	m := New(zaptest.NewLogger(t))
+	m.MustRegister(rec.component("database"))
+	m.MustRegister(Component{
+		Name:      "relay",
+		DependsOn: []string{"database"},
+		Stop: func(context.Context) error {
+			time.Sleep(time.Second)
+			return nil
+		},
+		Timeout: 20 * time.Millisecond,
+	})
+
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	start := time.Now()
+	err := m.Stop(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "stop relay: timed out") {
+		t.Errorf("Stop() error = %v, want the relay timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Stop() took %v, want the hung step abandoned at its timeout", elapsed)
+	}
+	if got := rec.get(); got[len(got)-1] != "stop database" {
+		t.Errorf("steps = %v, want the database stopped after the hung relay", got)
+	}
+}
+
+func TestManager_Run_ShutsDownWhenAComponentFails(t *testing.T) {
+	rec := &recorder{}
+	m := New(zaptest.NewLogger(t))
+	m.MustRegister(rec.component("database"))
+	m.MustRegister(Component{
+		Name:      "http",
+		DependsOn: []string{"database"},
+		Run:       func(context.Context) error { return errors.New("listen tcp: address already in use") },
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- m.Run(context.Background()) }()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("Run() = nil, want an error for the failed component")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Run() did not return after a component failed")
+	}
+	if got := rec.get(); got[len(got)-1] != "stop database" {
+		t.Errorf("steps = %v, want the database stopped", got)
+	}
+}
+
+func TestManager_RunReturningNilIsNotAFailure(t *testing.T) {
+	m := New(zaptest.NewLogger(t))
+	m.MustRegister(Component{Name: "listener", Run: func(context.Context) error { return nil }})
+
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	select {
+	case <-m.Failed():
+		t.Error("Failed() closed for a Run that finished without an error")
+	case <-time.After(20 * time.Millisecond):
+	}
+	if err := m.Stop(context.Background()); err != nil {
+		t.Errorf("Stop() error = %v", err)
+	}
+}
+
+func TestManager_Run_StopsWhenContextIsCancelled(t *testing.T) {
+	rec := &recorder{}
+	m := New(zaptest.NewLogger(t))
+	m.MustRegister(rec.component("database"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- m.Run(ctx) }()
+
+	for !m.Started() {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Run() did not return after its context was cancelled")
+	}
+	want := []string{"start database", "stop database"}
+	if got := rec.get(); !reflect.DeepEqual(got, want) {
+		t.Errorf("steps = %v, want %v", got, want)
+	}
+}
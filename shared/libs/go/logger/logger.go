@@ -2,6 +2,8 @@ package logger
 
 import (
 	"context"
+	"sync/atomic"
+	"time"
 
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
@@ -10,7 +12,11 @@ import (
 
 type Logger struct {
 	*zap.Logger
-	sugar *zap.SugaredLogger
+	sugar  *zap.SugaredLogger
+	level  zap.AtomicLevel
+	levels *levels
+	// dropped counts the lines sampling dropped, indexed from zapcore.DebugLevel.
+	dropped [zapcore.FatalLevel - zapcore.DebugLevel + 1]atomic.Uint64
 }
 
 type Config struct {
@@ -19,6 +25,20 @@ type Config struct {
 	Service     string   `json:"service"`
 	Version     string   `json:"version"`
 	OutputPaths []string `json:"output_paths"`
+	// Sampling is optional: nil keeps the environment's default, which samples in production
+	// only.
+	Sampling *SamplingConfig `json:"sampling,omitempty"`
+	// Redaction is optional: nil masks personal data with the default field list.
+	Redaction *RedactionConfig `json:"redaction,omitempty"`
+}
+
+// SamplingConfig caps repeated lines: within each Tick, the first Initial lines with the same
+// level and message are logged, then only every Thereafter-th. An Initial of 0 turns sampling
+// off. Dropped lines are counted by DroppedLines.
+type SamplingConfig struct {
+	Initial    int           `json:"initial"`
+	Thereafter int           `json:"thereafter"`
+	Tick       time.Duration `json:"tick"` // defaults to one second
 }
 
 func New(config Config) (*Logger, error) {
@@ -38,7 +58,30 @@ func New(config Config) (*Logger, error) {
 	if err != nil {
 		level = zapcore.InfoLevel
 	}
-	zapConfig.Level = zap.NewAtomicLevelAt(level)
+	atomicLevel := zap.NewAtomicLevelAt(level)
+	// The core zap builds lets every level through; scopedCore, wrapped around it below, applies
+	// atomicLevel together with any scoped levels.
+	zapConfig.Level = zap.NewAtomicLevelAt(zapcore.DebugLevel)
+	l := &Logger{level: atomicLevel, levels: newLevels(atomicLevel)}
+
+	redaction := RedactionConfig{Mode: RedactMask}
+	if config.Redaction != nil {
+		redaction = *config.Redaction
+	}
+	redactor, err := NewRedactor(redaction)
+	if err != nil {
+		return nil, err
+	}
+
+	sampling := zapConfig.Sampling
+	if config.Sampling != nil {
+		sampling = nil
+		if config.Sampling.Initial > 0 {
+			sampling = &zap.SamplingConfig{Initial: config.Sampling.Initial, Thereafter: config.Sampling.Thereafter}
+		}
+	}
+	// Sampling is applied here rather than by Build, so the tick is configurable.
+	zapConfig.Sampling = nil
 
 	// Set output paths
 	if len(config.OutputPaths) > 0 {
@@ -51,15 +94,63 @@ func New(config Config) (*Logger, error) {
 		"version": config.Version,
 	}
 
-	logger, err := zapConfig.Build()
+	logger, err := zapConfig.Build(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		// Redaction sits inside the sampler, so lines it drops are never redacted for nothing.
+		if redactor != nil {
+			core = &redactingCore{Core: core, redactor: redactor}
+		}
+		if sampling != nil {
+			tick := time.Second
+			if config.Sampling != nil && config.Sampling.Tick > 0 {
+				tick = config.Sampling.Tick
+			}
+			core = zapcore.NewSamplerWithOptions(core, tick, sampling.Initial, sampling.Thereafter,
+				zapcore.SamplerHook(l.countSampled))
+		}
+		return &scopedCore{Core: core, levels: l.levels}
+	}))
 	if err != nil {
 		return nil, err
 	}
 
-	return &Logger{
-		Logger: logger,
-		sugar:  logger.Sugar(),
-	}, nil
+	l.Logger = logger
+	l.sugar = logger.Sugar()
+	return l, nil
+}
+
+func (l *Logger) countSampled(ent zapcore.Entry, decision zapcore.SamplingDecision) {
+	if decision&zapcore.LogDropped == 0 || ent.Level < zapcore.DebugLevel || ent.Level > zapcore.FatalLevel {
+		return
+	}
+	l.dropped[ent.Level-zapcore.DebugLevel].Add(1)
+}
+
+// DroppedLines returns how many lines sampling has dropped since the logger was built, by level.
+// Levels with none dropped are left out.
+func (l *Logger) DroppedLines() map[zapcore.Level]uint64 {
+	out := make(map[zapcore.Level]uint64)
+	for i := range l.dropped {
+		if n := l.dropped[i].Load(); n > 0 {
+			out[zapcore.DebugLevel+zapcore.Level(i)] = n
+		}
+	}
+	return out
+}
+
+// SetLevel changes the minimum level logged, taking effect immediately for every logger derived
+// from l. An unknown level is rejected and leaves the current one in place.
+func (l *Logger) SetLevel(level string) error {
+	parsed, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	l.level.SetLevel(parsed)
+	return nil
+}
+
+// Level returns the minimum level currently logged.
+func (l *Logger) Level() zapcore.Level {
+	return l.level.Level()
 }
 
 func (l *Logger) WithTracing(ctx context.Context) *zap.Logger {
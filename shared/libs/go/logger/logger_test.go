@@ -4,6 +4,7 @@ import (
 	"context"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
@@ -56,6 +57,28 @@ func TestNew_InvalidLevelDefaultsToInfo(t *testing.T) {
 	}
 }
 
+func TestSetLevel(t *testing.T) {
+	l, err := New(testConfig("info", "production"))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	derived := l.WithRequestID("req-1")
+
+	if err := l.SetLevel("debug"); err != nil {
+		t.Fatalf("SetLevel() error = %v", err)
+	}
+	if !derived.Core().Enabled(zapcore.DebugLevel) {
+		t.Error("debug level should be enabled on derived loggers after SetLevel(debug)")
+	}
+
+	if err := l.SetLevel("verbose"); err == nil {
+		t.Error("SetLevel(verbose) error = nil, want an error")
+	}
+	if l.Level() != zapcore.DebugLevel {
+		t.Errorf("Level() = %v, want debug to be kept after a rejected level", l.Level())
+	}
+}
+
 func TestNew_ReturnsErrorForInvalidOutputPath(t *testing.T) {
 	_, err := New(Config{
 		Level:       "info",
@@ -199,3 +222,194 @@ func TestDefaultProductionConfig(t *testing.T) {
 		t.Errorf("Level = %q, want %q", cfg.Level, "info")
 	}
 }
+
+func TestSetScopedLevel_EnablesDebugForOneCorrelationID(t *testing.T) {
+	l, err := New(testConfig("info", "production"))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	traced := l.WithCorrelationID("corr-1")
+	other := l.WithCorrelationID("corr-2")
+
+	if err := l.SetScopedLevel(FieldCorrelationID, "corr-1", "debug", time.Minute); err != nil {
+		t.Fatalf("SetScopedLevel() error = %v", err)
+	}
+	if !traced.Core().Enabled(zapcore.DebugLevel) {
+		t.Error("debug should be enabled for the scoped correlation ID")
+	}
+	if !traced.With(zap.String("order_id", "o-1")).Core().Enabled(zapcore.DebugLevel) {
+		t.Error("debug should stay enabled on loggers derived from the scoped one")
+	}
+	if other.Core().Enabled(zapcore.DebugLevel) || l.Core().Enabled(zapcore.DebugLevel) {
+		t.Error("debug should stay off for other correlation IDs and the root logger")
+	}
+	if got := l.ScopedLevels(); len(got) != 1 || got[0].Value != "corr-1" || got[0].Level != zapcore.DebugLevel {
+		t.Errorf("ScopedLevels() = %+v, want the corr-1 debug override", got)
+	}
+
+	l.ClearScopedLevel(FieldCorrelationID, "corr-1")
+	if traced.Core().Enabled(zapcore.DebugLevel) {
+		t.Error("debug should be off again after ClearScopedLevel")
+	}
+}
+
+func TestSetScopedLevel_Expires(t *testing.T) {
+	l, err := New(testConfig("info", "production"))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	user := l.WithUserID("user-1")
+
+	if err := l.SetScopedLevel(FieldUserID, "user-1", "debug", 10*time.Millisecond); err != nil {
+		t.Fatalf("SetScopedLevel() error = %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if user.Core().Enabled(zapcore.DebugLevel) {
+		t.Error("debug should be off once the scoped level expired")
+	}
+	if got := l.ScopedLevels(); len(got) != 0 {
+		t.Errorf("ScopedLevels() = %+v, want none after expiry", got)
+	}
+}
+
+func TestSetScopedLevel_RejectsUnknownField(t *testing.T) {
+	l, err := New(testConfig("info", "production"))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := l.SetScopedLevel("order_id", "o-1", "debug", time.Minute); err == nil {
+		t.Error("SetScopedLevel(order_id) error = nil, want an error")
+	}
+	if err := l.SetScopedLevel(FieldUserID, "user-1", "loud", time.Minute); err == nil {
+		t.Error("SetScopedLevel(loud) error = nil, want an error")
+	}
+}
+
+func TestSampling_CountsDroppedLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.log")
+	l, err := New(Config{
+		Level:       "info",
+		Environment: "production",
+		OutputPaths: []string{path},
+		Sampling:    &SamplingConfig{Initial: 2, Thereafter: 0, Tick: time.Minute},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	for range 5 {
+		l.Warn("broker unreachable")
+	}
+	l.Debug("below the level, neither logged nor counted")
+
+	if got := l.DroppedLines()[zapcore.WarnLevel]; got != 3 {
+		t.Errorf("DroppedLines()[warn] = %d, want 3", got)
+	}
+	if got := l.DroppedLines()[zapcore.DebugLevel]; got != 0 {
+		t.Errorf("DroppedLines()[debug] = %d, want 0", got)
+	}
+}
+
+func TestSampling_ZeroInitialTurnsItOff(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.log")
+	l, err := New(Config{
+		Level:       "info",
+		Environment: "production",
+		OutputPaths: []string{path},
+		Sampling:    &SamplingConfig{},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	for range 200 {
+		l.Warn("broker unreachable")
+	}
+	if got := l.DroppedLines(); len(got) != 0 {
+		t.Errorf("DroppedLines() = %v, want none with sampling off", got)
+	}
+}
+
+func TestRedactor_Mask(t *testing.T) {
+	r, err := NewRedactor(RedactionConfig{Mode: RedactMask})
+	if err != nil {
+		t.Fatalf("NewRedactor() error = %v", err)
+	}
+	tests := []struct {
+		in, want string
+	}{
+		{"customer jane.doe@example.com signed up", "customer j***@example.com signed up"},
+		{"card 4111 1111 1111 1111 declined", "card ****1111 declined"},
+		{"card 4111111111111111", "card ****1111"},
+		{"from 203.0.113.42:51234", "from 203.0.x.x:51234"},
+		{"from [2001:db8::1]:443", "from [2001:db8:x]:443"},
+		// Not card numbers: fails Luhn, part of a UUID, a timestamp.
+		{"order 4111111111111112", "order 4111111111111112"},
+		{"order 11111111-1111-1111-1111-111111111111", "order 11111111-1111-1111-1111-111111111111"},
+		{"at 2026-10-18T10:30:00Z", "at 2026-10-18T10:30:00Z"},
+	}
+	for _, tt := range tests {
+		if got := r.String(tt.in); got != tt.want {
+			t.Errorf("String(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRedactor_HashIsStableAndKeyed(t *testing.T) {
+	a, _ := NewRedactor(RedactionConfig{Mode: RedactHash, HashKey: "a"})
+	b, _ := NewRedactor(RedactionConfig{Mode: RedactHash, HashKey: "b"})
+
+	first := a.String("jane@example.com")
+	if first == "jane@example.com" || first[:5] != "hash:" {
+		t.Fatalf("String() = %q, want a hash", first)
+	}
+	if again := a.String("jane@example.com"); again != first {
+		t.Errorf("String() = %q then %q, want the same hash", first, again)
+	}
+	if other := b.String("jane@example.com"); other == first {
+		t.Errorf("String() with another key = %q, want a different hash", other)
+	}
+}
+
+func TestNewRedactor_Modes(t *testing.T) {
+	if r, err := NewRedactor(RedactionConfig{Mode: RedactOff}); r != nil || err != nil {
+		t.Errorf("NewRedactor(off) = %v, %v, want nil, nil", r, err)
+	}
+	if _, err := NewRedactor(RedactionConfig{Mode: "scramble"}); err == nil {
+		t.Error("NewRedactor(scramble) error = nil, want an error")
+	}
+}
+
+func TestNew_RedactsFieldsAndMessages(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	r, err := NewRedactor(RedactionConfig{Fields: []string{"customer_ref"}})
+	if err != nil {
+		t.Fatalf("NewRedactor() error = %v", err)
+	}
+	log := zap.New(&redactingCore{Core: core, redactor: r}).With(zap.String("user_agent", "curl/8.0"))
+
+	log.Info("paid by jane@example.com",
+		zap.String("remote_addr", "203.0.113.42:51234"),
+		zap.Int("customer_ref", 42),
+		zap.ByteString("message", []byte(`{"card":"4111111111111111"}`)),
+		zap.String("order_id", "ord-1"),
+	)
+
+	entry := logs.All()[0]
+	if entry.Message != "paid by j***@example.com" {
+		t.Errorf("message = %q, want the email masked", entry.Message)
+	}
+	want := map[string]interface{}{
+		"user_agent":   "***",
+		"remote_addr":  "203.0.x.x:51234",
+		"customer_ref": "***",
+		"message":      `{"card":"****1111"}`,
+		"order_id":     "ord-1",
+	}
+	got := entry.ContextMap()
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("field %s = %v, want %v", k, got[k], v)
+		}
+	}
+}
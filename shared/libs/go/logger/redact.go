@@ -0,0 +1,346 @@
+package logger
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Redaction modes.
+const (
+	// RedactMask keeps enough of a value to tell values apart by eye: the first letter and domain
+	// of an email, the last four digits of a card number, the first half of an IP address.
+	RedactMask = "mask"
+	// RedactHash replaces a value with a keyed hash, so the same customer can be followed
+	// through the logs without the value itself being written.
+	RedactHash = "hash"
+	// RedactOff turns redaction off.
+	RedactOff = "off"
+)
+
+// DefaultRedactedFields are always redacted, whatever they hold.
+var DefaultRedactedFields = []string{
+	"email", "remote_addr", "client_ip", "ip", "user_agent",
+	"card_number", "password", "token", "authorization",
+}
+
+// RedactionConfig configures the redaction applied to every line a Logger writes.
+type RedactionConfig struct {
+	// Mode is RedactMask, RedactHash or RedactOff; empty means RedactMask.
+	Mode string `json:"mode"`
+	// Fields are redacted whatever they hold, on top of DefaultRedactedFields.
+	Fields []string `json:"fields,omitempty"`
+	// HashKey keys the hash in RedactHash mode. Without one, a short value such as an IP address
+	// can be recovered by hashing every candidate, so set it in production.
+	HashKey string `json:"-"`
+}
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	// cardPattern finds runs of digits, spaces and dashes long enough to be a card number;
+	// looksLikeCard then decides.
+	cardPattern = regexp.MustCompile(`[0-9][0-9 \-]{11,}[0-9]`)
+	ipv4Pattern = regexp.MustCompile(`\b(?:[0-9]{1,3}\.){3}[0-9]{1,3}\b`)
+	ipv6Pattern = regexp.MustCompile(`[0-9A-Fa-f]*:[0-9A-Fa-f:]*:[0-9A-Fa-f:.]*`)
+)
+
+// Redactor replaces personal data in log fields: any field named in its list, and, in every
+// string field and message, anything that looks like an email, a card number or an IP address.
+// Fields holding arrays or objects are only redacted by name.
+type Redactor struct {
+	hash   bool
+	key    []byte
+	fields map[string]bool
+}
+
+// NewRedactor builds a Redactor for cfg. It returns nil for RedactOff.
+func NewRedactor(cfg RedactionConfig) (*Redactor, error) {
+	r := &Redactor{key: []byte(cfg.HashKey), fields: make(map[string]bool)}
+	switch cfg.Mode {
+	case "", RedactMask:
+	case RedactHash:
+		r.hash = true
+	case RedactOff:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown redaction mode %q", cfg.Mode)
+	}
+	for _, f := range DefaultRedactedFields {
+		r.fields[f] = true
+	}
+	for _, f := range cfg.Fields {
+		r.fields[strings.ToLower(f)] = true
+	}
+	return r, nil
+}
+
+// String returns s with every email, card number and IP address in it redacted.
+func (r *Redactor) String(s string) string {
+	out, _ := r.redact(s)
+	return out
+}
+
+// redact returns s with its personal data redacted, and whether it found any.
+func (r *Redactor) redact(s string) (string, bool) {
+	found := false
+	if strings.Contains(s, "@") {
+		s = emailPattern.ReplaceAllStringFunc(s, func(m string) string {
+			found = true
+			return r.replace(m, maskEmail)
+		})
+	}
+	if len(s) >= 13 {
+		s = replaceMatches(cardPattern, s, func(m string, prev, next byte) (string, bool) {
+			if !looksLikeCard(m, prev, next) {
+				return m, false
+			}
+			found = true
+			return r.replace(m, maskCard), true
+		})
+	}
+	if strings.Contains(s, ".") {
+		s = ipv4Pattern.ReplaceAllStringFunc(s, func(m string) string {
+			if _, err := netip.ParseAddr(m); err != nil {
+				return m
+			}
+			found = true
+			return r.replace(m, maskIP)
+		})
+	}
+	if strings.Count(s, ":") >= 2 {
+		s = ipv6Pattern.ReplaceAllStringFunc(s, func(m string) string {
+			addr, err := netip.ParseAddr(m)
+			if err != nil || !addr.Is6() || strings.Trim(m, ":") == "" {
+				return m
+			}
+			found = true
+			return r.replace(m, maskIP)
+		})
+	}
+	return s, found
+}
+
+// whole redacts a value held in a field that is redacted by name.
+func (r *Redactor) whole(s string) string {
+	if out, found := r.redact(s); found {
+		return out
+	}
+	return r.replace(s, func(string) string { return "***" })
+}
+
+func (r *Redactor) replace(value string, mask func(string) string) string {
+	if !r.hash {
+		return mask(value)
+	}
+	var sum []byte
+	if len(r.key) > 0 {
+		mac := hmac.New(sha256.New, r.key)
+		mac.Write([]byte(value))
+		sum = mac.Sum(nil)
+	} else {
+		digest := sha256.Sum256([]byte(value))
+		sum = digest[:]
+	}
+	return "hash:" + hex.EncodeToString(sum[:8])
+}
+
+// Fields returns fields with personal data redacted. Fields that need nothing are returned as
+// they are, without being copied.
+func (r *Redactor) Fields(fields []zapcore.Field) []zapcore.Field {
+	var out []zapcore.Field
+	for i, f := range fields {
+		redacted, changed := r.field(f)
+		if !changed {
+			if out != nil {
+				out = append(out, f)
+			}
+			continue
+		}
+		if out == nil {
+			out = make([]zapcore.Field, i, len(fields))
+			copy(out, fields[:i])
+		}
+		out = append(out, redacted)
+	}
+	if out == nil {
+		return fields
+	}
+	return out
+}
+
+func (r *Redactor) field(f zapcore.Field) (zapcore.Field, bool) {
+	if f.Type == zapcore.NamespaceType || f.Type == zapcore.SkipType {
+		return f, false
+	}
+	if r.fields[strings.ToLower(f.Key)] {
+		return zap.String(f.Key, r.whole(fieldString(f))), true
+	}
+
+	var s string
+	switch f.Type {
+	case zapcore.StringType:
+		s = f.String
+	case zapcore.ByteStringType:
+		s = string(f.Interface.([]byte))
+	case zapcore.ErrorType:
+		if err, ok := f.Interface.(error); ok && err != nil {
+			s = err.Error()
+		}
+	case zapcore.StringerType:
+		if stringer, ok := f.Interface.(fmt.Stringer); ok && stringer != nil {
+			s = stringer.String()
+		}
+	default:
+		return f, false
+	}
+	out, found := r.redact(s)
+	if !found {
+		return f, false
+	}
+	return zap.String(f.Key, out), true
+}
+
+// fieldString renders any field as a string, the way an encoder would show its value.
+func fieldString(f zapcore.Field) string {
+	enc := zapcore.NewMapObjectEncoder()
+	f.AddTo(enc)
+	return fmt.Sprint(enc.Fields[f.Key])
+}
+
+// redactingCore redacts fields and messages before they reach the core it wraps.
+type redactingCore struct {
+	zapcore.Core
+	redactor *Redactor
+}
+
+func (c *redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactingCore{Core: c.Core.With(c.redactor.Fields(fields)), redactor: c.redactor}
+}
+
+func (c *redactingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *redactingCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ent.Message = c.redactor.String(ent.Message)
+	return c.Core.Write(ent, c.redactor.Fields(fields))
+}
+
+// replaceMatches is ReplaceAllStringFunc with the bytes either side of each match, which
+// looksLikeCard needs to tell a card number from part of a longer identifier.
+func replaceMatches(re *regexp.Regexp, s string, fn func(match string, prev, next byte) (string, bool)) string {
+	matches := re.FindAllStringIndex(s, -1)
+	if matches == nil {
+		return s
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		var prev, next byte
+		if m[0] > 0 {
+			prev = s[m[0]-1]
+		}
+		if m[1] < len(s) {
+			next = s[m[1]]
+		}
+		if out, ok := fn(s[m[0]:m[1]], prev, next); ok {
+			b.WriteString(s[last:m[0]])
+			b.WriteString(out)
+			last = m[1]
+		}
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// looksLikeCard reports whether m, found between prev and next, is a card number: 13 to 19
+// digits, ungrouped or grouped the way cards are printed, passing the Luhn check, and not part
+// of a longer identifier such as a UUID.
+func looksLikeCard(m string, prev, next byte) bool {
+	if isIdentifierByte(prev) || isIdentifierByte(next) {
+		return false
+	}
+	groups := strings.FieldsFunc(m, func(r rune) bool { return r == ' ' || r == '-' })
+	digits := strings.Join(groups, "")
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+	if len(groups) > 1 && !cardGrouping(groups) {
+		return false
+	}
+	return luhn(digits)
+}
+
+func isIdentifierByte(b byte) bool {
+	return b == '-' || b == '_' || b == '.' ||
+		(b >= '0' && b <= '9') || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+// cardGrouping accepts groups of four with a shorter last group, and the 4-6-5 grouping of
+// American Express.
+func cardGrouping(groups []string) bool {
+	if len(groups) == 3 && len(groups[0]) == 4 && len(groups[1]) == 6 && (len(groups[2]) == 5 || len(groups[2]) == 4) {
+		return true
+	}
+	for i, g := range groups {
+		if len(g) != 4 && (i != len(groups)-1 || len(g) > 4) {
+			return false
+		}
+	}
+	return true
+}
+
+func luhn(digits string) bool {
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+func maskEmail(email string) string {
+	at := strings.LastIndexByte(email, '@')
+	return email[:1] + "***" + email[at:]
+}
+
+func maskCard(card string) string {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, card)
+	return "****" + digits[len(digits)-4:]
+}
+
+func maskIP(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return "***"
+	}
+	if addr.Is4() {
+		b := addr.As4()
+		return fmt.Sprintf("%d.%d.x.x", b[0], b[1])
+	}
+	b := addr.As16()
+	return fmt.Sprintf("%x:%x:x", uint16(b[0])<<8|uint16(b[1]), uint16(b[2])<<8|uint16(b[3]))
+}
@@ -0,0 +1,166 @@
+package logger
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Fields a level can be scoped to with SetScopedLevel. They are the keys WithCorrelationID and
+// WithUserID attach.
+const (
+	FieldCorrelationID = "correlation_id"
+	FieldUserID        = "user_id"
+)
+
+// ScopedLevel is a level that applies only to loggers carrying Field with Value, until Expires.
+type ScopedLevel struct {
+	Field   string        `json:"field"`
+	Value   string        `json:"value"`
+	Level   zapcore.Level `json:"level"`
+	Expires time.Time     `json:"expires_at"`
+}
+
+type scope struct {
+	field string
+	value string
+}
+
+// levels decides what gets logged: the global level, lowered for scopes with an override.
+type levels struct {
+	global zap.AtomicLevel
+
+	mu        sync.RWMutex
+	overrides map[scope]ScopedLevel
+}
+
+func newLevels(global zap.AtomicLevel) *levels {
+	return &levels{global: global, overrides: make(map[scope]ScopedLevel)}
+}
+
+// enabled reports whether lvl is logged by a logger carrying scopes.
+func (l *levels) enabled(lvl zapcore.Level, scopes []scope) bool {
+	if l.global.Enabled(lvl) {
+		return true
+	}
+	if len(scopes) == 0 {
+		return false
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if len(l.overrides) == 0 {
+		return false
+	}
+	now := time.Now()
+	for _, s := range scopes {
+		if o, ok := l.overrides[s]; ok && now.Before(o.Expires) && lvl >= o.Level {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *levels) set(o ScopedLevel) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.prune(time.Now())
+	l.overrides[scope{o.Field, o.Value}] = o
+}
+
+func (l *levels) clear(field, value string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.overrides, scope{field, value})
+}
+
+func (l *levels) list() []ScopedLevel {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.prune(time.Now())
+
+	out := make([]ScopedLevel, 0, len(l.overrides))
+	for _, o := range l.overrides {
+		out = append(out, o)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Field != out[j].Field {
+			return out[i].Field < out[j].Field
+		}
+		return out[i].Value < out[j].Value
+	})
+	return out
+}
+
+// prune drops expired overrides; the caller holds mu for writing.
+func (l *levels) prune(now time.Time) {
+	for s, o := range l.overrides {
+		if !now.Before(o.Expires) {
+			delete(l.overrides, s)
+		}
+	}
+}
+
+// scopedCore filters entries by levels, remembering the correlation and user IDs attached to it
+// with With so that a scoped level can let its debug lines through.
+type scopedCore struct {
+	zapcore.Core
+	levels *levels
+	scopes []scope
+}
+
+func (c *scopedCore) Enabled(lvl zapcore.Level) bool {
+	return c.levels.enabled(lvl, c.scopes)
+}
+
+func (c *scopedCore) With(fields []zapcore.Field) zapcore.Core {
+	scopes := c.scopes
+	for _, f := range fields {
+		if f.Type == zapcore.StringType && (f.Key == FieldCorrelationID || f.Key == FieldUserID) {
+			scopes = append(scopes[:len(scopes):len(scopes)], scope{f.Key, f.String})
+		}
+	}
+	return &scopedCore{Core: c.Core.With(fields), levels: c.levels, scopes: scopes}
+}
+
+func (c *scopedCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// SetScopedLevel logs at level, for the next ttl, every line from a logger carrying field with
+// value: one built with WithCorrelationID or WithUserID, or with zap.String(field, value). It
+// can only lower the level below the global one; it never silences lines the global level logs.
+func (l *Logger) SetScopedLevel(field, value, level string, ttl time.Duration) error {
+	if field != FieldCorrelationID && field != FieldUserID {
+		return fmt.Errorf("level can only be scoped to %s or %s, not %q", FieldCorrelationID, FieldUserID, field)
+	}
+	if value == "" {
+		return fmt.Errorf("scoped level needs a %s", field)
+	}
+	if ttl <= 0 {
+		return fmt.Errorf("scoped level ttl must be positive, got %s", ttl)
+	}
+	parsed, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	l.levels.set(ScopedLevel{Field: field, Value: value, Level: parsed, Expires: time.Now().Add(ttl)})
+	return nil
+}
+
+// ClearScopedLevel removes the scoped level for field and value, if there is one.
+func (l *Logger) ClearScopedLevel(field, value string) {
+	l.levels.clear(field, value)
+}
+
+// ScopedLevels returns the scoped levels that have not expired yet.
+func (l *Logger) ScopedLevels() []ScopedLevel {
+	return l.levels.list()
+}
@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap/zapcore"
+)
+
+// DroppedLinesCounter is implemented by *logger.Logger.
+type DroppedLinesCounter interface {
+	DroppedLines() map[zapcore.Level]uint64
+}
+
+// LoggerMetrics reports how many log lines sampling dropped, read from the logger at scrape time.
+type LoggerMetrics struct {
+	logger  DroppedLinesCounter
+	dropped *prometheus.Desc
+}
+
+// NewLoggerMetrics builds a LoggerMetrics collector for logger, labelling every metric with
+// service. Register it with a prometheus.Registerer to have it scraped.
+func NewLoggerMetrics(logger DroppedLinesCounter, service string) *LoggerMetrics {
+	return &LoggerMetrics{
+		logger: logger,
+		dropped: prometheus.NewDesc(
+			"log_lines_dropped_total",
+			"Log lines dropped by sampling, by level.",
+			[]string{"level"}, prometheus.Labels{"service": service},
+		),
+	}
+}
+
+// Describe sends the metric descriptor this collector reports.
+func (m *LoggerMetrics) Describe(ch chan<- *prometheus.Desc) {
+	ch <- m.dropped
+}
+
+// Collect reports the dropped line counts.
+func (m *LoggerMetrics) Collect(ch chan<- prometheus.Metric) {
+	for level, n := range m.logger.DroppedLines() {
+		ch <- prometheus.MustNewConstMetric(m.dropped, prometheus.CounterValue, float64(n), level.String())
+	}
+}
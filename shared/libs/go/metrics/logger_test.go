@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap/zapcore"
+)
+
+type droppedLines map[zapcore.Level]uint64
+
+func (d droppedLines) DroppedLines() map[zapcore.Level]uint64 { return d }
+
+func TestLoggerMetrics_Collect_ReportsDroppedLinesByLevel(t *testing.T) {
+	m := NewLoggerMetrics(droppedLines{zapcore.WarnLevel: 3, zapcore.ErrorLevel: 1}, "order")
+
+	want := `
+# HELP log_lines_dropped_total Log lines dropped by sampling, by level.
+# TYPE log_lines_dropped_total counter
+log_lines_dropped_total{level="error",service="order"} 1
+log_lines_dropped_total{level="warn",service="order"} 3
+`
+	if err := testutil.CollectAndCompare(m, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+}
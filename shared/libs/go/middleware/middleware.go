@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"time"
 
+	apperrors "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/errors"
 	"github.com/google/uuid"
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
@@ -96,7 +97,7 @@ func Logging(logger *zap.Logger) Middleware {
 	}
 }
 
-// Recovery middleware recovers from panics
+// Recovery middleware recovers from panics, answering them with an INTERNAL_SERVER_ERROR problem
 func Recovery(logger *zap.Logger) Middleware {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -111,9 +112,7 @@ func Recovery(logger *zap.Logger) Middleware {
 						zap.Any("request_id", requestID),
 					)
 
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusInternalServerError)
-					if _, err := w.Write([]byte(`{"error": "Internal server error"}`)); err != nil {
+					if err := apperrors.WriteProblem(w, r, apperrors.NewInternalServerError("Internal server error")); err != nil {
 						logger.Error("Failed to write error response", zap.Error(err))
 					}
 				}
@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	apperrors "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/errors"
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest/observer"
@@ -225,16 +226,16 @@ func TestRecovery_RecoversPanicAsInternalServerError(t *testing.T) {
 	if rec.Code != http.StatusInternalServerError {
 		t.Errorf("status code = %d, want %d", rec.Code, http.StatusInternalServerError)
 	}
-	if got := rec.Header().Get("Content-Type"); got != "application/json" {
-		t.Errorf("Content-Type = %q, want %q", got, "application/json")
+	if got := rec.Header().Get("Content-Type"); got != apperrors.ProblemContentType {
+		t.Errorf("Content-Type = %q, want %q", got, apperrors.ProblemContentType)
 	}
 
-	var body map[string]string
-	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+	var problem apperrors.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
 		t.Fatalf("response body is not valid JSON: %v", err)
 	}
-	if body["error"] == "" {
-		t.Error("response body has no error message")
+	if problem.Code != "INTERNAL_SERVER_ERROR" || problem.Status != http.StatusInternalServerError || problem.Detail == "" {
+		t.Errorf("problem = %+v, want an INTERNAL_SERVER_ERROR problem with a detail", problem)
 	}
 
 	if entries := logs.All(); len(entries) != 1 {
@@ -0,0 +1,97 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Usage describes the arguments Run takes.
+const Usage = `usage: migrate <command>
+
+commands:
+  up            apply every pending migration
+  down [n]      roll back the last n migrations (default 1)
+  status        show the applied version and pending migrations
+  to <version>  migrate up or down to version (0 rolls everything back)`
+
+// ErrUsage is returned by Run for arguments it does not understand.
+var ErrUsage = errors.New(Usage)
+
+// Run carries out the migrate subcommand args on m, writing what it did to out. It backs the
+// "migrate" subcommand of every service binary.
+func Run(ctx context.Context, m *Migrator, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return ErrUsage
+	}
+
+	switch cmd, rest := args[0], args[1:]; cmd {
+	case "up":
+		if len(rest) != 0 {
+			return ErrUsage
+		}
+		if err := m.Up(ctx); err != nil {
+			return err
+		}
+	case "down":
+		steps := 1
+		if len(rest) > 1 {
+			return ErrUsage
+		}
+		if len(rest) == 1 {
+			n, err := strconv.Atoi(rest[0])
+			if err != nil || n < 1 {
+				return fmt.Errorf("down: step count must be a positive number, got %q", rest[0])
+			}
+			steps = n
+		}
+		if err := m.Down(ctx, steps); err != nil {
+			return err
+		}
+	case "to":
+		if len(rest) != 1 {
+			return ErrUsage
+		}
+		version, err := strconv.ParseUint(rest[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("to: version must be a number, got %q", rest[0])
+		}
+		if err := m.To(ctx, version); err != nil {
+			return err
+		}
+	case "status":
+		if len(rest) != 0 {
+			return ErrUsage
+		}
+	default:
+		return ErrUsage
+	}
+
+	return printStatus(ctx, m, out)
+}
+
+func printStatus(ctx context.Context, m *Migrator, out io.Writer) error {
+	status, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	line := fmt.Sprintf("version %d, latest %d", status.Current, status.Latest)
+	switch {
+	case status.Dirty:
+		line += " (dirty)"
+	case status.Current > status.Latest:
+		line += " (database is ahead of this binary)"
+	}
+	if _, err := fmt.Fprintln(out, line); err != nil {
+		return err
+	}
+	for _, mig := range status.Pending {
+		if _, err := fmt.Fprintf(out, "pending %06d_%s\n", mig.Version, mig.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
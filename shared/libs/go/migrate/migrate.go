@@ -0,0 +1,310 @@
+// Package migrate applies a service's SQL migrations, embedded in its binary, to its database.
+//
+// Migrations are NNNNNN_name.up.sql files with optional NNNNNN_name.down.sql counterparts. The
+// applied version is kept in schema_migrations, in the same layout the golang-migrate CLI uses,
+// so a database it migrated can be taken over without a reset and the CLI still works on one
+// this package migrated.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+// lockKey is the advisory lock replicas take while migrating, so only one of them applies
+// migrations at a time. Advisory locks are per database, so services sharing a server do not
+// block each other.
+const lockKey int64 = 0x65766d6967726174
+
+var (
+	// ErrSchemaAhead is returned when the database has migrations the binary does not know,
+	// typically because a newer release migrated it and this one is a rollback.
+	ErrSchemaAhead = errors.New("database schema is ahead of this binary")
+	// ErrDirty is returned when a migration failed part way through without a transaction to
+	// undo it. The schema has to be repaired by hand before migrating again.
+	ErrDirty = errors.New("database schema is dirty")
+)
+
+var fileName = regexp.MustCompile(`^([0-9]+)_(.+)\.(up|down)\.sql$`)
+
+// Migration is one schema version.
+type Migration struct {
+	Version uint64
+	Name    string
+	up      string
+	down    string
+	hasDown bool
+}
+
+// Load reads the migrations at the root of fsys, ordered by version. Files that are not named
+// like a migration are ignored.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("read migrations: %w", err)
+	}
+
+	byVersion := make(map[uint64]*Migration)
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil || version == 0 {
+			return nil, fmt.Errorf("migration %s: version must be a positive number", entry.Name())
+		}
+		body, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("read migration %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d is used by both %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.up = string(body)
+		} else {
+			m.down, m.hasDown = string(body), true
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Status is where a database stands against the migrations a binary carries.
+type Status struct {
+	// Current is the applied version, 0 for none.
+	Current uint64
+	// Latest is the newest version the binary carries.
+	Latest uint64
+	Dirty  bool
+	// Pending are the migrations Up would apply.
+	Pending []Migration
+}
+
+// Migrator applies migrations to a database.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	logger     *zap.Logger
+}
+
+// New loads the migrations in fsys for db.
+func New(db *sql.DB, fsys fs.FS, logger *zap.Logger) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Migrator{db: db, migrations: migrations, logger: logger}, nil
+}
+
+// Latest returns the newest version the migrator carries, 0 if it carries none.
+func (m *Migrator) Latest() uint64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Status reports the database's version and the migrations not yet applied to it.
+func (m *Migrator) Status(ctx context.Context) (Status, error) {
+	current, dirty, err := readVersion(ctx, m.db)
+	if err != nil {
+		return Status{}, err
+	}
+	status := Status{Current: current, Latest: m.Latest(), Dirty: dirty}
+	for _, mig := range m.migrations {
+		if mig.Version > current {
+			status.Pending = append(status.Pending, mig)
+		}
+	}
+	return status, nil
+}
+
+// Up applies every pending migration. It fails with ErrSchemaAhead, changing nothing, when the
+// database is at a version newer than the binary's latest.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.migrate(ctx, func(uint64) uint64 { return m.Latest() })
+}
+
+// Down rolls back the last steps migrations applied.
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	if steps < 1 {
+		return fmt.Errorf("down needs at least one step, got %d", steps)
+	}
+	return m.migrate(ctx, func(current uint64) uint64 {
+		i := m.index(current)
+		if i < steps {
+			return 0
+		}
+		return m.migrations[i-steps].Version
+	})
+}
+
+// To migrates up or down to version; 0 rolls every migration back.
+func (m *Migrator) To(ctx context.Context, version uint64) error {
+	if version != 0 && m.index(version) < 0 {
+		return fmt.Errorf("no migration has version %d", version)
+	}
+	return m.migrate(ctx, func(uint64) uint64 { return version })
+}
+
+// index returns the position of version in m.migrations, or -1.
+func (m *Migrator) index(version uint64) int {
+	for i, mig := range m.migrations {
+		if mig.Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+// migrate moves the database to the version target picks from the current one, holding the
+// advisory lock from reading the version until the last migration is applied.
+func (m *Migrator) migrate(ctx context.Context, target func(current uint64) uint64) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("get connection: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("take migration lock: %w", err)
+	}
+	defer func() {
+		// Unlock even if ctx is done; the lock would otherwise outlive the call on a pooled
+		// connection.
+		_, _ = conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey)
+	}()
+
+	if _, err := conn.ExecContext(ctx,
+		`CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)`); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+	current, dirty, err := readVersion(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("%w at version %d: repair it by hand, then clear the dirty flag in schema_migrations", ErrDirty, current)
+	}
+	if current > m.Latest() {
+		return fmt.Errorf("%w: database is at version %d, binary only knows up to %d", ErrSchemaAhead, current, m.Latest())
+	}
+	if current != 0 && m.index(current) < 0 {
+		return fmt.Errorf("database is at version %d, which this binary has no migration for", current)
+	}
+
+	to := target(current)
+
+	for _, mig := range m.migrations {
+		if mig.Version <= current || mig.Version > to {
+			continue
+		}
+		if err := m.apply(ctx, conn, mig, "up", mig.up, mig.Version); err != nil {
+			return err
+		}
+	}
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		mig := m.migrations[i]
+		if mig.Version > current || mig.Version <= to {
+			continue
+		}
+		if !mig.hasDown {
+			return fmt.Errorf("migration %d_%s has no down file", mig.Version, mig.Name)
+		}
+		var previous uint64
+		if i > 0 {
+			previous = m.migrations[i-1].Version
+		}
+		if err := m.apply(ctx, conn, mig, "down", mig.down, previous); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// apply runs one migration and records version as applied in the same transaction, so a
+// migration that fails leaves the schema and the recorded version as they were.
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, mig Migration, direction, body string, version uint64) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin migration %d: %w", mig.Version, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, body); err != nil {
+		return fmt.Errorf("migrate %s %d_%s: %w", direction, mig.Version, mig.Name, err)
+	}
+	if _, err := tx.ExecContext(ctx, `TRUNCATE schema_migrations`); err != nil {
+		return fmt.Errorf("record version %d: %w", version, err)
+	}
+	if version != 0 {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, false)`, int64(version)); err != nil {
+			return fmt.Errorf("record version %d: %w", version, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit migration %d: %w", mig.Version, err)
+	}
+
+	m.logger.Info("Applied migration",
+		zap.Uint64("version", mig.Version), zap.String("name", mig.Name), zap.String("direction", direction))
+	return nil
+}
+
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// readVersion returns the recorded version, 0 when nothing has been applied or the table does
+// not exist yet.
+func readVersion(ctx context.Context, q queryer) (uint64, bool, error) {
+	var exists bool
+	if err := q.QueryRowContext(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return 0, false, fmt.Errorf("check schema_migrations: %w", err)
+	}
+	if !exists {
+		return 0, false, nil
+	}
+
+	var (
+		version int64
+		dirty   bool
+	)
+	err := q.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("read schema version: %w", err)
+	}
+	if version < 0 {
+		return 0, dirty, nil
+	}
+	return uint64(version), dirty, nil
+}
@@ -0,0 +1,152 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"testing/fstest"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var testMigrations = fstest.MapFS{
+	"000001_create_orders.up.sql":   {Data: []byte("CREATE TABLE orders (id UUID)")},
+	"000001_create_orders.down.sql": {Data: []byte("DROP TABLE orders")},
+	"000002_add_status.up.sql":      {Data: []byte("ALTER TABLE orders ADD status TEXT")},
+	"000002_add_status.down.sql":    {Data: []byte("ALTER TABLE orders DROP status")},
+	"migrations.go":                 {Data: []byte("package migrations")},
+}
+
+func newTestMigrator(t *testing.T) (*Migrator, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New() error = %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	m, err := New(db, testMigrations, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return m, mock
+}
+
+// expectLocked expects the lock, the table check and a version read returning version.
+func expectLocked(mock sqlmock.Sqlmock, version int64) {
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_lock($1)")).WithArgs(lockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	expectVersion(mock, version)
+}
+
+func expectVersion(mock sqlmock.Sqlmock, version int64) {
+	mock.ExpectQuery("to_regclass").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	rows := sqlmock.NewRows([]string{"version", "dirty"})
+	if version > 0 {
+		rows.AddRow(version, false)
+	}
+	mock.ExpectQuery("SELECT version, dirty FROM schema_migrations").WillReturnRows(rows)
+}
+
+func expectApplied(mock sqlmock.Sqlmock, body string, version int64) {
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(body)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("TRUNCATE schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	if version > 0 {
+		mock.ExpectExec("INSERT INTO schema_migrations").WithArgs(version).WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+}
+
+func expectUnlocked(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_unlock($1)")).WithArgs(lockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func TestLoad_OrdersByVersionAndSkipsOtherFiles(t *testing.T) {
+	migrations, err := Load(testMigrations)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(migrations) != 2 || migrations[0].Version != 1 || migrations[1].Name != "add_status" {
+		t.Errorf("Load() = %+v, want create_orders then add_status", migrations)
+	}
+}
+
+func TestLoad_RejectsDownWithoutUp(t *testing.T) {
+	_, err := Load(fstest.MapFS{"000003_orphan.down.sql": {Data: []byte("SELECT 1")}})
+	if err == nil {
+		t.Error("Load() error = nil, want an error for a down file with no up file")
+	}
+}
+
+func TestUp_AppliesPendingMigrations(t *testing.T) {
+	m, mock := newTestMigrator(t)
+	expectLocked(mock, 1)
+	expectApplied(mock, "ALTER TABLE orders ADD status TEXT", 2)
+	expectUnlocked(mock)
+
+	if err := m.Up(context.Background()); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestUp_RefusesSchemaAhead(t *testing.T) {
+	m, mock := newTestMigrator(t)
+	expectLocked(mock, 3)
+	expectUnlocked(mock)
+
+	if err := m.Up(context.Background()); !errors.Is(err, ErrSchemaAhead) {
+		t.Fatalf("Up() error = %v, want ErrSchemaAhead", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestDown_RollsBackToNothing(t *testing.T) {
+	m, mock := newTestMigrator(t)
+	expectLocked(mock, 2)
+	expectApplied(mock, "ALTER TABLE orders DROP status", 1)
+	expectApplied(mock, "DROP TABLE orders", 0)
+	expectUnlocked(mock)
+
+	if err := m.Down(context.Background(), 5); err != nil {
+		t.Fatalf("Down() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestTo_UnknownVersion(t *testing.T) {
+	m, _ := newTestMigrator(t)
+	if err := m.To(context.Background(), 7); err == nil {
+		t.Error("To(7) error = nil, want an error for a version with no migration")
+	}
+}
+
+func TestRun_Status(t *testing.T) {
+	m, mock := newTestMigrator(t)
+	expectVersion(mock, 1)
+
+	var out bytes.Buffer
+	if err := Run(context.Background(), m, []string{"status"}, &out); err != nil {
+		t.Fatalf("Run(status) error = %v", err)
+	}
+	if want := "version 1, latest 2\npending 000002_add_status\n"; out.String() != want {
+		t.Errorf("Run(status) wrote %q, want %q", out.String(), want)
+	}
+}
+
+func TestRun_RejectsUnknownCommand(t *testing.T) {
+	m, _ := newTestMigrator(t)
+	for _, args := range [][]string{nil, {"sideways"}, {"down", "zero"}, {"to"}} {
+		if err := Run(context.Background(), m, args, &bytes.Buffer{}); err == nil {
+			t.Errorf("Run(%q) error = nil, want an error", args)
+		}
+	}
+}
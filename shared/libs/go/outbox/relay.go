@@ -92,27 +92,54 @@ type outboxRow struct {
 // rows it selects with FOR UPDATE SKIP LOCKED so multiple relay instances can run concurrently
 // without publishing the same message twice.
 func (r *Relay) RelayBatch(ctx context.Context) error {
+	_, _, err := r.relayBatch(ctx)
+	return err
+}
+
+// Flush publishes pending messages batch after batch until the outbox is empty, a message fails
+// to publish, or ctx ends. Run after Stop during shutdown, it sends what was enqueued since the
+// last poll before the publisher is closed.
+func (r *Relay) Flush(ctx context.Context) error {
+	for {
+		selected, failed, err := r.relayBatch(ctx)
+		if err != nil {
+			return err
+		}
+		if selected < r.batchSize || failed > 0 {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// relayBatch does the work of RelayBatch, reporting how many rows it selected and how many of
+// them failed to publish.
+func (r *Relay) relayBatch(ctx context.Context) (selected, failed int, err error) {
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to begin outbox relay transaction: %w", err)
+		return 0, 0, fmt.Errorf("failed to begin outbox relay transaction: %w", err)
 	}
 	defer func() { _ = tx.Rollback() }()
 
 	rows, err := r.selectPending(ctx, tx)
 	if err != nil {
-		return err
+		return 0, 0, err
 	}
 
 	for _, row := range rows {
-		r.publishRow(ctx, tx, row)
+		if !r.publishRow(ctx, tx, row) {
+			failed++
+		}
 	}
 
 	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit outbox relay transaction: %w", err)
+		return 0, 0, fmt.Errorf("failed to commit outbox relay transaction: %w", err)
 	}
 
 	r.reportPending(ctx)
-	return nil
+	return len(rows), failed, nil
 }
 
 // reportPending counts remaining unpublished outbox rows and reports them through metrics. It is
@@ -163,12 +190,12 @@ func (r *Relay) selectPending(ctx context.Context, tx *sql.Tx) ([]outboxRow, err
 }
 
 // publishRow publishes a single row and marks it published or records the failure, without
-// aborting the rest of the batch.
-func (r *Relay) publishRow(ctx context.Context, tx *sql.Tx, row outboxRow) {
+// aborting the rest of the batch. It reports whether the message was published.
+func (r *Relay) publishRow(ctx context.Context, tx *sql.Tx, row outboxRow) bool {
 	var data map[string]interface{}
 	if err := json.Unmarshal(row.payload, &data); err != nil {
 		r.markFailed(ctx, tx, row.id, fmt.Errorf("failed to unmarshal outbox payload: %w", err))
-		return
+		return false
 	}
 
 	event := events.Event{
@@ -180,13 +207,14 @@ func (r *Relay) publishRow(ctx context.Context, tx *sql.Tx, row outboxRow) {
 
 	if err := r.pub.Publish(ctx, row.topic, event); err != nil {
 		r.markFailed(ctx, tx, row.id, err)
-		return
+		return false
 	}
 
 	const markPublished = `UPDATE outbox_messages SET published_at = NOW() WHERE id = $1`
 	if _, err := tx.ExecContext(ctx, markPublished, row.id); err != nil {
 		r.logger.Error("failed to mark outbox message published", zap.String("id", row.id), zap.Error(err))
 	}
+	return true
 }
 
 func (r *Relay) markFailed(ctx context.Context, tx *sql.Tx, id string, cause error) {
@@ -426,6 +426,71 @@ func TestRelay_RelayBatch_NoPendingMessagesCommitsEmptyBatch(t *testing.T) {
 	}
 }
 
+func TestRelay_Flush_RelaysUntilABatchComesBackShort(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New() error = %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, topic, event_type, aggregate_id, payload, correlation_id")).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(pendingColumns).
+			AddRow("msg-1", "orders.events", "order.created", "order-1", []byte(`{}`), nil))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE outbox_messages SET published_at")).
+		WithArgs("msg-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, topic, event_type, aggregate_id, payload, correlation_id")).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(pendingColumns))
+	mock.ExpectCommit()
+
+	pub := &fakePublisher{}
+	relay := &Relay{db: db, pub: pub, logger: zap.NewNop(), batchSize: 1}
+
+	if err := relay.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if len(pub.published) != 1 {
+		t.Fatalf("published = %d events, want 1", len(pub.published))
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestRelay_Flush_StopsWhenAMessageFails(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New() error = %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, topic, event_type, aggregate_id, payload, correlation_id")).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(pendingColumns).
+			AddRow("msg-1", "orders.events", "order.created", "order-1", []byte(`{}`), nil))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE outbox_messages SET attempts")).
+		WithArgs("msg-1", "broker unavailable").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	relay := &Relay{db: db, pub: &fakePublisher{err: errors.New("broker unavailable")}, logger: zap.NewNop(), batchSize: 1}
+
+	// The failed message is still pending; retrying it in a loop would only spin until the
+	// shutdown deadline.
+	if err := relay.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
 func TestRelay_StartAndStop_RunsAndExitsCleanly(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -5,6 +5,7 @@ package resilience
 import (
 	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -50,14 +51,28 @@ type Config struct {
 // metrics labeled by name.
 type Breaker struct {
 	name string
-	cb   *gobreaker.CircuitBreaker[any]
+	cb   atomic.Pointer[gobreaker.CircuitBreaker[any]]
 }
 
 // NewBreaker builds a Breaker configured by cfg.
 func NewBreaker(cfg Config) *Breaker {
+	b := &Breaker{name: cfg.Name}
+	b.cb.Store(newCircuitBreaker(cfg))
+	return b
+}
+
+// Reconfigure applies new thresholds and timeouts to b, keeping its name. gobreaker settings are
+// fixed at construction, so the underlying breaker is replaced and starts over closed with no
+// recorded failures; calls already running finish against the old one.
+func (b *Breaker) Reconfigure(cfg Config) {
+	cfg.Name = b.name
+	b.cb.Store(newCircuitBreaker(cfg))
+}
+
+func newCircuitBreaker(cfg Config) *gobreaker.CircuitBreaker[any] {
 	breakerState.WithLabelValues(cfg.Name).Set(float64(gobreaker.StateClosed))
 
-	cb := gobreaker.NewCircuitBreaker[any](gobreaker.Settings{
+	return gobreaker.NewCircuitBreaker[any](gobreaker.Settings{
 		Name:        cfg.Name,
 		MaxRequests: 1,
 		Interval:    cfg.Window,
@@ -76,19 +91,17 @@ func NewBreaker(cfg Config) *Breaker {
 			breakerState.WithLabelValues(name).Set(float64(to))
 		},
 	})
-
-	return &Breaker{name: cfg.Name, cb: cb}
 }
 
 // State returns the breaker's current state.
 func (b *Breaker) State() gobreaker.State {
-	return b.cb.State()
+	return b.cb.Load().State()
 }
 
 // Execute runs fn if the circuit allows it. If the circuit is open, fn does not run and Execute
 // returns ErrOpen wrapped with the breaker's name.
 func Execute[T any](b *Breaker, fn func() (T, error)) (T, error) {
-	result, err := b.cb.Execute(func() (any, error) {
+	result, err := b.cb.Load().Execute(func() (any, error) {
 		return fn()
 	})
 	if err != nil {
@@ -2,6 +2,7 @@ package resilience
 
 import (
 	stderrors "errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -131,3 +132,34 @@ func TestExecute_PreservesUnderlyingError(t *testing.T) {
 		t.Error("Execute() error should not be ErrOpen while the circuit is closed")
 	}
 }
+
+func TestBreaker_ReconfigureAppliesNewThreshold(t *testing.T) {
+	b := NewBreaker(Config{Name: t.Name(), FailureThreshold: 1})
+	if _, err := Execute(b, fail); !stderrors.Is(err, errBoom) {
+		t.Fatalf("Execute() error = %v, want errBoom", err)
+	}
+	if b.State() != gobreaker.StateOpen {
+		t.Fatalf("State() = %v, want open", b.State())
+	}
+
+	b.Reconfigure(Config{FailureThreshold: 3})
+
+	if b.State() != gobreaker.StateClosed {
+		t.Errorf("State() after Reconfigure = %v, want closed", b.State())
+	}
+	for i := 0; i < 2; i++ {
+		_, _ = Execute(b, fail)
+	}
+	if b.State() != gobreaker.StateClosed {
+		t.Errorf("State() after 2 failures = %v, want closed under the new threshold of 3", b.State())
+	}
+	if _, err := Execute(b, fail); !stderrors.Is(err, errBoom) {
+		t.Fatalf("Execute() error = %v, want errBoom", err)
+	}
+	if b.State() != gobreaker.StateOpen {
+		t.Errorf("State() after 3 failures = %v, want open", b.State())
+	}
+	if _, err := Execute(b, succeed); !stderrors.Is(err, ErrOpen) || !strings.Contains(err.Error(), t.Name()) {
+		t.Errorf("Execute() error = %v, want ErrOpen naming the original breaker", err)
+	}
+}
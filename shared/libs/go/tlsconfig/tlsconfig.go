@@ -0,0 +1,201 @@
+// Package tlsconfig builds server and client TLS configurations for traffic between services from
+// config.TLSConfig, and keeps the certificates they use current as the files on disk are rotated.
+package tlsconfig
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/config"
+	"go.uber.org/zap"
+)
+
+// Reloader holds the certificate, key and CA bundle named by a config.TLSConfig and re-reads them
+// from disk, so that configurations built from it pick up rotated files on the next handshake.
+type Reloader struct {
+	cfg    config.TLSConfig
+	logger *zap.Logger
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+	pool *x509.CertPool
+	// raw is the file contents the current certificate and pool were parsed from, so a reload
+	// that finds nothing changed does not reparse or log.
+	raw [][]byte
+}
+
+// Load reads the files cfg names. It returns nil and no error when cfg configures no TLS at all,
+// so the result can be handed straight to options where nil means plaintext.
+func Load(cfg config.TLSConfig, logger *zap.Logger) (*Reloader, error) {
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	r := &Reloader{cfg: cfg, logger: logger}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload re-reads the files and swaps in what they hold. It reports whether anything changed. On
+// an error, such as a certificate that does not match its key, the previous files stay in use.
+func (r *Reloader) Reload() (bool, error) {
+	raw := make([][]byte, 3)
+	for i, path := range []string{r.cfg.CertFile, r.cfg.KeyFile, r.cfg.CAFile} {
+		if path == "" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return false, fmt.Errorf("read %s: %w", path, err)
+		}
+		raw[i] = data
+	}
+
+	r.mu.RLock()
+	unchanged := r.raw != nil && bytes.Equal(raw[0], r.raw[0]) && bytes.Equal(raw[1], r.raw[1]) && bytes.Equal(raw[2], r.raw[2])
+	r.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	var cert *tls.Certificate
+	if raw[0] != nil {
+		pair, err := tls.X509KeyPair(raw[0], raw[1])
+		if err != nil {
+			return false, fmt.Errorf("load TLS key pair %s: %w", r.cfg.CertFile, err)
+		}
+		cert = &pair
+	}
+	var pool *x509.CertPool
+	if raw[2] != nil {
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(raw[2]) {
+			return false, fmt.Errorf("no certificates found in CA file %s", r.cfg.CAFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert, r.pool, r.raw = cert, pool, raw
+	r.mu.Unlock()
+	return true, nil
+}
+
+// Run reloads the files every ReloadInterval until ctx is cancelled. It returns at once when
+// reloading is turned off.
+func (r *Reloader) Run(ctx context.Context) {
+	if r.cfg.ReloadInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(r.cfg.ReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := r.Reload()
+			if err != nil {
+				r.logger.Error("Failed to reload TLS files, keeping the previous ones", zap.Error(err))
+				continue
+			}
+			if changed {
+				r.logger.Info("Reloaded TLS files", zap.String("cert_file", r.cfg.CertFile), zap.String("ca_file", r.cfg.CAFile))
+			}
+		}
+	}
+}
+
+// ServerConfig returns a server configuration that serves the current certificate. When a CA is
+// configured, clients must present a certificate it signed; the check runs against the CA bundle
+// as of each handshake, so a rotated CA applies to new connections straight away.
+func (r *Reloader) ServerConfig() *tls.Config {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			if cert := r.certificate(); cert != nil {
+				return cert, nil
+			}
+			return nil, errors.New("no server certificate configured")
+		},
+	}
+	if r.cfg.CAFile != "" {
+		// Verification is done in VerifyConnection rather than through ClientCAs, which is fixed
+		// for the life of the config and would not see a rotated CA.
+		cfg.ClientAuth = tls.RequireAnyClientCert
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			return r.verifyClient(cs.PeerCertificates)
+		}
+	}
+	return cfg
+}
+
+// ClientConfig returns a client configuration that presents the current certificate, if one is
+// configured, and verifies servers against the CA bundle, or the system roots without one. The
+// CA bundle is read when ClientConfig is called, so a client only trusts a rotated CA once it is
+// rebuilt; serve the new and old CA side by side until then.
+func (r *Reloader) ClientConfig() *tls.Config {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: r.cfg.ServerName,
+	}
+
+	r.mu.RLock()
+	cfg.RootCAs = r.pool
+	r.mu.RUnlock()
+
+	if r.cfg.CertFile != "" {
+		cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			if cert := r.certificate(); cert != nil {
+				return cert, nil
+			}
+			return &tls.Certificate{}, nil
+		}
+	}
+	return cfg
+}
+
+func (r *Reloader) certificate() *tls.Certificate {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert
+}
+
+func (r *Reloader) verifyClient(chain []*x509.Certificate) error {
+	if len(chain) == 0 {
+		return errors.New("client certificate required")
+	}
+
+	r.mu.RLock()
+	pool := r.pool
+	r.mu.RUnlock()
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := chain[0].Verify(x509.VerifyOptions{
+		Roots:         pool,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return fmt.Errorf("verify client certificate: %w", err)
+	}
+	return nil
+}
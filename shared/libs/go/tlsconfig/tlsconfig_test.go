@@ -0,0 +1,230 @@
+package tlsconfig_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/config"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/httpclient"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/httpserver"
+	"github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/tlsconfig"
+	"go.uber.org/zap/zaptest"
+)
+
+// testCA issues certificates for the tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate CA key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "eventflow test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create CA certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse CA certificate: %v", err)
+	}
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a PEM certificate and key for name, usable by a server and a client.
+func (ca *testCA) issue(t *testing.T, name string, serial int64) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+// writeTLS writes a certificate for name and the CA bundle into dir and returns the config
+// naming them.
+func writeTLS(t *testing.T, dir string, ca *testCA, name string) config.TLSConfig {
+	t.Helper()
+	certPEM, keyPEM := ca.issue(t, name, 2)
+	cfg := config.TLSConfig{
+		CertFile:   filepath.Join(dir, name+".crt"),
+		KeyFile:    filepath.Join(dir, name+".key"),
+		CAFile:     filepath.Join(dir, "ca.crt"),
+		ServerName: "order",
+	}
+	writeFile(t, cfg.CertFile, certPEM)
+	writeFile(t, cfg.KeyFile, keyPEM)
+	writeFile(t, cfg.CAFile, ca.pem)
+	return cfg
+}
+
+func startServer(t *testing.T, reloader *tlsconfig.Reloader) string {
+	t.Helper()
+	srv := httpserver.New(httpserver.Options{
+		Addr: "127.0.0.1:0",
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Proto", r.Proto)
+			w.WriteHeader(http.StatusOK)
+		}),
+		TLS: reloader,
+	})
+	go func() { _ = srv.Start() }()
+	t.Cleanup(func() { _ = srv.Stop(context.Background()) })
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if addr := srv.Addr(); addr != "127.0.0.1:0" {
+			return addr
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("server did not start listening in time")
+	return ""
+}
+
+func TestLoad_NothingConfigured(t *testing.T) {
+	r, err := tlsconfig.Load(config.TLSConfig{}, zaptest.NewLogger(t))
+	if err != nil || r != nil {
+		t.Errorf("Load() = %v, %v, want nil, nil", r, err)
+	}
+}
+
+func TestLoad_MismatchedKeyFails(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	cfg := writeTLS(t, dir, ca, "order")
+	_, otherKey := ca.issue(t, "order", 3)
+	writeFile(t, cfg.KeyFile, otherKey)
+
+	if _, err := tlsconfig.Load(cfg, zaptest.NewLogger(t)); err == nil {
+		t.Error("Load() with a key that does not match the certificate, want an error")
+	}
+}
+
+func TestMutualTLS_OverHTTP2(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	serverTLS, err := tlsconfig.Load(writeTLS(t, dir, ca, "order"), zaptest.NewLogger(t))
+	if err != nil {
+		t.Fatalf("Load() server error = %v", err)
+	}
+	clientTLS, err := tlsconfig.Load(writeTLS(t, dir, ca, "inventory"), zaptest.NewLogger(t))
+	if err != nil {
+		t.Fatalf("Load() client error = %v", err)
+	}
+	addr := startServer(t, serverTLS)
+
+	client := &http.Client{Transport: httpclient.NewTransport(clientTLS), Timeout: 2 * time.Second}
+	resp, err := client.Get("https://" + addr + "/")
+	if err != nil {
+		t.Fatalf("GET with a client certificate: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("X-Proto") != "HTTP/2.0" {
+		t.Errorf("GET = %d over %s, want 200 over HTTP/2.0", resp.StatusCode, resp.Header.Get("X-Proto"))
+	}
+
+	// Trusting the CA but presenting no certificate is not enough.
+	noCert, err := tlsconfig.Load(config.TLSConfig{CAFile: filepath.Join(dir, "ca.crt"), ServerName: "order"}, zaptest.NewLogger(t))
+	if err != nil {
+		t.Fatalf("Load() CA-only error = %v", err)
+	}
+	client = &http.Client{Transport: httpclient.NewTransport(noCert), Timeout: 2 * time.Second}
+	if resp, err := client.Get("https://" + addr + "/"); err == nil {
+		_ = resp.Body.Close()
+		t.Error("GET without a client certificate succeeded, want the handshake rejected")
+	}
+}
+
+func TestReload_PicksUpRotatedCertificate(t *testing.T) {
+	dir := t.TempDir()
+	oldCA := newTestCA(t)
+	cfg := writeTLS(t, dir, oldCA, "order")
+	cfg.CAFile = ""
+	reloader, err := tlsconfig.Load(cfg, zaptest.NewLogger(t))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	addr := startServer(t, reloader)
+
+	newCA := newTestCA(t)
+	caFile := filepath.Join(dir, "new-ca.crt")
+	writeFile(t, caFile, newCA.pem)
+	trustsNew, err := tlsconfig.Load(config.TLSConfig{CAFile: caFile, ServerName: "order"}, zaptest.NewLogger(t))
+	if err != nil {
+		t.Fatalf("Load() client error = %v", err)
+	}
+	client := &http.Client{Transport: httpclient.NewTransport(trustsNew), Timeout: 2 * time.Second}
+
+	if resp, err := client.Get("https://" + addr + "/"); err == nil {
+		_ = resp.Body.Close()
+		t.Fatal("GET before rotation succeeded, want the old certificate rejected")
+	}
+
+	certPEM, keyPEM := newCA.issue(t, "order", 4)
+	writeFile(t, cfg.CertFile, certPEM)
+	writeFile(t, cfg.KeyFile, keyPEM)
+	if changed, err := reloader.Reload(); err != nil || !changed {
+		t.Fatalf("Reload() = %v, %v, want true, nil", changed, err)
+	}
+
+	client.CloseIdleConnections()
+	resp, err := client.Get("https://" + addr + "/")
+	if err != nil {
+		t.Fatalf("GET after rotation: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	if changed, err := reloader.Reload(); err != nil || changed {
+		t.Errorf("Reload() of unchanged files = %v, %v, want false, nil", changed, err)
+	}
+}
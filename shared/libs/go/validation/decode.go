@@ -0,0 +1,269 @@
+package validation
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	apperrors "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/errors"
+)
+
+// DefaultMaxBodyBytes caps a request body when DecodeOptions.MaxBytes is zero.
+const DefaultMaxBodyBytes int64 = 1 << 20
+
+// DecodeOptions controls how Decode reads a request body.
+type DecodeOptions struct {
+	// MaxBytes caps the body; a larger one is rejected with 413. Zero uses DefaultMaxBodyBytes.
+	MaxBytes int64
+
+	// AllowEmpty accepts an empty body, leaving dst as it was, for endpoints whose body is optional.
+	AllowEmpty bool
+}
+
+// DecodeJSON is Decode with the default options.
+func DecodeJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+	return Decode(w, r, dst, DecodeOptions{})
+}
+
+// Decode reads the JSON request body into dst, a pointer to a struct, and validates it with
+// Struct. Every failure is returned as an *errors.AppError ready to be written as a response:
+//
+//   - a body larger than the limit is PAYLOAD_TOO_LARGE (413);
+//   - malformed JSON, an empty body or trailing data after the value is BAD_REQUEST;
+//   - a field the struct does not declare, a value of the wrong JSON type and any broken
+//     `validate` rule are VALIDATION_ERROR, with the offending fields listed by JSON path.
+func Decode(w http.ResponseWriter, r *http.Request, dst any, opts DecodeOptions) error {
+	limit := opts.MaxBytes
+	if limit <= 0 {
+		limit = DefaultMaxBodyBytes
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+	if err != nil {
+		return decodeError(err, body, dst, limit)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(dst); err != nil {
+		if !(opts.AllowEmpty && errors.Is(err, io.EOF)) {
+			return decodeError(err, body, dst, limit)
+		}
+	} else if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		if err != nil {
+			return decodeError(err, body, dst, limit)
+		}
+		return apperrors.NewBadRequest("request body must contain a single JSON value")
+	}
+
+	return Struct(dst)
+}
+
+// decodeError translates an error from reading body into dst into the AppError reported to the
+// client.
+func decodeError(err error, body []byte, dst any, limit int64) error {
+	var tooLarge *http.MaxBytesError
+	var typeErr *json.UnmarshalTypeError
+
+	if errors.As(err, &tooLarge) {
+		return apperrors.NewPayloadTooLarge(fmt.Sprintf("request body must not exceed %d bytes", limit))
+	}
+
+	// encoding/json names the field behind a type error by its dotted path without slice indexes,
+	// and an unknown field only by its bare name in the error message, so the offending value is
+	// located again by walking the body against dst's type.
+	if errors.As(err, &typeErr) || strings.HasPrefix(err.Error(), "json: unknown field ") {
+		if field, ok := locate(body, reflect.TypeOf(dst)); ok && field.Field != "" {
+			return apperrors.NewValidationError(field.Field, field.Message)
+		}
+	}
+	return apperrors.NewBadRequest("invalid request body")
+}
+
+// locate returns the first value in body, in document order, that encoding/json would reject
+// when decoding into t: a field t does not declare or a value of the wrong JSON type. Its path is
+// written like the ones Struct reports, such as "items[2].quantity". ok is false when body holds
+// no such value or is not valid JSON.
+func locate(body []byte, t reflect.Type) (field apperrors.FieldError, ok bool) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	field, ok, err := locateValue(dec, t, "")
+	if err != nil {
+		return apperrors.FieldError{}, false
+	}
+	return field, ok
+}
+
+var (
+	jsonUnmarshalerType = reflect.TypeFor[json.Unmarshaler]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// locateValue reads the next value from dec, which is decoded into t at path, and reports the
+// first field in it that does not fit t. Values whose type decodes itself are skipped, since only
+// their own unmarshaler knows what it accepts.
+func locateValue(dec *json.Decoder, t reflect.Type, path string) (apperrors.FieldError, bool, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Interface || reflect.PointerTo(t).Implements(jsonUnmarshalerType) ||
+		reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return apperrors.FieldError{}, false, skipValue(dec)
+	}
+
+	tok, err := dec.Token()
+	if err != nil {
+		return apperrors.FieldError{}, false, err
+	}
+	mismatch := apperrors.FieldError{Field: path, Message: "must be " + jsonType(t)}
+
+	switch tok := tok.(type) {
+	case json.Delim:
+		switch {
+		case tok == '{' && t.Kind() == reflect.Struct:
+			return locateFields(dec, t, path)
+		case tok == '{' && t.Kind() == reflect.Map:
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return apperrors.FieldError{}, false, err
+				}
+				if field, ok, err := locateValue(dec, t.Elem(), join(path, fmt.Sprint(key))); ok || err != nil {
+					return field, ok, err
+				}
+			}
+		case tok == '[' && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array):
+			for i := 0; dec.More(); i++ {
+				if field, ok, err := locateValue(dec, t.Elem(), fmt.Sprintf("%s[%d]", path, i)); ok || err != nil {
+					return field, ok, err
+				}
+			}
+		default:
+			return mismatch, true, nil
+		}
+		_, err := dec.Token()
+		return apperrors.FieldError{}, false, err
+	case string:
+		return mismatch, t.Kind() != reflect.String, nil
+	case bool:
+		return mismatch, t.Kind() != reflect.Bool, nil
+	case json.Number:
+		return mismatch, !fitsNumber(tok, t), nil
+	default:
+		// null decodes into anything.
+		return apperrors.FieldError{}, false, nil
+	}
+}
+
+// locateFields reads the members of a JSON object decoded into the struct type t, after its
+// opening brace, and reports the first unknown or mistyped one.
+func locateFields(dec *json.Decoder, t reflect.Type, path string) (apperrors.FieldError, bool, error) {
+	fields := jsonFields(t, nil)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return apperrors.FieldError{}, false, err
+		}
+		key, _ := tok.(string)
+
+		field, known := fields[key]
+		if !known {
+			for name, f := range fields {
+				if strings.EqualFold(name, key) {
+					field, known = f, true
+					break
+				}
+			}
+		}
+		if !known {
+			return apperrors.FieldError{Field: join(path, key), Message: "is not a known field"}, true, nil
+		}
+
+		if found, ok, err := locateValue(dec, field.Type, join(path, key)); ok || err != nil {
+			return found, ok, err
+		}
+	}
+	_, err := dec.Token()
+	return apperrors.FieldError{}, false, err
+}
+
+// jsonFields maps the names the struct type t is decoded from to its fields, including those
+// promoted from embedded structs without a name of their own.
+func jsonFields(t reflect.Type, fields map[string]reflect.StructField) map[string]reflect.StructField {
+	if fields == nil {
+		fields = make(map[string]reflect.StructField)
+	}
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name, ok := jsonName(field)
+		if !ok || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+		if name == "" {
+			embedded := field.Type
+			for embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				jsonFields(embedded, fields)
+			}
+			continue
+		}
+		if _, taken := fields[name]; !taken {
+			fields[name] = field
+		}
+	}
+	return fields
+}
+
+// fitsNumber reports whether the JSON number n decodes into t without a type error.
+func fitsNumber(n json.Number, t reflect.Type) bool {
+	var err error
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		_, err = strconv.ParseInt(n.String(), 10, t.Bits())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		_, err = strconv.ParseUint(n.String(), 10, t.Bits())
+	case reflect.Float32, reflect.Float64:
+		_, err = strconv.ParseFloat(n.String(), t.Bits())
+	default:
+		return false
+	}
+	return err == nil
+}
+
+// skipValue reads past the next value in dec.
+func skipValue(dec *json.Decoder) error {
+	var raw json.RawMessage
+	return dec.Decode(&raw)
+}
+
+// jsonType describes the JSON value a Go type decodes from.
+func jsonType(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
@@ -0,0 +1,109 @@
+package validation
+
+import (
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	apperrors "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/errors"
+)
+
+type testPayment struct {
+	OrderID     string `json:"order_id" validate:"required,uuid"`
+	AmountCents int64  `json:"amount_cents" validate:"gt=0"`
+}
+
+func decode(body string, opts DecodeOptions) (testPayment, error) {
+	var dst testPayment
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	err := Decode(httptest.NewRecorder(), r, &dst, opts)
+	return dst, err
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		opts       DecodeOptions
+		wantStatus int
+		wantCode   string
+		wantField  string
+	}{
+		{name: "valid", body: `{"order_id":"` + validID + `","amount_cents":100}`},
+		{name: "malformed", body: `{`, wantStatus: http.StatusBadRequest, wantCode: "BAD_REQUEST"},
+		{name: "empty", body: ``, wantStatus: http.StatusBadRequest, wantCode: "BAD_REQUEST"},
+		{name: "empty allowed", body: ``, opts: DecodeOptions{AllowEmpty: true}, wantStatus: http.StatusBadRequest, wantCode: "VALIDATION_ERROR", wantField: "order_id"},
+		{name: "trailing data", body: `{"order_id":"` + validID + `","amount_cents":1} {}`, wantStatus: http.StatusBadRequest, wantCode: "BAD_REQUEST"},
+		{name: "unknown field", body: `{"order_id":"` + validID + `","amount_cents":1,"extra":true}`, wantStatus: http.StatusBadRequest, wantCode: "VALIDATION_ERROR", wantField: "extra"},
+		{name: "wrong type", body: `{"order_id":"` + validID + `","amount_cents":"ten"}`, wantStatus: http.StatusBadRequest, wantCode: "VALIDATION_ERROR", wantField: "amount_cents"},
+		{name: "rule broken", body: `{"order_id":"nope","amount_cents":1}`, wantStatus: http.StatusBadRequest, wantCode: "VALIDATION_ERROR", wantField: "order_id"},
+		{name: "too large", body: `{"order_id":"` + validID + `","amount_cents":1}`, opts: DecodeOptions{MaxBytes: 10}, wantStatus: http.StatusRequestEntityTooLarge, wantCode: "PAYLOAD_TOO_LARGE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decode(tt.body, tt.opts)
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Fatalf("Decode() error = %v, want nil", err)
+				}
+				return
+			}
+
+			var appErr *apperrors.AppError
+			if !stderrors.As(err, &appErr) {
+				t.Fatalf("Decode() error = %v, want *AppError", err)
+			}
+			if appErr.Code != tt.wantCode || appErr.HTTPCode != tt.wantStatus {
+				t.Errorf("Decode() error = %s/%d, want %s/%d", appErr.Code, appErr.HTTPCode, tt.wantCode, tt.wantStatus)
+			}
+			if tt.wantField != "" && (len(appErr.Fields) == 0 || appErr.Fields[0].Field != tt.wantField) {
+				t.Errorf("Decode() fields = %+v, want %s first", appErr.Fields, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestDecodeJSON_FillsDestination(t *testing.T) {
+	got, err := decode(`{"order_id":"`+validID+`","amount_cents":250}`, DecodeOptions{})
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if got.OrderID != validID || got.AmountCents != 250 {
+		t.Errorf("Decode() = %+v, want order %s and 250 cents", got, validID)
+	}
+}
+
+func TestDecode_ReportsIndexedPaths(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantField   string
+		wantMessage string
+	}{
+		{name: "wrong type in item", body: `{"items":[{"quantity":1},{"quantity":"two"}]}`, wantField: "items[1].quantity", wantMessage: "must be an integer"},
+		{name: "fraction in integer", body: `{"items":[{"quantity":1.5}]}`, wantField: "items[0].quantity", wantMessage: "must be an integer"},
+		{name: "unknown field in item", body: `{"items":[{"quantity":1},{},{"qty":2}]}`, wantField: "items[2].qty", wantMessage: "is not a known field"},
+		{name: "object instead of array", body: `{"items":{"quantity":1}}`, wantField: "items", wantMessage: "must be an array"},
+		{name: "wrong type in nested object", body: `{"address":{"country":3}}`, wantField: "address.country", wantMessage: "must be a string"},
+		{name: "first problem wins", body: `{"items":[{"extra":1}],"unknown":true}`, wantField: "items[0].extra", wantMessage: "is not a known field"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var dst testOrder
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			err := DecodeJSON(httptest.NewRecorder(), r, &dst)
+
+			var appErr *apperrors.AppError
+			if !stderrors.As(err, &appErr) || appErr.Code != "VALIDATION_ERROR" {
+				t.Fatalf("DecodeJSON() error = %v, want VALIDATION_ERROR", err)
+			}
+			if len(appErr.Fields) != 1 || appErr.Fields[0].Field != tt.wantField || appErr.Fields[0].Message != tt.wantMessage {
+				t.Errorf("DecodeJSON() fields = %+v, want %s %q", appErr.Fields, tt.wantField, tt.wantMessage)
+			}
+		})
+	}
+}
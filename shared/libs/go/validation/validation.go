@@ -0,0 +1,309 @@
+// Package validation checks decoded request bodies and reports every violation at once, as an
+// *errors.AppError whose Fields carry the JSON path of each invalid value.
+//
+// Rules are declared with a `validate` struct tag, separated by commas:
+//
+//	type itemRequest struct {
+//		ProductID string `json:"product_id" validate:"required,uuid"`
+//		Quantity  int    `json:"quantity" validate:"gt=0"`
+//	}
+//
+// Nested structs and slices of structs are checked too, so a bad quantity in the third item is
+// reported as "items[2].quantity". Checks that do not fit a tag can be collected by hand with
+// Violations.
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	apperrors "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/errors"
+	"github.com/google/uuid"
+)
+
+// Violations collects field errors found by hand-written checks. The zero value is ready to use.
+type Violations []apperrors.FieldError
+
+// Add records that the value at the JSON path field is invalid.
+func (v *Violations) Add(field, message string) {
+	*v = append(*v, apperrors.FieldError{Field: field, Message: message})
+}
+
+// Check records a violation of field unless ok holds.
+func (v *Violations) Check(ok bool, field, message string) {
+	if !ok {
+		v.Add(field, message)
+	}
+}
+
+// Err returns every recorded violation as a single validation *errors.AppError, or nil when there
+// are none.
+func (v Violations) Err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return apperrors.NewValidationErrors(v...)
+}
+
+// Struct checks v, a struct or a pointer to one, against its `validate` tags. It returns nil when
+// every rule holds and a validation *errors.AppError listing all violations otherwise. An unknown
+// rule or a malformed rule argument is a programming error and panics.
+func Struct(v any) error {
+	var violations Violations
+	walk(reflect.ValueOf(v), "", &violations)
+	return violations.Err()
+}
+
+// walk validates the fields of the struct in v, reporting paths below prefix, and descends into
+// nested structs and slices.
+func walk(v reflect.Value, prefix string, violations *Violations) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := range t.NumField() {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, ok := jsonName(field)
+			if !ok {
+				continue
+			}
+			value := v.Field(i)
+			if field.Anonymous && name == "" {
+				walk(value, prefix, violations)
+				continue
+			}
+
+			path := join(prefix, name)
+			if tag := field.Tag.Get("validate"); tag != "" {
+				checkRules(value, tag, path, t.Name()+"."+field.Name, violations)
+			}
+			walk(value, path, violations)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := range v.Len() {
+			walk(v.Index(i), fmt.Sprintf("%s[%d]", prefix, i), violations)
+		}
+	}
+}
+
+// jsonName returns the name field is encoded under. An embedded struct without a name of its own
+// returns "", meaning its fields are promoted; ok is false for fields that are never encoded.
+func jsonName(field reflect.StructField) (name string, ok bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	name, _, _ = strings.Cut(tag, ",")
+	if name != "" {
+		return name, true
+	}
+	if field.Anonymous {
+		return "", true
+	}
+	return field.Name, true
+}
+
+func join(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+// checkRules applies every rule in tag to value, recording at most one violation for path: the
+// first rule that fails. owner names the field in panics about malformed tags.
+func checkRules(value reflect.Value, tag, path, owner string, violations *Violations) {
+	for rule := range strings.SplitSeq(tag, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		check, ok := rules[name]
+		if !ok {
+			panic(fmt.Sprintf("validation: unknown rule %q on %s", name, owner))
+		}
+		if message := check(value, arg, owner); message != "" {
+			violations.Add(path, message)
+			return
+		}
+	}
+}
+
+// ruleFunc reports why value breaks a rule with argument arg, or "" when it holds.
+type ruleFunc func(value reflect.Value, arg, owner string) string
+
+var rules = map[string]ruleFunc{
+	"required": required,
+	"uuid":     isUUID,
+	"min":      bound(">=", "at least"),
+	"max":      bound("<=", "at most"),
+	"gt":       bound(">", "greater than"),
+	"len":      exactLen,
+	"oneof":    oneOf,
+}
+
+func required(value reflect.Value, _, _ string) string {
+	if isEmpty(value) {
+		return "is required"
+	}
+	return ""
+}
+
+// isUUID accepts an empty string, which is left to the required rule.
+func isUUID(value reflect.Value, _, owner string) string {
+	s := stringValue(value, "uuid", owner)
+	if s == "" {
+		return ""
+	}
+	if _, err := uuid.Parse(s); err != nil {
+		return "must be a valid uuid"
+	}
+	return ""
+}
+
+// bound builds the min, max and gt rules. Numbers are compared by value, strings by length in
+// characters and slices and maps by number of items. Empty strings pass, which is left to the
+// required rule; empty slices and zero numbers are checked, so `min=1` on a list also rejects an
+// empty one.
+func bound(op, phrase string) ruleFunc {
+	return func(value reflect.Value, arg, owner string) string {
+		limit, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			panic(fmt.Sprintf("validation: rule argument %q on %s is not a number", arg, owner))
+		}
+
+		value = indirect(value)
+		if !value.IsValid() {
+			return ""
+		}
+
+		var got float64
+		var unit string
+		switch value.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			got = float64(value.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			got = float64(value.Uint())
+		case reflect.Float32, reflect.Float64:
+			got = value.Float()
+		case reflect.String:
+			if value.Len() == 0 {
+				return ""
+			}
+			got = float64(utf8.RuneCountInString(value.String()))
+			unit = "character"
+		case reflect.Slice, reflect.Array, reflect.Map:
+			got = float64(value.Len())
+			unit = "item"
+		default:
+			panic(fmt.Sprintf("validation: rule %s does not apply to %s (%s)", op, owner, value.Kind()))
+		}
+
+		var ok bool
+		switch op {
+		case ">=":
+			ok = got >= limit
+		case "<=":
+			ok = got <= limit
+		case ">":
+			ok = got > limit
+		}
+		if ok {
+			return ""
+		}
+		if unit != "" {
+			return fmt.Sprintf("must have %s %s", phrase, count(arg, unit))
+		}
+		return fmt.Sprintf("must be %s %s", phrase, arg)
+	}
+}
+
+func exactLen(value reflect.Value, arg, owner string) string {
+	want, err := strconv.Atoi(arg)
+	if err != nil {
+		panic(fmt.Sprintf("validation: rule argument %q on %s is not an integer", arg, owner))
+	}
+
+	value = indirect(value)
+	if !value.IsValid() {
+		return ""
+	}
+	switch value.Kind() {
+	case reflect.String:
+		if value.Len() == 0 || utf8.RuneCountInString(value.String()) == want {
+			return ""
+		}
+		return "must be exactly " + count(arg, "character")
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if value.Len() == want {
+			return ""
+		}
+		return "must have exactly " + count(arg, "item")
+	default:
+		panic(fmt.Sprintf("validation: rule len does not apply to %s (%s)", owner, value.Kind()))
+	}
+}
+
+// oneOf accepts an empty string, which is left to the required rule. Allowed values are
+// separated by spaces.
+func oneOf(value reflect.Value, arg, owner string) string {
+	s := stringValue(value, "oneof", owner)
+	if s == "" {
+		return ""
+	}
+	allowed := strings.Fields(arg)
+	for _, a := range allowed {
+		if s == a {
+			return ""
+		}
+	}
+	return "must be one of: " + strings.Join(allowed, ", ")
+}
+
+// count renders n units, pluralising unit unless n is 1.
+func count(n, unit string) string {
+	if n == "1" {
+		return n + " " + unit
+	}
+	return n + " " + unit + "s"
+}
+
+func isEmpty(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Slice, reflect.Map, reflect.String, reflect.Array:
+		return value.Len() == 0
+	default:
+		return !value.IsValid() || value.IsZero()
+	}
+}
+
+// indirect follows pointers, returning the zero Value for a nil one.
+func indirect(value reflect.Value) reflect.Value {
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return reflect.Value{}
+		}
+		value = value.Elem()
+	}
+	return value
+}
+
+func stringValue(value reflect.Value, rule, owner string) string {
+	value = indirect(value)
+	if !value.IsValid() {
+		return ""
+	}
+	if value.Kind() != reflect.String {
+		panic(fmt.Sprintf("validation: rule %s does not apply to %s (%s)", rule, owner, value.Kind()))
+	}
+	return value.String()
+}
@@ -0,0 +1,120 @@
+package validation
+
+import (
+	stderrors "errors"
+	"reflect"
+	"testing"
+
+	apperrors "github.com/Kalaganov-Konstantin/eventflow-commerce/shared/libs/go/errors"
+)
+
+type testItem struct {
+	ProductID string `json:"product_id" validate:"required,uuid"`
+	Quantity  int    `json:"quantity" validate:"gt=0,max=100"`
+}
+
+type testAddress struct {
+	Country string `json:"country" validate:"required,len=2"`
+}
+
+type testOrder struct {
+	Items    []testItem   `json:"items" validate:"min=1"`
+	Currency string       `json:"currency" validate:"len=3"`
+	Priority string       `json:"priority,omitempty" validate:"oneof=low high"`
+	Note     string       `json:"note" validate:"max=5"`
+	Address  *testAddress `json:"address"`
+	Ignored  string       `json:"-" validate:"required"`
+}
+
+const validID = "5f0c6a8e-3d2b-4b9e-9f3e-2a1d6c7b8e90"
+
+func fieldsOf(t *testing.T, err error) []apperrors.FieldError {
+	t.Helper()
+	var appErr *apperrors.AppError
+	if !stderrors.As(err, &appErr) {
+		t.Fatalf("error = %v, want *AppError", err)
+	}
+	if appErr.Code != "VALIDATION_ERROR" {
+		t.Errorf("Code = %q, want VALIDATION_ERROR", appErr.Code)
+	}
+	return appErr.Fields
+}
+
+func TestStruct_Valid(t *testing.T) {
+	order := testOrder{
+		Items:    []testItem{{ProductID: validID, Quantity: 2}},
+		Currency: "USD",
+		Priority: "high",
+		Address:  &testAddress{Country: "DE"},
+	}
+
+	if err := Struct(&order); err != nil {
+		t.Errorf("Struct() error = %v, want nil", err)
+	}
+}
+
+func TestStruct_CollectsEveryViolationWithPaths(t *testing.T) {
+	order := testOrder{
+		Items: []testItem{
+			{ProductID: validID, Quantity: 1},
+			{ProductID: "", Quantity: 1},
+			{ProductID: "not-a-uuid", Quantity: 0},
+		},
+		Currency: "US",
+		Priority: "urgent",
+		Note:     "too long",
+		Address:  &testAddress{},
+	}
+
+	got := fieldsOf(t, Struct(order))
+
+	want := []apperrors.FieldError{
+		{Field: "items[1].product_id", Message: "is required"},
+		{Field: "items[2].product_id", Message: "must be a valid uuid"},
+		{Field: "items[2].quantity", Message: "must be greater than 0"},
+		{Field: "currency", Message: "must be exactly 3 characters"},
+		{Field: "priority", Message: "must be one of: low, high"},
+		{Field: "note", Message: "must have at most 5 characters"},
+		{Field: "address.country", Message: "is required"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Struct() fields =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestStruct_EmptySliceFailsMin(t *testing.T) {
+	got := fieldsOf(t, Struct(testOrder{}))
+
+	if len(got) != 1 || got[0].Field != "items" || got[0].Message != "must have at least 1 item" {
+		t.Errorf("Struct() fields = %+v, want only items rejected", got)
+	}
+}
+
+func TestStruct_UnknownRulePanics(t *testing.T) {
+	type bad struct {
+		Name string `json:"name" validate:"email"`
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Struct() did not panic on an unknown rule")
+		}
+	}()
+	_ = Struct(bad{})
+}
+
+func TestViolations(t *testing.T) {
+	var v Violations
+	if err := v.Err(); err != nil {
+		t.Errorf("Err() with no violations = %v, want nil", err)
+	}
+
+	v.Check(true, "a", "never recorded")
+	v.Check(false, "items[0].quantity", "must be greater than zero")
+	v.Add("currency", "is required")
+
+	got := fieldsOf(t, v.Err())
+	if len(got) != 2 || got[0].Field != "items[0].quantity" || got[1].Field != "currency" {
+		t.Errorf("Err() fields = %+v, want items[0].quantity and currency", got)
+	}
+}
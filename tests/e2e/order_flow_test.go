@@ -16,8 +16,9 @@ type createOrderResponse struct {
 }
 
 type orderResponse struct {
-	ID     string `json:"id"`
-	Status string `json:"status"`
+	ID               string `json:"id"`
+	Status           string `json:"status"`
+	TotalAmountCents int64  `json:"total_amount_cents"`
 }
 
 type inventoryResponse struct {
@@ -44,7 +45,7 @@ func TestE2E_OrderFlowConfirmsAndNotifies(t *testing.T) {
 
 	createReq := map[string]any{
 		"currency": "USD",
-		"items":    []any{orderItem(productID, orderQuantity, 1999)},
+		"items":    []any{orderItem(productID, orderQuantity)},
 	}
 
 	var created createOrderResponse
@@ -67,6 +68,10 @@ func TestE2E_OrderFlowConfirmsAndNotifies(t *testing.T) {
 		return s == http.StatusOK && order.Status == "confirmed"
 	})
 
+	if want := int64(orderQuantity * seededPriceCents); order.TotalAmountCents != want {
+		t.Errorf("total_amount_cents = %d, want %d priced from the catalog", order.TotalAmountCents, want)
+	}
+
 	notificationClient := newAPIClient(notificationURL(), "").withHeader("X-User-ID", customerID.String())
 	waitFor(t, 30*time.Second, "a notification for the confirmed order", func() bool {
 		var notifications []notificationResponse
@@ -110,7 +115,7 @@ func TestE2E_OrderForOutOfStockProductReturns409(t *testing.T) {
 
 	createReq := map[string]any{
 		"currency": "USD",
-		"items":    []any{orderItem(productID, 1, 999)},
+		"items":    []any{orderItem(productID, 1)},
 	}
 
 	status := client.do(t, http.MethodPost, "/api/v1/orders", createReq, nil)
@@ -123,3 +128,29 @@ func TestE2E_OrderForOutOfStockProductReturns409(t *testing.T) {
 		t.Errorf("quantity_reserved = %d, want 0: a rejected order must not reserve anything", reserved)
 	}
 }
+
+// TestE2E_OrderWithTamperedPriceReturns400 sends a unit price below the catalog's and expects the
+// order to be rejected before any stock is reserved.
+func TestE2E_OrderWithTamperedPriceReturns400(t *testing.T) {
+	db := openInventoryDB(t)
+	productID := seedProductWithStock(t, db, 5)
+
+	client := newAPIClient(gatewayURL(), issueJWT(t, uuid.New()))
+
+	item := orderItem(productID, 1)
+	item["unit_price_cents"] = 1
+	createReq := map[string]any{
+		"currency": "USD",
+		"items":    []any{item},
+	}
+
+	status := client.do(t, http.MethodPost, "/api/v1/orders", createReq, nil)
+	if status != http.StatusBadRequest {
+		t.Fatalf("create order with a tampered price status = %d, want %d", status, http.StatusBadRequest)
+	}
+
+	_, reserved := stockOf(t, db, productID)
+	if reserved != 0 {
+		t.Errorf("quantity_reserved = %d, want 0: a rejected order must not reserve anything", reserved)
+	}
+}
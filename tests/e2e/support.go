@@ -59,6 +59,9 @@ func openInventoryDB(t *testing.T) *sql.DB {
 	return db
 }
 
+// seededPriceCents is the catalog price of every product seedProductWithStock inserts.
+const seededPriceCents = 2999
+
 // seedProductWithStock inserts a product and its inventory row with the given available
 // quantity, returning the product id.
 func seedProductWithStock(t *testing.T, db *sql.DB, available int) uuid.UUID {
@@ -71,7 +74,7 @@ func seedProductWithStock(t *testing.T, db *sql.DB, available int) uuid.UUID {
 	if _, err := db.ExecContext(ctx, `
 		INSERT INTO products (id, name, sku, price_cents, currency, is_active, created_at, updated_at, version)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-	`, productID, "E2E Test Widget", "E2E-"+productID.String(), 2999, "USD", true, now, now, 1); err != nil {
+	`, productID, "E2E Test Widget", "E2E-"+productID.String(), seededPriceCents, "USD", true, now, now, 1); err != nil {
 		t.Fatalf("insert product: %v", err)
 	}
 
@@ -217,12 +220,11 @@ func waitFor(t *testing.T, timeout time.Duration, msg string, cond func() bool)
 }
 
 // orderItem builds a create-order request item without importing the order service's own
-// request type.
-func orderItem(productID uuid.UUID, quantity int, unitPriceCents int64) map[string]any {
+// request type. It leaves the name, SKU and price out: the order service takes them from the
+// catalog.
+func orderItem(productID uuid.UUID, quantity int) map[string]any {
 	return map[string]any{
-		"product_id":       productID.String(),
-		"product_name":     "E2E Test Widget",
-		"quantity":         quantity,
-		"unit_price_cents": unitPriceCents,
+		"product_id": productID.String(),
+		"quantity":   quantity,
 	}
 }
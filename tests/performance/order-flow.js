@@ -75,9 +75,7 @@ export default function () {
     items: [
       {
         product_id: PRODUCT_ID,
-        product_name: 'Load Test Widget',
         quantity: 1,
-        unit_price_cents: 999,
       },
     ],
   });